@@ -26,6 +26,9 @@ var MinioSecure string
 var MinioBucket string
 var MinioBasePath string
 var MinioLocation string
+var MinioRequireContentMD5 bool
+var MinioRestartExpiredUploads bool
+var MinioPartUrlExpireSeconds int64
 
 
 func loadFromConfigFile(configFilePath string)error{
@@ -57,6 +60,9 @@ func loadFromConfigFile(configFilePath string)error{
 	MinioBucket = jsonConfig.Get("MINIO_BUCKET").ToString()
 	MinioBasePath = jsonConfig.Get("MINIO_BASE_PATH").ToString()
 	MinioLocation = jsonConfig.Get("MINIO_LOCATION").ToString()
+	MinioRequireContentMD5 = jsonConfig.Get("MINIO_REQUIRE_CONTENT_MD5").ToBool()
+	MinioRestartExpiredUploads = jsonConfig.Get("MINIO_RESTART_EXPIRED_UPLOADS").ToBool()
+	MinioPartUrlExpireSeconds = jsonConfig.Get("MINIO_PART_URL_EXPIRE_SECONDS").ToInt64()
 
 	if MysqlIp == "" || MysqlUsername == "" || MysqlPassword == "" || MysqlPort == "" || PORT == "" || MysqlDbName == "" || MinioAddress == "" || MinioAccessKeyId == "" || keyTmp == "" || MinioSecure == "" {
 		return errors.New("config is error")
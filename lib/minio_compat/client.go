@@ -0,0 +1,210 @@
+package minio_compat
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"oss/lib/minio_ext"
+
+	miniov6 "github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
+)
+
+// Client - drop-in replacement for the minio-go v6 client. PutObject,
+// FPutObject and FGetObject and their context variants run in resumable
+// upload and download sessions, all other methods are served by the
+// embedded minio-go client unchanged.
+type Client struct {
+	*miniov6.Client
+
+	ext *minio_ext.Client
+
+	// Optional store to resume interrupted FPutObject uploads, even
+	// after a restart of the process.
+	States minio_ext.StateStore
+
+	// Base options of every upload, fields set by PutObjectOptions
+	// take precedence.
+	Upload minio_ext.UploadOptions
+
+	// Base options of every FGetObject download.
+	Download minio_ext.DownloadOptions
+
+	// Readers of PutObject which are not seekable or of unknown size are
+	// spooled part by part, up to SpoolMemory bytes in memory and into
+	// temporary files in SpoolDir beyond that.
+	SpoolMemory int64
+	SpoolDir    string
+}
+
+// New - instantiate a new client, takes the same arguments as the New
+// of minio-go.
+func New(endpoint, accessKeyID, secretAccessKey string, secure bool) (*Client, error) {
+	client, err := miniov6.New(endpoint, accessKeyID, secretAccessKey, secure)
+	if err != nil {
+		return nil, err
+	}
+	ext, err := minio_ext.New(endpoint, minio_ext.WithCredentials(accessKeyID, secretAccessKey), minio_ext.WithSecure(secure))
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Client: client, ext: ext}, nil
+}
+
+// Ext - returns the underlying resumable client.
+func (c *Client) Ext() *minio_ext.Client {
+	return c.ext
+}
+
+// resumable - can an upload with opts run in an upload session. SSE-C
+// needs the key on every part which sessions do not send.
+func resumable(opts miniov6.PutObjectOptions) bool {
+	return opts.ServerSideEncryption == nil || opts.ServerSideEncryption.Type() != encrypt.SSEC
+}
+
+// uploadOptions - maps minio-go put options onto upload options.
+func (c *Client) uploadOptions(opts miniov6.PutObjectOptions) minio_ext.UploadOptions {
+	uploadOpts := c.Upload
+	uploadOpts.Metadata = opts.Header()
+	if opts.PartSize > 0 {
+		uploadOpts.PartSize = int64(opts.PartSize)
+		uploadOpts.AdaptivePartSize = false
+	}
+	if opts.NumThreads > 0 {
+		uploadOpts.NumThreads = int(opts.NumThreads)
+	}
+	if opts.Progress != nil {
+		uploadOpts.Progress = progressFunc(opts.Progress, c.Upload.Progress)
+	}
+	return uploadOpts
+}
+
+// progressBuf - read by progress readers, only its length matters.
+var progressBuf = make([]byte, 32*1024)
+
+// progressFunc - returns a progress callback feeding a minio-go style
+// progress reader, which counts the bytes read from it.
+func progressFunc(progress io.Reader, next func(n int64)) func(n int64) {
+	var mutex sync.Mutex
+	return func(n int64) {
+		mutex.Lock()
+		for left := n; left > 0; {
+			chunk := int64(len(progressBuf))
+			if left < chunk {
+				chunk = left
+			}
+			progress.Read(progressBuf[:chunk])
+			left -= chunk
+		}
+		mutex.Unlock()
+		if next != nil {
+			next(n)
+		}
+	}
+}
+
+// PutObject - same as PutObject of minio-go, see PutObjectWithContext.
+func (c *Client) PutObject(bucketName, objectName string, reader io.Reader, objectSize int64,
+	opts miniov6.PutObjectOptions) (n int64, err error) {
+	return c.PutObjectWithContext(context.Background(), bucketName, objectName, reader, objectSize, opts)
+}
+
+// PutObjectWithContext - uploads reader in an upload session. Readers
+// implementing io.ReaderAt are read in place, other readers and readers
+// of unknown size are streamed and spooled part by part so failed parts
+// can be sent again.
+func (c *Client) PutObjectWithContext(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64,
+	opts miniov6.PutObjectOptions) (n int64, err error) {
+	if objectSize == 0 || !resumable(opts) {
+		return c.Client.PutObjectWithContext(ctx, bucketName, objectName, reader, objectSize, opts)
+	}
+
+	readerAt, ok := reader.(io.ReaderAt)
+	if !ok || objectSize < 0 {
+		if objectSize > 0 {
+			reader = io.LimitReader(reader, objectSize)
+		}
+		counter := &countingReader{reader: reader}
+		_, err = c.ext.UploadStream(ctx, counter, bucketName, objectName, minio_ext.StreamOptions{
+			Upload:      c.uploadOptions(opts),
+			MemoryLimit: c.SpoolMemory,
+			SpoolDir:    c.SpoolDir,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return counter.n, nil
+	}
+
+	session, err := c.ext.NewUploadSession(ctx, bucketName, objectName, readerAt, objectSize, c.uploadOptions(opts))
+	if err != nil {
+		return 0, err
+	}
+	if _, err = session.Upload(ctx); err != nil {
+		return session.Uploaded(), err
+	}
+	return objectSize, nil
+}
+
+// countingReader - counts the bytes read from a stream.
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+// Read - reads and counts data.
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// FPutObject - same as FPutObject of minio-go, see
+// FPutObjectWithContext.
+func (c *Client) FPutObject(bucketName, objectName, filePath string, opts miniov6.PutObjectOptions) (n int64, err error) {
+	return c.FPutObjectWithContext(context.Background(), bucketName, objectName, filePath, opts)
+}
+
+// FPutObjectWithContext - uploads the file filePath in an upload
+// session. With States set an interrupted upload of the same file is
+// resumed.
+func (c *Client) FPutObjectWithContext(ctx context.Context, bucketName, objectName, filePath string, opts miniov6.PutObjectOptions) (n int64, err error) {
+	st, err := os.Stat(filePath)
+	if err != nil {
+		return 0, err
+	}
+	if st.Size() == 0 || !resumable(opts) {
+		return c.Client.FPutObjectWithContext(ctx, bucketName, objectName, filePath, opts)
+	}
+	if _, err = c.ext.UploadFile(ctx, filePath, bucketName, objectName, c.States, c.uploadOptions(opts)); err != nil {
+		return 0, err
+	}
+	return st.Size(), nil
+}
+
+// FGetObject - same as FGetObject of minio-go, see
+// FGetObjectWithContext.
+func (c *Client) FGetObject(bucketName, objectName, filePath string, opts miniov6.GetObjectOptions) error {
+	return c.FGetObjectWithContext(context.Background(), bucketName, objectName, filePath, opts)
+}
+
+// FGetObjectWithContext - downloads an object into filePath in a
+// download session, an interrupted download of the same object version
+// is resumed. Options with headers, such as ranges or SSE-C keys, are
+// served by minio-go.
+func (c *Client) FGetObjectWithContext(ctx context.Context, bucketName, objectName, filePath string, opts miniov6.GetObjectOptions) error {
+	if len(opts.Header()) > 0 {
+		return c.Client.FGetObjectWithContext(ctx, bucketName, objectName, filePath, opts)
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0777); err != nil {
+		return err
+	}
+	session, err := c.ext.NewDownloadSession(ctx, bucketName, objectName, filePath, c.Download)
+	if err != nil {
+		return err
+	}
+	return session.Download(ctx)
+}
@@ -0,0 +1,72 @@
+package minio_ext
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// AccountingRecord - byte counts of a completed upload session for
+// billing, emitted once through UploadOptions.Accounting.
+type AccountingRecord struct {
+	BucketName string
+	ObjectName string
+	UploadID   string
+
+	// Size of the source.
+	LogicalBytes int64
+
+	// Part data sent to the server by this session, including attempts
+	// which failed and had to be repeated. Parts found on the server
+	// when the session was resumed are not included.
+	TransferredBytes int64
+
+	// Part data found on the server when the session was resumed.
+	ResumedBytes int64
+
+	// Bytes newly stored on the server, zero when the upload was
+	// skipped because the same content already existed.
+	StorageBytes int64
+
+	// Deduplicated is set when the upload was skipped entirely.
+	Deduplicated bool
+}
+
+// countingReader - counts the bytes read from a seekable reader,
+// re-reads after seeking are counted again as they are sent again.
+type countingReader struct {
+	reader io.ReadSeeker
+	count  *int64
+}
+
+// Read - reads and counts data.
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	atomic.AddInt64(r.count, int64(n))
+	return n, err
+}
+
+// Seek - seeks the underlying reader.
+func (r countingReader) Seek(offset int64, whence int) (int64, error) {
+	return r.reader.Seek(offset, whence)
+}
+
+// account - emits the accounting record of the session.
+func (s *UploadSession) account() {
+	if s.opts.Accounting == nil {
+		return
+	}
+	record := AccountingRecord{
+		BucketName:       s.bucketName,
+		ObjectName:       s.objectName,
+		UploadID:         s.uploadID,
+		LogicalBytes:     s.size,
+		TransferredBytes: atomic.LoadInt64(&s.transferred),
+		ResumedBytes:     s.resumed,
+		StorageBytes:     s.size,
+	}
+	if s.existing != nil {
+		record.StorageBytes = 0
+		record.Deduplicated = true
+	}
+	s.opts.Accounting(record)
+}
@@ -0,0 +1,141 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// UploadCorsRuleID - id of the rule installed by SetUploadBucketCors.
+const UploadCorsRuleID = "breakpoint-upload"
+
+// SetBucketCors - replaces the CORS rules of bucketName with rules, no
+// rules remove the CORS configuration. Note that MinIO serves CORS on
+// its own and answers NotImplemented, the rules are for S3 and other
+// servers implementing bucket CORS.
+func (c Client) SetBucketCors(ctx context.Context, bucketName string, rules []CorsRule) error {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return err
+	}
+
+	urlValues := make(url.Values)
+	urlValues.Set("cors", "")
+
+	if len(rules) == 0 {
+		resp, err := c.executeMethod(ctx, "DELETE", requestMetadata{
+			bucketName:       bucketName,
+			queryValues:      urlValues,
+			contentSHA256Hex: emptySHA256Hex,
+		})
+		defer closeResponse(resp)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+			return httpRespToErrorResponse(resp, bucketName, "")
+		}
+		return nil
+	}
+
+	corsBytes, err := xml.Marshal(corsConfiguration{Rules: rules})
+	if err != nil {
+		return err
+	}
+	// PutBucketCors requires Content-MD5.
+	md5Sum := md5.Sum(corsBytes)
+	resp, err := c.executeMethod(ctx, "PUT", requestMetadata{
+		bucketName:       bucketName,
+		queryValues:      urlValues,
+		contentBody:      bytes.NewReader(corsBytes),
+		contentLength:    int64(len(corsBytes)),
+		contentMD5Base64: base64.StdEncoding.EncodeToString(md5Sum[:]),
+		contentSHA256Hex: sum256Hex(corsBytes),
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return httpRespToErrorResponse(resp, bucketName, "")
+	}
+	return nil
+}
+
+// GetBucketCors - returns the CORS rules of bucketName, none when the
+// bucket has no CORS configuration.
+func (c Client) GetBucketCors(ctx context.Context, bucketName string) ([]CorsRule, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return nil, err
+	}
+
+	urlValues := make(url.Values)
+	urlValues.Set("cors", "")
+
+	resp, err := c.executeMethod(ctx, "GET", requestMetadata{
+		bucketName:       bucketName,
+		queryValues:      urlValues,
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		if ToErrorResponse(err).Code == "NoSuchCORSConfiguration" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = httpRespToErrorResponse(resp, bucketName, "")
+		if ToErrorResponse(err).Code == "NoSuchCORSConfiguration" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var config corsConfiguration
+	if err = xmlDecoder(resp.Body, &config); err != nil {
+		return nil, err
+	}
+	return config.Rules, nil
+}
+
+// DefaultUploadCorsRules - returns the rules browsers need to PUT parts
+// to presigned URLs and read objects from origins, any origin when
+// none is given. ETag is exposed so the browser can read the ETag of
+// uploaded parts.
+func DefaultUploadCorsRules(origins ...string) []CorsRule {
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+	return []CorsRule{{
+		ID:             UploadCorsRuleID,
+		AllowedOrigins: origins,
+		AllowedMethods: []string{"PUT", "GET", "HEAD"},
+		AllowedHeaders: []string{"*"},
+		ExposeHeaders:  []string{"ETag", "x-amz-request-id"},
+		MaxAgeSeconds:  3000,
+	}}
+}
+
+// SetUploadBucketCors - installs DefaultUploadCorsRules for origins on
+// bucketName. Rules of the bucket with other ids are kept, an earlier
+// upload rule is replaced.
+func (c Client) SetUploadBucketCors(ctx context.Context, bucketName string, origins ...string) error {
+	existing, err := c.GetBucketCors(ctx, bucketName)
+	if err != nil {
+		return err
+	}
+	var rules []CorsRule
+	for _, rule := range existing {
+		if rule.ID != UploadCorsRuleID {
+			rules = append(rules, rule)
+		}
+	}
+	return c.SetBucketCors(ctx, bucketName, append(rules, DefaultUploadCorsRules(origins...)...))
+}
@@ -0,0 +1,195 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// UploadLifecycleRuleID - id of the rule installed by
+// SetAbortIncompleteUploads.
+const UploadLifecycleRuleID = "breakpoint-upload-abort-incomplete"
+
+// PutBucketLifecycle - replaces the lifecycle rules of bucketName with
+// rules, no rules remove the lifecycle configuration.
+func (c Client) PutBucketLifecycle(ctx context.Context, bucketName string, rules []LifecycleRule) error {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.Status != "Enabled" && rule.Status != "Disabled" {
+			return ErrInvalidArgument("Lifecycle rule ‘" + rule.ID + "’ must have status ‘Enabled’ or ‘Disabled’.")
+		}
+		if rule.ExpirationDays < 0 || rule.AbortIncompleteUploadDays < 0 {
+			return ErrInvalidArgument("Lifecycle rule ‘" + rule.ID + "’ cannot have negative days.")
+		}
+	}
+	if len(rules) == 0 {
+		return c.putBucketLifecycle(ctx, bucketName, nil)
+	}
+	lifecycleBytes, err := xml.Marshal(lifecycleConfiguration{Rules: rules})
+	if err != nil {
+		return err
+	}
+	return c.putBucketLifecycle(ctx, bucketName, lifecycleBytes)
+}
+
+// putBucketLifecycle - sends the lifecycle configuration
+// lifecycleBytes of bucketName, removes it when empty.
+func (c Client) putBucketLifecycle(ctx context.Context, bucketName string, lifecycleBytes []byte) error {
+	urlValues := make(url.Values)
+	urlValues.Set("lifecycle", "")
+
+	if len(lifecycleBytes) == 0 {
+		resp, err := c.executeMethod(ctx, "DELETE", requestMetadata{
+			bucketName:       bucketName,
+			queryValues:      urlValues,
+			contentSHA256Hex: emptySHA256Hex,
+		})
+		defer closeResponse(resp)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+			return httpRespToErrorResponse(resp, bucketName, "")
+		}
+		return nil
+	}
+
+	// PutBucketLifecycle requires Content-MD5.
+	md5Sum := md5.Sum(lifecycleBytes)
+	resp, err := c.executeMethod(ctx, "PUT", requestMetadata{
+		bucketName:       bucketName,
+		queryValues:      urlValues,
+		contentBody:      bytes.NewReader(lifecycleBytes),
+		contentLength:    int64(len(lifecycleBytes)),
+		contentMD5Base64: base64.StdEncoding.EncodeToString(md5Sum[:]),
+		contentSHA256Hex: sum256Hex(lifecycleBytes),
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return httpRespToErrorResponse(resp, bucketName, "")
+	}
+	return nil
+}
+
+// GetBucketLifecycle - returns the lifecycle rules of bucketName, none
+// when the bucket has no lifecycle configuration.
+func (c Client) GetBucketLifecycle(ctx context.Context, bucketName string) ([]LifecycleRule, error) {
+	lifecycleBytes, err := c.getBucketLifecycle(ctx, bucketName)
+	if err != nil || lifecycleBytes == nil {
+		return nil, err
+	}
+	var config lifecycleConfiguration
+	if err = xmlDecoder(bytes.NewReader(lifecycleBytes), &config); err != nil {
+		return nil, err
+	}
+	return config.Rules, nil
+}
+
+// getBucketLifecycle - returns the lifecycle configuration of
+// bucketName as sent by the server, nil when there is none.
+func (c Client) getBucketLifecycle(ctx context.Context, bucketName string) ([]byte, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return nil, err
+	}
+
+	urlValues := make(url.Values)
+	urlValues.Set("lifecycle", "")
+
+	resp, err := c.executeMethod(ctx, "GET", requestMetadata{
+		bucketName:       bucketName,
+		queryValues:      urlValues,
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		if ToErrorResponse(err).Code == "NoSuchLifecycleConfiguration" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = httpRespToErrorResponse(resp, bucketName, "")
+		if ToErrorResponse(err).Code == "NoSuchLifecycleConfiguration" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// SetAbortIncompleteUploads - installs a rule on bucketName aborting
+// multipart uploads under prefix days after they were initiated, so
+// the server drops the parts of uploads nobody resumes. Rules of the
+// bucket with other ids are kept unchanged, an earlier upload rule is
+// replaced. Uploads are resumable for less than days after they
+// started.
+func (c Client) SetAbortIncompleteUploads(ctx context.Context, bucketName, prefix string, days int) error {
+	if days <= 0 {
+		return ErrInvalidArgument("Days must be positive.")
+	}
+	existing, err := c.getBucketLifecycle(ctx, bucketName)
+	if err != nil {
+		return err
+	}
+	config := rawLifecycleConfiguration{}
+	if existing != nil {
+		if err = xmlDecoder(bytes.NewReader(existing), &config); err != nil {
+			return err
+		}
+	}
+	uploadRule, err := lifecycleRuleXML(LifecycleRule{
+		ID:                        UploadLifecycleRuleID,
+		Status:                    "Enabled",
+		Prefix:                    prefix,
+		AbortIncompleteUploadDays: days,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Rules of others are sent back as received, so actions and
+	// filters LifecycleRule does not describe are kept.
+	rules := []rawLifecycleRule{}
+	for _, rule := range config.Rules {
+		var id struct {
+			ID string
+		}
+		if err = xml.Unmarshal([]byte("<Rule>"+rule.Inner+"</Rule>"), &id); err != nil {
+			return err
+		}
+		if strings.TrimSpace(id.ID) != UploadLifecycleRuleID {
+			rules = append(rules, rule)
+		}
+	}
+	config.Rules = append(rules, rawLifecycleRule{Inner: uploadRule})
+	lifecycleBytes, err := xml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return c.putBucketLifecycle(ctx, bucketName, lifecycleBytes)
+}
+
+// lifecycleRuleXML - returns the elements of rule inside its Rule
+// element.
+func lifecycleRuleXML(rule LifecycleRule) (string, error) {
+	var ruleBytes bytes.Buffer
+	if err := xml.NewEncoder(&ruleBytes).EncodeElement(rule, xml.StartElement{Name: xml.Name{Local: "Rule"}}); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(ruleBytes.String(), "<Rule>"), "</Rule>"), nil
+}
@@ -0,0 +1,168 @@
+package minio_ext
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lifecycleServer - test server keeping the lifecycle configuration of
+// a bucket in memory.
+type lifecycleServer struct {
+	mutex  sync.Mutex
+	config string
+}
+
+// ServeHTTP - answers lifecycle requests.
+func (s *lifecycleServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["lifecycle"]; !ok {
+		testResponse{http.StatusBadRequest, "InvalidRequest", ""}.write(w)
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	switch r.Method {
+	case "GET":
+		if s.config == "" {
+			testResponse{http.StatusNotFound, "NoSuchLifecycleConfiguration", ""}.write(w)
+			return
+		}
+		w.Write([]byte(s.config))
+	case "PUT":
+		if r.Header.Get("Content-Md5") == "" {
+			testResponse{http.StatusBadRequest, "MissingContentMD5", ""}.write(w)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			testResponse{http.StatusBadRequest, "IncompleteBody", ""}.write(w)
+			return
+		}
+		s.config = string(body)
+	case "DELETE":
+		s.config = ""
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestBucketLifecycleRoundTrip(t *testing.T) {
+	testCases := []struct {
+		rules      []LifecycleRule
+		shouldPass bool
+	}{
+		{[]LifecycleRule{{ID: "expire", Status: "Enabled", Prefix: "tmp/", ExpirationDays: 7}}, true},
+		{[]LifecycleRule{
+			{ID: "expire", Status: "Disabled", Prefix: "logs/", ExpirationDays: 30},
+			{ID: "abort", Status: "Enabled", AbortIncompleteUploadDays: 2},
+		}, true},
+		{nil, true},
+		{[]LifecycleRule{{ID: "status", Status: "enabled"}}, false},
+		{[]LifecycleRule{{ID: "days", Status: "Enabled", ExpirationDays: -1}}, false},
+	}
+	server := &lifecycleServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	c := newTestClient(t, ts.URL)
+	ctx := WithRequestRegion(context.Background(), "us-east-1")
+
+	for i, testCase := range testCases {
+		err := c.PutBucketLifecycle(ctx, "bucket", testCase.rules)
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: expected to pass, failed with %v", i+1, err)
+			continue
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: expected to fail, passed", i+1)
+			continue
+		}
+		if err != nil {
+			if ToErrorResponse(err).Code != "InvalidArgument" {
+				t.Errorf("Test %d: expected InvalidArgument, got %v", i+1, err)
+			}
+			continue
+		}
+		// Disabled actions are left out, S3 rejects empty ones.
+		if strings.Contains(server.config, "<Expiration></Expiration>") || strings.Contains(server.config, "<AbortIncompleteMultipartUpload></AbortIncompleteMultipartUpload>") {
+			t.Errorf("Test %d: unexpected empty action in %s", i+1, server.config)
+		}
+		rules, err := c.GetBucketLifecycle(ctx, "bucket")
+		if err != nil {
+			t.Errorf("Test %d: %v", i+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(rules, testCase.rules) {
+			t.Errorf("Test %d: expected rules %+v, got %+v", i+1, testCase.rules, rules)
+		}
+	}
+}
+
+func TestSetAbortIncompleteUploads(t *testing.T) {
+	// A rule LifecycleRule cannot describe, sent back as is.
+	foreign := "<ID>archive</ID><Filter><And><Prefix>media/</Prefix><Tag><Key>tier</Key><Value>cold</Value></Tag></And></Filter>" +
+		"<Status>Enabled</Status><Transition><Days>30</Days><StorageClass>GLACIER</StorageClass></Transition>"
+	upload := func(prefix, days string) string {
+		return "<Rule><ID>" + UploadLifecycleRuleID + "</ID><Status>Enabled</Status><Filter><Prefix>" + prefix + "</Prefix></Filter>" +
+			"<AbortIncompleteMultipartUpload><DaysAfterInitiation>" + days + "</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule>"
+	}
+	testCases := []struct {
+		name     string
+		config   string
+		prefix   string
+		days     int
+		expected string
+	}{
+		{"no configuration", "", "uploads/", 3,
+			"<LifecycleConfiguration>" + upload("uploads/", "3") + "</LifecycleConfiguration>"},
+		{"keeps other rules", "<LifecycleConfiguration><Rule>" + foreign + "</Rule></LifecycleConfiguration>", "", 1,
+			"<LifecycleConfiguration><Rule>" + foreign + "</Rule>" + upload("", "1") + "</LifecycleConfiguration>"},
+		{"replaces the upload rule", "<LifecycleConfiguration>" + upload("old/", "9") + "<Rule>" + foreign + "</Rule></LifecycleConfiguration>", "new/", 2,
+			"<LifecycleConfiguration><Rule>" + foreign + "</Rule>" + upload("new/", "2") + "</LifecycleConfiguration>"},
+		{"no days", "", "", 0, ""},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := &lifecycleServer{config: testCase.config}
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+			c := newTestClient(t, ts.URL)
+			ctx := WithRequestRegion(context.Background(), "us-east-1")
+
+			err := c.SetAbortIncompleteUploads(ctx, "bucket", testCase.prefix, testCase.days)
+			if testCase.expected == "" {
+				if ToErrorResponse(err).Code != "InvalidArgument" {
+					t.Errorf("Expected InvalidArgument, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if server.config != testCase.expected {
+				t.Errorf("Expected configuration\n%s\ngot\n%s", testCase.expected, server.config)
+			}
+
+			// The rules read back include the upload rule once.
+			rules, err := c.GetBucketLifecycle(ctx, "bucket")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var found int
+			for _, rule := range rules {
+				if rule.ID == UploadLifecycleRuleID {
+					found++
+					if rule.Prefix != testCase.prefix || rule.AbortIncompleteUploadDays != testCase.days {
+						t.Errorf("Unexpected upload rule %+v", rule)
+					}
+				}
+			}
+			if found != 1 || strings.Count(server.config, "<Rule>") != len(rules) {
+				t.Errorf("Expected one upload rule, got %+v", rules)
+			}
+		})
+	}
+}
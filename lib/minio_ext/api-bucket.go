@@ -0,0 +1,117 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// createBucketConfiguration container for MakeBucket request.
+type createBucketConfiguration struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CreateBucketConfiguration"`
+	Location string   `xml:"LocationConstraint"`
+}
+
+// MakeBucket - creates bucketName in location, the region of the
+// client or us-east-1 when empty. The location is cached, requests on
+// the new bucket do not look it up.
+func (c Client) MakeBucket(ctx context.Context, bucketName, location string) error {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return err
+	}
+	if location == "" {
+		location = c.region
+	}
+	if location == "" {
+		location = "us-east-1"
+	}
+
+	reqMetadata := requestMetadata{
+		bucketName:       bucketName,
+		bucketLocation:   location,
+		contentSHA256Hex: emptySHA256Hex,
+	}
+	// us-east-1 is the default, other locations are sent as
+	// LocationConstraint.
+	if location != "us-east-1" {
+		configBytes, err := xml.Marshal(createBucketConfiguration{Location: location})
+		if err != nil {
+			return err
+		}
+		reqMetadata.contentBody = bytes.NewReader(configBytes)
+		reqMetadata.contentLength = int64(len(configBytes))
+		reqMetadata.contentSHA256Hex = sum256Hex(configBytes)
+	}
+
+	// Execute PUT to create a new bucket.
+	resp, err := c.executeMethod(ctx, "PUT", reqMetadata)
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+	if resp != nil {
+		if resp.StatusCode != http.StatusOK {
+			return httpRespToErrorResponse(resp, bucketName, "")
+		}
+	}
+
+	// Save the location into cache on a successful makeBucket response.
+	c.bucketLocCache.Set(bucketName, location)
+	return nil
+}
+
+// BucketExists - reports whether bucketName exists, errors other than
+// a missing bucket, like AccessDenied, are returned.
+func (c Client) BucketExists(ctx context.Context, bucketName string) (bool, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return false, err
+	}
+
+	// Execute HEAD on bucketName.
+	resp, err := c.executeMethod(ctx, "HEAD", requestMetadata{
+		bucketName:       bucketName,
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	defer closeResponse(resp)
+	if err == nil && resp != nil && resp.StatusCode != http.StatusOK {
+		err = httpRespToErrorResponse(resp, bucketName, "")
+	}
+	// The location lookup may already find the bucket missing.
+	if errors.Is(err, ErrNoSuchBucket) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RemoveBucket - removes bucketName, which must be empty.
+func (c Client) RemoveBucket(ctx context.Context, bucketName string) error {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return err
+	}
+
+	// Execute DELETE on bucket.
+	resp, err := c.executeMethod(ctx, "DELETE", requestMetadata{
+		bucketName:       bucketName,
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+	if resp != nil {
+		if resp.StatusCode != http.StatusNoContent {
+			return httpRespToErrorResponse(resp, bucketName, "")
+		}
+	}
+
+	// Remove the location from cache on a successful delete.
+	c.bucketLocCache.Delete(bucketName)
+	return nil
+}
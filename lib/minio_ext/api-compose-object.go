@@ -0,0 +1,338 @@
+package minio_ext
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// ComposeSource - an object appended to the destination of
+// ComposeObject.
+type ComposeSource struct {
+	BucketName string
+	ObjectName string
+}
+
+// ComposeOptions - options for ComposeObject.
+type ComposeOptions struct {
+	// Optional headers to store the object with, such as Content-Type
+	// and X-Amz-Meta-* user metadata.
+	Metadata http.Header
+
+	// Optional store the state of the upload is kept in, an interrupted
+	// compose of the same sources is resumed from the parts already
+	// copied. States are keyed by bucket and object name.
+	States StateStore
+
+	// Optional callback invoked with the size of every copied part.
+	Progress func(n int64)
+}
+
+// composePart - a part of a compose, copied from a range of a source.
+type composePart struct {
+	PartState
+	source ComposeSource
+	etag   string
+	start  int64
+	whole  bool
+}
+
+// composeUpload - a running ComposeObject.
+type composeUpload struct {
+	client     *Client
+	bucketName string
+	objectName string
+	opts       ComposeOptions
+	key        string
+	state      UploadState
+	plan       []composePart
+	parts      map[int]ObjectPart
+}
+
+// ComposeObject - concatenates sources in order into
+// bucketName/objectName without downloading them, every source is
+// copied into parts of a multipart upload with UploadPartCopy, returns
+// the ETag of the object. All sources but the last must be at least
+// 5 MiB, larger sources are split into parts of at most 5 GiB.
+//
+// Sources are pinned to the ETag they have when the compose starts.
+// With a state store an interrupted compose is resumed like any
+// breakpoint upload, a state whose sources changed or whose upload id
+// expired is discarded and the compose starts over.
+func (c *Client) ComposeObject(ctx context.Context, bucketName, objectName string, sources []ComposeSource, opts ComposeOptions) (string, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return "", err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return "", err
+	}
+	if len(sources) == 0 {
+		return "", ErrInvalidArgument("There must be at least one source.")
+	}
+	if len(sources) > MaxPartsCount {
+		return "", ErrInvalidArgument(fmt.Sprintf("There cannot be more than %d sources.", MaxPartsCount))
+	}
+	defer c.trackSession()()
+
+	u := &composeUpload{
+		client:     c,
+		bucketName: bucketName,
+		objectName: objectName,
+		opts:       opts,
+		key:        bucketName + "/" + objectName,
+		parts:      make(map[int]ObjectPart),
+	}
+	if err := u.planParts(ctx, sources); err != nil {
+		return "", err
+	}
+	resumed, err := u.resume(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !resumed {
+		if err = u.initiate(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	etag, err := u.copyParts(ctx)
+	if err != nil {
+		if opts.States == nil {
+			// Nothing can resume the upload, drop its parts.
+			c.abortMultipartUpload(context.Background(), bucketName, objectName, u.state.UploadID)
+		} else if !IsUploadExpired(err) {
+			opts.States.Save(u.key, u.currentState())
+		}
+		return "", err
+	}
+	if opts.States != nil {
+		if err = opts.States.Delete(u.key); err != nil {
+			return "", err
+		}
+	}
+	return etag, nil
+}
+
+// planParts - stats the sources and splits them into parts.
+func (u *composeUpload) planParts(ctx context.Context, sources []ComposeSource) error {
+	var offset int64
+	for i, source := range sources {
+		info, err := u.client.statObject(ctx, source.BucketName, source.ObjectName, nil)
+		if err != nil {
+			return err
+		}
+		if i < len(sources)-1 && info.Size < absMinPartSize {
+			return ErrInvalidArgument(fmt.Sprintf("Source ‘%s/%s’ is smaller than %d bytes, only the last source may be.",
+				source.BucketName, source.ObjectName, absMinPartSize))
+		}
+		u.state.Sources = append(u.state.Sources, source.BucketName+"/"+source.ObjectName+"@"+info.ETag)
+
+		// Split evenly, the last part of a source must not be too small.
+		count := partsCount(info.Size, MaxPartSize)
+		for j := 0; j < count; j++ {
+			start := info.Size * int64(j) / int64(count)
+			end := info.Size * int64(j+1) / int64(count)
+			u.plan = append(u.plan, composePart{
+				PartState: PartState{PartNumber: len(u.plan) + 1, Offset: offset + start, Size: end - start},
+				source:    source,
+				etag:      info.ETag,
+				start:     start,
+				whole:     count == 1,
+			})
+		}
+		offset += info.Size
+	}
+	if offset > MaxMultipartPutObjectSize {
+		return ErrEntityTooLarge(offset, MaxMultipartPutObjectSize, u.bucketName, u.objectName)
+	}
+	if len(u.plan) > MaxPartsCount {
+		return ErrInvalidArgument(fmt.Sprintf("Sources result in more than %d parts.", MaxPartsCount))
+	}
+	u.state.Size = offset
+	return nil
+}
+
+// resume - continues the compose recorded in the state store, returns
+// false when there is none to continue.
+func (u *composeUpload) resume(ctx context.Context) (bool, error) {
+	if u.opts.States == nil {
+		return false, nil
+	}
+	state, err := u.opts.States.Load(u.key)
+	if err != nil || state == nil {
+		return false, err
+	}
+	if state.Expired {
+		return false, nil
+	}
+	if !sameSources(state.Sources, u.state.Sources) {
+		// Best effort, drop the parts of an upload started differently.
+		u.client.abortMultipartUpload(ctx, state.BucketName, state.ObjectName, state.UploadID)
+		return false, nil
+	}
+
+	uploaded, err := u.client.ListObjectPartsWithContext(ctx, state.BucketName, state.ObjectName, state.UploadID)
+	if err != nil {
+		if IsUploadExpired(err) {
+			return false, nil
+		}
+		if len(state.Completed) == 0 {
+			return false, err
+		}
+		uploaded = make(map[int]ObjectPart, len(state.Completed))
+		for _, part := range state.Completed {
+			uploaded[part.PartNumber] = ObjectPart{PartNumber: part.PartNumber, ETag: part.ETag, Size: part.Size}
+		}
+	}
+	u.state.UploadID, u.state.Initiated = state.UploadID, state.Initiated
+	for _, spec := range u.plan {
+		if part, ok := uploaded[spec.PartNumber]; ok && part.Size == spec.Size {
+			u.parts[spec.PartNumber] = part
+		}
+	}
+	return true, nil
+}
+
+// sameSources - reports whether two compose states copy the same
+// sources.
+func sameSources(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// initiate - starts the multipart upload of the compose and saves its
+// state.
+func (u *composeUpload) initiate(ctx context.Context) error {
+	initResult, err := u.client.initiateMultipartUpload(ctx, u.bucketName, u.objectName, initiateHeader(UploadOptions{Metadata: u.opts.Metadata}, nil))
+	if err != nil {
+		return err
+	}
+	u.state.UploadID = initResult.UploadID
+	u.state.Initiated = time.Now().UTC()
+	if u.opts.States != nil {
+		if err = u.opts.States.Save(u.key, u.currentState()); err != nil {
+			u.client.abortMultipartUpload(ctx, u.bucketName, u.objectName, initResult.UploadID)
+			return err
+		}
+	}
+	return nil
+}
+
+// currentState - returns the state of the compose with the parts
+// copied so far.
+func (u *composeUpload) currentState() UploadState {
+	state := u.state
+	state.BucketName, state.ObjectName = u.bucketName, u.objectName
+	state.PartSize = MaxPartSize
+	state.Parts = make([]PartState, 0, len(u.plan))
+	for _, spec := range u.plan {
+		state.Parts = append(state.Parts, spec.PartState)
+		if part, ok := u.parts[spec.PartNumber]; ok {
+			state.Completed = append(state.Completed, CompletedPart{
+				PartNumber: part.PartNumber,
+				Size:       part.Size,
+				ETag:       part.ETag,
+			})
+		}
+	}
+	return state
+}
+
+// copyParts - copies the missing parts and completes the upload.
+func (u *composeUpload) copyParts(ctx context.Context) (string, error) {
+	journal, _ := u.opts.States.(PartJournal)
+	for _, spec := range u.plan {
+		if _, ok := u.parts[spec.PartNumber]; ok {
+			continue
+		}
+		part, err := u.client.uploadPartCopy(ctx, u.bucketName, u.objectName, u.state.UploadID, spec)
+		if err != nil {
+			return "", err
+		}
+		u.parts[spec.PartNumber] = part
+		if journal != nil {
+			err = journal.AppendPart(u.key, u.state.UploadID, CompletedPart{
+				PartNumber: part.PartNumber,
+				Size:       part.Size,
+				ETag:       part.ETag,
+			})
+			if err != nil {
+				return "", err
+			}
+		}
+		if u.opts.Progress != nil {
+			u.opts.Progress(part.Size)
+		}
+	}
+
+	complete := make([]CompletePart, 0, len(u.plan))
+	for _, spec := range u.plan {
+		complete = append(complete, CompletePart{
+			PartNumber: spec.PartNumber,
+			ETag:       u.parts[spec.PartNumber].ETag,
+		})
+	}
+	return u.client.CompleteMultipartUploadWithContext(ctx, u.bucketName, u.objectName, u.state.UploadID, complete, nil)
+}
+
+// uploadPartCopy - copies the range of spec from its source into a
+// part of a multipart upload, as long as the source keeps its ETag.
+func (c Client) uploadPartCopy(ctx context.Context, bucketName, objectName, uploadID string, spec composePart) (ObjectPart, error) {
+	// Get resources properly escaped and lined up before using them in http request.
+	urlValues := make(url.Values)
+	urlValues.Set("partNumber", strconv.Itoa(spec.PartNumber))
+	urlValues.Set("uploadId", uploadID)
+
+	customHeader := make(http.Header)
+	customHeader.Set("X-Amz-Copy-Source", s3utils.EncodePath(spec.source.BucketName+"/"+spec.source.ObjectName))
+	customHeader.Set("X-Amz-Copy-Source-If-Match", spec.etag)
+	if !spec.whole {
+		customHeader.Set("X-Amz-Copy-Source-Range", fmt.Sprintf("bytes=%d-%d", spec.start, spec.start+spec.Size-1))
+	}
+
+	// Execute PUT on the part.
+	resp, err := c.executeMethod(ctx, "PUT", requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		queryValues:      urlValues,
+		customHeader:     customHeader,
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return ObjectPart{}, err
+	}
+	if resp != nil {
+		if resp.StatusCode != http.StatusOK {
+			errResp := httpRespToErrorResponse(resp, bucketName, objectName)
+			if ToErrorResponse(errResp).Code == "PreconditionFailed" {
+				return ObjectPart{}, ErrSourceChanged(uploadID, "‘"+spec.source.BucketName+"/"+spec.source.ObjectName+"’ was modified")
+			}
+			return ObjectPart{}, errResp
+		}
+	}
+
+	result, err := decodeCopyResult(resp, bucketName, objectName)
+	if err != nil {
+		return ObjectPart{}, err
+	}
+	return ObjectPart{
+		PartNumber:   spec.PartNumber,
+		ETag:         result.ETag,
+		Size:         spec.Size,
+		LastModified: result.LastModified,
+	}, nil
+}
@@ -0,0 +1,168 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// CopyObjectOptions - options for CopyObject.
+type CopyObjectOptions struct {
+	// Optional headers replacing the metadata of the source, such as
+	// Content-Type and X-Amz-Meta-* user metadata. The metadata of the
+	// source is copied when nil.
+	Metadata http.Header
+
+	// Optional tags replacing the tags of the source, which are copied
+	// when nil.
+	Tags map[string]string
+
+	// Optional conditions on the source, the copy fails with code
+	// "PreconditionFailed" unless all hold.
+	MatchETag       string
+	NoneMatchETag   string
+	ModifiedSince   time.Time
+	UnmodifiedSince time.Time
+}
+
+// CopyObject - copies srcBucketName/srcObjectName to
+// bucketName/objectName on the server in a single request, returns the
+// ETag of the copy. The copy appears atomically, so a completed upload
+// is moved from a staging prefix to its final key with CopyObject,
+// pinned to its ETag with MatchETag, followed by RemoveObject of the
+// source. Sources up to 5 GiB can be copied, larger ones are copied
+// with ComposeObject.
+func (c Client) CopyObject(ctx context.Context, bucketName, objectName, srcBucketName, srcObjectName string, opts CopyObjectOptions) (string, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return "", err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return "", err
+	}
+	if err := s3utils.CheckValidBucketName(srcBucketName); err != nil {
+		return "", err
+	}
+	if err := s3utils.CheckValidObjectName(srcObjectName); err != nil {
+		return "", err
+	}
+
+	customHeader := make(http.Header)
+	customHeader.Set("X-Amz-Copy-Source", s3utils.EncodePath(srcBucketName+"/"+srcObjectName))
+	if opts.Metadata != nil {
+		for k, v := range opts.Metadata {
+			customHeader[k] = v
+		}
+		customHeader.Set("X-Amz-Metadata-Directive", "REPLACE")
+	} else {
+		customHeader.Set("X-Amz-Metadata-Directive", "COPY")
+	}
+	if opts.Tags != nil {
+		tags := make(url.Values)
+		for k, v := range opts.Tags {
+			tags.Set(k, v)
+		}
+		customHeader.Set("X-Amz-Tagging", tags.Encode())
+		customHeader.Set("X-Amz-Tagging-Directive", "REPLACE")
+	} else {
+		customHeader.Set("X-Amz-Tagging-Directive", "COPY")
+	}
+	if opts.MatchETag != "" {
+		customHeader.Set("X-Amz-Copy-Source-If-Match", opts.MatchETag)
+	}
+	if opts.NoneMatchETag != "" {
+		customHeader.Set("X-Amz-Copy-Source-If-None-Match", opts.NoneMatchETag)
+	}
+	if !opts.ModifiedSince.IsZero() {
+		customHeader.Set("X-Amz-Copy-Source-If-Modified-Since", opts.ModifiedSince.UTC().Format(http.TimeFormat))
+	}
+	if !opts.UnmodifiedSince.IsZero() {
+		customHeader.Set("X-Amz-Copy-Source-If-Unmodified-Since", opts.UnmodifiedSince.UTC().Format(http.TimeFormat))
+	}
+
+	// Execute PUT on objectName.
+	resp, err := c.executeMethod(ctx, "PUT", requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		customHeader:     customHeader,
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return "", err
+	}
+	if resp != nil {
+		if resp.StatusCode != http.StatusOK {
+			return "", httpRespToErrorResponse(resp, bucketName, objectName)
+		}
+	}
+
+	result, err := decodeCopyResult(resp, bucketName, objectName)
+	if err != nil {
+		return "", err
+	}
+	return result.ETag, nil
+}
+
+// isCopyError - reports whether res of a CopyObject or UploadPartCopy
+// request carries an error instead of the copy result, which servers
+// may send with status 200 once the copy started. The body is kept for
+// the caller.
+func isCopyError(method string, metadata requestMetadata, res *http.Response) bool {
+	if method != "PUT" || metadata.customHeader.Get("X-Amz-Copy-Source") == "" {
+		return false
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	closeResponse(res)
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return xmlDecoder(bytes.NewReader(body), &ErrorResponse{}) == nil
+}
+
+// decodeCopyResult - decodes the result of CopyObject and
+// UploadPartCopy, an error body sent with status 200 is returned as
+// ErrorResponse.
+func decodeCopyResult(resp *http.Response, bucketName, objectName string) (copyObjectResult, error) {
+	result := copyObjectResult{}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return result, err
+	}
+	errResp := ErrorResponse{}
+	if xmlDecoder(bytes.NewReader(body), &errResp) == nil {
+		errResp.StatusCode = resp.StatusCode
+		if errResp.BucketName == "" {
+			errResp.BucketName, errResp.Key = bucketName, objectName
+		}
+		if errResp.RequestID == "" {
+			errResp.RequestID = resp.Header.Get("x-amz-request-id")
+		}
+		if errResp.HostID == "" {
+			errResp.HostID = resp.Header.Get("x-amz-id-2")
+		}
+		return result, errResp
+	}
+	if err = xmlDecoder(bytes.NewReader(body), &result); err != nil {
+		return result, err
+	}
+	if result.ETag == "" {
+		return result, ErrorResponse{
+			StatusCode: resp.StatusCode,
+			Code:       "InternalError",
+			Message:    "The copy returned no ETag.",
+			BucketName: bucketName,
+			Key:        objectName,
+			RequestID:  resp.Header.Get("x-amz-request-id"),
+			HostID:     resp.Header.Get("x-amz-id-2"),
+		}
+	}
+	result.ETag = trimEtag(result.ETag)
+	return result, nil
+}
@@ -0,0 +1,558 @@
+package minio_ext
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// downloadStateSuffix - suffix of the file next to a download which
+// records its completed ranges.
+const downloadStateSuffix = ".download"
+
+// DownloadOptions - options for NewDownloadSession.
+type DownloadOptions struct {
+	// Size of every range but the last one, defaults to MinPartSize.
+	RangeSize int64
+
+	// Number of ranges fetched in parallel, defaults to 4.
+	NumThreads int
+
+	// Retry policy for individual ranges.
+	RangeRetry PartRetryPolicy
+
+	// Optional callback invoked with the size of every range once it
+	// is written, it may be called from several goroutines at once.
+	Progress func(n int64)
+
+	// SkipVerify disables verifying the downloaded data against the
+	// part checksums and the ETag of the object.
+	SkipVerify bool
+}
+
+// DownloadState - persisted state of a download, kept next to the
+// downloaded file until the download completes.
+type DownloadState struct {
+	BucketName string `json:"bucketName"`
+	ObjectName string `json:"objectName"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size"`
+	RangeSize  int64  `json:"rangeSize"`
+
+	// Indexes of the completed ranges.
+	Done []int `json:"done"`
+
+	// Ranges following the parts of a multipart object, empty when all
+	// ranges but the last one have RangeSize.
+	Parts []RangeChecksum `json:"parts,omitempty"`
+
+	// Checksum of the whole object, empty when unknown.
+	Algorithm string `json:"algorithm,omitempty"`
+	Checksum  string `json:"checksum,omitempty"`
+}
+
+// DownloadError - returned by DownloadSession.Download when some
+// ranges could not be fetched. Completed ranges are kept, calling
+// Download again only fetches the failed ones.
+type DownloadError struct {
+	BucketName string
+	ObjectName string
+	Ranges     []PartError
+}
+
+// Error - Returns all range failures as string.
+func (e DownloadError) Error() string {
+	msgs := make([]string, 0, len(e.Ranges))
+	for _, r := range e.Ranges {
+		msgs = append(msgs, r.Error())
+	}
+	return fmt.Sprintf("download of ‘%s/%s’ failed: %s", e.BucketName, e.ObjectName, strings.Join(msgs, "; "))
+}
+
+// ErrObjectChanged - the object changed while it was downloaded.
+func ErrObjectChanged(bucketName, objectName string) error {
+	return ErrorResponse{
+		Code:       "ObjectChanged",
+		Message:    "The object changed during the download, it has to start over.",
+		BucketName: bucketName,
+		Key:        objectName,
+	}
+}
+
+// DownloadSession - a download of an object into a local file with
+// parallel ranged GETs, completed ranges are persisted so an
+// interrupted download resumes where it stopped.
+type DownloadSession struct {
+	client   *Client
+	filePath string
+	info     ObjectInfo
+	opts     DownloadOptions
+
+	// mutex protects state.
+	mutex sync.Mutex
+	state DownloadState
+}
+
+// NewDownloadSession - prepares the download of bucketName/objectName
+// into filePath. When an earlier download of the same object version
+// into filePath was interrupted it is resumed.
+func (c *Client) NewDownloadSession(ctx context.Context, bucketName, objectName, filePath string, opts DownloadOptions) (*DownloadSession, error) {
+	if opts.RangeSize <= 0 {
+		opts.RangeSize = MinPartSize
+	}
+	if opts.NumThreads <= 0 {
+		opts.NumThreads = totalWorkers
+	}
+	opts.RangeRetry = opts.RangeRetry.withDefaults()
+
+	info, err := c.statObject(ctx, bucketName, objectName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &DownloadSession{
+		client:   c,
+		filePath: filePath,
+		info:     info,
+		opts:     opts,
+		state: DownloadState{
+			BucketName: bucketName,
+			ObjectName: objectName,
+			ETag:       info.ETag,
+			Size:       info.Size,
+			RangeSize:  opts.RangeSize,
+		},
+	}
+	if !opts.SkipVerify {
+		s.planVerification(ctx)
+	}
+
+	data, err := ioutil.ReadFile(s.statePath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		var state DownloadState
+		if json.Unmarshal(data, &state) == nil && state.ETag == info.ETag && state.Size == info.Size &&
+			state.BucketName == bucketName && state.ObjectName == objectName && state.RangeSize > 0 {
+			// Same object version, resume when the file is still there.
+			if st, err := os.Stat(filePath); err == nil && st.Size() == info.Size {
+				s.state = state
+			}
+		}
+	}
+	return s, nil
+}
+
+// planVerification - fetches the checksums of the object, ranges follow
+// its parts when the server reports them. Servers without
+// GetObjectAttributes are verified against the ETag only.
+func (s *DownloadSession) planVerification(ctx context.Context) {
+	attrs, err := s.client.getObjectAttributes(ctx, s.state.BucketName, s.state.ObjectName)
+	if err != nil || attrs.ETag != "" && trimEtag(attrs.ETag) != s.state.ETag {
+		return
+	}
+	algorithm, checksum := attrs.Checksum.pick()
+	if !strings.Contains(checksum, "-") {
+		// Composite checksums of multipart objects are checksums of the
+		// part checksums, those are verified per range instead.
+		s.state.Algorithm, s.state.Checksum = algorithm, checksum
+	}
+
+	parts := attrs.ObjectParts.Parts
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	ranges := make([]RangeChecksum, 0, len(parts))
+	var offset int64
+	for _, part := range parts {
+		if part.Size <= 0 {
+			return
+		}
+		algorithm, checksum := part.pick()
+		ranges = append(ranges, RangeChecksum{
+			Offset:    offset,
+			Size:      part.Size,
+			Algorithm: algorithm,
+			Checksum:  checksum,
+		})
+		offset += part.Size
+	}
+	if len(ranges) > 0 && offset == s.state.Size {
+		s.state.Parts = ranges
+	}
+}
+
+// statePath - returns the file holding the download state.
+func (s *DownloadSession) statePath() string {
+	return s.filePath + downloadStateSuffix
+}
+
+// ObjectInfo - returns the object being downloaded.
+func (s *DownloadSession) ObjectInfo() ObjectInfo {
+	return s.info
+}
+
+// rangesCount - returns the number of ranges of the object.
+func (s *DownloadSession) rangesCount() int {
+	if len(s.state.Parts) > 0 {
+		return len(s.state.Parts)
+	}
+	return int((s.state.Size + s.state.RangeSize - 1) / s.state.RangeSize)
+}
+
+// Downloaded - returns the number of bytes downloaded so far.
+func (s *DownloadSession) Downloaded() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var downloaded int64
+	for _, i := range s.state.Done {
+		_, length := s.rangeAt(i)
+		downloaded += length
+	}
+	return downloaded
+}
+
+// rangeAt - returns offset and length of range i.
+func (s *DownloadSession) rangeAt(i int) (int64, int64) {
+	if len(s.state.Parts) > 0 {
+		return s.state.Parts[i].Offset, s.state.Parts[i].Size
+	}
+	offset := int64(i) * s.state.RangeSize
+	length := s.state.RangeSize
+	if offset+length > s.state.Size {
+		length = s.state.Size - offset
+	}
+	return offset, length
+}
+
+// missingRanges - returns the indexes of the ranges not downloaded yet.
+func (s *DownloadSession) missingRanges() []int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	done := make(map[int]bool, len(s.state.Done))
+	for _, i := range s.state.Done {
+		done[i] = true
+	}
+	var missing []int
+	for i := 0; i < s.rangesCount(); i++ {
+		if !done[i] {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// complete - records range i with the MD5 of its data as downloaded
+// and persists the state.
+func (s *DownloadSession) complete(i int, md5Sum []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.state.Done = append(s.state.Done, i)
+	if len(s.state.Parts) > 0 && md5Sum != nil {
+		s.state.Parts[i].MD5 = base64.StdEncoding.EncodeToString(md5Sum)
+	}
+	return s.persist()
+}
+
+// reset - forgets all downloaded ranges and persists the state.
+func (s *DownloadSession) reset() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.state.Done = nil
+	for i := range s.state.Parts {
+		s.state.Parts[i].MD5 = ""
+	}
+	return s.persist()
+}
+
+// persist - writes the state file, caller must hold the mutex.
+func (s *DownloadSession) persist() error {
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.statePath(), data)
+}
+
+// offsetWriter - writes sequentially into a file at an offset.
+type offsetWriter struct {
+	file   *os.File
+	offset int64
+}
+
+// Write - writes p at the current offset.
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.file.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
+
+// isRangeErrorRetryable - is a failed range download worth another
+// attempt, connections may also break while the body is read and
+// corrupted ranges are fetched again.
+func isRangeErrorRetryable(err error) bool {
+	if err == io.ErrUnexpectedEOF || isPartErrorRetryable(err) || ToErrorResponse(err).Code == "ChecksumMismatch" {
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
+// fetchRange - downloads range i into file. When ranges follow the
+// parts of the object the range is checked against the checksum of its
+// part and its MD5 is returned.
+func (s *DownloadSession) fetchRange(ctx context.Context, file *os.File, i int) ([]byte, error) {
+	offset, length := s.rangeAt(i)
+	body, _, err := s.client.getObjectRange(ctx, s.state.BucketName, s.state.ObjectName, offset, length, s.state.ETag, nil)
+	if err != nil {
+		if ToErrorResponse(err).Code == "PreconditionFailed" {
+			return nil, ErrObjectChanged(s.state.BucketName, s.state.ObjectName)
+		}
+		return nil, err
+	}
+	defer body.Close()
+
+	writers := []io.Writer{&offsetWriter{file: file, offset: offset}}
+	var md5Hash, sumHash hash.Hash
+	if !s.opts.SkipVerify && len(s.state.Parts) > 0 {
+		md5Hash = md5.New()
+		writers = append(writers, md5Hash)
+		if sumHash = newChecksumHash(s.state.Parts[i].Algorithm); sumHash != nil {
+			writers = append(writers, sumHash)
+		}
+	}
+
+	n, err := io.Copy(io.MultiWriter(writers...), io.LimitReader(body, length))
+	if err != nil {
+		return nil, err
+	}
+	if n != length {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if sumHash != nil && checksumString(sumHash) != s.state.Parts[i].Checksum {
+		return nil, ErrChecksumMismatch(s.state.BucketName, s.state.ObjectName,
+			fmt.Sprintf("Range %d does not match its %s checksum.", i+1, s.state.Parts[i].Algorithm))
+	}
+	if md5Hash == nil {
+		return nil, nil
+	}
+	return md5Hash.Sum(nil), nil
+}
+
+// fetchRangeWithRetry - downloads range i, retrying it according to
+// the range retry policy of the session.
+func (s *DownloadSession) fetchRangeWithRetry(ctx context.Context, file *os.File, i int) error {
+	policy := s.opts.RangeRetry
+
+	var err error
+	var attempts int
+	for timer := newRetryTimer(policy.MaxAttempts, policy.Unit, policy.Cap, nil); timer.next(ctx); {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if err == nil {
+				err = ctxErr
+			}
+			break
+		}
+		attempts++
+		var md5Sum []byte
+		if md5Sum, err = s.fetchRange(ctx, file, i); err == nil {
+			// Data has to be durable before the range is recorded.
+			if err = file.Sync(); err != nil {
+				break
+			}
+			if err = s.complete(i, md5Sum); err != nil {
+				break
+			}
+			if s.opts.Progress != nil {
+				_, length := s.rangeAt(i)
+				s.opts.Progress(length)
+			}
+			return nil
+		}
+		if attempts >= policy.MaxAttempts || !isRangeErrorRetryable(err) {
+			break
+		}
+	}
+	return PartError{
+		PartNumber: i + 1,
+		Attempts:   attempts,
+		Err:        err,
+	}
+}
+
+// Download - fetches all missing ranges into the file. On success the
+// file has the size of the object and the state file is removed. When
+// ranges fail a DownloadError is returned and Download may be called
+// again to fetch only the failed ranges. When the object changed in
+// between ErrObjectChanged is returned and the state is discarded.
+// Unless SkipVerify is set, ranges not matching their part checksum are
+// fetched again and the file is verified against the ETag or checksum
+// of the object, when it does not match all ranges are fetched once
+// more before ErrChecksumMismatch is returned.
+func (s *DownloadSession) Download(ctx context.Context) error {
+	defer s.client.trackSession()()
+	file, err := os.OpenFile(s.filePath, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err = file.Truncate(s.state.Size); err != nil {
+		return err
+	}
+
+	for refetched := false; ; refetched = true {
+		if err = s.fetchMissing(ctx, file); err != nil {
+			return err
+		}
+
+		// Verify the result before dropping the state.
+		if err = file.Sync(); err != nil {
+			return err
+		}
+		var st os.FileInfo
+		if st, err = file.Stat(); err != nil {
+			return err
+		}
+		if st.Size() != s.state.Size {
+			return ErrInvalidArgument(fmt.Sprintf("Downloaded file has %d bytes, expected %d.", st.Size(), s.state.Size))
+		}
+		if s.opts.SkipVerify {
+			break
+		}
+		err = s.verify(file)
+		if err == nil {
+			break
+		}
+		if refetched || ToErrorResponse(err).Code != "ChecksumMismatch" {
+			return err
+		}
+		// Which range is corrupt is unknown, fetch all of them again.
+		if err = s.reset(); err != nil {
+			return err
+		}
+	}
+	if err = os.Remove(s.statePath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// fetchMissing - fetches all missing ranges into file in parallel.
+func (s *DownloadSession) fetchMissing(ctx context.Context, file *os.File) error {
+	ranges := make(chan int)
+	go func() {
+		defer close(ranges)
+		for _, i := range s.missingRanges() {
+			select {
+			case ranges <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var errMutex sync.Mutex
+	var rangeErrs []PartError
+	for t := 0; t < s.opts.NumThreads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ranges {
+				if err := s.fetchRangeWithRetry(ctx, file, i); err != nil {
+					errMutex.Lock()
+					rangeErrs = append(rangeErrs, err.(PartError))
+					errMutex.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, rangeErr := range rangeErrs {
+		if ToErrorResponse(rangeErr.Err).Code == "ObjectChanged" {
+			os.Remove(s.statePath())
+			return rangeErr.Err
+		}
+	}
+	if len(rangeErrs) > 0 {
+		sort.Slice(rangeErrs, func(i, j int) bool { return rangeErrs[i].PartNumber < rangeErrs[j].PartNumber })
+		return DownloadError{
+			BucketName: s.state.BucketName,
+			ObjectName: s.state.ObjectName,
+			Ranges:     rangeErrs,
+		}
+	}
+	if len(s.missingRanges()) > 0 {
+		// Stopped early because ctx is done.
+		return ctx.Err()
+	}
+	return nil
+}
+
+// verify - checks the downloaded file against the checksum of the
+// object or its ETag. Multipart ETags are the MD5 of the part MD5s and
+// can only be checked when ranges follow the parts, ETags of encrypted
+// objects are not checked at all.
+func (s *DownloadSession) verify(file *os.File) error {
+	if s.state.Checksum != "" {
+		return s.verifyFile(file, newChecksumHash(s.state.Algorithm), s.state.Checksum, checksumString)
+	}
+	if s.info.Metadata.Get("X-Amz-Server-Side-Encryption") != "" ||
+		s.info.Metadata.Get(amzSSECustomerAlgorithm) != "" {
+		return nil
+	}
+
+	etag := s.state.ETag
+	n := strings.Index(etag, "-")
+	if n < 0 {
+		if len(etag) != 2*md5.Size {
+			return nil
+		}
+		return s.verifyFile(file, md5.New(), etag, func(h hash.Hash) string {
+			return hex.EncodeToString(h.Sum(nil))
+		})
+	}
+	if count, err := strconv.Atoi(etag[n+1:]); err != nil || count != len(s.state.Parts) {
+		return nil
+	}
+	md5Hash := md5.New()
+	for _, part := range s.state.Parts {
+		sum, err := base64.StdEncoding.DecodeString(part.MD5)
+		if err != nil || len(sum) != md5.Size {
+			// Downloaded without verification.
+			return nil
+		}
+		md5Hash.Write(sum)
+	}
+	if hex.EncodeToString(md5Hash.Sum(nil)) != etag[:n] {
+		return ErrChecksumMismatch(s.state.BucketName, s.state.ObjectName, "Downloaded file does not match the ETag.")
+	}
+	return nil
+}
+
+// verifyFile - hashes the whole file with h and compares the sum,
+// encoded with encode, with expected.
+func (s *DownloadSession) verifyFile(file *os.File, h hash.Hash, expected string, encode func(hash.Hash) string) error {
+	if h == nil {
+		return nil
+	}
+	if _, err := io.Copy(h, io.NewSectionReader(file, 0, s.state.Size)); err != nil {
+		return err
+	}
+	if encode(h) != expected {
+		return ErrChecksumMismatch(s.state.BucketName, s.state.ObjectName, "Downloaded file does not match the checksum of the object.")
+	}
+	return nil
+}
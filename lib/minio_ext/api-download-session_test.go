@@ -0,0 +1,227 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Answers of objectServer to ranged GETs.
+const (
+	rangeHonored = iota
+	// The range is ignored and the whole object sent with status 200.
+	rangeIgnored
+	// The range is answered from one byte later than requested.
+	rangeShifted
+)
+
+// objectServer - test server of a single object, HEAD and GET requests
+// are answered with http.ServeContent.
+type objectServer struct {
+	mutex sync.Mutex
+	data  []byte
+	etag  string
+	mode  int
+	gets  int
+}
+
+// newObjectServer - returns a server of size random bytes.
+func newObjectServer(size int) *objectServer {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+	sum := md5.Sum(data)
+	return &objectServer{data: data, etag: hex.EncodeToString(sum[:])}
+}
+
+// ServeHTTP - answers HEAD and GET requests of the object.
+func (s *objectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["location"]; ok {
+		w.Write([]byte("<LocationConstraint></LocationConstraint>"))
+		return
+	}
+	if _, ok := r.URL.Query()["attributes"]; ok {
+		testResponse{http.StatusNotImplemented, "NotImplemented", ""}.write(w)
+		return
+	}
+	s.mutex.Lock()
+	data, etag, mode := s.data, s.etag, s.mode
+	if r.Method == "GET" {
+		s.gets++
+	}
+	s.mutex.Unlock()
+
+	w.Header().Set("ETag", "\""+etag+"\"")
+	w.Header().Set("Content-Type", "application/octet-stream")
+	switch {
+	case r.Method == "GET" && r.Header.Get("Range") != "" && mode == rangeIgnored:
+		r.Header.Del("Range")
+	case r.Method == "GET" && r.Header.Get("Range") != "" && mode == rangeShifted:
+		var start int64
+		fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start+1, len(data)-1, len(data)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data[start+1:])
+		return
+	}
+	http.ServeContent(w, r, "", time.Unix(1700000000, 0), bytes.NewReader(data))
+}
+
+// setETag - changes the ETag of the object, as if it was overwritten.
+func (s *objectServer) setETag(etag string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.etag = etag
+}
+
+func TestDownloadSession(t *testing.T) {
+	testCases := []struct {
+		name string
+		// Ranges completed by an earlier download and whether the
+		// object changes before the download.
+		done    []int
+		changed bool
+		// Ranges expected to be fetched.
+		gets int
+	}{
+		{"ranges", nil, false, 4},
+		{"resumed", []int{0, 2}, false, 2},
+		{"changed", nil, true, 4},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := newObjectServer(10000)
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+			c := newTestClient(t, ts.URL)
+			ctx := context.Background()
+			dir, err := ioutil.TempDir("", "download")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			filePath := filepath.Join(dir, "object")
+
+			if testCase.done != nil {
+				// The completed ranges are in the file already.
+				if err = ioutil.WriteFile(filePath, server.data, 0600); err != nil {
+					t.Fatal(err)
+				}
+				state, err := json.Marshal(DownloadState{
+					BucketName: "bucket",
+					ObjectName: "object",
+					ETag:       server.etag,
+					Size:       10000,
+					RangeSize:  3000,
+					Done:       testCase.done,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err = ioutil.WriteFile(filePath+downloadStateSuffix, state, 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var progress int64
+			var progressMutex sync.Mutex
+			s, err := c.NewDownloadSession(ctx, "bucket", "object", filePath, DownloadOptions{
+				RangeSize:  3000,
+				NumThreads: 2,
+				RangeRetry: PartRetryPolicy{MaxAttempts: 1},
+				Progress: func(n int64) {
+					progressMutex.Lock()
+					progress += n
+					progressMutex.Unlock()
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if testCase.changed {
+				server.setETag("changed")
+			}
+			err = s.Download(ctx)
+			if server.gets != testCase.gets {
+				t.Errorf("Expected %d ranges fetched, got %d", testCase.gets, server.gets)
+			}
+			if testCase.changed {
+				if ToErrorResponse(err).Code != "ObjectChanged" {
+					t.Errorf("Expected ObjectChanged, got %v", err)
+				}
+				if _, err = os.Stat(filePath + downloadStateSuffix); !os.IsNotExist(err) {
+					t.Errorf("Expected the download state removed, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadFile(filePath)
+			if err != nil || !bytes.Equal(data, server.data) {
+				t.Errorf("Expected the object downloaded, got %d bytes, %v", len(data), err)
+			}
+			if downloaded := s.Downloaded(); downloaded != 10000 || progress != 10000-3000*int64(len(testCase.done)) {
+				t.Errorf("Unexpected %d bytes downloaded, %d reported", downloaded, progress)
+			}
+			if _, err = os.Stat(filePath + downloadStateSuffix); !os.IsNotExist(err) {
+				t.Errorf("Expected the download state removed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDownloadSessionUnexpectedRange(t *testing.T) {
+	testCases := []struct {
+		name string
+		mode int
+	}{
+		{"range ignored", rangeIgnored},
+		{"range shifted", rangeShifted},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := newObjectServer(10000)
+			server.mode = testCase.mode
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+			c := newTestClient(t, ts.URL)
+			ctx := context.Background()
+			dir, err := ioutil.TempDir("", "download")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			s, err := c.NewDownloadSession(ctx, "bucket", "object", filepath.Join(dir, "object"), DownloadOptions{
+				RangeSize:  3000,
+				NumThreads: 2,
+				RangeRetry: PartRetryPolicy{MaxAttempts: 1},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.Download(ctx)
+			downloadErr, ok := err.(DownloadError)
+			if !ok || len(downloadErr.Ranges) == 0 {
+				t.Fatalf("Expected DownloadError, got %v", err)
+			}
+			for _, r := range downloadErr.Ranges {
+				if ToErrorResponse(r.Err).Code != "UnexpectedRange" {
+					t.Errorf("Expected UnexpectedRange, got %v", r.Err)
+				}
+			}
+		})
+	}
+}
@@ -2,10 +2,46 @@ package minio_ext
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"net/http"
 )
 
+// Errors of the client, test for them with errors.Is, the returned
+// errors wrap them with more details.
+var (
+	// ErrInvalidPartSize - the size of a part is negative or above
+	// MaxPartSize.
+	ErrInvalidPartSize = errors.New("size is illegal")
+
+	// ErrInvalidPartNumber - the number of a part is out of range.
+	ErrInvalidPartNumber = errors.New("partNumber is illegal")
+
+	// ErrInvalidUploadID - the upload id is empty.
+	ErrInvalidUploadID = errors.New("uploadID is illegal")
+
+	// ErrConnectionReset - the server closed the connection before it
+	// answered, the request can be retried.
+	ErrConnectionReset = errors.New("connection closed by foreign host")
+
+	// ErrNoSuchBucket - the bucket does not exist.
+	ErrNoSuchBucket = errors.New("no such bucket")
+
+	// ErrNoSuchKey - the object does not exist.
+	ErrNoSuchKey = errors.New("no such key")
+
+	// ErrNoSuchUpload - the upload id does not exist on the server
+	// (anymore).
+	ErrNoSuchUpload = errors.New("no such upload")
+)
+
+// codeErrors - the errors ErrorResponse unwraps to by S3 error code.
+var codeErrors = map[string]error{
+	"NoSuchBucket": ErrNoSuchBucket,
+	"NoSuchKey":    ErrNoSuchKey,
+	"NoSuchUpload": ErrNoSuchUpload,
+}
+
 type ErrorResponse struct {
 	XMLName    xml.Name `xml:"Error" json:"-"`
 	Code       string
@@ -15,21 +51,40 @@ type ErrorResponse struct {
 	RequestID  string `xml:"RequestId"`
 	HostID     string `xml:"HostId"`
 
+	// Id of the MinIO deployment which answered, empty for other
+	// servers.
+	DeploymentID string `xml:"-"`
+
 	// Region where the bucket is located. This header is returned
 	// only in HEAD bucket and ListObjects response.
 	Region string
 
 	// Underlying HTTP status code for the returned error
 	StatusCode int `xml:"-" json:"-"`
+
+	// Err - error of the client the response wraps, nil for errors of
+	// the server.
+	Err error `xml:"-" json:"-"`
 }
 
 // Error - Returns HTTP error string
 func (e ErrorResponse) Error() string {
 	return e.Message
 }
+
+// Unwrap - returns the error of the client, or ErrNoSuchBucket,
+// ErrNoSuchKey or ErrNoSuchUpload for those S3 error codes.
+func (e ErrorResponse) Unwrap() error {
+	if e.Err != nil {
+		return e.Err
+	}
+	return codeErrors[e.Code]
+}
+
 const (
 	reportIssue = "Please report this issue at https://github.com/minio/minio/issues."
 )
+
 // httpRespToErrorResponse returns a new encoded ErrorResponse
 // structure as error.
 func httpRespToErrorResponse(resp *http.Response, bucketName, objectName string) error {
@@ -107,6 +162,7 @@ func httpRespToErrorResponse(resp *http.Response, bucketName, objectName string)
 	if errResp.Region == "" {
 		errResp.Region = resp.Header.Get("x-amz-bucket-region")
 	}
+	errResp.DeploymentID = resp.Header.Get(deploymentIDHeader)
 	if errResp.Code == "InvalidRegion" && errResp.Region != "" {
 		errResp.Message = fmt.Sprintf("Region does not match, expecting region ‘%s’.", errResp.Region)
 	}
@@ -122,6 +178,7 @@ func ToErrorResponse(err error) ErrorResponse {
 		return ErrorResponse{}
 	}
 }
+
 // ErrInvalidArgument - Invalid argument response.
 func ErrInvalidArgument(message string) error {
 	return ErrorResponse{
@@ -131,6 +188,16 @@ func ErrInvalidArgument(message string) error {
 	}
 }
 
+// errInvalidArgument - Invalid argument response wrapping err.
+func errInvalidArgument(err error) error {
+	return ErrorResponse{
+		Code:      "InvalidArgument",
+		Message:   err.Error(),
+		RequestID: "minio",
+		Err:       err,
+	}
+}
+
 // ErrEntityTooLarge - Input size is larger than supported maximum.
 func ErrEntityTooLarge(totalSize, maxObjectSize int64, bucketName, objectName string) error {
 	msg := fmt.Sprintf("Your proposed upload size ‘%d’ exceeds the maximum allowed object size ‘%d’ for single PUT operation.", totalSize, maxObjectSize)
@@ -0,0 +1,84 @@
+package minio_ext
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// RequestSpec - describes a raw S3 request for Execute.
+type RequestSpec struct {
+	// Bucket and object the request is sent to, both optional.
+	BucketName string
+	ObjectName string
+
+	// Query values and extra headers of the request.
+	QueryValues url.Values
+	Header      http.Header
+
+	// Request body, must be seekable for the request to be retried.
+	Body          io.Reader
+	ContentLength int64
+
+	// Optional content checksums, ContentSHA256Hex is used for signing
+	// when set, otherwise the payload is sent unsigned.
+	ContentMD5Base64 string
+	ContentSHA256Hex string
+
+	// Skips the bucket location lookup when set.
+	BucketLocation string
+}
+
+// List of methods accepted by Execute.
+var executeMethods = map[string]struct{}{
+	http.MethodGet:    {},
+	http.MethodHead:   {},
+	http.MethodPut:    {},
+	http.MethodPost:   {},
+	http.MethodDelete: {},
+}
+
+// Execute - sends a request described by spec through the same signing,
+// retry and tracing path as all other APIs of this package, for S3 APIs
+// which are not wrapped yet. Responses with a non success status are
+// returned as is, callers must close the response body.
+func (c Client) Execute(ctx context.Context, method string, spec RequestSpec) (*http.Response, error) {
+	// Input validation.
+	if _, ok := executeMethods[method]; !ok {
+		return nil, ErrInvalidArgument("Method ‘" + method + "’ is not supported.")
+	}
+	if spec.BucketName == "" && spec.ObjectName != "" {
+		return nil, ErrInvalidArgument("Object name requires a bucket name.")
+	}
+	if spec.BucketName != "" {
+		if err := s3utils.CheckValidBucketName(spec.BucketName); err != nil {
+			return nil, err
+		}
+	}
+	if spec.ObjectName != "" {
+		if err := s3utils.CheckValidObjectName(spec.ObjectName); err != nil {
+			return nil, err
+		}
+	}
+	if spec.Body == nil && spec.ContentLength > 0 {
+		return nil, ErrInvalidArgument("Content length is set without a body.")
+	}
+	if spec.Body != nil && spec.ContentLength == 0 {
+		return nil, ErrInvalidArgument("Body is set without a content length, use -1 for unknown length.")
+	}
+
+	return c.executeMethod(ctx, method, requestMetadata{
+		bucketName:       spec.BucketName,
+		objectName:       spec.ObjectName,
+		queryValues:      spec.QueryValues,
+		customHeader:     spec.Header,
+		contentBody:      spec.Body,
+		contentLength:    spec.ContentLength,
+		contentMD5Base64: spec.ContentMD5Base64,
+		contentSHA256Hex: spec.ContentSHA256Hex,
+		bucketLocation:   spec.BucketLocation,
+	})
+}
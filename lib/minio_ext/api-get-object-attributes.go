@@ -0,0 +1,63 @@
+package minio_ext
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// objectAttributesMaxParts - parts requested per GetObjectAttributes
+// call.
+const objectAttributesMaxParts = 1000
+
+// getObjectAttributes - returns the checksums and, for multipart
+// objects, the parts of an object with their sizes and checksums. All
+// pages of parts are fetched. Servers not implementing the API return
+// an error.
+func (c Client) getObjectAttributes(ctx context.Context, bucketName, objectName string) (getObjectAttributesResult, error) {
+	var result getObjectAttributesResult
+	marker := 0
+	for {
+		urlValues := make(url.Values)
+		urlValues.Set("attributes", "")
+
+		customHeader := make(http.Header)
+		customHeader.Set("X-Amz-Object-Attributes", "ETag,Checksum,ObjectParts,ObjectSize")
+		customHeader.Set("X-Amz-Max-Parts", strconv.Itoa(objectAttributesMaxParts))
+		if marker > 0 {
+			customHeader.Set("X-Amz-Part-Number-Marker", strconv.Itoa(marker))
+		}
+
+		resp, err := c.executeMethod(ctx, "GET", requestMetadata{
+			bucketName:       bucketName,
+			objectName:       objectName,
+			queryValues:      urlValues,
+			customHeader:     customHeader,
+			contentSHA256Hex: emptySHA256Hex,
+		})
+		if err != nil {
+			closeResponse(resp)
+			return getObjectAttributesResult{}, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = httpRespToErrorResponse(resp, bucketName, objectName)
+			closeResponse(resp)
+			return getObjectAttributesResult{}, err
+		}
+		var page getObjectAttributesResult
+		err = xmlDecoder(resp.Body, &page)
+		closeResponse(resp)
+		if err != nil {
+			return getObjectAttributesResult{}, err
+		}
+
+		parts := append(result.ObjectParts.Parts, page.ObjectParts.Parts...)
+		result = page
+		result.ObjectParts.Parts = parts
+		if !page.ObjectParts.IsTruncated || page.ObjectParts.NextPartNumberMarker <= marker {
+			return result, nil
+		}
+		marker = page.ObjectParts.NextPartNumberMarker
+	}
+}
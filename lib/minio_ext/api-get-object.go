@@ -0,0 +1,280 @@
+package minio_ext
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// getObjectRange - fetches length bytes of an object starting at
+// offset, a negative length reads until the end. With a non empty etag
+// the request fails with PreconditionFailed once the object changed.
+func (c Client) getObjectRange(ctx context.Context, bucketName, objectName string, offset, length int64, etag string, customHeader http.Header) (io.ReadCloser, ObjectInfo, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	if offset < 0 || length == 0 {
+		return nil, ObjectInfo{}, ErrInvalidArgument(fmt.Sprintf("Invalid range %d+%d.", offset, length))
+	}
+
+	header := make(http.Header)
+	for k, v := range customHeader {
+		header[k] = v
+	}
+	if length > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	if etag != "" {
+		header.Set("If-Match", "\""+etag+"\"")
+	}
+
+	// Execute GET on objectName.
+	resp, err := c.executeMethod(ctx, "GET", requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		contentSHA256Hex: emptySHA256Hex,
+		customHeader:     header,
+	})
+	if err != nil {
+		closeResponse(resp)
+		return nil, ObjectInfo{}, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		defer closeResponse(resp)
+		return nil, ObjectInfo{}, httpRespToErrorResponse(resp, bucketName, objectName)
+	}
+	// A server or proxy ignoring the range sends the whole object.
+	if header.Get("Range") != "" {
+		if err = checkContentRange(resp, offset, length); err != nil {
+			resp.Body.Close()
+			return nil, ObjectInfo{}, err
+		}
+	}
+
+	objInfo, err := toObjectInfo(bucketName, objectName, resp.Header)
+	if err != nil {
+		// Do not drain, the body may be large.
+		resp.Body.Close()
+		return nil, ObjectInfo{}, err
+	}
+	return resp.Body, objInfo, nil
+}
+
+// ErrUnexpectedRange - the server answered a ranged GET with another
+// range than the one requested.
+func ErrUnexpectedRange(requested, answered string) error {
+	return ErrorResponse{
+		StatusCode: http.StatusPartialContent,
+		Code:       "UnexpectedRange",
+		Message:    "Requested range ‘" + requested + "’, the server answered ‘" + answered + "’.",
+	}
+}
+
+// checkContentRange - checks that resp is the 206 response of the range
+// of length bytes at offset, until the end when length is negative.
+// Ranges reaching past the end of the object are cut at its end.
+func checkContentRange(resp *http.Response, offset, length int64) error {
+	requested := fmt.Sprintf("bytes %d-", offset)
+	if length > 0 {
+		requested += strconv.FormatInt(offset+length-1, 10)
+	}
+	contentRange := resp.Header.Get("Content-Range")
+	if resp.StatusCode != http.StatusPartialContent {
+		return ErrUnexpectedRange(requested, fmt.Sprintf("status %d", resp.StatusCode))
+	}
+	var start, end, size int64
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%d", &start, &end, &size); err != nil {
+		// The size may be unknown.
+		if _, err = fmt.Sscanf(contentRange, "bytes %d-%d/*", &start, &end); err != nil {
+			return ErrUnexpectedRange(requested, contentRange)
+		}
+		size = -1
+	}
+	last := end
+	if length > 0 {
+		last = offset + length - 1
+	} else if size >= 0 {
+		last = size - 1
+	}
+	// Only the end of the object cuts the range short.
+	if start != offset || end > last || (end < last && (size < 0 || end != size-1)) {
+		return ErrUnexpectedRange(requested, contentRange)
+	}
+	return nil
+}
+
+// GetObjectOptions - options for GetObject.
+type GetObjectOptions struct {
+	// Optional headers sent with every request, such as the SSE-C
+	// headers of an encrypted object.
+	Header http.Header
+}
+
+// Object - an object opened by GetObject, read with ranged GETs from
+// the current offset. Seeking only moves the offset, the next Read
+// starts a new request there. All requests are pinned to the ETag the
+// object had when it was opened, reads fail with ErrObjectChanged once
+// it changed. Safe for concurrent use, ReadAt does not move the offset.
+type Object struct {
+	ctx    context.Context
+	client Client
+	bucket string
+	info   ObjectInfo
+	header http.Header
+
+	// mutex protects the fields below.
+	mutex  sync.Mutex
+	offset int64
+	body   io.ReadCloser
+	closed bool
+}
+
+// GetObject - opens bucketName/objectName for reading with a HEAD
+// request, ctx cancels the requests of the returned Object.
+func (c Client) GetObject(ctx context.Context, bucketName, objectName string, opts GetObjectOptions) (*Object, error) {
+	info, err := c.statObject(ctx, bucketName, objectName, opts.Header)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{
+		ctx:    ctx,
+		client: c,
+		bucket: bucketName,
+		info:   info,
+		header: opts.Header,
+	}, nil
+}
+
+// Stat - returns the metadata of the object when it was opened.
+func (o *Object) Stat() ObjectInfo {
+	return o.info
+}
+
+// Read - implements io.Reader.
+func (o *Object) Read(p []byte) (int, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.closed {
+		return 0, os.ErrClosed
+	}
+	if o.offset >= o.info.Size {
+		return 0, io.EOF
+	}
+	if o.body == nil {
+		body, err := o.get(o.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		o.body = body
+	}
+	n, err := o.body.Read(p)
+	o.offset += int64(n)
+	if err == io.EOF && o.offset < o.info.Size {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil && err != io.EOF {
+		// The next Read starts over at the offset reached.
+		o.body.Close()
+		o.body = nil
+	}
+	return n, err
+}
+
+// Seek - implements io.Seeker, offsets beyond the end are allowed and
+// read io.EOF.
+func (o *Object) Seek(offset int64, whence int) (int64, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.closed {
+		return 0, os.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += o.info.Size
+	default:
+		return 0, ErrInvalidArgument(fmt.Sprintf("Invalid whence %d.", whence))
+	}
+	if offset < 0 {
+		return 0, ErrInvalidArgument(fmt.Sprintf("Negative offset %d.", offset))
+	}
+	if offset != o.offset && o.body != nil {
+		o.body.Close()
+		o.body = nil
+	}
+	o.offset = offset
+	return offset, nil
+}
+
+// ReadAt - implements io.ReaderAt with a ranged GET per call.
+func (o *Object) ReadAt(p []byte, offset int64) (int, error) {
+	o.mutex.Lock()
+	closed := o.closed
+	o.mutex.Unlock()
+	if closed {
+		return 0, os.ErrClosed
+	}
+	if offset < 0 {
+		return 0, ErrInvalidArgument(fmt.Sprintf("Negative offset %d.", offset))
+	}
+	if offset >= o.info.Size {
+		return 0, io.EOF
+	}
+	length := int64(len(p))
+	if length == 0 {
+		return 0, nil
+	}
+	if offset+length > o.info.Size {
+		length = o.info.Size - offset
+	}
+	body, err := o.get(offset, length)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	n, err := io.ReadFull(body, p[:length])
+	if err == nil && int64(n) < int64(len(p)) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Close - implements io.Closer.
+func (o *Object) Close() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+	if o.body != nil {
+		err := o.body.Close()
+		o.body = nil
+		return err
+	}
+	return nil
+}
+
+// get - fetches length bytes of the object at offset, until the end
+// when length is negative.
+func (o *Object) get(offset, length int64) (io.ReadCloser, error) {
+	body, _, err := o.client.getObjectRange(o.ctx, o.bucket, o.info.Key, offset, length, o.info.ETag, o.header)
+	if ToErrorResponse(err).Code == "PreconditionFailed" {
+		return nil, ErrObjectChanged(o.bucket, o.info.Key)
+	}
+	return body, err
+}
@@ -0,0 +1,147 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckContentRange(t *testing.T) {
+	testCases := []struct {
+		status       int
+		contentRange string
+		offset       int64
+		length       int64
+		shouldPass   bool
+	}{
+		{http.StatusPartialContent, "bytes 0-9/100", 0, 10, true},
+		{http.StatusPartialContent, "bytes 10-99/100", 10, -1, true},
+		{http.StatusPartialContent, "bytes 10-99/*", 10, 90, true},
+		// Ranges past the end are cut at the end.
+		{http.StatusPartialContent, "bytes 90-99/100", 90, 20, true},
+		{http.StatusPartialContent, "bytes 90-99/*", 90, -1, true},
+		// The whole object instead of the range.
+		{http.StatusOK, "", 10, 10, false},
+		{http.StatusOK, "bytes 10-19/100", 10, 10, false},
+		{http.StatusPartialContent, "", 10, 10, false},
+		{http.StatusPartialContent, "bytes 11-19/100", 10, 10, false},
+		{http.StatusPartialContent, "bytes 10-20/100", 10, 10, false},
+		{http.StatusPartialContent, "bytes 10-18/100", 10, 10, false},
+		{http.StatusPartialContent, "bytes 10-18/*", 10, 10, false},
+		{http.StatusPartialContent, "bytes 10-98/100", 10, -1, false},
+		{http.StatusPartialContent, "items 10-19/100", 10, 10, false},
+	}
+	for i, testCase := range testCases {
+		resp := &http.Response{StatusCode: testCase.status, Header: http.Header{}}
+		resp.Header.Set("Content-Range", testCase.contentRange)
+		err := checkContentRange(resp, testCase.offset, testCase.length)
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: expected to pass, failed with %v", i+1, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: expected to fail, passed", i+1)
+		}
+		if err != nil && ToErrorResponse(err).Code != "UnexpectedRange" {
+			t.Errorf("Test %d: expected UnexpectedRange, got %v", i+1, err)
+		}
+	}
+}
+
+func TestObjectReadAt(t *testing.T) {
+	server := newObjectServer(10000)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	c := newTestClient(t, ts.URL)
+	ctx := WithRequestRegion(context.Background(), "us-east-1")
+
+	obj, err := c.GetObject(ctx, "bucket", "object", GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Close()
+	if info := obj.Stat(); info.Size != 10000 || info.ETag != server.etag {
+		t.Fatalf("Unexpected object info %+v", info)
+	}
+
+	testCases := []struct {
+		offset int64
+		length int
+		n      int
+		err    error
+	}{
+		{0, 100, 100, nil},
+		{5000, 5000, 5000, nil},
+		{9990, 10, 10, nil},
+		{9990, 100, 10, io.EOF},
+		{10000, 10, 0, io.EOF},
+		{0, 0, 0, nil},
+	}
+	for i, testCase := range testCases {
+		buf := make([]byte, testCase.length)
+		n, err := obj.ReadAt(buf, testCase.offset)
+		if n != testCase.n || err != testCase.err {
+			t.Errorf("Test %d: expected %d, %v, got %d, %v", i+1, testCase.n, testCase.err, n, err)
+			continue
+		}
+		if !bytes.Equal(buf[:n], server.data[testCase.offset:testCase.offset+int64(n)]) {
+			t.Errorf("Test %d: unexpected data", i+1)
+		}
+	}
+
+	// Reads continue at the offset sought.
+	if _, err = obj.Seek(-1000, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(obj)
+	if err != nil || !bytes.Equal(data, server.data[9000:]) {
+		t.Errorf("Expected the last 1000 bytes, got %d bytes, %v", len(data), err)
+	}
+	if _, err = obj.Seek(-1, io.SeekStart); ToErrorResponse(err).Code != "InvalidArgument" {
+		t.Errorf("Expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestObjectUnexpectedRange(t *testing.T) {
+	testCases := []struct {
+		name string
+		mode int
+		code string
+	}{
+		{"ignored", rangeIgnored, "UnexpectedRange"},
+		{"shifted", rangeShifted, "UnexpectedRange"},
+		{"changed", rangeHonored, "ObjectChanged"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := newObjectServer(1000)
+			server.mode = testCase.mode
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+			c := newTestClient(t, ts.URL)
+			ctx := WithRequestRegion(context.Background(), "us-east-1")
+
+			obj, err := c.GetObject(ctx, "bucket", "object", GetObjectOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer obj.Close()
+			if testCase.code == "ObjectChanged" {
+				server.setETag("changed")
+			}
+			buf := make([]byte, 100)
+			if _, err = obj.ReadAt(buf, 100); ToErrorResponse(err).Code != testCase.code {
+				t.Errorf("Expected %s, got %v", testCase.code, err)
+			}
+			if _, err = obj.Seek(500, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = obj.Read(buf); ToErrorResponse(err).Code != testCase.code {
+				t.Errorf("Expected %s, got %v", testCase.code, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package minio_ext
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// ListObjectsOptions - options for ListObjectsV2.
+type ListObjectsOptions struct {
+	// Only objects whose keys start with Prefix are listed.
+	Prefix string
+
+	// Keys containing Delimiter after the prefix are rolled up into
+	// common prefixes, sent with the prefix as Key only. "/" lists one
+	// directory level, empty lists all objects below the prefix.
+	Delimiter string
+
+	// Listing starts after this key, to resume an earlier listing.
+	StartAfter string
+
+	// Keys per request, at most and by default 1000.
+	MaxKeys int
+}
+
+// ListObjectsV2 - lists the objects of bucketName in key order over
+// the returned channel, with as many ListObjectsV2 requests as the
+// continuation tokens of the server ask for. An error is sent as the
+// last ObjectInfo with Err set. The channel is closed when the listing
+// ends or ctx is done, the caller has to drain it or cancel ctx.
+func (c Client) ListObjectsV2(ctx context.Context, bucketName string, opts ListObjectsOptions) <-chan ObjectInfo {
+	objectCh := make(chan ObjectInfo, 1)
+
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		objectCh <- ObjectInfo{Err: err}
+		close(objectCh)
+		return objectCh
+	}
+	if err := s3utils.CheckValidObjectNamePrefix(opts.Prefix); err != nil {
+		objectCh <- ObjectInfo{Err: err}
+		close(objectCh)
+		return objectCh
+	}
+
+	go func() {
+		defer close(objectCh)
+		send := func(info ObjectInfo) bool {
+			select {
+			case objectCh <- info:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var continuationToken string
+		for {
+			result, err := c.listObjectsV2Query(ctx, bucketName, opts, continuationToken)
+			if err != nil {
+				send(ObjectInfo{Err: err})
+				return
+			}
+			for _, object := range result.Contents {
+				object.ETag = trimEtag(object.ETag)
+				if !send(object) {
+					return
+				}
+			}
+			for _, prefix := range result.CommonPrefixes {
+				if !send(ObjectInfo{Key: prefix.Prefix}) {
+					return
+				}
+			}
+			// Listing ends when the result is not truncated.
+			if !result.IsTruncated || result.NextContinuationToken == "" {
+				return
+			}
+			continuationToken = result.NextContinuationToken
+		}
+	}()
+	return objectCh
+}
+
+// listObjectsV2Query - lists up to MaxKeys objects of bucketName after
+// continuationToken, from the start when empty.
+func (c Client) listObjectsV2Query(ctx context.Context, bucketName string, opts ListObjectsOptions, continuationToken string) (listBucketV2Result, error) {
+	// Get resources properly escaped and lined up before using them in http request.
+	urlValues := make(url.Values)
+	urlValues.Set("list-type", "2")
+	urlValues.Set("encoding-type", "url")
+	urlValues.Set("prefix", opts.Prefix)
+	urlValues.Set("delimiter", opts.Delimiter)
+	if continuationToken != "" {
+		urlValues.Set("continuation-token", continuationToken)
+	}
+	if opts.StartAfter != "" {
+		urlValues.Set("start-after", opts.StartAfter)
+	}
+	// maxKeys should be 1000 or less.
+	maxKeys := opts.MaxKeys
+	if maxKeys <= 0 || maxKeys > 1000 {
+		maxKeys = 1000
+	}
+	urlValues.Set("max-keys", strconv.Itoa(maxKeys))
+
+	// Execute GET on bucket to list objects.
+	resp, err := c.executeMethod(ctx, "GET", requestMetadata{
+		bucketName:       bucketName,
+		queryValues:      urlValues,
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return listBucketV2Result{}, err
+	}
+	if resp != nil {
+		if resp.StatusCode != http.StatusOK {
+			return listBucketV2Result{}, httpRespToErrorResponse(resp, bucketName, "")
+		}
+	}
+
+	// Decode the listing XML.
+	result := listBucketV2Result{}
+	if err = xmlDecoder(resp.Body, &result); err != nil {
+		return result, err
+	}
+
+	// Keys are URL encoded with encoding-type url.
+	if result.EncodingType == "url" {
+		for i, object := range result.Contents {
+			if result.Contents[i].Key, err = url.QueryUnescape(object.Key); err != nil {
+				return result, err
+			}
+		}
+		for i, prefix := range result.CommonPrefixes {
+			if result.CommonPrefixes[i].Prefix, err = url.QueryUnescape(prefix.Prefix); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,201 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// RetentionMode - mode an object is retained in.
+type RetentionMode string
+
+const (
+	// Governance - the retention can be shortened or removed by users
+	// allowed to bypass governance retention.
+	Governance RetentionMode = "GOVERNANCE"
+
+	// Compliance - nobody can shorten or remove the retention, the
+	// object cannot be deleted until it ends.
+	Compliance RetentionMode = "COMPLIANCE"
+)
+
+// ObjectRetention - retention of an object, it cannot be overwritten
+// or deleted before RetainUntilDate.
+type ObjectRetention struct {
+	Mode            RetentionMode
+	RetainUntilDate time.Time
+}
+
+// validate - checks the mode and date of the retention.
+func (r ObjectRetention) validate() error {
+	if r.Mode != Governance && r.Mode != Compliance {
+		return ErrInvalidArgument("Retention mode must be ‘GOVERNANCE’ or ‘COMPLIANCE’.")
+	}
+	if r.RetainUntilDate.IsZero() {
+		return ErrInvalidArgument("Retention must have a retain until date.")
+	}
+	return nil
+}
+
+// setLockHeaders - sets the object lock headers of retention and
+// legalHold on header.
+func setLockHeaders(header http.Header, retention *ObjectRetention, legalHold bool) {
+	if retention != nil {
+		header.Set(amzObjectLockMode, string(retention.Mode))
+		header.Set(amzObjectLockRetainUntilDate, retention.RetainUntilDate.UTC().Format(time.RFC3339))
+	}
+	if legalHold {
+		header.Set(amzObjectLockLegalHold, "ON")
+	}
+}
+
+// PutObjectRetention - sets the retention of bucketName/objectName.
+// Governance retention can only be shortened or removed with
+// bypassGovernance, compliance retention can only be extended.
+func (c Client) PutObjectRetention(ctx context.Context, bucketName, objectName string, retention ObjectRetention, bypassGovernance bool) error {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return err
+	}
+	if err := retention.validate(); err != nil {
+		return err
+	}
+	retentionBytes, err := xml.Marshal(objectRetention{
+		Mode:            string(retention.Mode),
+		RetainUntilDate: retention.RetainUntilDate.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	urlValues := make(url.Values)
+	urlValues.Set("retention", "")
+
+	customHeader := make(http.Header)
+	if bypassGovernance {
+		customHeader.Set("X-Amz-Bypass-Governance-Retention", "true")
+	}
+	return c.putObjectLock(ctx, bucketName, objectName, urlValues, customHeader, retentionBytes)
+}
+
+// GetObjectRetention - returns the retention of bucketName/objectName,
+// nil when it has none.
+func (c Client) GetObjectRetention(ctx context.Context, bucketName, objectName string) (*ObjectRetention, error) {
+	var result objectRetention
+	if err := c.getObjectLock(ctx, bucketName, objectName, "retention", &result); err != nil {
+		if ToErrorResponse(err).Code == "NoSuchObjectLockConfiguration" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if result.Mode == "" {
+		return nil, nil
+	}
+	retainUntil, err := time.Parse(time.RFC3339, result.RetainUntilDate)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectRetention{Mode: RetentionMode(result.Mode), RetainUntilDate: retainUntil}, nil
+}
+
+// PutObjectLegalHold - places or removes a legal hold on
+// bucketName/objectName, an object under legal hold cannot be deleted
+// regardless of its retention.
+func (c Client) PutObjectLegalHold(ctx context.Context, bucketName, objectName string, on bool) error {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return err
+	}
+	status := "OFF"
+	if on {
+		status = "ON"
+	}
+	legalHoldBytes, err := xml.Marshal(objectLegalHold{Status: status})
+	if err != nil {
+		return err
+	}
+
+	urlValues := make(url.Values)
+	urlValues.Set("legal-hold", "")
+	return c.putObjectLock(ctx, bucketName, objectName, urlValues, nil, legalHoldBytes)
+}
+
+// GetObjectLegalHold - reports whether bucketName/objectName is under
+// legal hold.
+func (c Client) GetObjectLegalHold(ctx context.Context, bucketName, objectName string) (bool, error) {
+	var result objectLegalHold
+	if err := c.getObjectLock(ctx, bucketName, objectName, "legal-hold", &result); err != nil {
+		if ToErrorResponse(err).Code == "NoSuchObjectLockConfiguration" {
+			return false, nil
+		}
+		return false, err
+	}
+	return result.Status == "ON", nil
+}
+
+// putObjectLock - sends the retention or legal hold configBytes of an
+// object, both require Content-MD5.
+func (c Client) putObjectLock(ctx context.Context, bucketName, objectName string, urlValues url.Values, customHeader http.Header, configBytes []byte) error {
+	md5Sum := md5.Sum(configBytes)
+	resp, err := c.executeMethod(ctx, "PUT", requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		queryValues:      urlValues,
+		customHeader:     customHeader,
+		contentBody:      bytes.NewReader(configBytes),
+		contentLength:    int64(len(configBytes)),
+		contentMD5Base64: base64.StdEncoding.EncodeToString(md5Sum[:]),
+		contentSHA256Hex: sum256Hex(configBytes),
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return httpRespToErrorResponse(resp, bucketName, objectName)
+	}
+	return nil
+}
+
+// getObjectLock - fetches the retention or legal hold, as named by
+// resource, of an object into result.
+func (c Client) getObjectLock(ctx context.Context, bucketName, objectName, resource string, result interface{}) error {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return err
+	}
+
+	urlValues := make(url.Values)
+	urlValues.Set(resource, "")
+
+	resp, err := c.executeMethod(ctx, "GET", requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		queryValues:      urlValues,
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return httpRespToErrorResponse(resp, bucketName, objectName)
+	}
+	return xmlDecoder(resp.Body, result)
+}
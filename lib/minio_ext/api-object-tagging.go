@@ -0,0 +1,147 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+const (
+	// maxObjectTags - most tags of an object.
+	maxObjectTags = 10
+
+	// maxTagKeyLength, maxTagValueLength - longest key and value of a
+	// tag in characters.
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// PutObjectTagging - replaces the tags of bucketName/objectName with
+// tags, such as scanned=true for lifecycle rules to act on. Objects
+// have at most 10 tags.
+func (c Client) PutObjectTagging(ctx context.Context, bucketName, objectName string, tags map[string]string) error {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return err
+	}
+	if len(tags) > maxObjectTags {
+		return ErrInvalidArgument(fmt.Sprintf("Objects cannot have more than %d tags.", maxObjectTags))
+	}
+	request := tagging{}
+	for k, v := range tags {
+		if k == "" || utf8.RuneCountInString(k) > maxTagKeyLength {
+			return ErrInvalidArgument(fmt.Sprintf("Tag key ‘%s’ must have between 1 and %d characters.", k, maxTagKeyLength))
+		}
+		if utf8.RuneCountInString(v) > maxTagValueLength {
+			return ErrInvalidArgument(fmt.Sprintf("Value of tag ‘%s’ cannot have more than %d characters.", k, maxTagValueLength))
+		}
+		request.Tags = append(request.Tags, tag{Key: k, Value: v})
+	}
+	sort.Slice(request.Tags, func(i, j int) bool { return request.Tags[i].Key < request.Tags[j].Key })
+	taggingBytes, err := xml.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	urlValues := make(url.Values)
+	urlValues.Set("tagging", "")
+
+	// PutObjectTagging requires Content-MD5.
+	md5Sum := md5.Sum(taggingBytes)
+	resp, err := c.executeMethod(ctx, "PUT", requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		queryValues:      urlValues,
+		contentBody:      bytes.NewReader(taggingBytes),
+		contentLength:    int64(len(taggingBytes)),
+		contentMD5Base64: base64.StdEncoding.EncodeToString(md5Sum[:]),
+		contentSHA256Hex: sum256Hex(taggingBytes),
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return httpRespToErrorResponse(resp, bucketName, objectName)
+	}
+	return nil
+}
+
+// GetObjectTagging - returns the tags of bucketName/objectName, an
+// empty map when it has none.
+func (c Client) GetObjectTagging(ctx context.Context, bucketName, objectName string) (map[string]string, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return nil, err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return nil, err
+	}
+
+	urlValues := make(url.Values)
+	urlValues.Set("tagging", "")
+
+	resp, err := c.executeMethod(ctx, "GET", requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		queryValues:      urlValues,
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpRespToErrorResponse(resp, bucketName, objectName)
+	}
+	var result tagging
+	if err = xmlDecoder(resp.Body, &result); err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(result.Tags))
+	for _, t := range result.Tags {
+		tags[t.Key] = t.Value
+	}
+	return tags, nil
+}
+
+// RemoveObjectTagging - removes all tags of bucketName/objectName.
+func (c Client) RemoveObjectTagging(ctx context.Context, bucketName, objectName string) error {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return err
+	}
+
+	urlValues := make(url.Values)
+	urlValues.Set("tagging", "")
+
+	resp, err := c.executeMethod(ctx, "DELETE", requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		queryValues:      urlValues,
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return httpRespToErrorResponse(resp, bucketName, objectName)
+	}
+	return nil
+}
@@ -0,0 +1,493 @@
+package minio_ext
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/credentials"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// clientOptions - settings collected from the options of New.
+type clientOptions struct {
+	creds      *credentials.Credentials
+	secure     bool
+	region     string
+	lookup     BucketLookupType
+	httpClient *http.Client
+	transport  http.RoundTripper
+	signerType credentials.SignatureType
+
+	// Expiry of temporary credentials, see WithAssumeRole.
+	credsExpiration func() time.Time
+
+	// Payload signing policy, see SetPayloadSigning.
+	payloadSigning PayloadSigning
+
+	// Bucket location cache TTL, set by WithBucketLocationTTL, and
+	// store.
+	locationTTLSet bool
+	locationTTL    time.Duration
+	locationStore  LocationStore
+
+	// Negative TTL, set by WithBucketLocationNegativeTTL.
+	negativeTTLSet bool
+	negativeTTL    time.Duration
+
+	// Retries of failed requests, see SetRetryPolicy and SetRetryHook.
+	retryPolicy RequestRetryPolicy
+	retryHook   func(RetryEvent)
+
+	// S3 accelerate endpoint and hosts of the bucket locations, see
+	// SetS3TransferAccelerate and SetEndpointMap.
+	accelerateEndpoint string
+	endpointMap        map[string]string
+
+	// Endpoints requests fail over to, see SetFailoverEndpoints.
+	failoverEndpoints []string
+
+	// Hedging of small idempotent requests, see SetHedging.
+	hedging HedgePolicy
+
+	// Measurements of the requests and sessions, see SetMetrics.
+	metrics Metrics
+
+	// Spans of the requests and sessions, see SetTracerProvider.
+	tracerProvider trace.TracerProvider
+
+	// Wrappers of the sending of requests, see Use.
+	middleware []Middleware
+
+	// TLS settings of the DefaultTransport.
+	rootCAs            *x509.CertPool
+	clientCerts        []tls.Certificate
+	insecureSkipVerify bool
+
+	// Proxy of the DefaultTransport, set by WithProxyURL.
+	proxySet bool
+	proxyURL *url.URL
+
+	// Dialing of the DefaultTransport.
+	dialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	resolver    *net.Resolver
+
+	// HTTP/2 of the DefaultTransport, set by WithHTTP2.
+	http2Set bool
+	http2    bool
+
+	// Phase timeouts, see Timeouts.
+	timeouts Timeouts
+
+	traceOutput     io.Writer
+	traceErrorsOnly bool
+	traceFormatter  TraceFormatter
+
+	traceSampleEvery int
+	traceMaxBody     int64
+
+	appName    string
+	appVersion string
+}
+
+// Option - configures a Client created by New.
+type Option func(*clientOptions)
+
+// WithCredentials - signs requests with static access and secret keys
+// using signature V4. Clients without credentials are anonymous.
+func WithCredentials(accessKeyID, secretAccessKey string) Option {
+	return func(o *clientOptions) {
+		o.creds = credentials.NewStaticV4(accessKeyID, secretAccessKey, "")
+		o.credsExpiration = nil
+	}
+}
+
+// WithCredentialsProvider - takes the credentials from creds, for
+// example temporary credentials from IAM or STS.
+func WithCredentialsProvider(creds *credentials.Credentials) Option {
+	return func(o *clientOptions) {
+		o.creds = creds
+		o.credsExpiration = nil
+	}
+}
+
+// WithAssumeRole - takes temporary credentials from provider, see
+// NewAssumeRole. They are refreshed before they expire, presigned URLs
+// and post policies expire with them at the latest.
+func WithAssumeRole(provider *AssumeRoleProvider) Option {
+	return WithTemporaryCredentials(provider)
+}
+
+// WithWebIdentity - takes temporary credentials exchanged for an OIDC
+// token from provider, see NewWebIdentity and WithAssumeRole.
+func WithWebIdentity(provider *WebIdentityProvider) Option {
+	return WithTemporaryCredentials(provider)
+}
+
+// WithAWSCredentialChain - takes the credentials from the environment,
+// the shared credentials file, the ECS task role or the EC2 instance
+// profile, whichever has them first, so services on EC2 and ECS need
+// no keys. See NewAWSCredentialChain.
+func WithAWSCredentialChain() Option {
+	return WithTemporaryCredentials(NewAWSCredentialChain())
+}
+
+// WithTemporaryCredentials - takes expiring credentials from provider,
+// presigned URLs and post policies expire with them at the latest.
+func WithTemporaryCredentials(provider TemporaryCredentials) Option {
+	return func(o *clientOptions) {
+		o.creds = credentials.New(provider)
+		o.credsExpiration = provider.Expiration
+	}
+}
+
+// WithSecure - uses https when secure is set.
+func WithSecure(secure bool) Option {
+	return func(o *clientOptions) {
+		o.secure = secure
+	}
+}
+
+// WithRegion - sets the region of all buckets, bucket locations are
+// not looked up then.
+func WithRegion(region string) Option {
+	return func(o *clientOptions) {
+		o.region = region
+	}
+}
+
+// WithBucketLookup - sets whether buckets are addressed by DNS or by
+// path, BucketLookupAuto by default.
+func WithBucketLookup(lookup BucketLookupType) Option {
+	return func(o *clientOptions) {
+		o.lookup = lookup
+	}
+}
+
+// WithBucketLocationTTL - caches bucket locations for ttl, see
+// SetBucketLocationTTL.
+func WithBucketLocationTTL(ttl time.Duration) Option {
+	return func(o *clientOptions) {
+		o.locationTTLSet = true
+		o.locationTTL = ttl
+	}
+}
+
+// WithBucketLocationNegativeTTL - caches lookups of missing buckets
+// for ttl, see SetBucketLocationNegativeTTL.
+func WithBucketLocationNegativeTTL(ttl time.Duration) Option {
+	return func(o *clientOptions) {
+		o.negativeTTLSet = true
+		o.negativeTTL = ttl
+	}
+}
+
+// WithLocationStore - persists bucket locations in store, see
+// SetLocationStore.
+func WithLocationStore(store LocationStore) Option {
+	return func(o *clientOptions) {
+		o.locationStore = store
+	}
+}
+
+// WithS3TransferAccelerate - sends requests on buckets to the S3
+// accelerate endpoint, see SetS3TransferAccelerate.
+func WithS3TransferAccelerate(accelerateEndpoint string) Option {
+	return func(o *clientOptions) {
+		o.accelerateEndpoint = accelerateEndpoint
+	}
+}
+
+// WithEndpointMap - sends requests on buckets to the host registered
+// for their location, see SetEndpointMap.
+func WithEndpointMap(endpoints map[string]string) Option {
+	return func(o *clientOptions) {
+		o.endpointMap = endpoints
+	}
+}
+
+// WithHTTPClient - sends requests with a copy of httpClient, for its
+// timeouts, transport or cookie jar. Redirects are re-signed unless
+// the client checks them itself, WithTransport replaces its transport.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *clientOptions) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithTransport - sends requests with transport instead of the
+// DefaultTransport, for proxies, instrumentation or custom timeouts.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *clientOptions) {
+		o.transport = transport
+	}
+}
+
+// WithRootCAs - verifies the server certificate against rootCAs instead
+// of the system pool, for deployments with a private CA.
+func WithRootCAs(rootCAs *x509.CertPool) Option {
+	return func(o *clientOptions) {
+		o.rootCAs = rootCAs
+	}
+}
+
+// WithClientCertificates - presents certs to servers requiring mutual
+// TLS.
+func WithClientCertificates(certs ...tls.Certificate) Option {
+	return func(o *clientOptions) {
+		o.clientCerts = append(o.clientCerts, certs...)
+	}
+}
+
+// WithInsecureSkipVerify - accepts any server certificate. Connections
+// are open to man in the middle attacks, for tests only.
+func WithInsecureSkipVerify() Option {
+	return func(o *clientOptions) {
+		o.insecureSkipVerify = true
+	}
+}
+
+// WithProxyURL - sends requests through the proxy at proxyURL instead
+// of the proxies of HTTP_PROXY and HTTPS_PROXY, hosts in NO_PROXY are
+// still reached directly. A nil proxyURL disables proxies. Without this
+// option the environment is honored.
+func WithProxyURL(proxyURL *url.URL) Option {
+	return func(o *clientOptions) {
+		o.proxySet = true
+		o.proxyURL = proxyURL
+	}
+}
+
+// WithDialContext - opens the connections of the DefaultTransport with
+// dial, for example to pin the addresses of the MinIO nodes or to
+// balance the connections over them.
+func WithDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(o *clientOptions) {
+		o.dialContext = dial
+	}
+}
+
+// WithResolver - resolves the host names of the DefaultTransport with
+// resolver, for example a resolver using DNS over TLS. WithDialContext
+// takes precedence.
+func WithResolver(resolver *net.Resolver) Option {
+	return func(o *clientOptions) {
+		o.resolver = resolver
+	}
+}
+
+// WithHTTP2 - enables or disables HTTP/2 of the DefaultTransport. HTTP/2
+// is negotiated over https only and enabled by default, disable it for
+// gateways and load balancers mishandling large PUTs over HTTP/2.
+func WithHTTP2(enabled bool) Option {
+	return func(o *clientOptions) {
+		o.http2Set = true
+		o.http2 = enabled
+	}
+}
+
+// WithTimeouts - limits connecting, the TLS handshake and waiting for
+// the response headers of the DefaultTransport, and detects stalled
+// bodies of any transport, so a hung part upload fails even when its
+// context has no deadline. The connect timeout does not apply to
+// WithDialContext.
+func WithTimeouts(timeouts Timeouts) Option {
+	return func(o *clientOptions) {
+		o.timeouts = timeouts
+	}
+}
+
+// WithSignature - forces the signature type regardless of the
+// credentials, by default Amazon S3 is signed with V4 and Google Cloud
+// Storage with V2.
+func WithSignature(signerType credentials.SignatureType) Option {
+	return func(o *clientOptions) {
+		o.signerType = signerType
+	}
+}
+
+// WithPayloadSigning - sets the payload signing policy, see
+// SetPayloadSigning.
+func WithPayloadSigning(policy PayloadSigning) Option {
+	return func(o *clientOptions) {
+		o.payloadSigning = policy
+	}
+}
+
+// WithRetryPolicy - sets how often failed requests are retried, see
+// SetRetryPolicy.
+func WithRetryPolicy(policy RequestRetryPolicy) Option {
+	return func(o *clientOptions) {
+		o.retryPolicy = policy
+	}
+}
+
+// WithFailoverEndpoints - fails over to endpoints when the endpoint of
+// the client does not connect, see SetFailoverEndpoints.
+func WithFailoverEndpoints(endpoints ...string) Option {
+	return func(o *clientOptions) {
+		o.failoverEndpoints = endpoints
+	}
+}
+
+// WithMetrics - reports the measurements of the client to metrics, see
+// SetMetrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(o *clientOptions) {
+		o.metrics = metrics
+	}
+}
+
+// WithTracerProvider - traces the client with spans of provider, see
+// SetTracerProvider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *clientOptions) {
+		o.tracerProvider = provider
+	}
+}
+
+// WithMiddleware - wraps the sending of every request in middleware,
+// see Client.Use.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(o *clientOptions) {
+		o.middleware = append(o.middleware, middleware...)
+	}
+}
+
+// WithHedging - sends hedge requests under policy, see SetHedging.
+func WithHedging(policy HedgePolicy) Option {
+	return func(o *clientOptions) {
+		o.hedging = policy
+	}
+}
+
+// WithRetryHook - observes failed attempts of requests, see
+// SetRetryHook.
+func WithRetryHook(hook func(RetryEvent)) Option {
+	return func(o *clientOptions) {
+		o.retryHook = hook
+	}
+}
+
+// WithTrace - dumps every request and response to w, only those of
+// failed requests with errorsOnly set.
+func WithTrace(w io.Writer, errorsOnly bool) Option {
+	return func(o *clientOptions) {
+		o.traceOutput = w
+		o.traceErrorsOnly = errorsOnly
+	}
+}
+
+// WithTraceFormatter - formats traces with format, for example
+// JSONTrace, DumpTrace by default.
+func WithTraceFormatter(format TraceFormatter) Option {
+	return func(o *clientOptions) {
+		o.traceFormatter = format
+	}
+}
+
+// WithTraceLimits - samples and cuts traces, see SetTraceLimits.
+func WithTraceLimits(sampleEvery int, maxBody int64) Option {
+	return func(o *clientOptions) {
+		o.traceSampleEvery = sampleEvery
+		o.traceMaxBody = maxBody
+	}
+}
+
+// WithAppInfo - adds the application name and version to the User-Agent
+// of all requests.
+func WithAppInfo(appName, appVersion string) Option {
+	return func(o *clientOptions) {
+		o.appName = appName
+		o.appVersion = appVersion
+	}
+}
+
+// transportOptions - reports whether options of the DefaultTransport
+// are set.
+func (o clientOptions) transportOptions() bool {
+	return o.customTLS() || o.proxySet || o.dialContext != nil || o.resolver != nil || o.http2Set ||
+		o.timeouts.Connect > 0 || o.timeouts.TLSHandshake > 0 || o.timeouts.ResponseHeader > 0
+}
+
+// customTLS - reports whether TLS options are set.
+func (o clientOptions) customTLS() bool {
+	return o.rootCAs != nil || len(o.clientCerts) > 0 || o.insecureSkipVerify
+}
+
+// customTransport - reports whether the transport is not the
+// DefaultTransport.
+func (o clientOptions) customTransport() bool {
+	return o.transport != nil || o.httpClient != nil && o.httpClient.Transport != nil
+}
+
+// configureTransport - applies the TLS, proxy, dial, HTTP/2 and timeout
+// options to tr, a DefaultTransport.
+func (o clientOptions) configureTransport(tr *http.Transport) error {
+	if o.customTLS() {
+		if tr.TLSClientConfig == nil {
+			return ErrInvalidArgument("TLS options need a secure client.")
+		}
+		if o.rootCAs != nil {
+			tr.TLSClientConfig.RootCAs = o.rootCAs
+		}
+		tr.TLSClientConfig.Certificates = append(tr.TLSClientConfig.Certificates, o.clientCerts...)
+		tr.TLSClientConfig.InsecureSkipVerify = o.insecureSkipVerify
+	}
+	if o.proxySet {
+		tr.Proxy = proxyFunc(o.proxyURL)
+	}
+	switch {
+	case o.dialContext != nil:
+		tr.DialContext = o.dialContext
+	case o.resolver != nil || o.timeouts.Connect > 0:
+		connect := 30 * time.Second
+		if o.timeouts.Connect > 0 {
+			connect = o.timeouts.Connect
+		}
+		tr.DialContext = (&net.Dialer{
+			Timeout:   connect,
+			KeepAlive: 30 * time.Second,
+			Resolver:  o.resolver,
+		}).DialContext
+	}
+	if o.timeouts.TLSHandshake > 0 {
+		tr.TLSHandshakeTimeout = o.timeouts.TLSHandshake
+	}
+	if o.timeouts.ResponseHeader > 0 {
+		tr.ResponseHeaderTimeout = o.timeouts.ResponseHeader
+	}
+	if o.http2Set && !o.http2 && tr.TLSClientConfig != nil {
+		// A non-nil empty map keeps the transport on HTTP/1.1.
+		tr.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		var protos []string
+		for _, proto := range tr.TLSClientConfig.NextProtos {
+			if proto != "h2" {
+				protos = append(protos, proto)
+			}
+		}
+		tr.TLSClientConfig.NextProtos = protos
+	}
+	return nil
+}
+
+// proxyFunc - returns the proxy function of a transport sending
+// requests through proxyURL, except to the hosts of NO_PROXY.
+func proxyFunc(proxyURL *url.URL) func(*http.Request) (*url.URL, error) {
+	if proxyURL == nil {
+		return nil
+	}
+	config := httpproxy.FromEnvironment()
+	config.HTTPProxy = proxyURL.String()
+	config.HTTPSProxy = proxyURL.String()
+	proxy := config.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+}
@@ -0,0 +1,79 @@
+package minio_ext
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// presignedGetParams - query parameters allowed in presigned GET URLs,
+// all of them are signed.
+var presignedGetParams = map[string]bool{
+	"response-cache-control":       true,
+	"response-content-disposition": true,
+	"response-content-encoding":    true,
+	"response-content-language":    true,
+	"response-content-type":        true,
+	"response-expires":             true,
+	"versionId":                    true,
+}
+
+// maxPresignedExpires - longest lifetime of presigned URLs allowed by S3.
+const maxPresignedExpires = 7 * 24 * time.Hour
+
+// PresignedGetObject - returns a URL to download bucketName/objectName
+// valid for expires, at most seven days. reqParams may override the
+// headers of the response, like response-content-disposition for a
+// friendly file name, and select a version with versionId.
+func (c Client) PresignedGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration, reqParams url.Values) (*url.URL, error) {
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return nil, err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return nil, err
+	}
+	if expires < time.Second || expires > maxPresignedExpires {
+		return nil, ErrInvalidArgument("Expires must be between one second and seven days.")
+	}
+
+	query := make(url.Values)
+	for k, v := range reqParams {
+		if !presignedGetParams[k] {
+			return nil, ErrInvalidArgument("Query parameter ‘" + k + "’ cannot be presigned.")
+		}
+		if len(v) != 1 {
+			return nil, ErrInvalidArgument("Query parameter ‘" + k + "’ must have a single value.")
+		}
+		query.Set(k, v[0])
+	}
+
+	opts := requestOptionsFrom(ctx)
+	req, err := c.newRequest(ctx, "GET", requestMetadata{
+		presignURL:     true,
+		bucketName:     bucketName,
+		objectName:     objectName,
+		queryValues:    query,
+		expires:        int64(expires / time.Second),
+		bucketLocation: opts.location,
+		bucketLookup:   opts.lookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req.URL, nil
+}
+
+// ContentDisposition - returns a Content-Disposition value making the
+// browser save the download as filename, for response-content-disposition.
+func ContentDisposition(filename string) string {
+	ascii := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, filename)
+	return `attachment; filename="` + ascii + `"; filename*=UTF-8''` + strings.Replace(url.QueryEscape(filename), "+", "%20", -1)
+}
@@ -0,0 +1,68 @@
+package minio_ext
+
+import (
+	"context"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// GenHeadSignedUrl - returns a URL to HEAD bucketName/objectName valid
+// for expires, at most seven days, so that a browser or agent can check
+// that its upload exists. The bucket location is looked up when
+// bucketLocation is empty.
+func (c Client) GenHeadSignedUrl(bucketName string, objectName string, expires time.Duration, bucketLocation string) (string, error) {
+	return c.GenHeadSignedUrlWithContext(context.Background(), bucketName, objectName, expires, bucketLocation)
+}
+
+// GenHeadSignedUrlWithContext - same as GenHeadSignedUrl, ctx cancels the lookup
+// of the bucket location.
+func (c Client) GenHeadSignedUrlWithContext(ctx context.Context, bucketName string, objectName string, expires time.Duration, bucketLocation string) (string, error) {
+	return c.genObjectSignedUrl(ctx, "HEAD", bucketName, objectName, expires, bucketLocation)
+}
+
+// GenDeleteSignedUrl - returns a URL to DELETE bucketName/objectName
+// valid for expires, at most seven days, so that a browser or agent can
+// remove its own upload. The bucket location is looked up when
+// bucketLocation is empty.
+func (c Client) GenDeleteSignedUrl(bucketName string, objectName string, expires time.Duration, bucketLocation string) (string, error) {
+	return c.GenDeleteSignedUrlWithContext(context.Background(), bucketName, objectName, expires, bucketLocation)
+}
+
+// GenDeleteSignedUrlWithContext - same as GenDeleteSignedUrl, ctx cancels the lookup
+// of the bucket location.
+func (c Client) GenDeleteSignedUrlWithContext(ctx context.Context, bucketName string, objectName string, expires time.Duration, bucketLocation string) (string, error) {
+	return c.genObjectSignedUrl(ctx, "DELETE", bucketName, objectName, expires, bucketLocation)
+}
+
+// genObjectSignedUrl - presigns method on bucketName/objectName.
+func (c Client) genObjectSignedUrl(ctx context.Context, method, bucketName, objectName string, expires time.Duration, bucketLocation string) (string, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return "", err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return "", err
+	}
+	if expires < time.Second || expires > maxPresignedExpires {
+		return "", ErrInvalidArgument("Expires must be between one second and seven days.")
+	}
+
+	// A location and lookup set for the call apply as well.
+	opts := requestOptionsFrom(ctx)
+	if bucketLocation == "" {
+		bucketLocation = opts.location
+	}
+	req, err := c.newRequest(ctx, method, requestMetadata{
+		presignURL:     true,
+		bucketName:     bucketName,
+		objectName:     objectName,
+		expires:        int64(expires / time.Second),
+		bucketLocation: bucketLocation,
+		bucketLookup:   opts.lookup,
+	})
+	if err != nil {
+		return "", err
+	}
+	return req.URL.String(), nil
+}
@@ -0,0 +1,178 @@
+package minio_ext
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/pkg/s3signer"
+	"github.com/minio/minio-go/v6/pkg/credentials"
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// expirationDateFormat - date format of the expiration of POST policies.
+const expirationDateFormat = "2006-01-02T15:04:05.000Z"
+
+// PostPolicy - conditions of a single-shot browser upload with a plain
+// HTML form. Small files go this way, large files use the multipart
+// path with presigned part URLs.
+type PostPolicy struct {
+	BucketName string
+
+	// Key of the object, or with KeyPrefix set any key starting with
+	// KeyPrefix. The key form field defaults to KeyPrefix + "${filename}".
+	Key       string
+	KeyPrefix string
+
+	// Allowed size range of the file, no limit when MaxSize is 0.
+	MinSize int64
+	MaxSize int64
+
+	// Content-Type of the file, a type ending in "/", such as "image/",
+	// allows every type starting with it.
+	ContentType string
+
+	// URL the browser is redirected to after the upload, optional.
+	SuccessActionRedirect string
+
+	// Lifetime of the policy, at most seven days.
+	Expires time.Duration
+
+	// Location of the bucket, looked up when empty.
+	Location string
+}
+
+// PresignedPostPolicy - returns the URL to POST the form to and the
+// form fields of a signed policy for p. The fields go before the file
+// field of the form.
+func (c Client) PresignedPostPolicy(p PostPolicy) (*url.URL, map[string]string, error) {
+	return c.PresignedPostPolicyWithContext(context.Background(), p)
+}
+
+// PresignedPostPolicyWithContext - same as PresignedPostPolicy, ctx cancels the lookup
+// of the bucket location.
+func (c Client) PresignedPostPolicyWithContext(ctx context.Context, p PostPolicy) (*url.URL, map[string]string, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(p.BucketName); err != nil {
+		return nil, nil, err
+	}
+	if p.Key == "" && p.KeyPrefix == "" {
+		return nil, nil, ErrInvalidArgument("Key or key prefix must be specified.")
+	}
+	if p.Key != "" && p.KeyPrefix != "" {
+		return nil, nil, ErrInvalidArgument("Key and key prefix are mutually exclusive.")
+	}
+	if p.Expires <= 0 || p.Expires > 7*24*time.Hour {
+		return nil, nil, ErrInvalidArgument("Expires must be between 1 second and 7 days.")
+	}
+	if p.MinSize < 0 || p.MaxSize < 0 || p.MaxSize > 0 && p.MinSize > p.MaxSize {
+		return nil, nil, ErrInvalidArgument("Invalid content length range.")
+	}
+
+	start := time.Now()
+	ctx, span := c.startPresignSpan(ctx, "PostPolicy", p.BucketName, p.Key+p.KeyPrefix)
+	u, formData, err := c.presignedPostPolicy(ctx, p)
+	endSpan(span, err)
+	if err == nil {
+		c.observePresign("PostPolicy", time.Since(start))
+	}
+	return u, formData, err
+}
+
+// presignedPostPolicy - returns the URL and the form fields of the
+// signed policy of the validated p.
+func (c Client) presignedPostPolicy(ctx context.Context, p PostPolicy) (*url.URL, map[string]string, error) {
+	location := p.Location
+	if location == "" {
+		var err error
+		if location, err = c.getBucketLocation(ctx, p.BucketName); err != nil {
+			return nil, nil, err
+		}
+	}
+	isVirtualHost := c.isVirtualHostStyleRequest(*c.endpointURL, p.BucketName)
+	u, err := c.makeTargetURL(p.BucketName, "", location, isVirtualHost, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Get credentials from the configured credentials provider.
+	value, err := c.credsProvider.Get()
+	if err != nil {
+		return nil, nil, err
+	}
+	signerType := value.SignerType
+	// Custom signer set then override the behavior.
+	if c.overrideSignerType != credentials.SignatureDefault {
+		signerType = c.overrideSignerType
+	}
+	if signerType.IsAnonymous() {
+		return nil, nil, ErrInvalidArgument("Presigned operations are not supported for anonymous credentials.")
+	}
+
+	formData := map[string]string{"bucket": p.BucketName}
+	conditions := []interface{}{
+		[]string{"eq", "$bucket", p.BucketName},
+	}
+	if p.KeyPrefix != "" {
+		formData["key"] = p.KeyPrefix + "${filename}"
+		conditions = append(conditions, []string{"starts-with", "$key", p.KeyPrefix})
+	} else {
+		formData["key"] = p.Key
+		conditions = append(conditions, []string{"eq", "$key", p.Key})
+	}
+	if p.MaxSize > 0 {
+		conditions = append(conditions, []interface{}{"content-length-range", p.MinSize, p.MaxSize})
+	}
+	if strings.HasSuffix(p.ContentType, "/") {
+		conditions = append(conditions, []string{"starts-with", "$Content-Type", p.ContentType})
+	} else if p.ContentType != "" {
+		formData["Content-Type"] = p.ContentType
+		conditions = append(conditions, []string{"eq", "$Content-Type", p.ContentType})
+	}
+	if p.SuccessActionRedirect != "" {
+		formData["success_action_redirect"] = p.SuccessActionRedirect
+		conditions = append(conditions, []string{"eq", "$success_action_redirect", p.SuccessActionRedirect})
+	}
+
+	t := c.now()
+	if signerType.IsV2() {
+		formData["AWSAccessKeyId"] = value.AccessKeyID
+	} else {
+		credential := s3signer.GetCredential(value.AccessKeyID, location, t)
+		formData["x-amz-algorithm"] = signV4Algorithm
+		formData["x-amz-credential"] = credential
+		formData["x-amz-date"] = t.Format(iso8601DateFormat)
+		conditions = append(conditions,
+			[]string{"eq", "$x-amz-algorithm", signV4Algorithm},
+			[]string{"eq", "$x-amz-credential", credential},
+			[]string{"eq", "$x-amz-date", t.Format(iso8601DateFormat)})
+	}
+	if value.SessionToken != "" {
+		formData["x-amz-security-token"] = value.SessionToken
+		conditions = append(conditions, []string{"eq", "$x-amz-security-token", value.SessionToken})
+	}
+
+	// The policy never outlives the credentials signing it.
+	expiration := t.Add(p.Expires)
+	if credsExpiration := c.credentialsExpiration(); !credsExpiration.IsZero() && credsExpiration.Before(expiration) {
+		expiration = credsExpiration
+	}
+	policy, err := json.Marshal(struct {
+		Expiration string        `json:"expiration"`
+		Conditions []interface{} `json:"conditions"`
+	}{expiration.Format(expirationDateFormat), conditions})
+	if err != nil {
+		return nil, nil, err
+	}
+	policyBase64 := base64.StdEncoding.EncodeToString(policy)
+	formData["policy"] = policyBase64
+	if signerType.IsV2() {
+		formData["signature"] = s3signer.PostPresignSignatureV2(policyBase64, value.SecretAccessKey)
+	} else {
+		formData["x-amz-signature"] = s3signer.PostPresignSignatureV4(policyBase64, t, value.SecretAccessKey, location)
+	}
+	return u, formData, nil
+}
@@ -0,0 +1,327 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// CompleteMultipartUpload - completes multipart upload uploadID with
+// the given parts, parts are sorted by their part numbers before
+// completing. For uploads encrypted with SSE-C the key used for the
+// parts must be passed in sse, nil otherwise. The completion webhook,
+// if set, is notified in the background.
+func (c Client) CompleteMultipartUpload(bucketName, objectName, uploadID string, parts []CompletePart, sse *SSECustomerKey) (string, error) {
+	return c.CompleteMultipartUploadWithContext(context.Background(), bucketName, objectName, uploadID, parts, sse)
+}
+
+// CompleteMultipartUploadWithContext - same as CompleteMultipartUpload, ctx cancels the
+// completion.
+func (c Client) CompleteMultipartUploadWithContext(ctx context.Context, bucketName, objectName, uploadID string, parts []CompletePart, sse *SSECustomerKey) (string, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return "", err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return "", err
+	}
+	if uploadID == "" {
+		return "", errInvalidArgument(ErrInvalidUploadID)
+	}
+
+	customHeader := make(http.Header)
+	if sse != nil {
+		var err error
+		if customHeader, err = c.SSECustomerHeaders(uploadID, *sse); err != nil {
+			return "", err
+		}
+	}
+
+	// Sort all completed parts.
+	sortedParts := make([]CompletePart, len(parts))
+	copy(sortedParts, parts)
+	sort.Sort(completedParts(sortedParts))
+
+	res, err := c.completeMultipartUpload(ctx, bucketName, objectName, uploadID, completeMultipartUpload{Parts: sortedParts}, customHeader)
+	if err != nil {
+		return "", err
+	}
+
+	// The multipart upload is gone, so is its SSE-C session.
+	c.sseCSessions.Delete(uploadID)
+	c.notifyCompleted(ctx, bucketName, objectName, uploadID, res.ETag)
+	return res.ETag, nil
+}
+
+// CompleteMultipartUploadWithInfo - same as
+// CompleteMultipartUploadWithContext, also returns the identifiers of
+// the completing response for support requests.
+func (c Client) CompleteMultipartUploadWithInfo(ctx context.Context, bucketName, objectName, uploadID string, parts []CompletePart, sse *SSECustomerKey) (string, ResponseInfo, error) {
+	var info ResponseInfo
+	etag, err := c.CompleteMultipartUploadWithContext(WithResponseInfo(ctx, &info), bucketName, objectName, uploadID, parts, sse)
+	return etag, info, err
+}
+
+// NewMultipartUpload - initiates a multipart upload of
+// bucketName/objectName with the headers in customHeader, such as
+// Content-Type and metadata, and returns its upload id.
+func (c Client) NewMultipartUpload(ctx context.Context, bucketName, objectName string, customHeader http.Header) (string, error) {
+	if customHeader == nil {
+		customHeader = make(http.Header)
+	}
+	res, err := c.initiateMultipartUpload(ctx, bucketName, objectName, customHeader)
+	if err != nil {
+		return "", err
+	}
+	return res.UploadID, nil
+}
+
+// NewMultipartUploadWithInfo - same as NewMultipartUpload, also returns
+// the identifiers of the initiating response for support requests.
+func (c Client) NewMultipartUploadWithInfo(ctx context.Context, bucketName, objectName string, customHeader http.Header) (string, ResponseInfo, error) {
+	var info ResponseInfo
+	uploadID, err := c.NewMultipartUpload(WithResponseInfo(ctx, &info), bucketName, objectName, customHeader)
+	return uploadID, info, err
+}
+
+// AbortMultipartUpload - aborts multipart upload uploadID, the parts
+// uploaded so far are removed.
+func (c Client) AbortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error {
+	if uploadID == "" {
+		return errInvalidArgument(ErrInvalidUploadID)
+	}
+	return c.abortMultipartUpload(ctx, bucketName, objectName, uploadID)
+}
+
+// completeMultipartUpload - Completes a multipart upload by assembling previously uploaded parts.
+func (c Client) completeMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string,
+	complete completeMultipartUpload, customHeader http.Header) (completeMultipartUploadResult, error) {
+	// Initialize url queries.
+	urlValues := make(url.Values)
+	urlValues.Set("uploadId", uploadID)
+
+	// Marshal complete multipart body.
+	completeMultipartUploadBytes, err := xml.Marshal(complete)
+	if err != nil {
+		return completeMultipartUploadResult{}, err
+	}
+
+	// Instantiate all the complete multipart buffer.
+	completeMultipartUploadBuffer := bytes.NewReader(completeMultipartUploadBytes)
+	reqMetadata := requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		queryValues:      urlValues,
+		customHeader:     customHeader,
+		contentBody:      completeMultipartUploadBuffer,
+		contentLength:    int64(len(completeMultipartUploadBytes)),
+		contentSHA256Hex: sum256Hex(completeMultipartUploadBytes),
+	}
+
+	// Execute POST to complete multipart upload for an objectName.
+	resp, err := c.executeMethod(ctx, "POST", reqMetadata)
+	defer closeResponse(resp)
+	if err != nil {
+		return completeMultipartUploadResult{}, err
+	}
+	if resp != nil {
+		if resp.StatusCode != http.StatusOK {
+			return completeMultipartUploadResult{}, httpRespToErrorResponse(resp, bucketName, objectName)
+		}
+	}
+
+	// Read resp.Body into a []bytes to parse for Error response inside the body
+	var b []byte
+	b, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return completeMultipartUploadResult{}, err
+	}
+	// Decode completed multipart upload response on success.
+	completeMultipartUploadResult := completeMultipartUploadResult{}
+	err = xmlDecoder(bytes.NewReader(b), &completeMultipartUploadResult)
+	if err != nil {
+		// xml parsing failure due to presence an ill-formed xml fragment
+		return completeMultipartUploadResult, err
+	} else if completeMultipartUploadResult.Bucket == "" {
+		// xml's Decode method ignores well-formed xml that don't apply to the type of value supplied.
+		// In this case, it would leave completeMultipartUploadResult with the corresponding zero-values
+		// of the members.
+
+		// Decode completed multipart upload response on failure
+		completeMultipartUploadErr := ErrorResponse{}
+		err = xmlDecoder(bytes.NewReader(b), &completeMultipartUploadErr)
+		if err != nil {
+			// xml parsing failure due to presence an ill-formed xml fragment
+			return completeMultipartUploadResult, err
+		}
+		return completeMultipartUploadResult, completeMultipartUploadErr
+	}
+	return completeMultipartUploadResult, nil
+}
+
+// initiateMultipartUpload - Initiates a multipart upload and returns an upload ID.
+func (c Client) initiateMultipartUpload(ctx context.Context, bucketName, objectName string, customHeader http.Header) (initiateMultipartUploadResult, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return initiateMultipartUploadResult{}, err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return initiateMultipartUploadResult{}, err
+	}
+
+	// Initialize url queries.
+	urlValues := make(url.Values)
+	urlValues.Set("uploads", "")
+
+	reqMetadata := requestMetadata{
+		bucketName:   bucketName,
+		objectName:   objectName,
+		queryValues:  urlValues,
+		customHeader: customHeader,
+	}
+
+	// Execute POST on an objectName to initiate multipart upload.
+	resp, err := c.executeMethod(ctx, "POST", reqMetadata)
+	defer closeResponse(resp)
+	if err != nil {
+		return initiateMultipartUploadResult{}, err
+	}
+	if resp != nil {
+		if resp.StatusCode != http.StatusOK {
+			return initiateMultipartUploadResult{}, httpRespToErrorResponse(resp, bucketName, objectName)
+		}
+	}
+	// Decode xml for new multipart upload.
+	initiateMultipartUploadResult := initiateMultipartUploadResult{}
+	err = xmlDecoder(resp.Body, &initiateMultipartUploadResult)
+	if err != nil {
+		return initiateMultipartUploadResult, err
+	}
+	return initiateMultipartUploadResult, nil
+}
+
+// uploadPart - Uploads a part in a multipart upload.
+func (c Client) uploadPart(ctx context.Context, bucketName, objectName, uploadID string, reader io.Reader,
+	partNumber int, md5Base64, sha256Hex string, size int64, customHeader http.Header) (ObjectPart, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return ObjectPart{}, err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return ObjectPart{}, err
+	}
+	if size > MaxPartSize {
+		return ObjectPart{}, ErrEntityTooLarge(size, MaxPartSize, bucketName, objectName)
+	}
+	if size <= -1 {
+		return ObjectPart{}, ErrEntityTooSmall(size, bucketName, objectName)
+	}
+	if partNumber <= 0 {
+		return ObjectPart{}, ErrInvalidArgument("Part number cannot be negative or equal to zero.")
+	}
+	if uploadID == "" {
+		return ObjectPart{}, ErrInvalidArgument("UploadID cannot be empty.")
+	}
+
+	// Get resources properly escaped and lined up before using them in http request.
+	urlValues := make(url.Values)
+	// Set part number.
+	urlValues.Set("partNumber", strconv.Itoa(partNumber))
+	// Set upload id.
+	urlValues.Set("uploadId", uploadID)
+
+	reqMetadata := requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		queryValues:      urlValues,
+		customHeader:     customHeader,
+		contentBody:      reader,
+		contentLength:    size,
+		contentMD5Base64: md5Base64,
+		contentSHA256Hex: sha256Hex,
+	}
+
+	// Execute PUT on each part.
+	resp, err := c.executeMethod(ctx, "PUT", reqMetadata)
+	defer closeResponse(resp)
+	if err != nil {
+		return ObjectPart{}, err
+	}
+	if resp != nil {
+		if resp.StatusCode != http.StatusOK {
+			return ObjectPart{}, httpRespToErrorResponse(resp, bucketName, objectName)
+		}
+	}
+	// Once successfully uploaded, return completed part.
+	objPart := ObjectPart{}
+	objPart.Size = size
+	objPart.PartNumber = partNumber
+	// Trim off the odd double quotes from ETag in the beginning and end.
+	objPart.ETag = strings.TrimPrefix(resp.Header.Get("ETag"), "\"")
+	objPart.ETag = strings.TrimSuffix(objPart.ETag, "\"")
+	return objPart, nil
+}
+
+// abortMultipartUpload aborts a multipart upload for the given
+// uploadID, all previously uploaded parts are deleted.
+func (c Client) abortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return err
+	}
+
+	// Initialize url queries.
+	urlValues := make(url.Values)
+	urlValues.Set("uploadId", uploadID)
+
+	// Execute DELETE on multipart upload.
+	resp, err := c.executeMethod(ctx, "DELETE", requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		queryValues:      urlValues,
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+	if resp != nil {
+		if resp.StatusCode != http.StatusNoContent {
+			// Abort has no response body, handle it for any errors.
+			var errorResponse ErrorResponse
+			switch resp.StatusCode {
+			case http.StatusNotFound:
+				// This is needed specifically for abort and it cannot
+				// be converged into default case.
+				errorResponse = ErrorResponse{
+					Code:       "NoSuchUpload",
+					Message:    "The specified multipart upload does not exist.",
+					BucketName: bucketName,
+					Key:        objectName,
+					RequestID:  resp.Header.Get("x-amz-request-id"),
+					HostID:     resp.Header.Get("x-amz-id-2"),
+					Region:     resp.Header.Get("x-amz-bucket-region"),
+				}
+			default:
+				return httpRespToErrorResponse(resp, bucketName, objectName)
+			}
+			return errorResponse
+		}
+	}
+
+	// The multipart upload is gone, so is its SSE-C session.
+	c.sseCSessions.Delete(uploadID)
+	return nil
+}
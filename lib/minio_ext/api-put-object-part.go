@@ -0,0 +1,102 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// maxMemoryPart - largest part PutObjectPart holds in memory, larger
+// parts are spooled to a temporary file.
+const maxMemoryPart = 16 * 1024 * 1024
+
+// PartChecksums - base64 encoded checksums a client declared for a
+// part, empty when not declared. CRC32C is encoded like the S3
+// x-amz-checksum-crc32c header, the big-endian checksum in base64.
+type PartChecksums struct {
+	MD5    string
+	CRC32C string
+}
+
+// PutObjectPart - uploads part partNumber of an upload from reader,
+// which must deliver exactly size bytes, for servers relaying parts
+// sent by browsers. MD5 and CRC32C are computed while the part is read
+// and compared with the declared checksums before anything is sent, a
+// mismatch returns ErrChunkChecksumMismatch so that only this part has
+// to be sent again. The part is uploaded with its MD5, the server
+// verifies it once more.
+func (c Client) PutObjectPart(ctx context.Context, bucketName, objectName, uploadID string, partNumber int,
+	reader io.Reader, size int64, declared PartChecksums) (ObjectPart, error) {
+	if size > MaxPartSize {
+		return ObjectPart{}, ErrEntityTooLarge(size, MaxPartSize, bucketName, objectName)
+	}
+	if size <= -1 {
+		return ObjectPart{}, ErrEntityTooSmall(size, bucketName, objectName)
+	}
+
+	md5Hash := md5.New()
+	crcHash := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	body, n, release, err := spoolBody(reader, size, md5Hash, crcHash)
+	if err != nil {
+		return ObjectPart{}, err
+	}
+	defer release()
+	if n != size {
+		return ObjectPart{}, ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Code:       "IncompleteBody",
+			Message:    fmt.Sprintf("Part %d has %d bytes, expected %d.", partNumber, n, size),
+			BucketName: bucketName,
+			Key:        objectName,
+		}
+	}
+
+	md5Base64 := base64.StdEncoding.EncodeToString(md5Hash.Sum(nil))
+	if declared.MD5 != "" && declared.MD5 != md5Base64 {
+		return ObjectPart{}, ErrChunkChecksumMismatch(bucketName, objectName, partNumber, "MD5", declared.MD5, md5Base64)
+	}
+	if crc := checksumString(crcHash); declared.CRC32C != "" && declared.CRC32C != crc {
+		return ObjectPart{}, ErrChunkChecksumMismatch(bucketName, objectName, partNumber, ChecksumCRC32C, declared.CRC32C, crc)
+	}
+	return c.uploadPart(ctx, bucketName, objectName, uploadID, body, partNumber, md5Base64, "", size, nil)
+}
+
+// spoolBody - reads reader up to one byte beyond size into memory, or
+// into a temporary file when size is beyond maxMemoryPart, and feeds
+// the bytes to hashes. Returns the spooled bytes, which can be sent
+// again, their number and the function removing the temporary file.
+func spoolBody(reader io.Reader, size int64, hashes ...io.Writer) (io.ReadSeeker, int64, func(), error) {
+	if size <= maxMemoryPart {
+		buf := bytes.NewBuffer(make([]byte, 0, size))
+		n, err := io.Copy(io.MultiWriter(append(hashes, buf)...), io.LimitReader(reader, size+1))
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		return bytes.NewReader(buf.Bytes()), n, func() {}, nil
+	}
+
+	f, err := ioutil.TempFile("", "minio-part-")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	release := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	n, err := io.Copy(io.MultiWriter(append(hashes, f)...), io.LimitReader(reader, size+1))
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		release()
+		return nil, 0, nil, err
+	}
+	return f, n, release, nil
+}
@@ -0,0 +1,139 @@
+package minio_ext
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// PutObjectOptions - options for PutObject.
+type PutObjectOptions struct {
+	// Optional headers to store the object with, such as Content-Type
+	// and X-Amz-Meta-* user metadata.
+	Metadata http.Header
+
+	// Optional callback invoked with the size of the object once it is
+	// uploaded.
+	Progress func(n int64)
+
+	// Optional retention and legal hold to lock the object with, the
+	// bucket needs object lock enabled.
+	Retention *ObjectRetention
+	LegalHold bool
+}
+
+// PutObject - uploads size bytes of reader to bucketName/objectName in
+// a single PUT with their MD5, returns the ETag of the object. Meant
+// for objects below the part size, larger ones are better uploaded in
+// an UploadSession. The bytes are read before anything is sent, the
+// request is retried like all others. The completion webhook, if set,
+// is notified in the background.
+func (c Client) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, size int64, opts PutObjectOptions) (string, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return "", err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return "", err
+	}
+	if size > MaxPartSize {
+		return "", ErrEntityTooLarge(size, MaxPartSize, bucketName, objectName)
+	}
+	if size <= -1 {
+		return "", ErrEntityTooSmall(size, bucketName, objectName)
+	}
+
+	md5Hash := md5.New()
+	body, n, release, err := spoolBody(reader, size, md5Hash)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	if n != size {
+		return "", ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Code:       "IncompleteBody",
+			Message:    fmt.Sprintf("Object has %d bytes, expected %d.", n, size),
+			BucketName: bucketName,
+			Key:        objectName,
+		}
+	}
+	return c.putObject(ctx, bucketName, objectName, body, size, base64.StdEncoding.EncodeToString(md5Hash.Sum(nil)), opts)
+}
+
+// putObject - uploads the size bytes of body with MD5 md5Base64 in a
+// single PUT.
+func (c Client) putObject(ctx context.Context, bucketName, objectName string, body io.ReadSeeker, size int64, md5Base64 string, opts PutObjectOptions) (string, error) {
+	if opts.Retention != nil {
+		if err := opts.Retention.validate(); err != nil {
+			return "", err
+		}
+	}
+	customHeader := make(http.Header)
+	for k, v := range opts.Metadata {
+		customHeader[k] = v
+	}
+	setLockHeaders(customHeader, opts.Retention, opts.LegalHold)
+
+	resp, err := c.executeMethod(ctx, "PUT", requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		customHeader:     customHeader,
+		contentBody:      body,
+		contentLength:    size,
+		contentMD5Base64: md5Base64,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return "", err
+	}
+	if resp != nil {
+		if resp.StatusCode != http.StatusOK {
+			return "", httpRespToErrorResponse(resp, bucketName, objectName)
+		}
+	}
+
+	etag := trimEtag(resp.Header.Get("ETag"))
+	if opts.Progress != nil {
+		opts.Progress(size)
+	}
+	c.notifyCompleted(ctx, bucketName, objectName, "", etag)
+	return etag, nil
+}
+
+// singleShot - reports whether the uploaders send size bytes with opts
+// in a single PUT instead of an UploadSession: the object fits in one
+// part and no option needs a session, like a journal expecting its
+// parts.
+func singleShot(size int64, opts UploadOptions) bool {
+	partSize := opts.PartSize
+	if partSize <= 0 {
+		partSize = optimalPartSize(size)
+	}
+	return size < partSize && opts.Fingerprint == nil && opts.Encryption == nil &&
+		opts.RateLimiter == nil && opts.Accounting == nil && opts.Summary == nil && opts.Started == nil &&
+		opts.Journal == nil
+}
+
+// putReaderAt - uploads the size bytes of reader in a single PUT.
+func (c Client) putReaderAt(ctx context.Context, reader io.ReaderAt, size int64, bucketName, objectName string, opts UploadOptions) (string, error) {
+	body := io.NewSectionReader(reader, 0, size)
+	md5Hash := md5.New()
+	if _, err := io.Copy(md5Hash, body); err != nil {
+		return "", err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return c.putObject(ctx, bucketName, objectName, body, size, base64.StdEncoding.EncodeToString(md5Hash.Sum(nil)), PutObjectOptions{
+		Metadata:  opts.Metadata,
+		Progress:  opts.Progress,
+		Retention: opts.Retention,
+		LegalHold: opts.LegalHold,
+	})
+}
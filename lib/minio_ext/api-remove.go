@@ -0,0 +1,154 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// maxDeleteObjects - most keys of a single DeleteObjects request.
+const maxDeleteObjects = 1000
+
+// RemoveObject - removes bucketName/objectName, removing a missing
+// object succeeds.
+func (c Client) RemoveObject(ctx context.Context, bucketName, objectName string) error {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return err
+	}
+
+	// Execute DELETE on objectName.
+	resp, err := c.executeMethod(ctx, "DELETE", requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+	if resp != nil {
+		// S3 answers 204 also for missing objects.
+		if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+			return httpRespToErrorResponse(resp, bucketName, objectName)
+		}
+	}
+	return nil
+}
+
+// RemoveObjectError - describes an object RemoveObjects could not
+// remove.
+type RemoveObjectError struct {
+	ObjectName string
+	Err        error
+}
+
+// Error - Returns the object failure as string.
+func (e RemoveObjectError) Error() string {
+	return fmt.Sprintf("%s: %v", e.ObjectName, e.Err)
+}
+
+// Unwrap - returns the error of the object.
+func (e RemoveObjectError) Unwrap() error {
+	return e.Err
+}
+
+// RemoveObjects - removes objectNames from bucketName with
+// multi-object deletes of up to 1000 keys in quiet mode, so that the
+// server only reports the keys it failed to remove. Those are returned
+// with their S3 errors, the error fails the whole call and is returned
+// with the keys of the batches not sent.
+func (c Client) RemoveObjects(ctx context.Context, bucketName string, objectNames []string) ([]RemoveObjectError, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return nil, err
+	}
+	for _, objectName := range objectNames {
+		if err := s3utils.CheckValidObjectName(objectName); err != nil {
+			return nil, err
+		}
+	}
+
+	var failed []RemoveObjectError
+	for start := 0; start < len(objectNames); start += maxDeleteObjects {
+		end := start + maxDeleteObjects
+		if end > len(objectNames) {
+			end = len(objectNames)
+		}
+		batch, err := c.removeObjects(ctx, bucketName, objectNames[start:end])
+		if err != nil {
+			for _, objectName := range objectNames[start:] {
+				failed = append(failed, RemoveObjectError{ObjectName: objectName, Err: err})
+			}
+			return failed, err
+		}
+		failed = append(failed, batch...)
+	}
+	return failed, nil
+}
+
+// removeObjects - removes up to 1000 objects with a DeleteObjects
+// request, returns the objects the server failed to remove.
+func (c Client) removeObjects(ctx context.Context, bucketName string, objectNames []string) ([]RemoveObjectError, error) {
+	request := deleteMultiObjects{Quiet: true}
+	for _, objectName := range objectNames {
+		request.Objects = append(request.Objects, deleteObject{Key: objectName})
+	}
+	deleteBytes, err := xml.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	urlValues := make(url.Values)
+	urlValues.Set("delete", "")
+
+	// DeleteObjects requires Content-MD5.
+	md5Sum := md5.Sum(deleteBytes)
+	resp, err := c.executeMethod(ctx, "POST", requestMetadata{
+		bucketName:       bucketName,
+		queryValues:      urlValues,
+		contentBody:      bytes.NewReader(deleteBytes),
+		contentLength:    int64(len(deleteBytes)),
+		contentMD5Base64: base64.StdEncoding.EncodeToString(md5Sum[:]),
+		contentSHA256Hex: sum256Hex(deleteBytes),
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpRespToErrorResponse(resp, bucketName, "")
+	}
+
+	result := deleteMultiObjectsResult{}
+	// Quiet results without failures may come without a body.
+	if err = xmlDecoder(resp.Body, &result); err != nil && err != io.EOF {
+		return nil, err
+	}
+	failed := make([]RemoveObjectError, 0, len(result.UnDeletedObjects))
+	for _, object := range result.UnDeletedObjects {
+		failed = append(failed, RemoveObjectError{
+			ObjectName: object.Key,
+			Err: ErrorResponse{
+				Code:       object.Code,
+				Message:    object.Message,
+				BucketName: bucketName,
+				Key:        object.Key,
+				RequestID:  resp.Header.Get("x-amz-request-id"),
+				HostID:     resp.Header.Get("x-amz-id-2"),
+			},
+		})
+	}
+	return failed, nil
+}
@@ -0,0 +1,72 @@
+package minio_ext
+
+import (
+	"context"
+)
+
+// requestOptionsKey - context key of the request options.
+type requestOptionsKey struct{}
+
+// requestOptions - settings of the requests of a single call overriding
+// those of the client, carried by the context of the call.
+type requestOptions struct {
+	// Bucket location, looked up when empty.
+	location string
+
+	// Bucket lookup, that of the client when BucketLookupAuto.
+	lookup BucketLookupType
+
+	// Retries, the fields set override those of the client.
+	retryPolicy RequestRetryPolicy
+
+	// Uploads completed without notifying the completion webhook.
+	withoutWebhook bool
+}
+
+// requestOptionsFrom - returns the request options of ctx.
+func requestOptionsFrom(ctx context.Context) requestOptions {
+	if ctx == nil {
+		return requestOptions{}
+	}
+	opts, _ := ctx.Value(requestOptionsKey{}).(requestOptions)
+	return opts
+}
+
+// WithRequestRegion - returns a copy of ctx making the requests of calls
+// taking it use region as bucket location, the location of the bucket
+// is not looked up then. For deployments with a known region per bucket.
+func WithRequestRegion(ctx context.Context, region string) context.Context {
+	opts := requestOptionsFrom(ctx)
+	opts.location = region
+	return context.WithValue(ctx, requestOptionsKey{}, opts)
+}
+
+// WithRequestBucketLookup - returns a copy of ctx making the requests of
+// calls taking it address the bucket by DNS or by path regardless of the
+// lookup of the client, for example path style for buckets with dots in
+// a workload preferring DNS style. BucketLookupAuto keeps the lookup of
+// the client.
+func WithRequestBucketLookup(ctx context.Context, lookup BucketLookupType) context.Context {
+	opts := requestOptionsFrom(ctx)
+	opts.lookup = lookup
+	return context.WithValue(ctx, requestOptionsKey{}, opts)
+}
+
+// WithRequestRetry - returns a copy of ctx making the requests of calls
+// taking it retry with policy, its zero fields keep the policy of the
+// client.
+func WithRequestRetry(ctx context.Context, policy RequestRetryPolicy) context.Context {
+	opts := requestOptionsFrom(ctx)
+	opts.retryPolicy = policy
+	return context.WithValue(ctx, requestOptionsKey{}, opts)
+}
+
+// WithoutCompletionWebhook - returns a copy of ctx making the uploads
+// completed by calls taking it not notify the completion webhook of the
+// client, for callers processing the object further and notifying the
+// webhook with NotifyCompleted once the object is final.
+func WithoutCompletionWebhook(ctx context.Context) context.Context {
+	opts := requestOptionsFrom(ctx)
+	opts.withoutWebhook = true
+	return context.WithValue(ctx, requestOptionsKey{}, opts)
+}
@@ -0,0 +1,309 @@
+package minio_ext
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// PartState - position of a planned part in the source.
+type PartState struct {
+	PartNumber int   `json:"partNumber"`
+	Offset     int64 `json:"offset"`
+	Size       int64 `json:"size"`
+}
+
+// UploadState - persistable state of an UploadSession, enough to
+// resume the upload with OpenUploadSession after a restart. Uploaded
+// parts are not part of the state, they are listed from the server.
+type UploadState struct {
+	BucketName string `json:"bucketName"`
+	ObjectName string `json:"objectName"`
+	UploadID   string `json:"uploadId"`
+
+	// Time the upload id was created, zero when unknown.
+	Initiated time.Time `json:"initiated,omitempty"`
+
+	// Source the upload was started with.
+	Size        int64            `json:"size"`
+	ModTime     time.Time        `json:"modTime,omitempty"`
+	Fingerprint *FileFingerprint `json:"fingerprint,omitempty"`
+
+	// Part plan, later parts of adaptive uploads are planned on resume.
+	PartSize int64       `json:"partSize"`
+	Adaptive bool        `json:"adaptive,omitempty"`
+	Parts    []PartState `json:"parts"`
+
+	// Manifest of end to end encrypted uploads, Size and Parts then
+	// describe the plaintext, see SentPart. Useless without the master
+	// key.
+	Encryption *EncryptionManifest `json:"encryption,omitempty"`
+
+	// Expired is set once the upload id is known to be gone on the
+	// server, such a state cannot be resumed.
+	Expired bool `json:"expired,omitempty"`
+
+	// Stream is set for uploads of UploadStream, Parts and Size then
+	// describe the parts flushed so far.
+	Stream bool `json:"stream,omitempty"`
+
+	// Sources of uploads of ComposeObject as bucket/object@etag, Parts
+	// then describe the ranges copied from them in order.
+	Sources []string `json:"sources,omitempty"`
+
+	// Quota subjects the size is reserved for by the upload server.
+	QuotaSubjects []string `json:"quotaSubjects,omitempty"`
+
+	// Hex encoded MD5 of the source declared to the upload server, it
+	// is added to its content index once the object is verified.
+	ContentMD5 string `json:"contentMd5,omitempty"`
+
+	// Parts known to be uploaded when the state was taken, including
+	// parts recorded in the journal of the store since.
+	Completed []CompletedPart `json:"completed,omitempty"`
+}
+
+// ErrSourceChanged - the source of a resumed upload differs from the
+// one the upload was started with.
+func ErrSourceChanged(uploadID, reason string) error {
+	return ErrorResponse{
+		StatusCode: http.StatusBadRequest,
+		Code:       "SourceChanged",
+		Message:    "The source of multipart upload ‘" + uploadID + "’ has changed: " + reason + ".",
+	}
+}
+
+// SentPart - returns part of the plan as sent to the server, the part
+// itself unless the upload is encrypted.
+func (s UploadState) SentPart(part PartState) PartState {
+	if s.Encryption == nil {
+		return part
+	}
+	return s.Encryption.SealedPart(part)
+}
+
+// State - returns the current state of the session, it should be
+// persisted after the session is created and may be refreshed while
+// uploading so adaptive uploads resume with their latest plan.
+func (s *UploadSession) State() UploadState {
+	state := UploadState{
+		BucketName:  s.bucketName,
+		ObjectName:  s.objectName,
+		UploadID:    s.uploadID,
+		Initiated:   s.initiated,
+		Size:        s.size,
+		ModTime:     s.opts.ModTime,
+		Fingerprint: s.opts.Fingerprint,
+		Expired:     s.Expired(),
+	}
+	if s.planner != nil {
+		state.PartSize, state.Adaptive = s.planner.settings()
+		for _, spec := range s.planner.parts() {
+			state.Parts = append(state.Parts, PartState(spec))
+		}
+	}
+	if s.key != nil {
+		manifest := s.key.manifest
+		state.Encryption = &manifest
+	}
+	s.mutex.Lock()
+	for _, part := range s.parts {
+		state.Completed = append(state.Completed, CompletedPart{
+			PartNumber: part.PartNumber,
+			Size:       part.Size,
+			ETag:       part.ETag,
+			MD5:        s.md5s[part.PartNumber],
+		})
+	}
+	s.mutex.Unlock()
+	sort.Slice(state.Completed, func(i, j int) bool { return state.Completed[i].PartNumber < state.Completed[j].PartNumber })
+	return state
+}
+
+// OpenUploadSession - resumes the multipart upload described by state
+// with the source reader of size bytes. The source is checked against
+// the size, modification time and fingerprint recorded in state, then
+// the parts already on the server are listed and only missing parts or
+// parts of the wrong size are uploaded again. Part size and mode are
+// taken from state, all other options apply as for NewUploadSession.
+func (c *Client) OpenUploadSession(ctx context.Context, state UploadState, reader io.ReaderAt, size int64, opts UploadOptions) (*UploadSession, error) {
+	// Input validation.
+	if reader == nil {
+		return nil, ErrInvalidArgument("Reader cannot be nil.")
+	}
+	if state.UploadID == "" {
+		return nil, ErrInvalidArgument("Upload state has no upload id.")
+	}
+	if state.Stream {
+		return nil, ErrSourceChanged(state.UploadID, "upload was started from a stream")
+	}
+	if len(state.Sources) > 0 {
+		return nil, ErrSourceChanged(state.UploadID, "upload composes objects")
+	}
+	if state.Expired {
+		return nil, UploadExpiredError{
+			BucketName: state.BucketName,
+			ObjectName: state.ObjectName,
+			UploadID:   state.UploadID,
+		}
+	}
+	if err := s3utils.CheckValidBucketName(state.BucketName); err != nil {
+		return nil, err
+	}
+	if err := s3utils.CheckValidObjectName(state.ObjectName); err != nil {
+		return nil, err
+	}
+	if opts.NumThreads <= 0 {
+		opts.NumThreads = totalWorkers
+	}
+	opts.PartRetry = opts.PartRetry.withDefaults()
+	if opts.Retention != nil || opts.LegalHold {
+		opts.SendContentMD5 = true
+	}
+
+	// Make sure the source is still the same.
+	if size != state.Size {
+		return nil, ErrSourceChanged(state.UploadID, "size differs")
+	}
+	if !state.ModTime.IsZero() && !opts.ModTime.IsZero() && !state.ModTime.Equal(opts.ModTime) {
+		return nil, ErrSourceChanged(state.UploadID, "modification time differs")
+	}
+	if state.Fingerprint != nil {
+		fp := opts.Fingerprint
+		if fp == nil && opts.StrictResume {
+			computed, err := Fingerprint(io.NewSectionReader(reader, 0, size))
+			if err != nil {
+				return nil, err
+			}
+			fp = &computed
+		}
+		if fp != nil && *fp != *state.Fingerprint {
+			return nil, ErrSourceChanged(state.UploadID, "fingerprint differs")
+		}
+		opts.Fingerprint = state.Fingerprint
+	}
+	if !state.ModTime.IsZero() {
+		opts.ModTime = state.ModTime
+	}
+
+	var key *sessionKey
+	switch {
+	case state.Encryption != nil && opts.Encryption == nil:
+		return nil, ErrInvalidArgument("Upload is encrypted, encryption options are required to resume it.")
+	case state.Encryption == nil && opts.Encryption != nil:
+		return nil, ErrInvalidArgument("Upload was started without encryption.")
+	case state.Encryption != nil:
+		if state.Encryption.Size != size {
+			return nil, ErrSourceChanged(state.UploadID, "encrypted size differs")
+		}
+		var err error
+		if key, err = opts.Encryption.openManifest(*state.Encryption); err != nil {
+			return nil, err
+		}
+		for _, part := range state.Parts {
+			if part.Offset%key.manifest.ChunkSize != 0 {
+				return nil, ErrInvalidArgument(fmt.Sprintf("Part %d of the encrypted upload does not start at a chunk.", part.PartNumber))
+			}
+		}
+	}
+
+	plan := make([]partSpec, 0, len(state.Parts))
+	for _, part := range state.Parts {
+		plan = append(plan, partSpec(part))
+	}
+	if state.PartSize < absMinPartSize || state.PartSize > MaxPartSize {
+		return nil, ErrInvalidArgument("Upload state has an invalid part size.")
+	}
+	planner, err := restorePartPlanner(size, state.PartSize, state.Adaptive, plan)
+	if err != nil {
+		return nil, err
+	}
+	if key != nil {
+		planner.align = key.manifest.ChunkSize
+	}
+
+	s := &UploadSession{
+		client:     c,
+		bucketName: state.BucketName,
+		objectName: state.ObjectName,
+		uploadID:   state.UploadID,
+		initiated:  state.Initiated,
+		reader:     reader,
+		size:       size,
+		planner:    planner,
+		opts:       opts,
+		parts:      make(map[int]ObjectPart),
+		md5s:       make(map[int]string),
+		limiter:    opts.RateLimiter,
+		key:        key,
+	}
+	if err = s.reconcileParts(ctx, state.Completed); err != nil {
+		return nil, err
+	}
+	s.resumed = s.Uploaded()
+	return s, nil
+}
+
+// reconcileParts - keeps the uploaded parts which match the plan, in
+// strict mode only when their MD5 matches the local data as well. The
+// parts are listed from the server, when listing fails for another
+// reason than an expired upload the completed parts recorded in the
+// state are used instead.
+func (s *UploadSession) reconcileParts(ctx context.Context, completed []CompletedPart) error {
+	journaled := make(map[int]CompletedPart, len(completed))
+	for _, part := range completed {
+		journaled[part.PartNumber] = part
+	}
+	uploaded, err := s.client.ListObjectParts(s.bucketName, s.objectName, s.uploadID)
+	if err != nil {
+		if len(journaled) == 0 || IsUploadExpired(err) {
+			return err
+		}
+		uploaded = make(map[int]ObjectPart, len(journaled))
+		for number, part := range journaled {
+			uploaded[number] = ObjectPart{PartNumber: number, ETag: part.ETag, Size: part.Size}
+		}
+	}
+	for _, spec := range s.planner.parts() {
+		part, ok := uploaded[spec.PartNumber]
+		if !ok || part.Size != s.sentSize(spec) {
+			continue
+		}
+		expected := part.ETag
+		if j, ok := journaled[spec.PartNumber]; ok && j.ETag == part.ETag && j.MD5 != "" {
+			expected = j.MD5
+			s.md5s[spec.PartNumber] = j.MD5
+		}
+		if s.opts.StrictResume {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+			md5Sum, err := partMD5(s.partReader(spec))
+			if err != nil {
+				return err
+			}
+			if !strings.EqualFold(hex.EncodeToString(md5Sum), expected) {
+				continue
+			}
+		}
+		s.parts[spec.PartNumber] = part
+	}
+	return nil
+}
+
+// partMD5 - returns the md5sum of a part.
+func partMD5(reader io.Reader) ([]byte, error) {
+	hash := md5.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
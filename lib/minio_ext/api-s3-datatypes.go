@@ -18,6 +18,7 @@
 package minio_ext
 
 import (
+	"encoding/xml"
 	"time"
 )
 
@@ -68,4 +69,238 @@ type ListObjectPartsResult struct {
 	ObjectParts []ObjectPart `xml:"Part"`
 
 	EncodingType string
-}
\ No newline at end of file
+}
+
+// initiateMultipartUploadResult container for InitiateMultiPartUpload
+// response.
+type initiateMultipartUploadResult struct {
+	Bucket   string
+	Key      string
+	UploadID string `xml:"UploadId"`
+}
+
+// CompletePart sub container lists individual part numbers and their
+// md5sum, part of completeMultipartUpload.
+type CompletePart struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ Part" json:"-"`
+
+	// Part number identifies the part.
+	PartNumber int
+	ETag       string
+}
+
+// completeMultipartUpload container for completing multipart upload.
+type completeMultipartUpload struct {
+	XMLName xml.Name       `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CompleteMultipartUpload" json:"-"`
+	Parts   []CompletePart `xml:"Part"`
+}
+
+// completeMultipartUploadResult container for completed multipart
+// upload response.
+type completeMultipartUploadResult struct {
+	Location string
+	Bucket   string
+	Key      string
+	ETag     string
+}
+
+// completedParts is a collection of parts sortable by their part numbers.
+// used for sorting the uploaded parts before completing the multipart request.
+type completedParts []CompletePart
+
+func (a completedParts) Len() int           { return len(a) }
+func (a completedParts) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a completedParts) Less(i, j int) bool { return a[i].PartNumber < a[j].PartNumber }
+
+// objectChecksums container for the checksums of an object or a part,
+// part of getObjectAttributesResult.
+type objectChecksums struct {
+	ChecksumCRC32  string
+	ChecksumCRC32C string
+	ChecksumSHA1   string
+	ChecksumSHA256 string
+}
+
+// objectAttributesPart container for a part of an object, part of
+// getObjectAttributesResult.
+type objectAttributesPart struct {
+	objectChecksums
+	PartNumber int
+	Size       int64
+}
+
+// getObjectAttributesResult container for GetObjectAttributes response.
+type getObjectAttributesResult struct {
+	ETag        string
+	ObjectSize  int64
+	Checksum    objectChecksums
+	ObjectParts struct {
+		IsTruncated          bool
+		NextPartNumberMarker int
+		PartsCount           int
+		Parts                []objectAttributesPart `xml:"Part"`
+	}
+}
+
+// CorsRule container for a CORS rule of a bucket.
+type CorsRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedHeaders []string `xml:"AllowedHeader,omitempty"`
+	ExposeHeaders  []string `xml:"ExposeHeader,omitempty"`
+	MaxAgeSeconds  int      `xml:"MaxAgeSeconds,omitempty"`
+}
+
+// corsConfiguration container for PutBucketCors request and
+// GetBucketCors response.
+type corsConfiguration struct {
+	XMLName xml.Name   `xml:"CORSConfiguration"`
+	Rules   []CorsRule `xml:"CORSRule"`
+}
+
+// LifecycleRule container for a lifecycle rule of a bucket, the rule
+// applies to objects and uploads under Prefix. Status is ‘Enabled’ or
+// ‘Disabled’, zero days disable the action.
+type LifecycleRule struct {
+	ID     string `xml:"ID,omitempty"`
+	Status string `xml:"Status"`
+	Prefix string `xml:"Filter>Prefix"`
+
+	// Days after creation objects are removed.
+	ExpirationDays int `xml:"Expiration>Days,omitempty"`
+
+	// Days after initiation incomplete multipart uploads are aborted.
+	AbortIncompleteUploadDays int `xml:"AbortIncompleteMultipartUpload>DaysAfterInitiation,omitempty"`
+}
+
+// MarshalXML - encodes the rule without the elements of disabled
+// actions, omitempty keeps the empty parents of nested elements.
+func (r LifecycleRule) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type days struct {
+		Days int `xml:"Days"`
+	}
+	type daysAfterInitiation struct {
+		DaysAfterInitiation int `xml:"DaysAfterInitiation"`
+	}
+	rule := struct {
+		ID                             string               `xml:"ID,omitempty"`
+		Status                         string               `xml:"Status"`
+		Prefix                         string               `xml:"Filter>Prefix"`
+		Expiration                     *days                `xml:"Expiration,omitempty"`
+		AbortIncompleteMultipartUpload *daysAfterInitiation `xml:"AbortIncompleteMultipartUpload,omitempty"`
+	}{ID: r.ID, Status: r.Status, Prefix: r.Prefix}
+	if r.ExpirationDays != 0 {
+		rule.Expiration = &days{r.ExpirationDays}
+	}
+	if r.AbortIncompleteUploadDays != 0 {
+		rule.AbortIncompleteMultipartUpload = &daysAfterInitiation{r.AbortIncompleteUploadDays}
+	}
+	return e.EncodeElement(rule, start)
+}
+
+// lifecycleConfiguration container for PutBucketLifecycle request and
+// GetBucketLifecycle response.
+type lifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"LifecycleConfiguration"`
+	Rules   []LifecycleRule `xml:"Rule"`
+}
+
+// rawLifecycleRule container for a lifecycle rule kept as received,
+// including actions and filters LifecycleRule does not describe.
+type rawLifecycleRule struct {
+	Inner string `xml:",innerxml"`
+}
+
+// rawLifecycleConfiguration container for lifecycle rules kept as
+// received.
+type rawLifecycleConfiguration struct {
+	XMLName xml.Name           `xml:"LifecycleConfiguration"`
+	Rules   []rawLifecycleRule `xml:"Rule"`
+}
+
+// deleteObject container for an object of a DeleteObjects request.
+type deleteObject struct {
+	Key string
+}
+
+// deleteMultiObjects container for DeleteObjects request.
+type deleteMultiObjects struct {
+	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool
+	Objects []deleteObject `xml:"Object"`
+}
+
+// nonDeletedObject container for an object a DeleteObjects request
+// failed to remove.
+type nonDeletedObject struct {
+	Key     string
+	Code    string
+	Message string
+}
+
+// deleteMultiObjectsResult container for DeleteObjects response.
+type deleteMultiObjectsResult struct {
+	XMLName          xml.Name           `xml:"DeleteResult"`
+	UnDeletedObjects []nonDeletedObject `xml:"Error"`
+}
+
+// listBucketV2Result container for ListObjectsV2 response.
+type listBucketV2Result struct {
+	// A response can contain CommonPrefixes only if you have
+	// specified a delimiter.
+	CommonPrefixes []CommonPrefix
+	// Metadata about each object returned.
+	Contents  []ObjectInfo
+	Delimiter string
+
+	// Encoding type used to encode object keys in the response.
+	EncodingType string
+
+	// A flag that indicates whether or not ListObjects returned all of the results
+	// that satisfied the search criteria.
+	IsTruncated bool
+	MaxKeys     int64
+	Name        string
+
+	// Token to continue a truncated listing with.
+	NextContinuationToken string
+
+	ContinuationToken string
+	Prefix            string
+	StartAfter        string
+}
+
+// copyObjectResult container for CopyObject and UploadPartCopy response.
+type copyObjectResult struct {
+	ETag         string
+	LastModified time.Time
+}
+
+// tag container for a tag of an object.
+type tag struct {
+	Key   string
+	Value string
+}
+
+// tagging container for PutObjectTagging request and GetObjectTagging
+// response.
+type tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Tags    []tag    `xml:"TagSet>Tag"`
+}
+
+// objectRetention container for PutObjectRetention request and
+// GetObjectRetention response.
+type objectRetention struct {
+	XMLName         xml.Name `xml:"Retention"`
+	Mode            string   `xml:"Mode,omitempty"`
+	RetainUntilDate string   `xml:"RetainUntilDate,omitempty"`
+}
+
+// objectLegalHold container for PutObjectLegalHold request and
+// GetObjectLegalHold response.
+type objectLegalHold struct {
+	XMLName xml.Name `xml:"LegalHold"`
+	Status  string   `xml:"Status"`
+}
@@ -0,0 +1,150 @@
+package minio_ext
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// statObject - fetches the metadata of an object with a HEAD request.
+func (c Client) statObject(ctx context.Context, bucketName, objectName string, customHeader http.Header) (ObjectInfo, error) {
+	// Input validation.
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return ObjectInfo{}, err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return ObjectInfo{}, err
+	}
+
+	// Execute HEAD on objectName.
+	resp, err := c.executeMethod(ctx, "HEAD", requestMetadata{
+		bucketName:       bucketName,
+		objectName:       objectName,
+		contentSHA256Hex: emptySHA256Hex,
+		customHeader:     customHeader,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	if resp != nil {
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+			return ObjectInfo{}, httpRespToErrorResponse(resp, bucketName, objectName)
+		}
+	}
+
+	return toObjectInfo(bucketName, objectName, resp.Header)
+}
+
+// StatObject - returns size, ETag, content type, metadata and version
+// of an object.
+func (c Client) StatObject(ctx context.Context, bucketName, objectName string) (ObjectInfo, error) {
+	return c.statObject(ctx, bucketName, objectName, nil)
+}
+
+// ObjectExists - reports whether bucketName/objectName exists, errors
+// other than a missing object are returned.
+func (c Client) ObjectExists(ctx context.Context, bucketName, objectName string) (bool, error) {
+	_, err := c.statObject(ctx, bucketName, objectName, nil)
+	if errors.Is(err, ErrNoSuchKey) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// trimEtag - trims off the odd double quotes from ETag in the
+// beginning and end.
+func trimEtag(etag string) string {
+	etag = strings.TrimPrefix(etag, "\"")
+	return strings.TrimSuffix(etag, "\"")
+}
+
+// extractObjMetadata - returns the headers describing the object.
+func extractObjMetadata(header http.Header) http.Header {
+	filteredHeader := make(http.Header)
+	for k, v := range header {
+		if isStandardHeader(k) || isStorageClassHeader(k) || isAmzHeader(k) {
+			filteredHeader[k] = v
+		}
+	}
+	return filteredHeader
+}
+
+// toObjectInfo - converts the response headers of a HEAD or GET object
+// request into ObjectInfo.
+func toObjectInfo(bucketName string, objectName string, h http.Header) (ObjectInfo, error) {
+	var err error
+	etag := trimEtag(h.Get("ETag"))
+
+	// Parse content length is exists
+	var size int64 = -1
+	contentLengthStr := h.Get("Content-Length")
+	if contentLengthStr != "" {
+		size, err = strconv.ParseInt(contentLengthStr, 10, 64)
+		if err != nil {
+			// Content-Length is not valid
+			return ObjectInfo{}, ErrorResponse{
+				Code:       "InternalError",
+				Message:    "Content-Length is invalid. " + reportIssue,
+				BucketName: bucketName,
+				Key:        objectName,
+				RequestID:  h.Get("x-amz-request-id"),
+				HostID:     h.Get("x-amz-id-2"),
+				Region:     h.Get("x-amz-bucket-region"),
+			}
+		}
+	}
+
+	// Parse Last-Modified has http time format.
+	date, err := time.Parse(http.TimeFormat, h.Get("Last-Modified"))
+	if err != nil {
+		return ObjectInfo{}, ErrorResponse{
+			Code:       "InternalError",
+			Message:    "Last-Modified time format is invalid. " + reportIssue,
+			BucketName: bucketName,
+			Key:        objectName,
+			RequestID:  h.Get("x-amz-request-id"),
+			HostID:     h.Get("x-amz-id-2"),
+			Region:     h.Get("x-amz-bucket-region"),
+		}
+	}
+
+	// Fetch content type if any present.
+	contentType := strings.TrimSpace(h.Get("Content-Type"))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	expiryStr := h.Get("Expires")
+	var expTime time.Time
+	if t, err := time.Parse(http.TimeFormat, expiryStr); err == nil {
+		expTime = t.UTC()
+	}
+
+	metadata := extractObjMetadata(h)
+	userMetadata := make(StringMap)
+	for k, v := range metadata {
+		if strings.HasPrefix(k, "X-Amz-Meta-") {
+			userMetadata[strings.TrimPrefix(k, "X-Amz-Meta-")] = v[0]
+		}
+	}
+
+	// Save object metadata info.
+	return ObjectInfo{
+		ETag:         etag,
+		Key:          objectName,
+		Size:         size,
+		LastModified: date,
+		ContentType:  contentType,
+		Expires:      expTime,
+		Metadata:     metadata,
+		UserMetadata: userMetadata,
+		StorageClass: h.Get(amzStorageClass),
+		VersionID:    h.Get(amzVersionID),
+	}, nil
+}
@@ -0,0 +1,257 @@
+package minio_ext
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// WalkOptions - options for UploadDir and UploadDirFS.
+type WalkOptions struct {
+	// Glob patterns as understood by path.Match. Patterns containing a
+	// slash are matched against the slash separated path relative to
+	// the uploaded directory, all others against the file name. When
+	// Include is set only matching files are uploaded, files and
+	// directories matching Exclude are always skipped.
+	Include []string
+	Exclude []string
+
+	// Number of files uploaded in parallel, defaults to 1. Every file
+	// uses Upload.NumThreads parallel parts.
+	NumFiles int
+
+	// Options for every file upload, Fingerprint and ModTime are set
+	// per file.
+	Upload UploadOptions
+
+	// Optional store to resume interrupted file uploads.
+	States StateStore
+
+	// Optional callback invoked whenever the overall progress changes,
+	// calls are serialized.
+	Progress func(DirProgress)
+}
+
+// DirProgress - overall progress of an UploadDir or UploadDirFS call.
+type DirProgress struct {
+	Files     int
+	FilesDone int
+	Bytes     int64
+	BytesDone int64
+}
+
+// FileError - describes a file which could not be uploaded.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+// Error - Returns the file failure as string.
+func (e FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap - returns the error of the file.
+func (e FileError) Unwrap() error {
+	return e.Err
+}
+
+// DirUploadError - returned by UploadDir and UploadDirFS when some
+// files could not be uploaded, all other files are uploaded.
+type DirUploadError struct {
+	Files []FileError
+}
+
+// Error - Returns all file failures as string.
+func (e DirUploadError) Error() string {
+	msgs := make([]string, 0, len(e.Files))
+	for _, file := range e.Files {
+		msgs = append(msgs, file.Error())
+	}
+	return fmt.Sprintf("%d file(s) failed: %s", len(e.Files), strings.Join(msgs, "; "))
+}
+
+// matchesAny - reports whether the relative path rel matches any of
+// patterns.
+func matchesAny(patterns []string, rel string) (bool, error) {
+	for _, pattern := range patterns {
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			return false, ErrInvalidArgument("Invalid pattern ‘" + pattern + "’.")
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// dirFile - a file found by walkFS.
+type dirFile struct {
+	path string
+	rel  string
+	size int64
+}
+
+// walkFS - returns the regular files below root in fsys selected by
+// opts, paths are slash separated.
+func walkFS(fsys fs.FS, root string, opts WalkOptions) ([]dirFile, error) {
+	var files []dirFile
+	err := fs.WalkDir(fsys, root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath == root {
+			return nil
+		}
+		rel := filePath
+		if root != "." {
+			rel = strings.TrimPrefix(filePath, root+"/")
+		}
+
+		excluded, err := matchesAny(opts.Exclude, rel)
+		if err != nil {
+			return err
+		}
+		if excluded {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			// Directories are walked, symlinks and devices skipped.
+			return nil
+		}
+		if len(opts.Include) > 0 {
+			included, err := matchesAny(opts.Include, rel)
+			if err != nil {
+				return err
+			}
+			if !included {
+				return nil
+			}
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, dirFile{path: filePath, rel: rel, size: info.Size()})
+		return nil
+	})
+	return files, err
+}
+
+// UploadDir - uploads all files below localDir selected by opts to
+// bucketName, the object name of every file is prefix followed by its
+// slash separated path relative to localDir. Every file is uploaded
+// with UploadFile, failed files do not stop the others and are
+// reported in a DirUploadError.
+func (c *Client) UploadDir(ctx context.Context, localDir, bucketName, prefix string, opts WalkOptions) error {
+	files, err := walkFS(os.DirFS(localDir), ".", opts)
+	if err != nil {
+		return err
+	}
+	for i := range files {
+		files[i].path = filepath.Join(localDir, filepath.FromSlash(files[i].path))
+	}
+	return c.uploadFiles(ctx, files, opts, func(file dirFile, uploadOpts UploadOptions) error {
+		_, err := c.UploadFile(ctx, file.path, bucketName, prefix+file.rel, opts.States, uploadOpts)
+		return err
+	})
+}
+
+// UploadDirFS - same as UploadDir for the files below root in fsys,
+// every file is uploaded with UploadFS.
+func (c *Client) UploadDirFS(ctx context.Context, fsys fs.FS, root, bucketName, prefix string, opts WalkOptions) error {
+	files, err := walkFS(fsys, root, opts)
+	if err != nil {
+		return err
+	}
+	return c.uploadFiles(ctx, files, opts, func(file dirFile, uploadOpts UploadOptions) error {
+		_, err := c.UploadFS(ctx, fsys, file.path, bucketName, prefix+file.rel, opts.States, uploadOpts)
+		return err
+	})
+}
+
+// uploadFiles - uploads files with upload in parallel and reports the
+// overall progress.
+func (c *Client) uploadFiles(ctx context.Context, files []dirFile, opts WalkOptions, upload func(dirFile, UploadOptions) error) error {
+	if opts.NumFiles <= 0 {
+		opts.NumFiles = 1
+	}
+	var progressMutex sync.Mutex
+	progress := DirProgress{Files: len(files)}
+	for _, file := range files {
+		progress.Bytes += file.size
+	}
+	report := func(files int, bytes int64) {
+		progressMutex.Lock()
+		defer progressMutex.Unlock()
+		progress.FilesDone += files
+		progress.BytesDone += bytes
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+	report(0, 0)
+
+	fileCh := make(chan dirFile)
+	go func() {
+		defer close(fileCh)
+		for _, file := range files {
+			select {
+			case fileCh <- file:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var errMutex sync.Mutex
+	var fileErrs []FileError
+	for i := 0; i < opts.NumFiles; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range fileCh {
+				uploadOpts := opts.Upload
+				uploadOpts.Fingerprint = nil
+				uploadOpts.Progress = func(n int64) {
+					report(0, n)
+					if opts.Upload.Progress != nil {
+						opts.Upload.Progress(n)
+					}
+				}
+				if err := upload(file, uploadOpts); err != nil {
+					errMutex.Lock()
+					fileErrs = append(fileErrs, FileError{Path: file.path, Err: err})
+					errMutex.Unlock()
+					continue
+				}
+				report(1, 0)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(fileErrs) > 0 {
+		sort.Slice(fileErrs, func(i, j int) bool { return fileErrs[i].Path < fileErrs[j].Path })
+		return DirUploadError{Files: fileErrs}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package minio_ext
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// UploadFile - uploads the local file filePath to bucketName/objectName
+// in an UploadSession, returns the ETag of the object. With a store the
+// session state is persisted and an interrupted upload of the same file
+// is resumed. A state whose file changed is discarded and the upload
+// starts over, a state whose upload id expired on the server is marked
+// expired in the store and only started over with RestartExpired.
+// Files smaller than a part are sent in a single PUT instead when no
+// option needs a session.
+func (c *Client) UploadFile(ctx context.Context, filePath, bucketName, objectName string, store StateStore, opts UploadOptions) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	st, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	if !st.Mode().IsRegular() {
+		return "", ErrInvalidArgument("‘" + filePath + "’ is not a regular file.")
+	}
+	opts.ModTime = st.ModTime()
+	return c.uploadReaderAt(ctx, file, st.Size(), bucketName, objectName, store, opts)
+}
+
+// uploadReaderAt - uploads size bytes of reader in an UploadSession,
+// resuming the upload recorded in store as described for UploadFile.
+func (c *Client) uploadReaderAt(ctx context.Context, reader io.ReaderAt, size int64, bucketName, objectName string, store StateStore, opts UploadOptions) (string, error) {
+	key := bucketName + "/" + objectName
+	// The journal of the store needs no parts of a single PUT, unlike
+	// the journal of the caller.
+	oneShot := singleShot(size, opts)
+	if journal, ok := store.(PartJournal); ok {
+		next := opts.Journal
+		opts.Journal = func(uploadID string, part CompletedPart) error {
+			if err := journal.AppendPart(key, uploadID, part); err != nil {
+				return err
+			}
+			if next != nil {
+				return next(uploadID, part)
+			}
+			return nil
+		}
+	}
+	// Objects fitting in one part go in a single PUT, unless an upload
+	// of them is to be resumed.
+	if oneShot {
+		if store == nil {
+			return c.putReaderAt(ctx, reader, size, bucketName, objectName, opts)
+		}
+		if state, err := store.Load(key); err != nil {
+			return "", err
+		} else if state == nil {
+			return c.putReaderAt(ctx, reader, size, bucketName, objectName, opts)
+		}
+	}
+
+	var session *UploadSession
+	var err error
+	if store != nil {
+		state, err := store.Load(key)
+		if err != nil {
+			return "", err
+		}
+		if state != nil {
+			session, err = c.OpenUploadSession(ctx, *state, reader, size, opts)
+			if err != nil {
+				switch {
+				case IsUploadExpired(err) && !opts.RestartExpired:
+					state.Expired = true
+					store.Save(key, *state)
+					return "", err
+				case IsUploadExpired(err):
+				case ToErrorResponse(err).Code == "SourceChanged":
+					// Best effort, drop the parts of the stale upload.
+					c.abortMultipartUpload(ctx, state.BucketName, state.ObjectName, state.UploadID)
+				default:
+					return "", err
+				}
+				session = nil
+			}
+		}
+	}
+	if session == nil {
+		session, err = c.NewUploadSession(ctx, bucketName, objectName, reader, size, opts)
+		if err != nil {
+			return "", err
+		}
+		if store != nil && !session.Deduplicated() {
+			if err = store.Save(key, session.State()); err != nil {
+				session.Abort(ctx)
+				return "", err
+			}
+		}
+	}
+
+	if uploaded := session.Uploaded(); uploaded > 0 && opts.Progress != nil {
+		opts.Progress(uploaded)
+	}
+	if opts.Started != nil {
+		opts.Started(session)
+	}
+	etag, err := session.Upload(ctx)
+	if store != nil {
+		if err != nil {
+			// Keep the latest plan for the next attempt.
+			if !session.Deduplicated() {
+				store.Save(key, session.State())
+			}
+			return "", err
+		}
+		if err = store.Delete(key); err != nil {
+			return "", err
+		}
+	}
+	return etag, err
+}
@@ -0,0 +1,116 @@
+package minio_ext
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUploadFileExpired(t *testing.T) {
+	const size = 3 * absMinPartSize
+	const key = "bucket/object"
+	testCases := []struct {
+		name string
+		// Whether the stored upload is gone already, the parts after
+		// which it is aborted otherwise and whether to start over.
+		gone        bool
+		expireAfter int
+		restart     bool
+		shouldPass  bool
+	}{
+		{"resumed", false, 0, false, true},
+		{"gone", true, 0, false, false},
+		{"gone restarted", true, 0, true, true},
+		{"expires on part", false, 2, false, false},
+		{"expires on part restarted", false, 2, true, true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := newMultipartServer()
+			server.expireAfter = testCase.expireAfter
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+			c := newTestClient(t, ts.URL)
+			ctx := context.Background()
+			dir, err := ioutil.TempDir("", "upload")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			filePath := filepath.Join(dir, "file")
+			if err = ioutil.WriteFile(filePath, make([]byte, size), 0600); err != nil {
+				t.Fatal(err)
+			}
+			st, err := os.Stat(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			store, err := NewFileStateStore(filepath.Join(dir, "state"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// An interrupted upload of the file.
+			state := UploadState{
+				BucketName: "bucket",
+				ObjectName: "object",
+				UploadID:   server.addUpload(nil),
+				Size:       size,
+				ModTime:    st.ModTime(),
+				PartSize:   absMinPartSize,
+				Parts: []PartState{
+					{PartNumber: 1, Offset: 0, Size: absMinPartSize},
+					{PartNumber: 2, Offset: absMinPartSize, Size: absMinPartSize},
+					{PartNumber: 3, Offset: 2 * absMinPartSize, Size: absMinPartSize},
+				},
+			}
+			if testCase.gone {
+				server.expire(state.UploadID)
+			}
+			if err = store.Save(key, state); err != nil {
+				t.Fatal(err)
+			}
+
+			opts := UploadOptions{
+				NumThreads:     1,
+				PartRetry:      PartRetryPolicy{MaxAttempts: 1},
+				RestartExpired: testCase.restart,
+			}
+			_, err = c.UploadFile(ctx, filePath, "bucket", "object", store, opts)
+			stored, loadErr := store.Load(key)
+			if loadErr != nil {
+				t.Fatal(loadErr)
+			}
+			if testCase.shouldPass {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if obj, ok := server.object("/bucket/object"); !ok || obj.size != size {
+					t.Errorf("Expected the object uploaded, got %+v", obj)
+				}
+				if stored != nil {
+					t.Errorf("Expected the state deleted, got %+v", stored)
+				}
+				return
+			}
+
+			if !IsUploadExpired(err) {
+				t.Fatalf("Expected the upload expired, got %v", err)
+			}
+			if stored == nil || !stored.Expired || stored.UploadID != state.UploadID {
+				t.Fatalf("Expected the state marked expired, got %+v", stored)
+			}
+			// Later attempts fail without asking the server.
+			requests := server.count("list") + server.count("part")
+			if _, err = c.UploadFile(ctx, filePath, "bucket", "object", store, opts); !IsUploadExpired(err) {
+				t.Errorf("Expected the upload expired, got %v", err)
+			}
+			if n := server.count("list") + server.count("part"); n != requests {
+				t.Errorf("Expected no requests, got %d", n-requests)
+			}
+		})
+	}
+}
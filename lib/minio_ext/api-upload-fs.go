@@ -0,0 +1,39 @@
+package minio_ext
+
+import (
+	"context"
+	"io"
+	"io/fs"
+)
+
+// UploadFS - uploads the file name of fsys to bucketName/objectName,
+// returns the ETag of the object. Files implementing io.ReaderAt are
+// uploaded in an UploadSession and resumed like with UploadFile, all
+// other files are uploaded with UploadStream, which supports neither
+// fingerprints nor encryption.
+func (c *Client) UploadFS(ctx context.Context, fsys fs.FS, name, bucketName, objectName string, store StateStore, opts UploadOptions) (string, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	st, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	if !st.Mode().IsRegular() {
+		return "", ErrInvalidArgument("‘" + name + "’ is not a regular file.")
+	}
+	if !st.ModTime().IsZero() {
+		opts.ModTime = st.ModTime()
+	}
+
+	if reader, ok := file.(io.ReaderAt); ok {
+		return c.uploadReaderAt(ctx, reader, st.Size(), bucketName, objectName, store, opts)
+	}
+	return c.UploadStream(ctx, file, bucketName, objectName, StreamOptions{
+		Upload: opts,
+		States: store,
+	})
+}
@@ -0,0 +1,832 @@
+package minio_ext
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PartRetryPolicy - controls how often a single failed part is retried
+// by an UploadSession. These retries are on top of the request level
+// retries of the client, a failed part never restarts the whole upload.
+type PartRetryPolicy struct {
+	// Maximum number of attempts per part, defaults to 3.
+	MaxAttempts int
+
+	// Backoff unit and maximum wait between two attempts of a part,
+	// default to DefaultRetryUnit and DefaultRetryCap.
+	Unit time.Duration
+	Cap  time.Duration
+}
+
+// defaultPartMaxAttempts - default number of attempts per part.
+const defaultPartMaxAttempts = 3
+
+// withDefaults - returns the policy with unset fields defaulted.
+func (p PartRetryPolicy) withDefaults() PartRetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultPartMaxAttempts
+	}
+	if p.Unit <= 0 {
+		p.Unit = DefaultRetryUnit
+	}
+	if p.Cap <= 0 {
+		p.Cap = DefaultRetryCap
+	}
+	return p
+}
+
+// UploadOptions - options for NewUploadSession.
+type UploadOptions struct {
+	// Size of every part but the last one, picked automatically
+	// when zero. In adaptive mode the size of the first part.
+	PartSize int64
+
+	// Adaptive mode starts with small parts and grows them while
+	// throughput and latency allow, shrinking them again on slow or
+	// failing parts.
+	AdaptivePartSize bool
+
+	// Number of parts uploaded in parallel, defaults to 4.
+	NumThreads int
+
+	// Retry policy for individual parts.
+	PartRetry PartRetryPolicy
+
+	// Optional bandwidth limit for part uploads, a limiter can be
+	// shared by many sessions.
+	RateLimiter *RateLimiter
+
+	// Optional fingerprint of the source. It is stored with the object
+	// and indexed by a marker below FingerprintPrefix. When the object,
+	// or another object of the bucket, already has the fingerprint the
+	// upload is skipped entirely and ObjectName of the session returns
+	// the key of the existing object.
+	Fingerprint *FileFingerprint
+
+	// Optional end to end encryption, parts are encrypted before they
+	// leave the client, see EncryptionManifest. The part size must be
+	// a multiple of 64 KiB.
+	Encryption *PartEncryption
+
+	// Optional modification time of the source, kept in the session
+	// state so OpenUploadSession can detect a changed source.
+	ModTime time.Time
+
+	// SendContentMD5 sends the MD5 of every part, required by buckets
+	// whose policy enforces Content-MD5 and by object lock. Every part
+	// is read twice.
+	SendContentMD5 bool
+
+	// Optional retention and legal hold to lock the object with, the
+	// bucket needs object lock enabled. Parts are sent with their MD5.
+	Retention *ObjectRetention
+	LegalHold bool
+
+	// Optional callback invoked with the size of every part once it
+	// is uploaded, it may be called from several goroutines at once.
+	Progress func(n int64)
+
+	// RestartExpired makes Upload start the upload over with a new
+	// upload id when the current one expired on the server.
+	RestartExpired bool
+
+	// Optional callback receiving the accounting record of the
+	// session when Upload succeeds.
+	Accounting func(AccountingRecord)
+
+	// StrictResume makes OpenUploadSession compare the MD5 of every
+	// uploaded part with the local data instead of trusting part sizes.
+	StrictResume bool
+
+	// Optional write ahead journal invoked with every part once it is
+	// uploaded, before the worker takes the next part. It has to record
+	// the part durably, an error fails the part. UploadFile journals to
+	// state stores implementing PartJournal.
+	Journal func(uploadID string, part CompletedPart) error
+
+	// Optional headers to initiate the upload with, such as
+	// Content-Type and X-Amz-Meta-* user metadata.
+	Metadata http.Header
+
+	// Optional writer receiving the statistics of the session as one
+	// line of JSON when Upload succeeds.
+	Summary io.Writer
+
+	// Optional callback invoked with every session UploadFile creates
+	// or resumes, before it starts uploading. The session may be shut
+	// down with Shutdown from then on.
+	Started func(*UploadSession)
+
+	// Optional scheduler sharing part upload slots between sessions,
+	// slots are handed out in proportion to Priority, which defaults to
+	// PriorityBackground.
+	Scheduler *PartScheduler
+	Priority  int
+}
+
+// PartError - describes a part which could not be uploaded.
+type PartError struct {
+	PartNumber int
+	Attempts   int
+	Err        error
+}
+
+// Error - Returns the part failure as string.
+func (e PartError) Error() string {
+	return fmt.Sprintf("part %d failed after %d attempt(s): %v", e.PartNumber, e.Attempts, e.Err)
+}
+
+// Unwrap - returns the error of the last attempt.
+func (e PartError) Unwrap() error {
+	return e.Err
+}
+
+// UploadError - returned by UploadSession.Upload when some parts could
+// not be uploaded. All other parts are kept, calling Upload again only
+// uploads the failed ones.
+type UploadError struct {
+	UploadID string
+	Parts    []PartError
+}
+
+// Error - Returns all part failures as string.
+func (e UploadError) Error() string {
+	msgs := make([]string, 0, len(e.Parts))
+	for _, part := range e.Parts {
+		msgs = append(msgs, part.Error())
+	}
+	return fmt.Sprintf("upload ‘%s’ failed: %s", e.UploadID, strings.Join(msgs, "; "))
+}
+
+// UploadExpiredError - returned when the upload id of a session no
+// longer exists on the server, e.g. because a lifecycle rule aborted
+// the upload while it was in progress. All uploaded parts are lost.
+type UploadExpiredError struct {
+	BucketName string
+	ObjectName string
+	UploadID   string
+}
+
+// Error - Returns the expired upload as string.
+func (e UploadExpiredError) Error() string {
+	return fmt.Sprintf("upload ‘%s’ of ‘%s/%s’ no longer exists on the server", e.UploadID, e.BucketName, e.ObjectName)
+}
+
+// Unwrap - returns ErrNoSuchUpload.
+func (e UploadExpiredError) Unwrap() error {
+	return ErrNoSuchUpload
+}
+
+// IsUploadExpired - reports whether err means that the upload id no
+// longer exists on the server.
+func IsUploadExpired(err error) bool {
+	return errors.Is(err, ErrNoSuchUpload)
+}
+
+// ErrUploadShutdown - the session was shut down before all parts were
+// uploaded, the upload can be resumed from its state.
+func ErrUploadShutdown(uploadID string) error {
+	return ErrorResponse{
+		Code:    "UploadShutdown",
+		Message: "Multipart upload ‘" + uploadID + "’ was shut down, resume it from its state.",
+	}
+}
+
+// UploadSession - a multipart upload of a local source, parts are
+// uploaded in parallel and failed parts can be retried without
+// restarting the upload.
+type UploadSession struct {
+	// transferred counts the part bytes sent, accessed atomically and
+	// first in the struct to stay 64 bit aligned.
+	transferred int64
+
+	// resumed is the size of the parts found on the server on resume.
+	resumed int64
+
+	client     *Client
+	bucketName string
+	objectName string
+	uploadID   string
+
+	// initiated is when the upload id was created, zero for sessions
+	// resumed from states without it.
+	initiated time.Time
+
+	reader  io.ReaderAt
+	size    int64
+	planner *partPlanner
+	opts    UploadOptions
+
+	// mutex protects parts, limiter, expired and the shutdown fields,
+	// uploadID only changes while no parts are uploaded.
+	mutex sync.Mutex
+
+	// parts holds the uploaded parts by part number, md5s the hex
+	// encoded MD5 of those parts when it is known.
+	parts map[int]ObjectPart
+	md5s  map[int]string
+
+	// limiter throttles part uploads, nil when unlimited.
+	limiter *RateLimiter
+
+	// key encrypts the parts when end to end encryption is used.
+	key *sessionKey
+
+	// existing is set when an object with the same fingerprint was
+	// found and nothing needs to be uploaded.
+	existing *ObjectInfo
+
+	// expired is set once the upload id is gone on the server.
+	expired bool
+
+	// stats collects timings and retries of the session.
+	stats sessionStats
+
+	// flow is the state of the session in the part scheduler.
+	flow schedulerFlow
+
+	// draining is set by Shutdown, running is closed and cancelParts
+	// interrupts the parts in flight while Upload runs.
+	draining    bool
+	running     chan struct{}
+	cancelParts context.CancelFunc
+}
+
+// optimalPartSize - returns the smallest part size, rounded to MiB, so
+// that size fits into MaxPartsCount parts.
+func optimalPartSize(size int64) int64 {
+	partSize := int64(absMinPartSize)
+	if minSize := (size + MaxPartsCount - 1) / MaxPartsCount; minSize > partSize {
+		partSize = (minSize + 1<<20 - 1) / (1 << 20) * (1 << 20)
+	}
+	return partSize
+}
+
+// partsCount - returns the number of parts of size bytes, an empty
+// source is uploaded as a single empty part.
+func partsCount(size, partSize int64) int {
+	if size == 0 {
+		return 1
+	}
+	return int((size + partSize - 1) / partSize)
+}
+
+// NewUploadSession - initiates a new multipart upload of size bytes
+// read from reader to bucketName/objectName.
+func (c *Client) NewUploadSession(ctx context.Context, bucketName, objectName string, reader io.ReaderAt, size int64, opts UploadOptions) (*UploadSession, error) {
+	// Input validation.
+	if reader == nil {
+		return nil, ErrInvalidArgument("Reader cannot be nil.")
+	}
+	if size < 0 {
+		return nil, ErrEntityTooSmall(size, bucketName, objectName)
+	}
+	if size > MaxMultipartPutObjectSize {
+		return nil, ErrEntityTooLarge(size, MaxMultipartPutObjectSize, bucketName, objectName)
+	}
+	partSize := opts.PartSize
+	if partSize == 0 {
+		partSize = optimalPartSize(size)
+		if opts.AdaptivePartSize {
+			partSize = absMinPartSize
+		}
+	}
+	if partSize < absMinPartSize || partSize > MaxPartSize {
+		return nil, ErrInvalidArgument(fmt.Sprintf("Part size must be between %d and %d.", absMinPartSize, MaxPartSize))
+	}
+	if !opts.AdaptivePartSize && partsCount(size, partSize) > MaxPartsCount {
+		return nil, ErrInvalidArgument(fmt.Sprintf("Part size %d results in more than %d parts.", partSize, MaxPartsCount))
+	}
+	if opts.Encryption != nil && (partSize%e2eChunkSize != 0 || partSize > maxEncryptedPartSize) {
+		return nil, ErrInvalidArgument(fmt.Sprintf("Part size of encrypted uploads must be a multiple of %d up to %d.", e2eChunkSize, maxEncryptedPartSize))
+	}
+	if opts.NumThreads <= 0 {
+		opts.NumThreads = totalWorkers
+	}
+	opts.PartRetry = opts.PartRetry.withDefaults()
+	if opts.Retention != nil {
+		if err := opts.Retention.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Retention != nil || opts.LegalHold {
+		opts.SendContentMD5 = true
+	}
+
+	if opts.Fingerprint != nil {
+		if opts.Fingerprint.Size != size {
+			return nil, ErrInvalidArgument("Fingerprint size does not match the upload size.")
+		}
+		existing, err := c.findFingerprint(ctx, bucketName, objectName, *opts.Fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			// Same content is already stored, skip the upload.
+			return &UploadSession{
+				client:     c,
+				bucketName: bucketName,
+				objectName: objectName,
+				size:       size,
+				opts:       opts,
+				existing:   existing,
+			}, nil
+		}
+	}
+
+	var key *sessionKey
+	if opts.Encryption != nil {
+		var err error
+		if key, err = opts.Encryption.newSessionKey(size); err != nil {
+			return nil, err
+		}
+	}
+
+	initResult, err := c.initiateMultipartUpload(ctx, bucketName, objectName, initiateHeader(opts, key))
+	if err != nil {
+		return nil, err
+	}
+
+	planner := newPartPlanner(size, partSize, opts.AdaptivePartSize)
+	if key != nil {
+		planner.align = key.manifest.ChunkSize
+	}
+	return &UploadSession{
+		client:     c,
+		bucketName: bucketName,
+		objectName: objectName,
+		uploadID:   initResult.UploadID,
+		initiated:  time.Now().UTC(),
+		reader:     reader,
+		size:       size,
+		planner:    planner,
+		opts:       opts,
+		parts:      make(map[int]ObjectPart),
+		md5s:       make(map[int]string),
+		limiter:    opts.RateLimiter,
+		key:        key,
+	}, nil
+}
+
+// initiateHeader - returns the headers to initiate an upload with, the
+// metadata, fingerprint and encryption metadata are stored with the
+// object.
+func initiateHeader(opts UploadOptions, key *sessionKey) http.Header {
+	customHeader := make(http.Header)
+	for k, v := range opts.Metadata {
+		customHeader[k] = v
+	}
+	if opts.Fingerprint != nil {
+		customHeader.Set(amzMetaFingerprint, opts.Fingerprint.String())
+	}
+	if key != nil {
+		for k, v := range key.manifest.Metadata() {
+			customHeader[k] = v
+		}
+	}
+	setLockHeaders(customHeader, opts.Retention, opts.LegalHold)
+	return customHeader
+}
+
+// UploadID - returns the upload id of the session, empty when the
+// upload was skipped because the object already exists.
+func (s *UploadSession) UploadID() string {
+	return s.uploadID
+}
+
+// ObjectName - returns the key of the uploaded object, of the object
+// found with the same fingerprint when the upload is skipped.
+func (s *UploadSession) ObjectName() string {
+	if s.existing != nil {
+		return s.existing.Key
+	}
+	return s.objectName
+}
+
+// Deduplicated - reports whether an object of the bucket already had
+// the same fingerprint and the upload is skipped.
+func (s *UploadSession) Deduplicated() bool {
+	return s.existing != nil
+}
+
+// Parts - returns the uploaded parts sorted by part number.
+func (s *UploadSession) Parts() []ObjectPart {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	parts := make([]ObjectPart, 0, len(s.parts))
+	for _, part := range s.parts {
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts
+}
+
+// Uploaded - returns the number of bytes uploaded so far, including
+// parts found on the server when the session was resumed.
+func (s *UploadSession) Uploaded() int64 {
+	if s.existing != nil {
+		return s.size
+	}
+	plan := s.planner.parts()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var uploaded int64
+	for _, spec := range plan {
+		if _, ok := s.parts[spec.PartNumber]; ok {
+			uploaded += spec.Size
+		}
+	}
+	return uploaded
+}
+
+// SetRateLimit - changes the bandwidth limit of the session while it
+// is uploading, a rate of zero or less removes the limit. When the
+// session shares its limiter with other sessions all of them are
+// affected.
+func (s *UploadSession) SetRateLimit(bytesPerSec, burst int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.limiter == nil {
+		s.limiter = NewRateLimiter(bytesPerSec, burst)
+		return
+	}
+	s.limiter.SetLimit(bytesPerSec, burst)
+}
+
+// rateLimiter - returns the current limiter of the session.
+func (s *UploadSession) rateLimiter() *RateLimiter {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.limiter
+}
+
+// missingParts - returns the planned parts which are not uploaded yet.
+func (s *UploadSession) missingParts() []partSpec {
+	plan := s.planner.parts()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var missing []partSpec
+	for _, spec := range plan {
+		if _, ok := s.parts[spec.PartNumber]; !ok {
+			missing = append(missing, spec)
+		}
+	}
+	return missing
+}
+
+// partReader - returns the part data as sent to the server.
+func (s *UploadSession) partReader(spec partSpec) io.ReadSeeker {
+	if s.key != nil {
+		return newSealReader(s.key, s.reader, spec.Offset, spec.Size)
+	}
+	return io.NewSectionReader(s.reader, spec.Offset, spec.Size)
+}
+
+// sentSize - returns the size of the part data as sent to the server,
+// encrypted parts are larger than planned.
+func (s *UploadSession) sentSize(spec partSpec) int64 {
+	if s.key != nil {
+		_, size := s.key.manifest.sealedRange(spec.Offset, spec.Size)
+		return size
+	}
+	return spec.Size
+}
+
+// isPartErrorRetryable - is a failed part upload worth another attempt.
+func isPartErrorRetryable(err error) bool {
+	switch e := err.(type) {
+	case ErrorResponse:
+		return isS3CodeRetryable(e.Code) || isHTTPStatusRetryable(e.StatusCode)
+	case *url.Error:
+		// Connection level failures which outlasted the client retries.
+		return true
+	}
+	return false
+}
+
+// uploadPartWithRetry - uploads a single part, retrying it according
+// to the part retry policy of the session.
+func (s *UploadSession) uploadPartWithRetry(ctx context.Context, spec partSpec) (err error) {
+	policy := s.opts.PartRetry
+	partNumber, length := spec.PartNumber, spec.Size
+
+	ctx, span := s.client.startSessionSpan(ctx, "UploadSession.UploadPart", s.bucketName, s.objectName, s.UploadID(),
+		attrPartNumber.Int(partNumber), attrBytes.Int64(length))
+	defer func() { endSpan(span, err) }()
+
+	var md5Base64, md5Hex string
+	if s.opts.SendContentMD5 {
+		md5Sum, err := partMD5(s.partReader(spec))
+		if err != nil {
+			return PartError{PartNumber: partNumber, Err: err}
+		}
+		md5Base64 = base64.StdEncoding.EncodeToString(md5Sum)
+		md5Hex = hex.EncodeToString(md5Sum)
+	}
+
+	var attempts int
+	for timer := newRetryTimer(policy.MaxAttempts, policy.Unit, policy.Cap, nil); timer.next(ctx); {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if err == nil {
+				err = ctxErr
+			}
+			break
+		}
+		if attempts > 0 && s.isDraining() {
+			// No new attempts once the session shuts down.
+			break
+		}
+		attempts++
+
+		if s.opts.Scheduler != nil {
+			if err = s.opts.Scheduler.acquire(ctx, &s.flow, s.opts.Priority); err != nil {
+				break
+			}
+		}
+		reader := newLimitedReader(ctx, countingReader{s.partReader(spec), &s.transferred}, s.rateLimiter())
+
+		start := time.Now()
+		var part ObjectPart
+		part, err = s.client.uploadPart(ctx, s.bucketName, s.objectName, s.uploadID,
+			reader, partNumber, md5Base64, "", s.sentSize(spec), nil)
+		if s.opts.Scheduler != nil {
+			s.opts.Scheduler.release()
+		}
+		if err == nil && s.opts.Journal != nil {
+			err = s.opts.Journal(s.uploadID, CompletedPart{
+				PartNumber: partNumber,
+				Size:       part.Size,
+				ETag:       part.ETag,
+				MD5:        md5Hex,
+			})
+			if err != nil {
+				break
+			}
+		}
+		if err == nil {
+			latency := time.Since(start)
+			s.planner.observe(length, latency, attempts)
+			s.stats.part(attempts, latency)
+			s.mutex.Lock()
+			s.parts[partNumber] = part
+			if md5Hex != "" {
+				s.md5s[partNumber] = md5Hex
+			}
+			s.mutex.Unlock()
+			if s.opts.Progress != nil {
+				s.opts.Progress(length)
+			}
+			return nil
+		}
+		if attempts >= policy.MaxAttempts || !isPartErrorRetryable(err) {
+			break
+		}
+	}
+	s.stats.part(attempts, 0)
+	return PartError{
+		PartNumber: partNumber,
+		Attempts:   attempts,
+		Err:        err,
+	}
+}
+
+// Upload - uploads all missing parts and completes the multipart
+// upload, returns the ETag of the object. When parts fail an
+// UploadError is returned and Upload may be called again to
+// retry only the failed parts. When the upload id expired on the
+// server an UploadExpiredError is returned, unless the session
+// restarts expired uploads.
+func (s *UploadSession) Upload(ctx context.Context) (etag string, err error) {
+	defer s.client.trackSession()()
+	ctx, span := s.client.startSessionSpan(ctx, "UploadSession.Upload", s.bucketName, s.objectName, s.UploadID(),
+		attrBytes.Int64(s.size))
+	defer func() { endSpan(span, err) }()
+	s.stats.begin()
+	if s.existing != nil {
+		s.stats.end()
+		s.account()
+		s.summarize()
+		return s.existing.ETag, nil
+	}
+
+	etag, err = s.upload(ctx)
+	if IsUploadExpired(err) && s.opts.RestartExpired {
+		if err = s.restart(ctx); err != nil {
+			return "", err
+		}
+		etag, err = s.upload(ctx)
+	}
+	s.stats.end()
+	if err == nil {
+		if s.opts.Fingerprint != nil {
+			// The object is uploaded, a missing marker only costs
+			// later uploads of the content their shortcut.
+			s.client.recordFingerprint(ctx, s.bucketName, s.objectName, *s.opts.Fingerprint)
+		}
+		s.account()
+		s.summarize()
+	}
+	return etag, err
+}
+
+// upload - uploads all missing parts and completes the multipart upload.
+func (s *UploadSession) upload(ctx context.Context) (string, error) {
+	if s.Expired() {
+		return "", s.expiredError()
+	}
+
+	// Stop all workers as soon as the upload turns out to be expired.
+	partCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.mutex.Lock()
+	if s.draining {
+		s.mutex.Unlock()
+		return "", ErrUploadShutdown(s.uploadID)
+	}
+	running := make(chan struct{})
+	s.running, s.cancelParts = running, cancel
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		s.running, s.cancelParts = nil, nil
+		s.mutex.Unlock()
+		close(running)
+	}()
+
+	// Previously planned parts go first, then new parts are planned
+	// as workers become free so adaptive sizing sees recent uploads.
+	pending := s.missingParts()
+	var pendingMutex sync.Mutex
+	nextPart := func() (partSpec, bool) {
+		pendingMutex.Lock()
+		defer pendingMutex.Unlock()
+		if partCtx.Err() != nil || s.isDraining() {
+			return partSpec{}, false
+		}
+		if len(pending) > 0 {
+			spec := pending[0]
+			pending = pending[1:]
+			return spec, true
+		}
+		return s.planner.next()
+	}
+
+	var wg sync.WaitGroup
+	var errMutex sync.Mutex
+	var partErrs []PartError
+	for i := 0; i < s.opts.NumThreads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for spec, ok := nextPart(); ok; spec, ok = nextPart() {
+				if err := s.uploadPartWithRetry(partCtx, spec); err != nil {
+					if IsUploadExpired(err) {
+						s.setExpired()
+						cancel()
+					}
+					errMutex.Lock()
+					partErrs = append(partErrs, err.(PartError))
+					errMutex.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s.Expired() {
+		return "", s.expiredError()
+	}
+	if s.isDraining() && (len(s.missingParts()) > 0 || !s.planner.complete()) {
+		return "", ErrUploadShutdown(s.uploadID)
+	}
+	if len(partErrs) == 0 && (len(s.missingParts()) > 0 || !s.planner.complete()) {
+		// Uploading stopped early because ctx is done.
+		return "", ctx.Err()
+	}
+	if len(partErrs) > 0 {
+		sort.Slice(partErrs, func(i, j int) bool { return partErrs[i].PartNumber < partErrs[j].PartNumber })
+		return "", UploadError{
+			UploadID: s.uploadID,
+			Parts:    partErrs,
+		}
+	}
+
+	var complete []CompletePart
+	for _, part := range s.Parts() {
+		complete = append(complete, CompletePart{
+			PartNumber: part.PartNumber,
+			ETag:       part.ETag,
+		})
+	}
+	etag, err := s.client.CompleteMultipartUploadWithContext(ctx, s.bucketName, s.objectName, s.uploadID, complete, nil)
+	if IsUploadExpired(err) {
+		s.setExpired()
+		return "", s.expiredError()
+	}
+	return etag, err
+}
+
+// Expired - reports whether the upload id of the session no longer
+// exists on the server, e.g. because a lifecycle rule aborted it.
+func (s *UploadSession) Expired() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.expired
+}
+
+// setExpired - marks the upload id of the session as expired.
+func (s *UploadSession) setExpired() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.expired = true
+}
+
+// expiredError - returns the error describing the expired upload.
+func (s *UploadSession) expiredError() error {
+	return UploadExpiredError{
+		BucketName: s.bucketName,
+		ObjectName: s.objectName,
+		UploadID:   s.uploadID,
+	}
+}
+
+// restart - initiates a new multipart upload for the session, all
+// parts are uploaded again and reported to Progress again.
+func (s *UploadSession) restart(ctx context.Context) (err error) {
+	ctx, span := s.client.startSessionSpan(ctx, "UploadSession.Restart", s.bucketName, s.objectName, s.UploadID())
+	defer func() { endSpan(span, err) }()
+	initResult, err := s.client.initiateMultipartUpload(ctx, s.bucketName, s.objectName, initiateHeader(s.opts, s.key))
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.uploadID = initResult.UploadID
+	s.initiated = time.Now().UTC()
+	s.parts = make(map[int]ObjectPart)
+	s.md5s = make(map[int]string)
+	s.resumed = 0
+	s.expired = false
+	return nil
+}
+
+// isDraining - reports whether the session is shutting down.
+func (s *UploadSession) isDraining() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.draining
+}
+
+// drain - stops the session from starting new parts without waiting.
+func (s *UploadSession) drain() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.draining = true
+}
+
+// Shutdown - stops the session from starting new parts and waits until
+// the parts in flight are uploaded, a running Upload then returns an
+// error with code "UploadShutdown" unless all parts were uploaded.
+// Returns the state to resume the upload with OpenUploadSession. When
+// ctx is done first the parts in flight are interrupted and the error
+// of ctx is returned along with the state.
+func (s *UploadSession) Shutdown(ctx context.Context) (UploadState, error) {
+	s.mutex.Lock()
+	s.draining = true
+	running, cancel := s.running, s.cancelParts
+	s.mutex.Unlock()
+
+	var err error
+	if running != nil {
+		select {
+		case <-running:
+		case <-ctx.Done():
+			cancel()
+			<-running
+			err = ctx.Err()
+		}
+	}
+	return s.State(), err
+}
+
+// Abort - aborts the multipart upload, all uploaded parts are removed.
+func (s *UploadSession) Abort(ctx context.Context) error {
+	if s.existing != nil {
+		// Nothing was uploaded.
+		return nil
+	}
+	return s.client.abortMultipartUpload(ctx, s.bucketName, s.objectName, s.uploadID)
+}
@@ -0,0 +1,546 @@
+package minio_ext
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeObject - an object completed on a multipartServer.
+type fakeObject struct {
+	size  int64
+	parts int
+	etag  string
+}
+
+// multipartServer - fake backend of the multipart upload API keeping
+// the sizes and ETags of parts, not their data.
+type multipartServer struct {
+	mutex   sync.Mutex
+	nextID  int
+	uploads map[string]map[int]ObjectPart
+	objects map[string]fakeObject
+
+	// expireAfter aborts the first upload reaching that many parts,
+	// like a lifecycle rule firing mid-session, none when 0.
+	expireAfter int
+
+	// requests counts the requests by operation.
+	requests map[string]int
+}
+
+// newMultipartServer - returns an empty backend.
+func newMultipartServer() *multipartServer {
+	return &multipartServer{
+		uploads:  make(map[string]map[int]ObjectPart),
+		objects:  make(map[string]fakeObject),
+		requests: make(map[string]int),
+	}
+}
+
+// addUpload - creates an upload with parts and returns its id.
+func (s *multipartServer) addUpload(parts map[int]ObjectPart) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.nextID++
+	uploadID := "upload-" + strconv.Itoa(s.nextID)
+	if parts == nil {
+		parts = make(map[int]ObjectPart)
+	}
+	s.uploads[uploadID] = parts
+	return uploadID
+}
+
+// expire - aborts uploadID as a lifecycle rule would.
+func (s *multipartServer) expire(uploadID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.uploads, uploadID)
+}
+
+// count - returns the number of requests of operation.
+func (s *multipartServer) count(operation string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests[operation]
+}
+
+// object - returns the object completed under key, the path of its
+// requests.
+func (s *multipartServer) object(key string) (fakeObject, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	obj, ok := s.objects[key]
+	return obj, ok
+}
+
+// splitTestPath - returns the bucket and object of a path style request.
+func splitTestPath(path string) (bucket, object string) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// writeXML - sends v as XML with status 200.
+func writeXML(w http.ResponseWriter, v interface{}) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		testResponse{http.StatusInternalServerError, "InternalError", ""}.write(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(data)
+}
+
+// ServeHTTP - answers the multipart upload API.
+func (s *multipartServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		w.Write([]byte("<LocationConstraint></LocationConstraint>"))
+		return
+	}
+	if r.Method == "HEAD" && len(query) == 0 {
+		s.stat(w, r)
+		return
+	}
+	_, initiate := query["uploads"]
+	uploadID := query.Get("uploadId")
+	var operation string
+	switch {
+	case r.Method == "POST" && initiate:
+		operation = "initiate"
+	case r.Method == "PUT" && uploadID != "":
+		operation = "part"
+	case r.Method == "GET" && uploadID != "":
+		operation = "list"
+	case r.Method == "POST" && uploadID != "":
+		operation = "complete"
+	case r.Method == "DELETE" && uploadID != "":
+		operation = "abort"
+	default:
+		testResponse{http.StatusNotImplemented, "NotImplemented", ""}.write(w)
+		return
+	}
+
+	s.mutex.Lock()
+	s.requests[operation]++
+	parts, ok := s.uploads[uploadID]
+	s.mutex.Unlock()
+	if operation != "initiate" && !ok {
+		testResponse{http.StatusNotFound, "NoSuchUpload", ""}.write(w)
+		return
+	}
+
+	switch operation {
+	case "initiate":
+		bucket, key := splitTestPath(r.URL.Path)
+		writeXML(w, initiateMultipartUploadResult{Bucket: bucket, Key: key, UploadID: s.addUpload(nil)})
+	case "part":
+		s.putPart(w, r, uploadID)
+	case "list":
+		s.listParts(w, r, parts)
+	case "complete":
+		s.complete(w, r, uploadID)
+	case "abort":
+		s.expire(uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// stat - answers HEAD requests of completed objects.
+func (s *multipartServer) stat(w http.ResponseWriter, r *http.Request) {
+	obj, ok := s.object(r.URL.Path)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(obj.size, 10))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("ETag", "\""+obj.etag+"\"")
+	w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+}
+
+// putPart - stores the size and MD5 of a part, the data is dropped.
+func (s *multipartServer) putPart(w http.ResponseWriter, r *http.Request, uploadID string) {
+	partNumber, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > MaxPartsCount {
+		testResponse{http.StatusBadRequest, "InvalidArgument", ""}.write(w)
+		return
+	}
+	hash := md5.New()
+	var size int64
+	expected := r.ContentLength
+	if r.Header.Get("X-Amz-Content-Sha256") == "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+		expected, _ = strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		size, err = readChunked(hash, r.Body)
+	} else {
+		size, err = io.Copy(hash, r.Body)
+	}
+	if err != nil || size != expected {
+		testResponse{http.StatusBadRequest, "IncompleteBody", ""}.write(w)
+		return
+	}
+	etag := hex.EncodeToString(hash.Sum(nil))
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	parts, ok := s.uploads[uploadID]
+	if !ok {
+		testResponse{http.StatusNotFound, "NoSuchUpload", ""}.write(w)
+		return
+	}
+	parts[partNumber] = ObjectPart{PartNumber: partNumber, ETag: etag, Size: size, LastModified: time.Now().UTC()}
+	if s.expireAfter > 0 && len(parts) >= s.expireAfter {
+		delete(s.uploads, uploadID)
+		s.expireAfter = 0
+	}
+	w.Header().Set("ETag", "\""+etag+"\"")
+}
+
+// readChunked - copies the payload of an aws-chunked body to w.
+func readChunked(w io.Writer, body io.Reader) (int64, error) {
+	reader := bufio.NewReader(body)
+	var total int64
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return total, err
+		}
+		size, err := strconv.ParseInt(strings.SplitN(line, ";", 2)[0], 16, 64)
+		if err != nil {
+			return total, err
+		}
+		if size == 0 {
+			return total, nil
+		}
+		if _, err = io.CopyN(w, reader, size); err != nil {
+			return total, err
+		}
+		total += size
+		if _, err = reader.Discard(2); err != nil {
+			return total, err
+		}
+	}
+}
+
+// listParts - lists up to max-parts parts after part-number-marker.
+func (s *multipartServer) listParts(w http.ResponseWriter, r *http.Request, parts map[int]ObjectPart) {
+	marker, _ := strconv.Atoi(r.URL.Query().Get("part-number-marker"))
+	maxParts, _ := strconv.Atoi(r.URL.Query().Get("max-parts"))
+	s.mutex.Lock()
+	numbers := make([]int, 0, len(parts))
+	for number := range parts {
+		if number > marker {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+	result := ListObjectPartsResult{PartNumberMarker: marker, MaxParts: maxParts}
+	for _, number := range numbers {
+		if len(result.ObjectParts) == maxParts {
+			result.IsTruncated = true
+			break
+		}
+		result.ObjectParts = append(result.ObjectParts, parts[number])
+		result.NextPartNumberMarker = number
+	}
+	s.mutex.Unlock()
+	writeXML(w, result)
+}
+
+// complete - checks the parts like S3 does and stores the object.
+func (s *multipartServer) complete(w http.ResponseWriter, r *http.Request, uploadID string) {
+	var request completeMultipartUpload
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Parts) == 0 || len(request.Parts) > MaxPartsCount {
+		testResponse{http.StatusBadRequest, "MalformedXML", ""}.write(w)
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	parts, ok := s.uploads[uploadID]
+	if !ok {
+		testResponse{http.StatusNotFound, "NoSuchUpload", ""}.write(w)
+		return
+	}
+	var obj fakeObject
+	hash := md5.New()
+	for i, completed := range request.Parts {
+		part, ok := parts[completed.PartNumber]
+		if !ok || part.ETag != trimEtag(completed.ETag) || (i > 0 && completed.PartNumber <= request.Parts[i-1].PartNumber) {
+			testResponse{http.StatusBadRequest, "InvalidPart", ""}.write(w)
+			return
+		}
+		if i < len(request.Parts)-1 && part.Size < absMinPartSize {
+			testResponse{http.StatusBadRequest, "EntityTooSmall", ""}.write(w)
+			return
+		}
+		sum, _ := hex.DecodeString(part.ETag)
+		hash.Write(sum)
+		obj.size += part.Size
+	}
+	obj.parts = len(request.Parts)
+	obj.etag = fmt.Sprintf("%s-%d", hex.EncodeToString(hash.Sum(nil)), obj.parts)
+	s.objects[r.URL.Path] = obj
+	delete(s.uploads, uploadID)
+	bucket, key := splitTestPath(r.URL.Path)
+	writeXML(w, completeMultipartUploadResult{Bucket: bucket, Key: key, ETag: "\"" + obj.etag + "\""})
+}
+
+// zeroReader - io.ReaderAt of zero bytes.
+type zeroReader struct{}
+
+// ReadAt - implements io.ReaderAt.
+func (zeroReader) ReadAt(p []byte, offset int64) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestUploadSessionMaxObjectSize(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Sends the last part of a 5 TiB object.")
+	}
+	const size = MaxMultipartPutObjectSize
+
+	// A sparse 5 TiB source, only the last part is ever read.
+	dir, err := ioutil.TempDir("", "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file, err := os.Create(filepath.Join(dir, "sparse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err = file.Truncate(size); err != nil {
+		t.Skipf("The file system cannot hold a sparse 5 TiB file: %v", err)
+	}
+
+	var plan []PartState
+	for _, spec := range newPartPlanner(size, optimalPartSize(size), false).parts() {
+		plan = append(plan, PartState(spec))
+	}
+	last := plan[len(plan)-1]
+	if len(plan) > MaxPartsCount || last.Offset+last.Size != size {
+		t.Fatalf("Unexpected plan of %d parts ending at %d", len(plan), last.Offset+last.Size)
+	}
+
+	// All parts but the last one were uploaded before an interruption.
+	server := newMultipartServer()
+	uploaded := make(map[int]ObjectPart, len(plan))
+	for _, part := range plan[:len(plan)-1] {
+		uploaded[part.PartNumber] = ObjectPart{PartNumber: part.PartNumber, ETag: fmt.Sprintf("%032x", part.PartNumber), Size: part.Size}
+	}
+	uploadID := server.addUpload(uploaded)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	c := newTestClient(t, ts.URL)
+	ctx := context.Background()
+
+	// Offsets and sizes survive the checkpoint.
+	data, err := json.Marshal(UploadState{
+		BucketName: "bucket",
+		ObjectName: "object",
+		UploadID:   uploadID,
+		Size:       size,
+		PartSize:   plan[0].Size,
+		Parts:      plan,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state UploadState
+	if err = json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Size != size || state.Parts[len(plan)-1] != last {
+		t.Fatalf("Checkpoint lost the plan: %+v", state.Parts[len(plan)-1])
+	}
+
+	var progress int64
+	session, err := c.OpenUploadSession(ctx, state, file, size, UploadOptions{
+		NumThreads: 1,
+		PartRetry:  PartRetryPolicy{MaxAttempts: 1},
+		Progress:   func(n int64) { progress += n },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploaded := session.Uploaded(); uploaded != last.Offset {
+		t.Fatalf("Expected %d bytes uploaded before, got %d", last.Offset, uploaded)
+	}
+
+	// The part is streamed, the heap never holds a sizable share of it.
+	var before runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	stop, peak := make(chan struct{}), make(chan uint64)
+	go func() {
+		var stats runtime.MemStats
+		var max uint64
+		for {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > max {
+				max = stats.HeapAlloc
+			}
+			select {
+			case <-stop:
+				peak <- max
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}()
+	etag, err := session.Upload(ctx)
+	close(stop)
+	if grown := int64(<-peak) - int64(before.HeapAlloc); grown > 64<<20 {
+		t.Errorf("Uploading a part of %d bytes grew the heap by %d bytes", last.Size, grown)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj, ok := server.object("/bucket/object")
+	if !ok || obj.size != size || obj.parts != len(plan) || obj.etag != trimEtag(etag) {
+		t.Errorf("Unexpected object %+v, ETag %s", obj, etag)
+	}
+	if server.count("part") != 1 {
+		t.Errorf("Expected only the last part sent, got %d parts", server.count("part"))
+	}
+	if session.Uploaded() != size || progress != last.Size {
+		t.Errorf("Expected %d bytes uploaded and %d reported, got %d and %d", size, last.Size, session.Uploaded(), progress)
+	}
+}
+
+func TestUploadSessionExpired(t *testing.T) {
+	const size = 3 * absMinPartSize
+	testCases := []struct {
+		name string
+		// Parts after which the upload is aborted and whether the
+		// session starts over then.
+		expireAfter int
+		restart     bool
+		// Whether the upload fails as expired and the uploads initiated.
+		expired   bool
+		initiated int
+	}{
+		{"not expired", 0, false, false, 1},
+		{"expires on part", 2, false, true, 1},
+		{"expires before completion", 3, false, true, 1},
+		{"restarted", 2, true, false, 2},
+		{"restarted before completion", 3, true, false, 2},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := newMultipartServer()
+			server.expireAfter = testCase.expireAfter
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+			c := newTestClient(t, ts.URL)
+			ctx := context.Background()
+
+			session, err := c.NewUploadSession(ctx, "bucket", "object", zeroReader{}, size, UploadOptions{
+				PartSize:       absMinPartSize,
+				NumThreads:     1,
+				PartRetry:      PartRetryPolicy{MaxAttempts: 1},
+				RestartExpired: testCase.restart,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			uploadID := session.UploadID()
+			_, err = session.Upload(ctx)
+			if n := server.count("initiate"); n != testCase.initiated {
+				t.Errorf("Expected %d uploads initiated, got %d", testCase.initiated, n)
+			}
+			if !testCase.expired {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if obj, ok := server.object("/bucket/object"); !ok || obj.size != size {
+					t.Errorf("Expected the object uploaded, got %+v", obj)
+				}
+				if restarted := session.UploadID() != uploadID; restarted != testCase.restart {
+					t.Errorf("Expected a new upload id %v, got %s", testCase.restart, session.UploadID())
+				}
+				return
+			}
+
+			expiredErr, ok := err.(UploadExpiredError)
+			if !ok || !IsUploadExpired(err) || expiredErr.UploadID != uploadID {
+				t.Fatalf("Expected UploadExpiredError of %s, got %v", uploadID, err)
+			}
+			if n := server.count("complete"); testCase.expireAfter < 3 && n != 0 {
+				t.Errorf("Expected no completion, got %d", n)
+			}
+			state := session.State()
+			if !session.Expired() || !state.Expired {
+				t.Fatal("Expected the session expired")
+			}
+			// An expired state is not resumed, its parts are not listed.
+			lists := server.count("list")
+			if _, err = c.OpenUploadSession(ctx, state, zeroReader{}, size, UploadOptions{}); !IsUploadExpired(err) {
+				t.Errorf("Expected UploadExpiredError, got %v", err)
+			}
+			status, err := c.UploadStatus(state, 0)
+			if err != nil || !status.Expired {
+				t.Errorf("Expected status expired, got %+v, %v", status, err)
+			}
+			if n := server.count("list"); n != lists {
+				t.Errorf("Expected no listing, got %d", n-lists)
+			}
+		})
+	}
+}
+
+func TestOpenUploadSessionExpired(t *testing.T) {
+	const size = 2 * absMinPartSize
+	server := newMultipartServer()
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	c := newTestClient(t, ts.URL)
+	ctx := context.Background()
+
+	state := UploadState{
+		BucketName: "bucket",
+		ObjectName: "object",
+		UploadID:   server.addUpload(nil),
+		Size:       size,
+		PartSize:   absMinPartSize,
+		Parts: []PartState{
+			{PartNumber: 1, Offset: 0, Size: absMinPartSize},
+			{PartNumber: 2, Offset: absMinPartSize, Size: absMinPartSize},
+		},
+	}
+	server.expire(state.UploadID)
+
+	// The listing of the parts finds the upload gone.
+	if _, err := c.OpenUploadSession(ctx, state, zeroReader{}, size, UploadOptions{}); !IsUploadExpired(err) {
+		t.Errorf("Expected the upload expired, got %v", err)
+	}
+	status, err := c.UploadStatus(state, 0)
+	if err != nil || !status.Expired || status.PartsDone != 0 {
+		t.Errorf("Expected status expired, got %+v, %v", status, err)
+	}
+}
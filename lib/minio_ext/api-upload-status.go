@@ -0,0 +1,85 @@
+package minio_ext
+
+import (
+	"context"
+	"time"
+)
+
+// UploadStatus - consolidated status of a multipart upload, computed
+// from its state and the parts listed from the server.
+type UploadStatus struct {
+	BucketName string `json:"bucket"`
+	ObjectName string `json:"object"`
+	UploadID   string `json:"uploadId"`
+
+	// Declared size and the bytes of planned parts the server holds
+	// with their planned size.
+	Size           int64 `json:"size"`
+	ConfirmedBytes int64 `json:"confirmedBytes"`
+
+	// Planned parts, and those not uploaded or uploaded with another
+	// size than planned.
+	PartsTotal   int   `json:"partsTotal"`
+	PartsDone    int   `json:"partsDone"`
+	MissingParts []int `json:"missingParts"`
+
+	// Time the upload was initiated and the time it is aborted by the
+	// bucket lifecycle, zero when unknown.
+	Initiated time.Time `json:"initiated,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+
+	// Expired is set when the upload is gone on the server.
+	Expired bool `json:"expired"`
+
+	// Completable is set when all planned parts are uploaded and the
+	// upload has not expired.
+	Completable bool `json:"completable"`
+}
+
+// UploadStatus - returns the status of the upload of state, the parts
+// are listed from the server. abortAfter is the age at which the bucket
+// lifecycle aborts incomplete uploads, 0 when unknown. An upload gone
+// on the server is reported as expired, not as an error.
+func (c Client) UploadStatus(state UploadState, abortAfter time.Duration) (UploadStatus, error) {
+	return c.UploadStatusWithContext(context.Background(), state, abortAfter)
+}
+
+// UploadStatusWithContext - same as UploadStatus, ctx cancels the listing of
+// the parts.
+func (c Client) UploadStatusWithContext(ctx context.Context, state UploadState, abortAfter time.Duration) (UploadStatus, error) {
+	status := UploadStatus{
+		BucketName:   state.BucketName,
+		ObjectName:   state.ObjectName,
+		UploadID:     state.UploadID,
+		Size:         state.Size,
+		PartsTotal:   len(state.Parts),
+		MissingParts: []int{},
+		Initiated:    state.Initiated,
+		Expired:      state.Expired,
+	}
+	if !state.Initiated.IsZero() && abortAfter > 0 {
+		status.ExpiresAt = state.Initiated.Add(abortAfter)
+	}
+	if status.Expired {
+		return status, nil
+	}
+
+	partsInfo, err := c.ListObjectPartsWithContext(ctx, state.BucketName, state.ObjectName, state.UploadID)
+	if err != nil {
+		if IsUploadExpired(err) {
+			status.Expired = true
+			return status, nil
+		}
+		return status, err
+	}
+	for _, spec := range state.Parts {
+		if part, ok := partsInfo[spec.PartNumber]; ok && part.Size == state.SentPart(spec).Size {
+			status.PartsDone++
+			status.ConfirmedBytes += spec.Size
+			continue
+		}
+		status.MissingParts = append(status.MissingParts, spec.PartNumber)
+	}
+	status.Completable = status.PartsTotal > 0 && len(status.MissingParts) == 0
+	return status, nil
+}
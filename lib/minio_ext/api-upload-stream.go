@@ -0,0 +1,473 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// StreamOptions - options for UploadStream.
+type StreamOptions struct {
+	// Options of the upload. Every part but the last one has PartSize,
+	// MinPartSize by default, so a stream holds at most MaxPartsCount
+	// parts of that size. Part sizes are never adapted, fingerprints and
+	// encryption are not supported.
+	Upload UploadOptions
+
+	// Parts are buffered in memory as long as all buffered parts fit
+	// into MemoryLimit bytes, other parts are spooled to temporary files
+	// in SpoolDir, the system temporary directory by default.
+	MemoryLimit int64
+	SpoolDir    string
+
+	// Optional store to resume an interrupted stream. Flushed parts are
+	// recorded, when the same stream is uploaded again its parts are
+	// compared with the uploaded ones and only differing parts are sent.
+	States StateStore
+}
+
+// spoolPart - a part of a stream buffered in memory or in a file.
+type spoolPart struct {
+	number int
+	size   int64
+	md5    []byte
+	data   []byte
+	file   *os.File
+}
+
+// reader - returns a reader over the data of the part.
+func (p *spoolPart) reader() io.ReadSeeker {
+	if p.file != nil {
+		return io.NewSectionReader(p.file, 0, p.size)
+	}
+	return bytes.NewReader(p.data)
+}
+
+// streamUpload - a multipart upload fed from a stream.
+type streamUpload struct {
+	// Part data sent and skipped, accessed atomically.
+	transferred int64
+	resumed     int64
+
+	client     *Client
+	bucketName string
+	objectName string
+	partSize   int64
+	opts       StreamOptions
+	key        string
+
+	// flow is the state of the upload in the part scheduler.
+	flow schedulerFlow
+
+	// mutex protects all fields below.
+	mutex  sync.Mutex
+	state  UploadState
+	parts  map[int]ObjectPart
+	memory int64
+}
+
+// UploadStream - uploads a stream of unknown length to
+// bucketName/objectName, returns the ETag of the object. The stream is
+// cut into parts which are spooled into memory or temporary files while
+// they are uploaded, so failed parts can be sent again. With a state
+// store flushed parts are recorded and an interrupted upload of the
+// same stream only sends the parts which are not on the server yet.
+func (c *Client) UploadStream(ctx context.Context, reader io.Reader, bucketName, objectName string, opts StreamOptions) (string, error) {
+	// Input validation.
+	if reader == nil {
+		return "", ErrInvalidArgument("Reader cannot be nil.")
+	}
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return "", err
+	}
+	if err := s3utils.CheckValidObjectName(objectName); err != nil {
+		return "", err
+	}
+	if opts.Upload.Encryption != nil || opts.Upload.Fingerprint != nil {
+		return "", ErrInvalidArgument("Streams support neither encryption nor fingerprints.")
+	}
+	partSize := opts.Upload.PartSize
+	if partSize == 0 {
+		partSize = MinPartSize
+	}
+	if partSize < absMinPartSize || partSize > MaxPartSize {
+		return "", ErrInvalidArgument(fmt.Sprintf("Part size must be between %d and %d.", absMinPartSize, MaxPartSize))
+	}
+	if opts.Upload.NumThreads <= 0 {
+		opts.Upload.NumThreads = totalWorkers
+	}
+	opts.Upload.PartRetry = opts.Upload.PartRetry.withDefaults()
+	if opts.Upload.Retention != nil {
+		if err := opts.Upload.Retention.validate(); err != nil {
+			return "", err
+		}
+	}
+	if opts.Upload.Retention != nil || opts.Upload.LegalHold {
+		opts.Upload.SendContentMD5 = true
+	}
+	defer c.trackSession()()
+
+	u := &streamUpload{
+		client:     c,
+		bucketName: bucketName,
+		objectName: objectName,
+		partSize:   partSize,
+		opts:       opts,
+		key:        bucketName + "/" + objectName,
+		parts:      make(map[int]ObjectPart),
+	}
+	resumed, err := u.resume(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !resumed {
+		initResult, err := c.initiateMultipartUpload(ctx, bucketName, objectName, initiateHeader(opts.Upload, nil))
+		if err != nil {
+			return "", err
+		}
+		u.state = UploadState{
+			BucketName: bucketName,
+			ObjectName: objectName,
+			UploadID:   initResult.UploadID,
+			PartSize:   partSize,
+			Stream:     true,
+		}
+		if err = u.save(); err != nil {
+			c.abortMultipartUpload(ctx, bucketName, objectName, initResult.UploadID)
+			return "", err
+		}
+	}
+
+	etag, err := u.upload(ctx, reader)
+	if err != nil {
+		if opts.States == nil {
+			// Nothing can resume the upload, drop its parts.
+			c.abortMultipartUpload(context.Background(), bucketName, objectName, u.state.UploadID)
+		}
+		return "", err
+	}
+	if opts.States != nil {
+		if err = opts.States.Delete(u.key); err != nil {
+			return "", err
+		}
+	}
+	return etag, nil
+}
+
+// resume - continues the stream upload recorded in the state store,
+// returns false when there is none to continue.
+func (u *streamUpload) resume(ctx context.Context) (bool, error) {
+	if u.opts.States == nil {
+		return false, nil
+	}
+	state, err := u.opts.States.Load(u.key)
+	if err != nil || state == nil {
+		return false, err
+	}
+	if !state.Stream || state.PartSize != u.partSize {
+		// Best effort, drop the parts of an upload started differently.
+		u.client.abortMultipartUpload(ctx, state.BucketName, state.ObjectName, state.UploadID)
+		return false, nil
+	}
+	expired := UploadExpiredError{
+		BucketName: state.BucketName,
+		ObjectName: state.ObjectName,
+		UploadID:   state.UploadID,
+	}
+	if state.Expired {
+		if u.opts.Upload.RestartExpired {
+			return false, nil
+		}
+		return false, expired
+	}
+
+	uploaded, err := u.client.ListObjectParts(state.BucketName, state.ObjectName, state.UploadID)
+	if err != nil {
+		if !IsUploadExpired(err) {
+			return false, err
+		}
+		if u.opts.Upload.RestartExpired {
+			return false, nil
+		}
+		state.Expired = true
+		u.opts.States.Save(u.key, *state)
+		return false, expired
+	}
+
+	u.state = *state
+	u.state.Parts = nil
+	for _, spec := range state.Parts {
+		if part, ok := uploaded[spec.PartNumber]; ok && part.Size == spec.Size {
+			u.parts[spec.PartNumber] = part
+			u.state.Parts = append(u.state.Parts, spec)
+		}
+	}
+	return true, nil
+}
+
+// save - persists the state, caller must hold the mutex once the
+// upload is running.
+func (u *streamUpload) save() error {
+	if u.opts.States == nil {
+		return nil
+	}
+	return u.opts.States.Save(u.key, u.state)
+}
+
+// spool - reads part number of the stream, into memory while the
+// memory limit allows it and into a temporary file otherwise. A part
+// shorter than the part size is the last one.
+func (u *streamUpload) spool(reader io.Reader, number int) (*spoolPart, error) {
+	part := &spoolPart{number: number}
+	md5Hash := md5.New()
+
+	u.mutex.Lock()
+	inMemory := u.memory+u.partSize <= u.opts.MemoryLimit
+	if inMemory {
+		u.memory += u.partSize
+	}
+	u.mutex.Unlock()
+
+	var err error
+	if inMemory {
+		part.data = make([]byte, u.partSize)
+		var n int
+		n, err = io.ReadFull(reader, part.data)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = nil
+		}
+		part.data = part.data[:n]
+		part.size = int64(n)
+		md5Hash.Write(part.data)
+	} else if part.file, err = ioutil.TempFile(u.opts.SpoolDir, "stream-part-"); err == nil {
+		part.size, err = io.Copy(io.MultiWriter(part.file, md5Hash), io.LimitReader(reader, u.partSize))
+	}
+	if err != nil {
+		u.release(part)
+		return nil, err
+	}
+	part.md5 = md5Hash.Sum(nil)
+	return part, nil
+}
+
+// release - frees the memory or the temporary file of a part.
+func (u *streamUpload) release(part *spoolPart) {
+	if part.file != nil {
+		part.file.Close()
+		os.Remove(part.file.Name())
+		part.file = nil
+	}
+	if part.data != nil {
+		u.mutex.Lock()
+		u.memory -= int64(cap(part.data))
+		u.mutex.Unlock()
+		part.data = nil
+	}
+}
+
+// uploaded - is part already on the server with the same content.
+func (u *streamUpload) uploaded(part *spoolPart) bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	objPart, ok := u.parts[part.number]
+	return ok && objPart.Size == part.size && strings.EqualFold(objPart.ETag, hex.EncodeToString(part.md5))
+}
+
+// flushed - records part as uploaded and persists the state.
+func (u *streamUpload) flushed(part *spoolPart, objPart ObjectPart) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.parts[part.number] = objPart
+	spec := PartState{
+		PartNumber: part.number,
+		Offset:     int64(part.number-1) * u.partSize,
+		Size:       part.size,
+	}
+	replaced := false
+	for i := range u.state.Parts {
+		if u.state.Parts[i].PartNumber == part.number {
+			u.state.Parts[i] = spec
+			replaced = true
+		}
+	}
+	if !replaced {
+		u.state.Parts = append(u.state.Parts, spec)
+	}
+	if end := spec.Offset + spec.Size; end > u.state.Size {
+		u.state.Size = end
+	}
+	return u.save()
+}
+
+// uploadPartWithRetry - uploads a spooled part, retrying it according
+// to the part retry policy.
+func (u *streamUpload) uploadPartWithRetry(ctx context.Context, part *spoolPart) error {
+	policy := u.opts.Upload.PartRetry
+
+	var md5Base64 string
+	if u.opts.Upload.SendContentMD5 {
+		md5Base64 = base64.StdEncoding.EncodeToString(part.md5)
+	}
+
+	var err error
+	var attempts int
+	for timer := newRetryTimer(policy.MaxAttempts, policy.Unit, policy.Cap, nil); timer.next(ctx); {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if err == nil {
+				err = ctxErr
+			}
+			break
+		}
+		attempts++
+
+		if u.opts.Upload.Scheduler != nil {
+			if err = u.opts.Upload.Scheduler.acquire(ctx, &u.flow, u.opts.Upload.Priority); err != nil {
+				break
+			}
+		}
+		reader := newLimitedReader(ctx, countingReader{part.reader(), &u.transferred}, u.opts.Upload.RateLimiter)
+		var objPart ObjectPart
+		objPart, err = u.client.uploadPart(ctx, u.bucketName, u.objectName, u.state.UploadID,
+			reader, part.number, md5Base64, "", part.size, nil)
+		if u.opts.Upload.Scheduler != nil {
+			u.opts.Upload.Scheduler.release()
+		}
+		if err == nil {
+			if err = u.flushed(part, objPart); err != nil {
+				break
+			}
+			if u.opts.Upload.Progress != nil {
+				u.opts.Upload.Progress(part.size)
+			}
+			return nil
+		}
+		if attempts >= policy.MaxAttempts || !isPartErrorRetryable(err) {
+			break
+		}
+	}
+	return PartError{
+		PartNumber: part.number,
+		Attempts:   attempts,
+		Err:        err,
+	}
+}
+
+// upload - reads the stream part by part, uploads every part which is
+// not on the server yet and completes the multipart upload.
+func (u *streamUpload) upload(ctx context.Context, reader io.Reader) (string, error) {
+	// Stop reading as soon as a part failed for good.
+	partCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parts := make(chan *spoolPart)
+	var wg sync.WaitGroup
+	var errMutex sync.Mutex
+	var partErrs []PartError
+	for i := 0; i < u.opts.Upload.NumThreads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range parts {
+				err := u.uploadPartWithRetry(partCtx, part)
+				u.release(part)
+				if err != nil {
+					errMutex.Lock()
+					partErrs = append(partErrs, err.(PartError))
+					errMutex.Unlock()
+					cancel()
+				}
+			}
+		}()
+	}
+
+	var readErr error
+	var size int64
+	count := 0
+	for number := 1; readErr == nil; number++ {
+		part, err := u.spool(reader, number)
+		if err != nil {
+			readErr = err
+			break
+		}
+		if part.size == 0 && number > 1 {
+			u.release(part)
+			break
+		}
+		if number > MaxPartsCount {
+			u.release(part)
+			readErr = ErrInvalidArgument(fmt.Sprintf("Stream has more than %d parts of %d bytes.", MaxPartsCount, u.partSize))
+			break
+		}
+		count, size = number, size+part.size
+
+		if u.uploaded(part) {
+			atomic.AddInt64(&u.resumed, part.size)
+			if u.opts.Upload.Progress != nil {
+				u.opts.Upload.Progress(part.size)
+			}
+			u.release(part)
+		} else {
+			select {
+			case parts <- part:
+			case <-partCtx.Done():
+				u.release(part)
+				readErr = partCtx.Err()
+			}
+		}
+		if part.size < u.partSize {
+			break
+		}
+	}
+	close(parts)
+	wg.Wait()
+
+	if len(partErrs) > 0 {
+		sort.Slice(partErrs, func(i, j int) bool { return partErrs[i].PartNumber < partErrs[j].PartNumber })
+		return "", UploadError{
+			UploadID: u.state.UploadID,
+			Parts:    partErrs,
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if readErr != nil {
+		return "", readErr
+	}
+
+	complete := make([]CompletePart, 0, count)
+	for number := 1; number <= count; number++ {
+		complete = append(complete, CompletePart{
+			PartNumber: number,
+			ETag:       u.parts[number].ETag,
+		})
+	}
+	etag, err := u.client.CompleteMultipartUpload(u.bucketName, u.objectName, u.state.UploadID, complete, nil)
+	if err != nil {
+		return "", err
+	}
+	if u.opts.Upload.Accounting != nil {
+		u.opts.Upload.Accounting(AccountingRecord{
+			BucketName:       u.bucketName,
+			ObjectName:       u.objectName,
+			UploadID:         u.state.UploadID,
+			LogicalBytes:     size,
+			TransferredBytes: atomic.LoadInt64(&u.transferred),
+			ResumedBytes:     atomic.LoadInt64(&u.resumed),
+			StorageBytes:     size,
+		})
+	}
+	return etag, nil
+}
@@ -0,0 +1,155 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// failingReader - returns err once the data of reader is read.
+type failingReader struct {
+	reader io.Reader
+	err    error
+}
+
+// Read - implements io.Reader.
+func (r failingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err == io.EOF {
+		err = r.err
+	}
+	return n, err
+}
+
+func TestStreamUploadSpool(t *testing.T) {
+	const partSize = absMinPartSize
+	testCases := []struct {
+		memoryLimit int64
+		// Whether the parts read are in memory, parts marked released
+		// are released before the next part is read.
+		inMemory []bool
+		released []bool
+	}{
+		{0, []bool{false, false, false}, []bool{false, false, false}},
+		{partSize, []bool{true, false, false}, []bool{false, false, false}},
+		{2 * partSize, []bool{true, true, false}, []bool{false, false, false}},
+		{partSize, []bool{true, true, true}, []bool{true, true, true}},
+		{partSize - 1, []bool{false, false, false}, []bool{true, true, true}},
+	}
+	for i, testCase := range testCases {
+		dir, err := ioutil.TempDir("", "spool")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		u := &streamUpload{
+			partSize: partSize,
+			opts:     StreamOptions{MemoryLimit: testCase.memoryLimit, SpoolDir: dir},
+		}
+		data := make([]byte, 5*partSize/2)
+		rand.New(rand.NewSource(int64(i))).Read(data)
+		reader := bytes.NewReader(data)
+
+		var parts []*spoolPart
+		for number := 1; number <= 3; number++ {
+			part, err := u.spool(reader, number)
+			if err != nil {
+				t.Fatalf("Test %d: %v", i+1, err)
+			}
+			parts = append(parts, part)
+			if inMemory := part.file == nil; inMemory != testCase.inMemory[number-1] {
+				t.Errorf("Test %d: expected part %d in memory %v", i+1, number, testCase.inMemory[number-1])
+			}
+			offset := int64(number-1) * partSize
+			expected := data[offset:]
+			if len(expected) > partSize {
+				expected = expected[:partSize]
+			}
+			if got, _ := ioutil.ReadAll(part.reader()); !bytes.Equal(got, expected) {
+				t.Errorf("Test %d: unexpected data of part %d", i+1, number)
+			}
+			if testCase.released[number-1] {
+				u.release(part)
+			}
+		}
+		for _, part := range parts {
+			u.release(part)
+		}
+		if files, _ := ioutil.ReadDir(dir); len(files) != 0 || u.memory != 0 {
+			t.Errorf("Test %d: expected all parts released, got %d files and %d bytes", i+1, len(files), u.memory)
+		}
+	}
+}
+
+func TestUploadStreamResume(t *testing.T) {
+	const partSize = absMinPartSize
+	server := newMultipartServer()
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	c := newTestClient(t, ts.URL)
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewFileStateStore(filepath.Join(dir, "state"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 7*partSize/2)
+	rand.New(rand.NewSource(1)).Read(data)
+	opts := StreamOptions{
+		Upload: UploadOptions{
+			PartSize:   partSize,
+			NumThreads: 1,
+			PartRetry:  PartRetryPolicy{MaxAttempts: 1},
+		},
+		MemoryLimit: partSize,
+		SpoolDir:    dir,
+		States:      store,
+	}
+
+	// The stream breaks while the third part is read.
+	errBroken := errors.New("stream broken")
+	broken := failingReader{bytes.NewReader(data[:5*partSize/2]), errBroken}
+	if _, err = c.UploadStream(ctx, broken, "bucket", "object", opts); err != errBroken {
+		t.Fatalf("Expected the stream error, got %v", err)
+	}
+	state, err := store.Load("bucket/object")
+	if err != nil || state == nil || !state.Stream || len(state.Parts) != 2 || state.Size != 2*partSize {
+		t.Fatalf("Expected two flushed parts recorded, got %+v, %v", state, err)
+	}
+
+	// The stream is sent again, the flushed parts are skipped.
+	var progress int64
+	opts.Upload.Progress = func(n int64) { progress += n }
+	if _, err = c.UploadStream(ctx, bytes.NewReader(data), "bucket", "object", opts); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.count("part"); n != 4 {
+		t.Errorf("Expected 4 parts sent, got %d", n)
+	}
+	if n := server.count("initiate"); n != 1 {
+		t.Errorf("Expected the upload resumed, got %d uploads", n)
+	}
+	if obj, ok := server.object("/bucket/object"); !ok || obj.size != int64(len(data)) || obj.parts != 4 {
+		t.Errorf("Expected the object uploaded, got %+v", obj)
+	}
+	if progress != int64(len(data)) {
+		t.Errorf("Expected %d bytes reported, got %d", len(data), progress)
+	}
+	if state, err = store.Load("bucket/object"); err != nil || state != nil {
+		t.Errorf("Expected the state deleted, got %+v, %v", state, err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Expected the spooled parts removed, got %d files", len(files))
+	}
+}
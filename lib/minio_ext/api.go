@@ -3,8 +3,9 @@ package minio_ext
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/base64"
 	"errors"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
@@ -12,7 +13,6 @@ import (
 	"net"
 	"net/http"
 	"net/http/cookiejar"
-	"net/http/httputil"
 	"net/url"
 	"os"
 	"path"
@@ -20,11 +20,13 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/minio/minio-go/pkg/s3signer"
 	"github.com/minio/minio-go/pkg/s3utils"
 	"github.com/minio/minio-go/v6/pkg/credentials"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/publicsuffix"
 )
 
@@ -57,6 +59,9 @@ type requestMetadata struct {
 
 	// Generated by our internal code.
 	bucketLocation   string
+	bucketLookup     BucketLookupType // overrides the lookup of the client unless auto
+	retryPolicy      RequestRetryPolicy // overrides the retry policy of the client where set
+	hedge            bool               // small idempotent request, hedged like HEAD requests
 	contentBody      io.Reader
 	contentLength    int64
 	contentMD5Base64 string // carries base64 encoded md5sum
@@ -67,14 +72,54 @@ type BucketLookupType int
 // bucketLocationCache - Provides simple mechanism to hold bucket
 // locations in memory.
 type bucketLocationCache struct {
+	// hits, misses and negativeHits count the lookups, accessed
+	// atomically and first in the struct to stay 64 bit aligned.
+	hits         uint64
+	misses       uint64
+	negativeHits uint64
+
 	// mutex is used for handling the concurrent
 	// read/write requests for cache.
 	sync.RWMutex
 
 	// items holds the cached bucket locations.
-	items map[string]string
+	items map[string]bucketLocationEntry
+
+	// ttl is how long locations are cached, forever when 0, negativeTTL
+	// how long lookups of missing buckets are.
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	// lookups holds the lookups in flight, concurrent lookups of a
+	// bucket share one request.
+	lookups map[string]*bucketLocationLookup
+
+	// store optionally persists the locations, see SetLocationStore.
+	store LocationStore
 }
 
+// bucketLocationLookup - a lookup in flight, location and err are set
+// when done is closed.
+type bucketLocationLookup struct {
+	done      chan struct{}
+	location  string
+	err       error
+	cancelled bool
+}
+
+// bucketLocationEntry - a cached bucket location.
+type bucketLocationEntry struct {
+	location string
+	expires  time.Time
+
+	// err is the error of a failed lookup, location is empty then.
+	err error
+}
+
+// defaultBucketLocationTTL - how long bucket locations are cached by
+// default, buckets may be recreated in another region.
+const defaultBucketLocationTTL = time.Hour
+
 // Client implements Amazon S3 compatible methods.
 type Client struct {
 	///  Standard options.
@@ -88,6 +133,20 @@ type Client struct {
 	// Custom signerType value overrides all credentials.
 	overrideSignerType credentials.SignatureType
 
+	// Expiry of temporary credentials, presigned URLs expire with them.
+	credsExpiration func() time.Time
+
+	// Payload signing policy of signature V4 requests.
+	payloadSigning PayloadSigning
+
+	// Retries of failed requests, see SetRetryPolicy and SetRetryHook.
+	retryPolicy RequestRetryPolicy
+	retryHook   func(RetryEvent)
+	retryStats  *retryCounters
+
+	// Latencies and throughput by operation, see Stats.
+	operationStats *operationCounters
+
 	// User supplied.
 	appInfo struct {
 		appName    string
@@ -100,15 +159,45 @@ type Client struct {
 	// Needs allocation.
 	httpClient     *http.Client
 	bucketLocCache *bucketLocationCache
+	sseCSessions   *sseCustomerSessionCache
+	webhooks       *webhookNotifier
 
 	// Advanced functionality.
 	isTraceEnabled  bool
 	traceErrorsOnly bool
 	traceOutput     io.Writer
+	traceFormatter  TraceFormatter
+
+	// Trace sampling and body limit, traceCount needs allocation.
+	traceSampleEvery int
+	traceMaxBody     int64
+	traceCount       *uint64
+
+	// Offset of the server clock in nanoseconds, needs allocation.
+	clockOffset *int64
 
 	// S3 specific accelerated endpoint.
 	s3AccelerateEndpoint string
 
+	// Hosts of the bucket locations, take precedence over the AWS
+	// endpoints.
+	endpointMap map[string]string
+
+	// Endpoints requests fail over to, see SetFailoverEndpoints.
+	failover *endpointFailover
+
+	// Hedging of small idempotent requests, see SetHedging.
+	hedger *hedger
+
+	// Spans of requests and sessions, see SetTracerProvider.
+	tracerProvider trace.TracerProvider
+
+	// Measurements of the requests and sessions, see SetMetrics.
+	metrics Metrics
+
+	// Wrappers of the sending of requests, see Use.
+	middleware []Middleware
+
 	// Region endpoint
 	region string
 
@@ -150,6 +239,18 @@ const (
 	BucketLookupPath
 )
 
+// unsignableHeaders - headers of presigned requests which are set by
+// the browser or by the signature.
+var unsignableHeaders = map[string]bool{
+	"Authorization":        true,
+	"Content-Length":       true,
+	"Host":                 true,
+	"User-Agent":           true,
+	"X-Amz-Content-Sha256": true,
+	"X-Amz-Date":           true,
+	"X-Amz-Security-Token": true,
+}
+
 // awsS3EndpointMap Amazon S3 endpoint map.
 var awsS3EndpointMap = map[string]string{
 	"us-east-1":      "s3.dualstack.us-east-1.amazonaws.com",
@@ -229,7 +330,10 @@ var successStatus = []int{
 // used internally with the client object.
 func newBucketLocationCache() *bucketLocationCache {
 	return &bucketLocationCache{
-		items: make(map[string]string),
+		items:       make(map[string]bucketLocationEntry),
+		ttl:         defaultBucketLocationTTL,
+		negativeTTL: defaultBucketLocationNegativeTTL,
+		lookups:     make(map[string]*bucketLocationLookup),
 	}
 }
 
@@ -288,7 +392,7 @@ func (c *Client) redirectHeaders(req *http.Request, via []*http.Request) error {
 		case signerType.IsV2():
 			return errors.New("signature V2 cannot support redirection")
 		case signerType.IsV4():
-			s3signer.SignV4(*req, accessKeyID, secretAccessKey, sessionToken, getDefaultLocation(*c.endpointURL, region))
+			signV4At(*req, accessKeyID, secretAccessKey, sessionToken, getDefaultLocation(*c.endpointURL, region), c.now())
 		}
 	}
 	return nil
@@ -340,6 +444,24 @@ func privateNew(endpoint string, creds *credentials.Credentials, secure bool, re
 	// Instantiate bucket location cache.
 	clnt.bucketLocCache = newBucketLocationCache()
 
+	// Instantiate SSE-C session cache.
+	clnt.sseCSessions = newSSECustomerSessionCache()
+
+	// Instantiate completion webhook notifier.
+	clnt.webhooks = newWebhookNotifier()
+
+	// Instantiate retry counters.
+	clnt.retryStats = newRetryCounters()
+
+	// Instantiate operation statistics.
+	clnt.operationStats = newOperationCounters()
+
+	// Instantiate trace sampling counter.
+	clnt.traceCount = new(uint64)
+
+	// Instantiate clock skew, the offset of the server clock.
+	clnt.clockOffset = new(int64)
+
 	// Introduce a new locked random seed.
 	clnt.random = rand.New(&lockedRandSource{src: rand.NewSource(time.Now().UTC().UnixNano())})
 
@@ -350,30 +472,193 @@ func privateNew(endpoint string, creds *credentials.Credentials, secure bool, re
 	return clnt, nil
 }
 
-// New - instantiate minio client, adds automatic verification of signature.
-func New(endpoint, accessKeyID, secretAccessKey string, secure bool) (*Client, error) {
-	creds := credentials.NewStaticV4(accessKeyID, secretAccessKey, "")
-	clnt, err := privateNew(endpoint, creds, secure, "", BucketLookupAuto)
+// New - instantiate minio client configured by opts, adds automatic
+// verification of signature. Clients without WithCredentials are
+// anonymous.
+func New(endpoint string, opts ...Option) (*Client, error) {
+	var o clientOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	creds := o.creds
+	if creds == nil {
+		creds = credentials.NewStaticV4("", "", "")
+	}
+	clnt, err := privateNew(endpoint, creds, o.secure, o.region, o.lookup)
 	if err != nil {
 		return nil, err
 	}
-	// Google cloud storage should be set to signature V2, force it if not.
-	if s3utils.IsGoogleEndpoint(*clnt.endpointURL) {
-		clnt.overrideSignerType = credentials.SignatureV2
+	clnt.credsExpiration = o.credsExpiration
+	clnt.payloadSigning = o.payloadSigning
+	if o.locationTTLSet {
+		clnt.SetBucketLocationTTL(o.locationTTL)
+	}
+	clnt.retryPolicy = o.retryPolicy
+	clnt.retryHook = o.retryHook
+	if o.negativeTTLSet {
+		clnt.SetBucketLocationNegativeTTL(o.negativeTTL)
+	}
+	if o.locationStore != nil {
+		clnt.SetLocationStore(o.locationStore)
+	}
+	if o.transportOptions() && o.customTransport() {
+		return nil, ErrInvalidArgument("Transport options cannot be combined with a custom transport.")
+	}
+	if tr, ok := clnt.httpClient.Transport.(*http.Transport); ok {
+		if err = o.configureTransport(tr); err != nil {
+			return nil, err
+		}
+	}
+	if o.httpClient != nil {
+		httpClient := *o.httpClient
+		if httpClient.Transport == nil {
+			httpClient.Transport = clnt.httpClient.Transport
+		}
+		if httpClient.Jar == nil {
+			httpClient.Jar = clnt.httpClient.Jar
+		}
+		if httpClient.CheckRedirect == nil {
+			httpClient.CheckRedirect = clnt.redirectHeaders
+		}
+		clnt.httpClient = &httpClient
+	}
+	if o.transport != nil {
+		clnt.httpClient.Transport = o.transport
+	}
+	if o.timeouts.IdleBody > 0 {
+		clnt.httpClient.Transport = &stallTransport{
+			transport: clnt.httpClient.Transport,
+			timeout:   o.timeouts.IdleBody,
+		}
+	}
+	if o.traceOutput != nil {
+		clnt.isTraceEnabled = true
+		clnt.traceErrorsOnly = o.traceErrorsOnly
+		clnt.traceOutput = o.traceOutput
+	}
+	clnt.traceFormatter = o.traceFormatter
+	clnt.traceSampleEvery = o.traceSampleEvery
+	clnt.traceMaxBody = o.traceMaxBody
+	clnt.SetS3TransferAccelerate(o.accelerateEndpoint)
+	if o.endpointMap != nil {
+		clnt.SetEndpointMap(o.endpointMap)
+	}
+	clnt.SetHedging(o.hedging)
+	clnt.metrics = o.metrics
+	clnt.tracerProvider = o.tracerProvider
+	clnt.Use(o.middleware...)
+	if len(o.failoverEndpoints) > 0 {
+		if err = clnt.SetFailoverEndpoints(o.failoverEndpoints...); err != nil {
+			return nil, err
+		}
 	}
-	// If Amazon S3 set to signature v4.
-	if s3utils.IsAmazonEndpoint(*clnt.endpointURL) {
+	clnt.appInfo.appName = o.appName
+	clnt.appInfo.appVersion = o.appVersion
+
+	switch {
+	case o.signerType != credentials.SignatureDefault:
+		clnt.overrideSignerType = o.signerType
+	case s3utils.IsGoogleEndpoint(*clnt.endpointURL):
+		// Google cloud storage should be set to signature V2, force it if not.
+		clnt.overrideSignerType = credentials.SignatureV2
+	case s3utils.IsAmazonEndpoint(*clnt.endpointURL):
+		// If Amazon S3 set to signature v4.
 		clnt.overrideSignerType = credentials.SignatureV4
 	}
 	return clnt, nil
 }
 
+// NewStatic - instantiate minio client with static credentials, the
+// former positional constructor.
+//
+// Deprecated: use New with WithCredentials and WithSecure.
+func NewStatic(endpoint, accessKeyID, secretAccessKey string, secure bool) (*Client, error) {
+	return New(endpoint, WithCredentials(accessKeyID, secretAccessKey), WithSecure(secure))
+}
+
+// NewWithCredentials - instantiate minio client taking its credentials
+// from creds, for example a chain of environment, IAM and STS providers
+// of services running on EC2, ECS or Kubernetes. Credentials are
+// refreshed by creds when they expire, presigned URLs are signed with
+// the current ones. An empty region looks the bucket locations up.
+func NewWithCredentials(endpoint string, creds *credentials.Credentials, secure bool, region string) (*Client, error) {
+	if creds == nil {
+		return nil, ErrInvalidArgument("Credentials cannot be nil.")
+	}
+	return New(endpoint, WithCredentialsProvider(creds), WithSecure(secure), WithRegion(region))
+}
+
+// SetAppInfo - add application details to user agent.
+func (c *Client) SetAppInfo(appName string, appVersion string) {
+	// if app name and version not set, we do not set a new user agent.
+	if appName != "" && appVersion != "" {
+		c.appInfo.appName = appName
+		c.appInfo.appVersion = appVersion
+	}
+}
+
+// TraceOn - enable HTTP tracing of all requests to outputStream,
+// os.Stdout when nil.
+func (c *Client) TraceOn(outputStream io.Writer) {
+	// if outputStream is nil then default to os.Stdout.
+	if outputStream == nil {
+		outputStream = os.Stdout
+	}
+	// Sets a new output stream.
+	c.traceOutput = outputStream
+
+	// Enable tracing.
+	c.isTraceEnabled = true
+	c.traceErrorsOnly = false
+}
+
+// TraceErrorsOnlyOn - same as TraceOn, but only failed requests are
+// traced.
+func (c *Client) TraceErrorsOnlyOn(outputStream io.Writer) {
+	c.TraceOn(outputStream)
+	c.traceErrorsOnly = true
+}
+
+// TraceOff - disable HTTP tracing.
+func (c *Client) TraceOff() {
+	// Disable tracing.
+	c.isTraceEnabled = false
+	c.traceErrorsOnly = false
+}
+
+// SetS3TransferAccelerate - sends the requests on buckets, presigned
+// part URLs included, to the S3 accelerate endpoint, for example
+// "s3-accelerate.amazonaws.com", empty turns acceleration off. Only
+// Amazon S3 supports acceleration, for other endpoints this does
+// nothing. Bucket names with dots cannot be accelerated.
+// http://docs.aws.amazon.com/AmazonS3/latest/dev/transfer-acceleration.html
+func (c *Client) SetS3TransferAccelerate(accelerateEndpoint string) {
+	if s3utils.IsAmazonEndpoint(*c.endpointURL) {
+		c.s3AccelerateEndpoint = accelerateEndpoint
+	}
+}
+
+// SetEndpointMap - sends requests on buckets in a location of endpoints
+// to the host, with optional port, registered for the location, for
+// multi-region private clouds. Registered locations take precedence
+// over the AWS endpoints, other locations go to the endpoint of the
+// client as before.
+func (c *Client) SetEndpointMap(endpoints map[string]string) {
+	c.endpointMap = make(map[string]string, len(endpoints))
+	for location, host := range endpoints {
+		c.endpointMap[location] = host
+	}
+}
+
 // Get - Returns a value of a given key if it exists.
 func (r *bucketLocationCache) Get(bucketName string) (location string, ok bool) {
 	r.RLock()
 	defer r.RUnlock()
-	location, ok = r.items[bucketName]
-	return
+	entry, ok := r.items[bucketName]
+	if !ok || entry.err != nil || (!entry.expires.IsZero() && time.Now().After(entry.expires)) {
+		return "", false
+	}
+	return entry.location, true
 }
 
 // set User agent.
@@ -385,13 +670,14 @@ func (c Client) setUserAgent(req *http.Request) {
 }
 
 // getBucketLocationRequest - Wrapper creates a new getBucketLocation request.
-func (c Client) getBucketLocationRequest(bucketName string) (*http.Request, error) {
+func (c Client) getBucketLocationRequest(ctx context.Context, bucketName string) (*http.Request, error) {
 	// Set location query.
 	urlValues := make(url.Values)
 	urlValues.Set("location", "")
 
 	// Set get bucket location always as path style.
 	targetURL := *c.endpointURL
+	targetURL.Host = c.endpointHost()
 
 	// as it works in makeTargetURL method from api.go file
 	if h, p, err := net.SplitHostPort(targetURL.Host); err == nil {
@@ -408,6 +694,7 @@ func (c Client) getBucketLocationRequest(bucketName string) (*http.Request, erro
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 
 	// Set UserAgent for the request.
 	c.setUserAgent(req)
@@ -454,82 +741,44 @@ func (c Client) getBucketLocationRequest(bucketName string) (*http.Request, erro
 	}
 
 	req.Header.Set("X-Amz-Content-Sha256", contentSha256)
-	req = s3signer.SignV4(*req, accessKeyID, secretAccessKey, sessionToken, "us-east-1")
+	req = signV4At(*req, accessKeyID, secretAccessKey, sessionToken, "us-east-1", c.now())
 	return req, nil
 }
 
-// dumpHTTP - dump HTTP request and response.
-func (c Client) dumpHTTP(req *http.Request, resp *http.Response) error {
-	// Starts http dump.
-	_, err := fmt.Fprintln(c.traceOutput, "---------START-HTTP---------")
+// dumpHTTP - dump HTTP request and response with the trace formatter.
+func (c Client) dumpHTTP(req *http.Request, resp *http.Response, start time.Time) error {
+	format := c.traceFormatter
+	if format == nil {
+		format = DumpTrace
+	}
+	// Written at once so that traces of concurrent requests do not
+	// interleave and a rotating output never splits one.
+	var buf bytes.Buffer
+	err := format(&buf, HTTPTrace{
+		Request:  redactRequest(req),
+		Response: resp,
+		Start:    start,
+		Duration: time.Since(start),
+		MaxBody:  c.traceMaxBody,
+	})
 	if err != nil {
 		return err
 	}
-
-	// Filter out Signature field from Authorization header.
-	origAuth := req.Header.Get("Authorization")
-	if origAuth != "" {
-		req.Header.Set("Authorization", redactSignature(origAuth))
-	}
-
-	// Only display request header.
-	reqTrace, err := httputil.DumpRequestOut(req, false)
-	if err != nil {
-		return err
-	}
-
-	// Write request to trace output.
-	_, err = fmt.Fprint(c.traceOutput, string(reqTrace))
-	if err != nil {
-		return err
-	}
-
-	// Only display response header.
-	var respTrace []byte
-
-	// For errors we make sure to dump response body as well.
-	if resp.StatusCode != http.StatusOK &&
-		resp.StatusCode != http.StatusPartialContent &&
-		resp.StatusCode != http.StatusNoContent {
-		respTrace, err = httputil.DumpResponse(resp, true)
-		if err != nil {
-			return err
-		}
-	} else {
-		respTrace, err = httputil.DumpResponse(resp, false)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Write response to trace output.
-	_, err = fmt.Fprint(c.traceOutput, strings.TrimSuffix(string(respTrace), "\r\n"))
-	if err != nil {
-		return err
-	}
-
-	// Ends the http dump.
-	_, err = fmt.Fprintln(c.traceOutput, "---------END-HTTP---------")
-	if err != nil {
-		return err
-	}
-
-	// Returns success.
-	return nil
+	_, err = c.traceOutput.Write(buf.Bytes())
+	return err
 }
 
 // do - execute http request.
 func (c Client) do(req *http.Request) (*http.Response, error) {
-	resp, err := c.httpClient.Do(req)
+	start := time.Now()
+	resp, err := c.roundTrip(req)
 	if err != nil {
 		// Handle this specifically for now until future Golang versions fix this issue properly.
-		if urlErr, ok := err.(*url.Error); ok {
-			if strings.Contains(urlErr.Err.Error(), "EOF") {
-				return nil, &url.Error{
-					Op:  urlErr.Op,
-					URL: urlErr.URL,
-					Err: errors.New("Connection closed by foreign host " + urlErr.URL + ". Retry again."),
-				}
+		if urlErr, ok := err.(*url.Error); ok && isConnectionReset(urlErr.Err) {
+			return nil, &url.Error{
+				Op:  urlErr.Op,
+				URL: urlErr.URL,
+				Err: ErrConnectionReset,
 			}
 		}
 		return nil, err
@@ -543,8 +792,8 @@ func (c Client) do(req *http.Request) (*http.Response, error) {
 
 	// If trace is enabled, dump http request and response,
 	// except when the traceErrorsOnly enabled and the response's status code is ok
-	if c.isTraceEnabled && !(c.traceErrorsOnly && resp.StatusCode == http.StatusOK) {
-		err = c.dumpHTTP(req, resp)
+	if c.isTraceEnabled && !(c.traceErrorsOnly && resp.StatusCode == http.StatusOK) && c.traceSampled(resp) {
+		err = c.dumpHTTP(req, resp, start)
 		if err != nil {
 			return nil, err
 		}
@@ -555,7 +804,7 @@ func (c Client) do(req *http.Request) (*http.Response, error) {
 
 // getBucketLocation - Get location for the bucketName from location map cache, if not
 // fetch freshly by making a new request.
-func (c Client) getBucketLocation(bucketName string) (string, error) {
+func (c Client) getBucketLocation(ctx context.Context, bucketName string) (string, error) {
 	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
 		return "", err
 	}
@@ -566,34 +815,127 @@ func (c Client) getBucketLocation(bucketName string) (string, error) {
 	}
 
 	if location, ok := c.bucketLocCache.Get(bucketName); ok {
+		atomic.AddUint64(&c.bucketLocCache.hits, 1)
 		return location, nil
 	}
-
-	// Initialize a new request.
-	req, err := c.getBucketLocationRequest(bucketName)
-	if err != nil {
+	if err := c.bucketLocCache.failed(bucketName); err != nil {
+		atomic.AddUint64(&c.bucketLocCache.negativeHits, 1)
 		return "", err
 	}
+	atomic.AddUint64(&c.bucketLocCache.misses, 1)
 
-	// Initiate the request.
-	resp, err := c.do(req)
-	defer closeResponse(resp)
-	if err != nil {
-		return "", err
-	}
-	location, err := processBucketLocationResponse(resp, bucketName)
-	if err != nil {
-		return "", err
-	}
-	c.bucketLocCache.Set(bucketName, location)
-	return location, nil
+	// Concurrent lookups of the bucket share one request.
+	return c.bucketLocCache.Lookup(ctx, bucketName, func() (location string, err error) {
+		metadata := requestMetadata{bucketName: bucketName, queryValues: url.Values{"location": {""}}}
+		ctx, span := c.startRequestSpan(ctx, "GET", metadata)
+		defer func() { endSpan(span, err) }()
+
+		// Initialize a new request.
+		req, err := c.getBucketLocationRequest(ctx, bucketName)
+		if err != nil {
+			return "", err
+		}
+		injectTraceContext(ctx, req.Header)
+
+		// Initiate the request.
+		start := time.Now()
+		resp, err := c.doHedged(req)
+		c.observeRequest("GET", metadata, resp, time.Since(start))
+		defer closeResponse(resp)
+		if err != nil {
+			return "", err
+		}
+		return processBucketLocationResponse(resp, bucketName)
+	})
 }
 
 // Set - Will persist a value into cache.
 func (r *bucketLocationCache) Set(bucketName string, location string) {
 	r.Lock()
 	defer r.Unlock()
-	r.items[bucketName] = location
+	entry := bucketLocationEntry{location: location}
+	if r.ttl > 0 {
+		entry.expires = time.Now().Add(r.ttl)
+	}
+	r.items[bucketName] = entry
+}
+
+// Lookup - Looks the location of a bucket up with fetch and caches it,
+// concurrent lookups of the bucket wait for the first one.
+func (r *bucketLocationCache) Lookup(ctx context.Context, bucketName string, fetch func() (string, error)) (string, error) {
+	r.Lock()
+	if l, ok := r.lookups[bucketName]; ok {
+		r.Unlock()
+		select {
+		case <-l.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if l.cancelled {
+			// The context of the first lookup was cancelled, not ours.
+			return r.Lookup(ctx, bucketName, fetch)
+		}
+		return l.location, l.err
+	}
+	l := &bucketLocationLookup{done: make(chan struct{})}
+	r.lookups[bucketName] = l
+	r.Unlock()
+
+	l.location, l.err = r.loadOrFetch(bucketName, fetch)
+	if l.err != nil {
+		l.cancelled = ctx.Err() != nil
+		r.setFailed(bucketName, l.err)
+	}
+	r.Lock()
+	delete(r.lookups, bucketName)
+	r.Unlock()
+	close(l.done)
+	return l.location, l.err
+}
+
+// Delete - Deletes the cached location of a bucket, also from the
+// location store.
+func (r *bucketLocationCache) Delete(bucketName string) {
+	r.Lock()
+	delete(r.items, bucketName)
+	store := r.store
+	r.Unlock()
+	if store != nil {
+		store.Delete(bucketName)
+	}
+}
+
+// Clear - Deletes all cached locations, the location store is kept.
+func (r *bucketLocationCache) Clear() {
+	r.Lock()
+	defer r.Unlock()
+	r.items = make(map[string]bucketLocationEntry)
+}
+
+// SetTTL - Caches locations set from now on for ttl, forever when 0.
+func (r *bucketLocationCache) SetTTL(ttl time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.ttl = ttl
+}
+
+// InvalidateBucketLocation - forgets the cached location of bucketName,
+// it is looked up again by the next request on the bucket.
+func (c *Client) InvalidateBucketLocation(bucketName string) {
+	c.bucketLocCache.Delete(bucketName)
+}
+
+// ClearLocationCache - forgets all cached bucket locations, those of
+// the location store are kept.
+func (c *Client) ClearLocationCache() {
+	c.bucketLocCache.Clear()
+}
+
+// SetBucketLocationTTL - caches bucket locations for ttl, one hour by
+// default, forever when 0. Buckets deleted and recreated in another
+// region are found again after at most ttl.
+func (c *Client) SetBucketLocationTTL(ttl time.Duration) {
+	c.bucketLocCache.SetTTL(ttl)
 }
 
 // processes the getBucketLocation http response from the server.
@@ -663,14 +1005,24 @@ func getDefaultLocation(u url.URL, regionOverride string) (location string) {
 
 // returns true if virtual hosted style requests are to be used.
 func (c *Client) isVirtualHostStyleRequest(url url.URL, bucketName string) bool {
+	return c.isVirtualHostStyleLookup(url, bucketName, c.lookup)
+}
+
+// returns true if virtual hosted style requests are to be used with lookup.
+func (c *Client) isVirtualHostStyleLookup(url url.URL, bucketName string, lookup BucketLookupType) bool {
 	if bucketName == "" {
 		return false
 	}
 
-	if c.lookup == BucketLookupDNS {
+	// The accelerate endpoint only serves virtual host style requests.
+	if c.s3AccelerateEndpoint != "" {
 		return true
 	}
-	if c.lookup == BucketLookupPath {
+
+	if lookup == BucketLookupDNS {
+		return true
+	}
+	if lookup == BucketLookupPath {
 		return false
 	}
 
@@ -701,9 +1053,12 @@ func getS3Endpoint(bucketLocation string) (s3Endpoint string) {
 
 // makeTargetURL make a new target url.
 func (c Client) makeTargetURL(bucketName, objectName, bucketLocation string, isVirtualHostStyle bool, queryValues url.Values) (*url.URL, error) {
-	host := c.endpointURL.Host
-	// For Amazon S3 endpoint, try to fetch location based endpoint.
-	if s3utils.IsAmazonEndpoint(*c.endpointURL) {
+	host := c.endpointHost()
+	if endpoint, ok := c.endpointMap[bucketLocation]; ok && bucketName != "" && c.s3AccelerateEndpoint == "" {
+		// Host registered for the location of the bucket.
+		host = endpoint
+	} else if s3utils.IsAmazonEndpoint(*c.endpointURL) {
+		// For Amazon S3 endpoint, try to fetch location based endpoint.
 		if c.s3AccelerateEndpoint != "" && bucketName != "" {
 			// http://docs.aws.amazon.com/AmazonS3/latest/dev/transfer-acceleration.html
 			// Disable transfer acceleration for non-compliant bucket names.
@@ -764,18 +1119,42 @@ func (c Client) makeTargetURL(bucketName, objectName, bucketLocation string, isV
 	return url.Parse(urlStr)
 }
 
+// ObjectURL - returns the unsigned URL of bucketName/objectName.
+func (c Client) ObjectURL(bucketName, objectName string) (*url.URL, error) {
+	return c.ObjectURLWithContext(context.Background(), bucketName, objectName)
+}
+
+// ObjectURLWithContext - same as ObjectURL, ctx cancels the lookup
+// of the bucket location.
+func (c Client) ObjectURLWithContext(ctx context.Context, bucketName, objectName string) (*url.URL, error) {
+	location, err := c.getBucketLocation(ctx, bucketName)
+	if err != nil {
+		return nil, err
+	}
+	isVirtualHost := c.isVirtualHostStyleRequest(*c.endpointURL, bucketName)
+	return c.makeTargetURL(bucketName, objectName, location, isVirtualHost, nil)
+}
+
 // newRequest - instantiate a new HTTP request for a given method.
-func (c Client) newRequest(method string, metadata requestMetadata) (req *http.Request, err error) {
+func (c Client) newRequest(ctx context.Context, method string, metadata requestMetadata) (req *http.Request, err error) {
 	// If no method is supplied default to 'POST'.
 	if method == "" {
 		method = "POST"
 	}
 
+	// Presigning looks the bucket location up, trace it.
+	start := time.Now()
+	if metadata.presignURL {
+		var span trace.Span
+		ctx, span = c.startPresignSpan(ctx, operationName(method, metadata), metadata.bucketName, metadata.objectName)
+		defer func() { endSpan(span, err) }()
+	}
+
 	location := metadata.bucketLocation
 	if location == "" {
 		if metadata.bucketName != "" {
 			// Gather location only if bucketName is present.
-			location, err = c.getBucketLocation(metadata.bucketName)
+			location, err = c.getBucketLocation(ctx, metadata.bucketName)
 			if err != nil {
 				return nil, err
 			}
@@ -789,7 +1168,11 @@ func (c Client) newRequest(method string, metadata requestMetadata) (req *http.R
 	// We explicitly disallow MakeBucket calls to not use virtual DNS style,
 	// since the resolution may fail.
 	isMakeBucket := (metadata.objectName == "" && method == "PUT" && len(metadata.queryValues) == 0)
-	isVirtualHost := c.isVirtualHostStyleRequest(*c.endpointURL, metadata.bucketName) && !isMakeBucket
+	lookup := c.lookup
+	if metadata.bucketLookup != BucketLookupAuto {
+		lookup = metadata.bucketLookup
+	}
+	isVirtualHost := c.isVirtualHostStyleLookup(*c.endpointURL, metadata.bucketName, lookup) && !isMakeBucket
 
 	// Construct a new target URL.
 	targetURL, err := c.makeTargetURL(metadata.bucketName, metadata.objectName, location,
@@ -833,13 +1216,43 @@ func (c Client) newRequest(method string, metadata requestMetadata) (req *http.R
 		if signerType.IsAnonymous() {
 			return nil, ErrInvalidArgument("Presigned URLs cannot be generated with anonymous credentials.")
 		}
+		// Presigned URLs never outlive the credentials signing them.
+		expires := metadata.expires
+		if expiration := c.credentialsExpiration(); !expiration.IsZero() {
+			remaining := int64(expiration.Sub(c.now()) / time.Second)
+			if remaining < 1 {
+				return nil, ErrInvalidArgument("Presigned URLs cannot be generated with expired credentials.")
+			}
+			if expires > remaining {
+				expires = remaining
+			}
+		}
+		// Headers set before presigning are part of the signature,
+		// the caller has to send them along with the request.
+		for k, v := range metadata.customHeader {
+			req.Header.Set(k, v[0])
+		}
 		if signerType.IsV2() {
 			// Presign URL with signature v2.
-			req = s3signer.PreSignV2(*req, accessKeyID, secretAccessKey, metadata.expires, isVirtualHost)
+			// Expiry is absolute with signature v2, shift it to the server clock.
+			// The session token is signed as header and sent as query parameter.
+			if sessionToken != "" {
+				req.Header.Set("X-Amz-Security-Token", sessionToken)
+			}
+			req = s3signer.PreSignV2(*req, accessKeyID, secretAccessKey, expires+int64(c.clockSkew()/time.Second), isVirtualHost)
+			if sessionToken != "" {
+				req.URL.RawQuery += "&X-Amz-Security-Token=" + url.QueryEscape(sessionToken)
+			}
 		} else if signerType.IsV4() {
+			// Signed payloads need the SHA-256 of the payload, signed as header.
+			if c.payloadSigning == PayloadSigningSigned && method != "GET" && method != "HEAD" &&
+				req.Header.Get("X-Amz-Content-Sha256") == "" {
+				return nil, ErrInvalidArgument("Presigned URLs with signed payloads need the SHA-256 of the payload.")
+			}
 			// Presign URL with signature v4.
-			req = s3signer.PreSignV4(*req, accessKeyID, secretAccessKey, sessionToken, location, metadata.expires)
+			req = preSignV4At(*req, accessKeyID, secretAccessKey, sessionToken, location, expires, c.now())
 		}
+		c.observePresign(operationName(method, metadata), time.Since(start))
 		return req, nil
 	}
 
@@ -882,22 +1295,23 @@ func (c Client) newRequest(method string, metadata requestMetadata) (req *http.R
 	case signerType.IsV2():
 		// Add signature version '2' authorization header.
 		req = s3signer.SignV2(*req, accessKeyID, secretAccessKey, isVirtualHost)
-	case metadata.objectName != "" && method == "PUT" && metadata.customHeader.Get("X-Amz-Copy-Source") == "" && !c.secure:
+	case metadata.objectName != "" && method == "PUT" && metadata.customHeader.Get("X-Amz-Copy-Source") == "" && !c.secure &&
+		c.payloadSigning == PayloadSigningAuto:
 		// Streaming signature is used by default for a PUT object request. Additionally we also
 		// look if the initialized client is secure, if yes then we don't need to perform
 		// streaming signature.
 		req = s3signer.StreamingSignV4(req, accessKeyID,
-			secretAccessKey, sessionToken, location, metadata.contentLength, time.Now().UTC())
+			secretAccessKey, sessionToken, location, metadata.contentLength, c.now())
 	default:
 		// Set sha256 sum for signature calculation only with signature version '4'.
-		shaHeader := unsignedPayload
-		if metadata.contentSHA256Hex != "" {
-			shaHeader = metadata.contentSHA256Hex
+		shaHeader, err := c.payloadSHA256(metadata)
+		if err != nil {
+			return nil, err
 		}
 		req.Header.Set("X-Amz-Content-Sha256", shaHeader)
 
 		// Add signature version '4' authorization header.
-		req = s3signer.SignV4(*req, accessKeyID, secretAccessKey, sessionToken, location)
+		req = signV4At(*req, accessKeyID, secretAccessKey, sessionToken, location, c.now())
 	}
 
 	// Return request.
@@ -905,7 +1319,90 @@ func (c Client) newRequest(method string, metadata requestMetadata) (req *http.R
 }
 
 
-func (c Client) GenUploadPartSignedUrl(uploadID string, bucketName string, objectName string, partNumber int, size int64, expires time.Duration, bucketLocation string) (string, error){
+func (c Client) GenUploadPartSignedUrl(uploadID string, bucketName string, objectName string, partNumber int, size int64, expires time.Duration, bucketLocation string) (string, error) {
+	return c.GenUploadPartSignedUrlWithContext(context.Background(), uploadID, bucketName, objectName, partNumber, size, expires, bucketLocation)
+}
+
+// GenUploadPartSignedUrlWithContext - same as GenUploadPartSignedUrl, ctx cancels the lookup
+// of the bucket location.
+func (c Client) GenUploadPartSignedUrlWithContext(ctx context.Context, uploadID string, bucketName string, objectName string, partNumber int, size int64, expires time.Duration, bucketLocation string) (string, error) {
+	return c.genUploadPartSignedUrl(ctx, uploadID, bucketName, objectName, partNumber, size, expires, bucketLocation, make(http.Header))
+}
+
+// GenUploadPartSignedUrlSSEC - same as GenUploadPartSignedUrl for a
+// multipart upload encrypted with a customer provided key. The returned
+// headers are signed and must be sent with the part PUT.
+func (c Client) GenUploadPartSignedUrlSSEC(uploadID string, bucketName string, objectName string, partNumber int, size int64, expires time.Duration, bucketLocation string, key SSECustomerKey) (string, http.Header, error) {
+	return c.GenUploadPartSignedUrlSSECWithContext(context.Background(), uploadID, bucketName, objectName, partNumber, size, expires, bucketLocation, key)
+}
+
+// GenUploadPartSignedUrlSSECWithContext - same as GenUploadPartSignedUrlSSEC, ctx cancels the lookup
+// of the bucket location.
+func (c Client) GenUploadPartSignedUrlSSECWithContext(ctx context.Context, uploadID string, bucketName string, objectName string, partNumber int, size int64, expires time.Duration, bucketLocation string, key SSECustomerKey) (string, http.Header, error) {
+	customHeader, err := c.SSECustomerHeaders(uploadID, key)
+	if err != nil {
+		return "", nil, err
+	}
+	signedUrl, err := c.genUploadPartSignedUrl(ctx, uploadID, bucketName, objectName, partNumber, size, expires, bucketLocation, customHeader)
+	if err != nil {
+		return "", nil, err
+	}
+	return signedUrl, customHeader, nil
+}
+
+// GenUploadPartSignedUrlMD5 - same as GenUploadPartSignedUrl with the
+// base64 encoded md5sum of the part in the signature, for buckets
+// enforcing Content-MD5. The returned headers are signed and must be
+// sent with the part PUT.
+func (c Client) GenUploadPartSignedUrlMD5(uploadID string, bucketName string, objectName string, partNumber int, size int64, expires time.Duration, bucketLocation string, md5Base64 string) (string, http.Header, error) {
+	return c.GenUploadPartSignedUrlMD5WithContext(context.Background(), uploadID, bucketName, objectName, partNumber, size, expires, bucketLocation, md5Base64)
+}
+
+// GenUploadPartSignedUrlMD5WithContext - same as GenUploadPartSignedUrlMD5, ctx cancels the lookup
+// of the bucket location.
+func (c Client) GenUploadPartSignedUrlMD5WithContext(ctx context.Context, uploadID string, bucketName string, objectName string, partNumber int, size int64, expires time.Duration, bucketLocation string, md5Base64 string) (string, http.Header, error) {
+	if md5Sum, err := base64.StdEncoding.DecodeString(md5Base64); err != nil || len(md5Sum) != md5.Size {
+		return "", nil, ErrInvalidArgument("Content-MD5 must be a base64 encoded md5sum.")
+	}
+	customHeader := make(http.Header)
+	customHeader.Set("Content-Md5", md5Base64)
+	signedUrl, err := c.genUploadPartSignedUrl(ctx, uploadID, bucketName, objectName, partNumber, size, expires, bucketLocation, customHeader)
+	if err != nil {
+		return "", nil, err
+	}
+	return signedUrl, customHeader, nil
+}
+
+// GenUploadPartSignedUrlHeaders - same as GenUploadPartSignedUrl with
+// the headers in header in the signature, such as Content-Type, SSE-C
+// keys or checksums. The returned headers are signed and must be sent
+// with the part PUT. Headers set by browsers or by the signature itself
+// cannot be signed.
+func (c Client) GenUploadPartSignedUrlHeaders(uploadID string, bucketName string, objectName string, partNumber int, size int64, expires time.Duration, bucketLocation string, header http.Header) (string, http.Header, error) {
+	return c.GenUploadPartSignedUrlHeadersWithContext(context.Background(), uploadID, bucketName, objectName, partNumber, size, expires, bucketLocation, header)
+}
+
+// GenUploadPartSignedUrlHeadersWithContext - same as GenUploadPartSignedUrlHeaders, ctx cancels the lookup
+// of the bucket location.
+func (c Client) GenUploadPartSignedUrlHeadersWithContext(ctx context.Context, uploadID string, bucketName string, objectName string, partNumber int, size int64, expires time.Duration, bucketLocation string, header http.Header) (string, http.Header, error) {
+	customHeader := make(http.Header)
+	for k, v := range header {
+		if unsignableHeaders[http.CanonicalHeaderKey(k)] {
+			return "", nil, ErrInvalidArgument("Header ‘" + k + "’ cannot be signed.")
+		}
+		if len(v) != 1 {
+			return "", nil, ErrInvalidArgument("Header ‘" + k + "’ must have a single value to be signed.")
+		}
+		customHeader.Set(k, v[0])
+	}
+	signedUrl, err := c.genUploadPartSignedUrl(ctx, uploadID, bucketName, objectName, partNumber, size, expires, bucketLocation, customHeader)
+	if err != nil {
+		return "", nil, err
+	}
+	return signedUrl, customHeader, nil
+}
+
+func (c Client) genUploadPartSignedUrl(ctx context.Context, uploadID string, bucketName string, objectName string, partNumber int, size int64, expires time.Duration, bucketLocation string, customHeader http.Header) (string, error){
 	signedUrl := ""
 
 	// Input validation.
@@ -915,17 +1412,17 @@ func (c Client) GenUploadPartSignedUrl(uploadID string, bucketName string, objec
 	if err := s3utils.CheckValidObjectName(objectName); err != nil {
 		return signedUrl, err
 	}
-	if size > maxPartSize {
-		return signedUrl, errors.New("size is illegal")
+	if size > MaxPartSize {
+		return signedUrl, errInvalidArgument(ErrInvalidPartSize)
 	}
 	if size <= -1 {
-		return signedUrl, errors.New("size is illegal")
+		return signedUrl, errInvalidArgument(ErrInvalidPartSize)
 	}
 	if partNumber <= 0 {
-		return signedUrl, errors.New("partNumber is illegal")
+		return signedUrl, errInvalidArgument(ErrInvalidPartNumber)
 	}
 	if uploadID == "" {
-		return signedUrl, errors.New("uploadID is illegal")
+		return signedUrl, errInvalidArgument(ErrInvalidUploadID)
 	}
 
 	// Get resources properly escaped and lined up before using them in http request.
@@ -935,9 +1432,6 @@ func (c Client) GenUploadPartSignedUrl(uploadID string, bucketName string, objec
 	// Set upload id.
 	urlValues.Set("uploadId", uploadID)
 
-	// Set encryption headers, if any.
-	customHeader := make(http.Header)
-
 	reqMetadata := requestMetadata{
 		presignURL:		  true,
 		bucketName:       bucketName,
@@ -952,7 +1446,14 @@ func (c Client) GenUploadPartSignedUrl(uploadID string, bucketName string, objec
 		bucketLocation:		bucketLocation,
 	}
 
-	req, err := c.newRequest("PUT", reqMetadata)
+	// A location and lookup set for the call apply as well.
+	opts := requestOptionsFrom(ctx)
+	if reqMetadata.bucketLocation == "" {
+		reqMetadata.bucketLocation = opts.location
+	}
+	reqMetadata.bucketLookup = opts.lookup
+
+	req, err := c.newRequest(ctx, "PUT", reqMetadata)
 	if err != nil {
 		log.Println("newRequest failed:", err.Error())
 		return signedUrl, err
@@ -969,7 +1470,19 @@ func (c Client) GenUploadPartSignedUrl(uploadID string, bucketName string, objec
 func (c Client) executeMethod(ctx context.Context, method string, metadata requestMetadata) (res *http.Response, err error) {
 	var isRetryable bool     // Indicates if request can be retried.
 	var bodySeeker io.Seeker // Extracted seeker from io.Reader.
-	var reqRetry = MaxRetry  // Indicates how many times we can retry the request
+
+	// A location set for the call skips the location lookup, a lookup
+	// and retry policy set for the call override those of the client.
+	callOpts := requestOptionsFrom(ctx)
+	if metadata.bucketLocation == "" {
+		metadata.bucketLocation = callOpts.location
+	}
+	if metadata.bucketLookup == BucketLookupAuto {
+		metadata.bucketLookup = callOpts.lookup
+	}
+	metadata.retryPolicy = callOpts.retryPolicy.override(metadata.retryPolicy)
+	retryPolicy := c.retryPolicyFor(metadata)
+	var reqRetry = retryPolicy.MaxAttempts // Indicates how many times we can retry the request
 
 	if metadata.contentBody != nil {
 		// Check if body is seekable then it is retryable.
@@ -992,20 +1505,58 @@ func (c Client) executeMethod(ctx context.Context, method string, metadata reque
 		}
 	}
 
-	// Create a done channel to control 'newRetryTimer' go routine.
-	doneCh := make(chan struct{}, 1)
+	// One span covers all attempts of the request.
+	ctx, span := c.startRequestSpan(ctx, method, metadata)
+	var attempts int
+	defer func() {
+		span.SetAttributes(attrAttempts.Int(attempts))
+		endRequestSpan(span, res, err)
+	}()
 
-	// Indicate to our routine to exit cleanly upon return.
-	defer close(doneCh)
+	// Wait before the next attempt, the Retry-After of a throttled
+	// response replaces the backoff of the retry policy.
+	var wait, retryAfter time.Duration
+	var failedOver bool
+	backoff := retryPolicy.Backoff
+	if backoff == nil {
+		backoff = ExponentialBackoff{Jitter: MaxJitter}
+	}
 
-	// Blank indentifier is kept here on purpose since 'range' without
-	// blank identifiers is only supported since go1.4
-	// https://golang.org/doc/go1.4#forrange.
-	for range c.newRetryTimer(reqRetry, DefaultRetryUnit, DefaultRetryCap, MaxJitter, doneCh) {
+	// Error of the last attempt, reported with the wait before the
+	// next one.
+	var lastErr error
+	var failed bool
+	retrying := func(attempt int, err error) {
+		lastErr, failed = err, true
+	}
+
+	for attempt := 1; attempt <= reqRetry; attempt++ {
+		attempts = attempt
 		// Retry executes the following function body if request has an
 		// error until maxRetries have been exhausted, retry attempts are
 		// performed after waiting for a given period of time in a
 		// binomial fashion.
+		if attempt > 1 {
+			wait = backoff.Wait(attempt-2, wait, retryPolicy.Unit, retryPolicy.Cap)
+			if retryAfter > 0 {
+				wait, retryAfter = retryAfter, 0
+			}
+			if failedOver {
+				// The next endpoint did not fail yet.
+				wait, failedOver = 0, false
+			}
+			// Give up rather than wake up after the deadline.
+			failed = false
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+				c.notifyRetry(method, metadata, attempt-1, lastErr, wait, false)
+				return nil, RetryDeadlineError{Attempts: attempt - 1, Wait: wait, Err: lastErr}
+			}
+			c.notifyRetry(method, metadata, attempt-1, lastErr, wait, true)
+			addRetryEvent(span, attempt-1, lastErr, wait)
+			if err = sleepContext(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
 		if isRetryable {
 			// Seek back to beginning for each attempt.
 			if _, err = bodySeeker.Seek(0, 0); err != nil {
@@ -1016,32 +1567,50 @@ func (c Client) executeMethod(ctx context.Context, method string, metadata reque
 
 		// Instantiate a new request.
 		var req *http.Request
-		req, err = c.newRequest(method, metadata)
+		endpoint := c.failover.active()
+		req, err = c.newRequest(ctx, method, metadata)
 		if err != nil {
 			errResponse := ToErrorResponse(err)
 			if isS3CodeRetryable(errResponse.Code) {
+				retrying(attempt, err)
 				continue // Retry.
 			}
 			return nil, err
 		}
 
-		// Add context to request
+		// Add context to request, the trace context is not signed.
 		req = req.WithContext(ctx)
+		injectTraceContext(ctx, req.Header)
 
 		// Initiate the request.
-		res, err = c.do(req)
+		start := time.Now()
+		if metadata.hedge || method == "HEAD" {
+			res, err = c.doHedged(req)
+		} else {
+			res, err = c.do(req)
+		}
+		c.observeRequest(method, metadata, res, time.Since(start))
+		recordResponse(ctx, res)
 		if err != nil {
 			// For supported http requests errors verify.
 			if isHTTPReqErrorRetryable(err) {
+				if ctx.Err() == nil {
+					failedOver = c.failover.failed(endpoint, err)
+				}
+				retrying(attempt, err)
 				continue // Retry.
 			}
 			// For other errors, return here no need to retry.
 			return nil, err
 		}
 
+		c.failover.succeeded(endpoint)
+
 		// For any known successful http status, return quickly.
+		// Copies may fail after answering 200, those are retried like
+		// any other error.
 		for _, httpStatus := range successStatus {
-			if httpStatus == res.StatusCode {
+			if httpStatus == res.StatusCode && !isCopyError(method, metadata, res) {
 				return res, nil
 			}
 		}
@@ -1076,25 +1645,53 @@ func (c Client) executeMethod(ctx context.Context, method string, metadata reque
 				if metadata.bucketName != "" && errResponse.Region != "" {
 					// Gather Cached location only if bucketName is present.
 					if _, cachedLocationError := c.bucketLocCache.Get(metadata.bucketName); cachedLocationError != false {
-						c.bucketLocCache.Set(metadata.bucketName, errResponse.Region)
+						c.bucketLocCache.Save(metadata.bucketName, errResponse.Region)
+						retrying(attempt, errResponse)
 						continue // Retry.
 					}
 				}
 			}
 		}
 
+		// A deleted bucket may be recreated in another region.
+		if errResponse.Code == "NoSuchBucket" && metadata.bucketName != "" {
+			c.bucketLocCache.Delete(metadata.bucketName)
+		}
+
+		// The clock of the client is off, sign with the clock of
+		// the server from now on.
+		if errResponse.Code == "RequestTimeTooSkewed" && c.adjustClockSkew(res) {
+			retrying(attempt, errResponse)
+			continue // Retry.
+		}
+
+		// A throttled server tells when to come back, fail rather than
+		// wait longer than the cap of the retry policy.
+		if delay, ok := retryAfterDelay(res); ok {
+			if delay > retryPolicy.Cap {
+				break
+			}
+			retryAfter = delay
+		}
+
 		// Verify if error response code is retryable.
 		if isS3CodeRetryable(errResponse.Code) {
+			retrying(attempt, errResponse)
 			continue // Retry.
 		}
 
 		// Verify if http status code is retryable.
 		if isHTTPStatusRetryable(res.StatusCode) {
+			retrying(attempt, errResponse)
 			continue // Retry.
 		}
 
 		// For all other cases break out of the retry loop.
 		break
 	}
+	if failed {
+		// The last attempt failed.
+		c.notifyRetry(method, metadata, reqRetry, lastErr, 0, false)
+	}
 	return res, err
 }
@@ -0,0 +1,132 @@
+package minio_ext
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff - computes the wait between two attempts of a request.
+// Implementations must be safe for concurrent use.
+type Backoff interface {
+	// Wait returns the wait after attempt, counted from 0, previous is
+	// the wait after the attempt before, 0 after the first one. unit and
+	// cap are those of the retry policy.
+	Wait(attempt int, previous, unit, cap time.Duration) time.Duration
+}
+
+// ExponentialBackoff - doubles the wait after every attempt, up to cap,
+// and subtracts a random part of up to Jitter, between NoJitter and
+// MaxJitter, of it, see
+// https://www.awsarchitectureblog.com/2015/03/backoff.html
+type ExponentialBackoff struct {
+	Jitter float64
+}
+
+// Wait - implements Backoff.
+func (b ExponentialBackoff) Wait(attempt int, previous, unit, cap time.Duration) time.Duration {
+	// normalize jitter to the range [0, 1.0]
+	jitter := b.Jitter
+	if jitter < NoJitter {
+		jitter = NoJitter
+	}
+	if jitter > MaxJitter {
+		jitter = MaxJitter
+	}
+
+	//sleep = random_between(0, min(cap, base * 2 ** attempt))
+	sleep := cap
+	if attempt < 32 && unit < cap>>uint(attempt) {
+		sleep = unit << uint(attempt)
+	}
+	if jitter != NoJitter {
+		sleep -= time.Duration(rand.Float64() * float64(sleep) * jitter)
+	}
+	return sleep
+}
+
+// DecorrelatedJitterBackoff - waits a random duration between unit and
+// three times the previous wait, up to cap. Spreads clients retrying
+// together better than ExponentialBackoff.
+type DecorrelatedJitterBackoff struct{}
+
+// Wait - implements Backoff.
+func (DecorrelatedJitterBackoff) Wait(attempt int, previous, unit, cap time.Duration) time.Duration {
+	if previous < unit {
+		previous = unit
+	}
+	//sleep = min(cap, random_between(base, sleep * 3))
+	upper := previous * 3
+	if upper > cap || upper < previous {
+		upper = cap
+	}
+	sleep := unit
+	if upper > unit {
+		sleep += time.Duration(rand.Int63n(int64(upper - unit)))
+	}
+	if sleep > cap {
+		sleep = cap
+	}
+	return sleep
+}
+
+// ConstantBackoff - waits unit after every attempt.
+type ConstantBackoff struct{}
+
+// Wait - implements Backoff.
+func (ConstantBackoff) Wait(attempt int, previous, unit, cap time.Duration) time.Duration {
+	if unit > cap {
+		return cap
+	}
+	return unit
+}
+
+// RetryEvent - a failed attempt of a request, passed to the retry hook.
+type RetryEvent struct {
+	Method     string
+	BucketName string
+	ObjectName string
+
+	// Attempt which failed, starting from 1, its error and the S3 error
+	// code, empty for network errors.
+	Attempt int
+	Err     error
+	Code    string
+
+	// Whether the request is attempted again after Wait, false after
+	// the last attempt or when the deadline of the request expires
+	// first.
+	Retry bool
+	Wait  time.Duration
+}
+
+// SetRetryHook - calls hook with every failed attempt of a request
+// which may be retried, for logging or metrics. hook is called from the
+// goroutine of the request and must not block.
+func (c *Client) SetRetryHook(hook func(RetryEvent)) {
+	c.retryHook = hook
+}
+
+// notifyRetry - counts the failed attempt of a request with metadata
+// and passes it to the retry hook, if any. wait is the wait before the
+// next attempt, retry whether there is one.
+func (c Client) notifyRetry(method string, metadata requestMetadata, attempt int, err error, wait time.Duration, retry bool) {
+	event := RetryEvent{
+		Method:     method,
+		BucketName: metadata.bucketName,
+		ObjectName: metadata.objectName,
+		Attempt:    attempt,
+		Err:        err,
+		Code:       ToErrorResponse(err).Code,
+		Retry:      retry,
+	}
+	if retry {
+		event.Wait = wait
+	}
+	c.retryStats.record(event)
+	if retry {
+		c.observeRetry(method, metadata, event.Code)
+	}
+	if c.retryHook != nil {
+		c.retryHook(event)
+	}
+}
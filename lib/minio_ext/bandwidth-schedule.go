@@ -0,0 +1,119 @@
+package minio_ext
+
+import (
+	"context"
+	"time"
+)
+
+// BandwidthProfile - rate limit in effect during a time of day window.
+// Start and End are offsets since midnight, a window with End before
+// Start wraps around midnight.
+type BandwidthProfile struct {
+	Start time.Duration
+	End   time.Duration
+
+	// Limit in bytes per second, zero or less is unlimited.
+	BytesPerSec int64
+	Burst       int64
+}
+
+// contains - reports whether offset since midnight is in the window.
+func (p BandwidthProfile) contains(offset time.Duration) bool {
+	if p.Start <= p.End {
+		return offset >= p.Start && offset < p.End
+	}
+	return offset >= p.Start || offset < p.End
+}
+
+// BandwidthSchedule - time of day bandwidth profiles, e.g. 10 MB/s
+// during business hours and unlimited at night. The first matching
+// profile wins, outside all profiles the default limit applies.
+type BandwidthSchedule struct {
+	Profiles []BandwidthProfile
+
+	// Limit outside all profiles, zero or less is unlimited.
+	DefaultBytesPerSec int64
+	DefaultBurst       int64
+
+	// Time zone of the profiles, defaults to local time.
+	Location *time.Location
+}
+
+// location - returns the time zone of the schedule.
+func (s BandwidthSchedule) location() *time.Location {
+	if s.Location == nil {
+		return time.Local
+	}
+	return s.Location
+}
+
+// midnight - returns the start of the day of t in the schedule time zone.
+func (s BandwidthSchedule) midnight(t time.Time) time.Time {
+	t = t.In(s.location())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// limitAt - returns the limit in effect at t.
+func (s BandwidthSchedule) limitAt(t time.Time) (bytesPerSec, burst int64) {
+	offset := t.Sub(s.midnight(t))
+	for _, profile := range s.Profiles {
+		if profile.contains(offset) {
+			return profile.BytesPerSec, profile.Burst
+		}
+	}
+	return s.DefaultBytesPerSec, s.DefaultBurst
+}
+
+// nextChange - returns the next profile boundary after t.
+func (s BandwidthSchedule) nextChange(t time.Time) time.Time {
+	midnight := s.midnight(t)
+	next := midnight.AddDate(0, 0, 1)
+	for _, day := range []time.Time{midnight, midnight.AddDate(0, 0, 1)} {
+		for _, profile := range s.Profiles {
+			for _, offset := range []time.Duration{profile.Start, profile.End} {
+				if boundary := day.Add(offset); boundary.After(t) && boundary.Before(next) {
+					next = boundary
+				}
+			}
+		}
+	}
+	return next
+}
+
+// validate - checks the profile windows.
+func (s BandwidthSchedule) validate() error {
+	for _, profile := range s.Profiles {
+		if profile.Start < 0 || profile.Start >= 24*time.Hour || profile.End < 0 || profile.End > 24*time.Hour {
+			return ErrInvalidArgument("Bandwidth profile window must be within a day.")
+		}
+		if profile.Start == profile.End {
+			return ErrInvalidArgument("Bandwidth profile window cannot be empty.")
+		}
+	}
+	return nil
+}
+
+// ApplySchedule - sets the limit of l according to schedule and keeps
+// it updated at every profile boundary until ctx is done. All sessions
+// sharing l follow the schedule.
+func (l *RateLimiter) ApplySchedule(ctx context.Context, schedule BandwidthSchedule) error {
+	if err := schedule.validate(); err != nil {
+		return err
+	}
+	l.SetLimit(schedule.limitAt(time.Now()))
+
+	go func() {
+		for {
+			now := time.Now()
+			timer := time.NewTimer(schedule.nextChange(now).Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case t := <-timer.C:
+				l.SetLimit(schedule.limitAt(t))
+			}
+		}
+	}()
+	return nil
+}
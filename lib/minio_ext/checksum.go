@@ -0,0 +1,102 @@
+package minio_ext
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"net/http"
+)
+
+// Checksum algorithms of S3 object and part checksums.
+const (
+	ChecksumCRC32  = "CRC32"
+	ChecksumCRC32C = "CRC32C"
+	ChecksumSHA1   = "SHA1"
+	ChecksumSHA256 = "SHA256"
+)
+
+// RangeChecksum - a range of a download following a part of the
+// object, with the checksum the server reported for the part.
+type RangeChecksum struct {
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+
+	// Algorithm and base64 encoded checksum, empty when the part has
+	// no checksum.
+	Algorithm string `json:"algorithm,omitempty"`
+	Checksum  string `json:"checksum,omitempty"`
+
+	// Base64 encoded MD5 of the downloaded range, used to verify the
+	// ETag of the object.
+	MD5 string `json:"md5,omitempty"`
+}
+
+// pick - returns the strongest checksum present, empty strings when
+// there is none.
+func (c objectChecksums) pick() (string, string) {
+	switch {
+	case c.ChecksumSHA256 != "":
+		return ChecksumSHA256, c.ChecksumSHA256
+	case c.ChecksumSHA1 != "":
+		return ChecksumSHA1, c.ChecksumSHA1
+	case c.ChecksumCRC32C != "":
+		return ChecksumCRC32C, c.ChecksumCRC32C
+	case c.ChecksumCRC32 != "":
+		return ChecksumCRC32, c.ChecksumCRC32
+	}
+	return "", ""
+}
+
+// newChecksumHash - returns a hash computing checksums of algorithm,
+// nil for unknown algorithms.
+func newChecksumHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case ChecksumSHA256:
+		return sha256.New()
+	case ChecksumSHA1:
+		return sha1.New()
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case ChecksumCRC32:
+		return crc32.NewIEEE()
+	}
+	return nil
+}
+
+// checksumString - returns the sum of h encoded like S3 checksums.
+func checksumString(h hash.Hash) string {
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// ErrChecksumMismatch - downloaded data does not match its checksum.
+func ErrChecksumMismatch(bucketName, objectName, message string) error {
+	return ErrorResponse{
+		Code:       "ChecksumMismatch",
+		Message:    message,
+		BucketName: bucketName,
+		Key:        objectName,
+	}
+}
+
+// ErrChunkChecksumMismatch - a part relayed to the server does not
+// match the checksum declared by its sender, the part was not sent.
+func ErrChunkChecksumMismatch(bucketName, objectName string, partNumber int, algorithm, declared, computed string) error {
+	return ErrorResponse{
+		StatusCode: http.StatusBadRequest,
+		Code:       "ChunkChecksumMismatch",
+		Message:    fmt.Sprintf("Part %d has the %s checksum %s, declared was %s.", partNumber, algorithm, computed, declared),
+		BucketName: bucketName,
+		Key:        objectName,
+	}
+}
+
+// IsChunkChecksumMismatch - reports whether err means that a part did
+// not match its declared checksum and has to be sent again.
+func IsChunkChecksumMismatch(err error) bool {
+	var e ErrorResponse
+	return errors.As(err, &e) && e.Code == "ChunkChecksumMismatch"
+}
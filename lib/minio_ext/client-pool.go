@@ -0,0 +1,267 @@
+package minio_ext
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// PoolKey - identifies the clients of a ClientPool.
+type PoolKey struct {
+	Endpoint    string
+	AccessKeyID string
+	Region      string
+}
+
+// PoolOptions - options for NewClientPool.
+type PoolOptions struct {
+	// Creates the client of a key, required. Secret keys are looked up
+	// by the caller, they are not part of the key.
+	New func(key PoolKey) (*Client, error)
+
+	// Interval of the health checks of the clients, disabled when 0.
+	// Clients of endpoints failing the check are not handed out until
+	// they pass it again.
+	HealthCheckInterval time.Duration
+
+	// Clients not used for IdleTimeout are evicted, never when 0.
+	IdleTimeout time.Duration
+
+	// Most clients kept, the least recently used one is evicted beyond,
+	// no limit when 0.
+	MaxClients int
+}
+
+// pooledClient - a client of a ClientPool.
+type pooledClient struct {
+	client   *Client
+	lastUsed time.Time
+	offline  bool
+}
+
+// ClientPool - creates clients lazily and caches them per endpoint,
+// access key and region, for services uploading to many MinIO
+// deployments. Safe for concurrent use.
+type ClientPool struct {
+	opts PoolOptions
+
+	// mutex protects clients and closed.
+	mutex   sync.Mutex
+	clients map[PoolKey]*pooledClient
+	closed  bool
+
+	// done stops the maintenance of the pool.
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// ErrEndpointOffline - the endpoint of a pooled client failed its last
+// health check.
+func ErrEndpointOffline(endpoint string) error {
+	return ErrorResponse{
+		StatusCode: http.StatusServiceUnavailable,
+		Code:       "EndpointOffline",
+		Message:    "Endpoint ‘" + endpoint + "’ failed its last health check.",
+	}
+}
+
+// NewClientPool - returns an empty pool creating its clients with
+// opts.New. Health checks and eviction of idle clients run in the
+// background until Close.
+func NewClientPool(opts PoolOptions) (*ClientPool, error) {
+	if opts.New == nil {
+		return nil, ErrInvalidArgument("Client pool needs a function creating its clients.")
+	}
+	p := &ClientPool{
+		opts:    opts,
+		clients: make(map[PoolKey]*pooledClient),
+		done:    make(chan struct{}),
+	}
+	interval := opts.HealthCheckInterval
+	if interval <= 0 {
+		interval = opts.IdleTimeout / 2
+	}
+	if interval > 0 {
+		p.wg.Add(1)
+		go p.maintain(interval)
+	}
+	return p, nil
+}
+
+// Get - returns the client of key, creating it on first use. Fails with
+// ErrEndpointOffline while the endpoint fails its health checks.
+func (p *ClientPool) Get(key PoolKey) (*Client, error) {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil, ErrInvalidArgument("Client pool is closed.")
+	}
+	if pc, ok := p.clients[key]; ok {
+		pc.lastUsed = time.Now()
+		offline := pc.offline
+		p.mutex.Unlock()
+		if offline {
+			return nil, ErrEndpointOffline(key.Endpoint)
+		}
+		return pc.client, nil
+	}
+	p.mutex.Unlock()
+
+	// Created without the lock, New may look up secrets.
+	client, err := p.opts.New(key)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		// Closed while the client was created.
+		closeIdleConnections(client)
+		return nil, ErrInvalidArgument("Client pool is closed.")
+	}
+	if pc, ok := p.clients[key]; ok {
+		// Created concurrently, keep the first client.
+		closeIdleConnections(client)
+		pc.lastUsed = time.Now()
+		if pc.offline {
+			return nil, ErrEndpointOffline(key.Endpoint)
+		}
+		return pc.client, nil
+	}
+	p.clients[key] = &pooledClient{client: client, lastUsed: time.Now()}
+	if p.opts.MaxClients > 0 && len(p.clients) > p.opts.MaxClients {
+		p.evictOldest()
+	}
+	return client, nil
+}
+
+// Remove - evicts the client of key, for example after its credentials
+// were rotated.
+func (p *ClientPool) Remove(key PoolKey) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if pc, ok := p.clients[key]; ok {
+		delete(p.clients, key)
+		closeIdleConnections(pc.client)
+	}
+}
+
+// Close - stops the health checks and evicts all clients. Clients
+// already handed out keep working.
+func (p *ClientPool) Close() {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return
+	}
+	p.closed = true
+	for key, pc := range p.clients {
+		delete(p.clients, key)
+		closeIdleConnections(pc.client)
+	}
+	p.mutex.Unlock()
+
+	close(p.done)
+	p.wg.Wait()
+}
+
+// evictOldest - evicts the least recently used client, caller must hold
+// the mutex.
+func (p *ClientPool) evictOldest() {
+	var oldest PoolKey
+	var oldestUsed time.Time
+	for key, pc := range p.clients {
+		if oldestUsed.IsZero() || pc.lastUsed.Before(oldestUsed) {
+			oldest, oldestUsed = key, pc.lastUsed
+		}
+	}
+	closeIdleConnections(p.clients[oldest].client)
+	delete(p.clients, oldest)
+}
+
+// maintain - evicts idle clients and checks the health of the others
+// every interval until Close.
+func (p *ClientPool) maintain(interval time.Duration) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		p.mutex.Lock()
+		clients := make(map[PoolKey]*Client, len(p.clients))
+		for key, pc := range p.clients {
+			if p.opts.IdleTimeout > 0 && time.Since(pc.lastUsed) >= p.opts.IdleTimeout {
+				delete(p.clients, key)
+				closeIdleConnections(pc.client)
+				continue
+			}
+			clients[key] = pc.client
+		}
+		p.mutex.Unlock()
+
+		if p.opts.HealthCheckInterval > 0 {
+			p.checkHealth(clients, interval)
+		}
+	}
+}
+
+// checkHealth - checks the health of clients in parallel, each check
+// taking at most timeout.
+func (p *ClientPool) checkHealth(clients map[PoolKey]*Client, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for key, client := range clients {
+		wg.Add(1)
+		go func(key PoolKey, client *Client) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			err := client.HealthCheck(ctx)
+
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			if pc, ok := p.clients[key]; ok && pc.client == client {
+				pc.offline = err != nil
+			}
+		}(key, client)
+	}
+	wg.Wait()
+}
+
+// HealthCheck - probes the liveness endpoint of the MinIO server, fails
+// when it cannot be reached or answers with a server error. Endpoints
+// without the liveness endpoint pass as long as they answer.
+func (c *Client) HealthCheck(ctx context.Context) error {
+	u := *c.endpointURL
+	u.Path = "/minio/health/live"
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return ErrEndpointOffline(c.endpointURL.Host)
+	}
+	return nil
+}
+
+// closeIdleConnections - drops the idle connections of the transport of
+// c, the connections of evicted clients are not reused.
+func closeIdleConnections(c *Client) {
+	if closer, ok := c.httpClient.Transport.(idleConnectionsCloser); ok {
+		closer.CloseIdleConnections()
+	}
+}
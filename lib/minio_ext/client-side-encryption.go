@@ -0,0 +1,496 @@
+package minio_ext
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Client side encryption metadata headers, stored with the object so
+// that only holders of the master key can read its data.
+const (
+	amzMetaE2EAlgorithm   = "X-Amz-Meta-E2e-Algorithm"
+	amzMetaE2EKey         = "X-Amz-Meta-E2e-Key"
+	amzMetaE2ENoncePrefix = "X-Amz-Meta-E2e-Nonce-Prefix"
+	amzMetaE2EChunkSize   = "X-Amz-Meta-E2e-Chunk-Size"
+
+	e2eAlgorithm = "AES256-GCM-CHUNKED"
+
+	// e2eChunkSize - plaintext bytes sealed at once, parts of encrypted
+	// uploads start at a chunk.
+	e2eChunkSize = 64 * 1024
+
+	// e2eNoncePrefixSize, e2eTagSize - random nonce prefix of a data
+	// key and tag appended to every chunk.
+	e2eNoncePrefixSize = 4
+	e2eTagSize         = 16
+
+	// e2eDataKeySize - size of a data key.
+	e2eDataKeySize = 32
+
+	// maxEncryptedPartSize - largest part of plaintext whose encrypted
+	// part fits into MaxPartSize.
+	maxEncryptedPartSize = MaxPartSize / (e2eChunkSize + e2eTagSize) * e2eChunkSize
+)
+
+// ErrDecryptionFailed - a chunk of a client side encrypted object is
+// not what was encrypted: modified, reordered or cut off.
+var ErrDecryptionFailed = errors.New("client side encrypted data is corrupt")
+
+// PartEncryption - end to end encryption of upload data with a master
+// key which never leaves the client. Every upload uses a fresh data key
+// sealed with the master key, parts are encrypted in chunks with
+// AES-GCM, so every chunk is authenticated and can be decrypted on its
+// own. Any proxy or gateway on the way, such as an upload server
+// relaying the parts, only ever sees ciphertext and the sealed key.
+type PartEncryption struct {
+	master cipher.AEAD
+}
+
+// NewPartEncryption - returns a part encryption using the 32 byte
+// masterKey.
+func NewPartEncryption(masterKey []byte) (*PartEncryption, error) {
+	if len(masterKey) != 32 {
+		return nil, ErrInvalidArgument("Master key must be 32 bytes long.")
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	master, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &PartEncryption{master: master}, nil
+}
+
+// EncryptionManifest - key metadata of an end to end encrypted upload,
+// kept in the upload state and stored with the object. The data key is
+// sealed with the master key, holding the manifest does not allow to
+// decrypt anything.
+//
+// The object is a sequence of chunks, each ChunkSize bytes of plaintext
+// sealed with AES-256-GCM followed by the 16 byte tag, only the last
+// chunk may be shorter. Chunk i is sealed with the nonce NoncePrefix
+// followed by i as big endian uint64 and the single byte 1 for the last
+// chunk, 0 otherwise, as additional data, so chunks can neither be
+// moved nor cut off. An empty plaintext is sealed as one empty chunk.
+// Parts of encrypted uploads start at a chunk.
+type EncryptionManifest struct {
+	Algorithm   string `json:"algorithm"`
+	SealedKey   string `json:"sealedKey"`   // base64 encoded
+	NoncePrefix string `json:"noncePrefix"` // base64 encoded
+	ChunkSize   int64  `json:"chunkSize"`
+
+	// Size of the plaintext.
+	Size int64 `json:"size"`
+}
+
+// Validate - checks the manifest for algorithm, key and sizes, without
+// opening the key.
+func (m EncryptionManifest) Validate() error {
+	if m.Algorithm != e2eAlgorithm {
+		return ErrInvalidArgument("Encryption algorithm must be ‘" + e2eAlgorithm + "’.")
+	}
+	if m.ChunkSize != e2eChunkSize {
+		return ErrInvalidArgument(fmt.Sprintf("Encryption chunk size must be %d.", e2eChunkSize))
+	}
+	if m.Size < 0 || m.Size > MaxMultipartPutObjectSize {
+		return ErrInvalidArgument(fmt.Sprintf("Size must be between 0 and %d.", MaxMultipartPutObjectSize))
+	}
+	sealed, err := base64.StdEncoding.DecodeString(m.SealedKey)
+	if err != nil || len(sealed) != 12+e2eDataKeySize+e2eTagSize {
+		return ErrInvalidArgument("Malformed sealed key.")
+	}
+	prefix, err := base64.StdEncoding.DecodeString(m.NoncePrefix)
+	if err != nil || len(prefix) != e2eNoncePrefixSize {
+		return ErrInvalidArgument("Malformed nonce prefix.")
+	}
+	return nil
+}
+
+// chunks - returns the number of chunks of the object.
+func (m EncryptionManifest) chunks() int64 {
+	if m.Size == 0 {
+		return 1
+	}
+	return (m.Size + m.ChunkSize - 1) / m.ChunkSize
+}
+
+// SealedSize - returns the size of the encrypted object.
+func (m EncryptionManifest) SealedSize() int64 {
+	return m.Size + m.chunks()*e2eTagSize
+}
+
+// SealedOffset - returns the offset in the encrypted object of the
+// chunk holding plaintext byte offset.
+func (m EncryptionManifest) SealedOffset(offset int64) int64 {
+	return offset / m.ChunkSize * (m.ChunkSize + e2eTagSize)
+}
+
+// sealedRange - returns offset and size in the encrypted object of
+// size bytes of plaintext at offset, which starts at a chunk.
+func (m EncryptionManifest) sealedRange(offset, size int64) (int64, int64) {
+	start := m.SealedOffset(offset)
+	end := m.SealedOffset(offset + size)
+	if offset+size >= m.Size {
+		end = m.SealedSize()
+	}
+	return start, end - start
+}
+
+// SealedPart - returns part of the plaintext as uploaded, its offset
+// and size in the encrypted object.
+func (m EncryptionManifest) SealedPart(part PartState) PartState {
+	offset, size := m.sealedRange(part.Offset, part.Size)
+	return PartState{PartNumber: part.PartNumber, Offset: offset, Size: size}
+}
+
+// PlanParts - returns the part plan of the plaintext, with parts of
+// partSize rounded up to whole chunks, see PlanParts. Upload part n as
+// SealPart returns it.
+func (m EncryptionManifest) PlanParts(partSize int64) ([]PartState, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if partSize == 0 {
+		partSize = optimalPartSize(m.Size)
+	}
+	if rem := partSize % m.ChunkSize; rem != 0 {
+		partSize += m.ChunkSize - rem
+	}
+	if m.SealedOffset(partSize) > MaxPartSize {
+		return nil, ErrInvalidArgument(fmt.Sprintf("Encrypted part size exceeds %d.", MaxPartSize))
+	}
+	return PlanParts(m.Size, partSize)
+}
+
+// Metadata - returns the headers storing the manifest with the object,
+// to initiate the upload with.
+func (m EncryptionManifest) Metadata() http.Header {
+	header := make(http.Header)
+	header.Set(amzMetaE2EAlgorithm, m.Algorithm)
+	header.Set(amzMetaE2EKey, m.SealedKey)
+	header.Set(amzMetaE2ENoncePrefix, m.NoncePrefix)
+	header.Set(amzMetaE2EChunkSize, strconv.FormatInt(m.ChunkSize, 10))
+	return header
+}
+
+// ObjectManifest - returns the manifest of a client side encrypted
+// object from the metadata and size of objInfo, as returned by
+// StatObject.
+func ObjectManifest(objInfo ObjectInfo) (EncryptionManifest, error) {
+	m := EncryptionManifest{
+		Algorithm:   objInfo.Metadata.Get(amzMetaE2EAlgorithm),
+		SealedKey:   objInfo.Metadata.Get(amzMetaE2EKey),
+		NoncePrefix: objInfo.Metadata.Get(amzMetaE2ENoncePrefix),
+	}
+	if m.Algorithm == "" {
+		return m, ErrInvalidArgument("Object ‘" + objInfo.Key + "’ is not client side encrypted.")
+	}
+	m.ChunkSize, _ = strconv.ParseInt(objInfo.Metadata.Get(amzMetaE2EChunkSize), 10, 64)
+	if m.ChunkSize > 0 && objInfo.Size >= e2eTagSize {
+		// Every chunk but the last is complete.
+		sealedChunk := m.ChunkSize + e2eTagSize
+		chunks := (objInfo.Size + sealedChunk - 1) / sealedChunk
+		m.Size = objInfo.Size - chunks*e2eTagSize
+	}
+	if m.SealedSize() != objInfo.Size {
+		return m, ErrInvalidArgument("Object ‘" + objInfo.Key + "’ has a malformed encrypted size.")
+	}
+	if err := m.Validate(); err != nil {
+		return m, ErrInvalidArgument("Object ‘" + objInfo.Key + "’: " + err.Error())
+	}
+	return m, nil
+}
+
+// sessionKey - opened data key of a single upload.
+type sessionKey struct {
+	aead     cipher.AEAD
+	prefix   []byte
+	manifest EncryptionManifest
+}
+
+// NewManifest - generates a data key for an upload of size bytes of
+// plaintext and returns its manifest, for clients uploading the parts
+// themselves, for example through an upload server.
+func (e *PartEncryption) NewManifest(size int64) (EncryptionManifest, error) {
+	dataKey := make([]byte, e2eDataKeySize)
+	prefix := make([]byte, e2eNoncePrefixSize)
+	nonce := make([]byte, e.master.NonceSize())
+	for _, b := range [][]byte{dataKey, prefix, nonce} {
+		if _, err := io.ReadFull(rand.Reader, b); err != nil {
+			return EncryptionManifest{}, err
+		}
+	}
+	sealed := e.master.Seal(nonce, nonce, dataKey, []byte(e2eAlgorithm))
+	m := EncryptionManifest{
+		Algorithm:   e2eAlgorithm,
+		SealedKey:   base64.StdEncoding.EncodeToString(sealed),
+		NoncePrefix: base64.StdEncoding.EncodeToString(prefix),
+		ChunkSize:   e2eChunkSize,
+		Size:        size,
+	}
+	return m, m.Validate()
+}
+
+// newSessionKey - generates a data key for a new upload of size bytes.
+func (e *PartEncryption) newSessionKey(size int64) (*sessionKey, error) {
+	m, err := e.NewManifest(size)
+	if err != nil {
+		return nil, err
+	}
+	return e.openManifest(m)
+}
+
+// openManifest - recovers the data key of m.
+func (e *PartEncryption) openManifest(m EncryptionManifest) (*sessionKey, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	sealed, _ := base64.StdEncoding.DecodeString(m.SealedKey)
+	prefix, _ := base64.StdEncoding.DecodeString(m.NoncePrefix)
+	nonceSize := e.master.NonceSize()
+	dataKey, err := e.master.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(m.Algorithm))
+	if err != nil {
+		return nil, ErrInvalidArgument("Data key was sealed with a different master key.")
+	}
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionKey{aead: aead, prefix: prefix, manifest: m}, nil
+}
+
+// nonce - returns the nonce and additional data of chunk index.
+func (k *sessionKey) nonce(index int64) ([]byte, []byte) {
+	nonce := make([]byte, e2eNoncePrefixSize+8)
+	copy(nonce, k.prefix)
+	binary.BigEndian.PutUint64(nonce[e2eNoncePrefixSize:], uint64(index))
+	last := []byte{0}
+	if index == k.manifest.chunks()-1 {
+		last[0] = 1
+	}
+	return nonce, last
+}
+
+// sealChunk - appends chunk index sealed to dst.
+func (k *sessionKey) sealChunk(dst, plaintext []byte, index int64) []byte {
+	nonce, last := k.nonce(index)
+	return k.aead.Seal(dst, nonce, plaintext, last)
+}
+
+// openChunk - appends chunk index opened to dst.
+func (k *sessionKey) openChunk(dst, sealed []byte, index int64) ([]byte, error) {
+	nonce, last := k.nonce(index)
+	plaintext, err := k.aead.Open(dst, nonce, sealed, last)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+// sealReader - encrypted part of size bytes of plaintext read from src
+// at offset, which starts at a chunk. Chunks are sealed as they are
+// read, seeking is free so requests stay retryable.
+type sealReader struct {
+	key    *sessionKey
+	src    io.ReaderAt
+	offset int64
+	size   int64
+
+	// sealedSize is the size of the encrypted part, pos the read
+	// position in it.
+	sealedSize int64
+	pos        int64
+
+	// chunk is the sealed chunk held in buf, -1 for none.
+	chunk int64
+	buf   []byte
+}
+
+// newSealReader - returns the encrypted part of size bytes of plaintext
+// read from src at offset.
+func newSealReader(key *sessionKey, src io.ReaderAt, offset, size int64) *sealReader {
+	_, sealedSize := key.manifest.sealedRange(offset, size)
+	return &sealReader{
+		key:        key,
+		src:        src,
+		offset:     offset,
+		size:       size,
+		sealedSize: sealedSize,
+		chunk:      -1,
+	}
+}
+
+// Read - reads encrypted data.
+func (r *sealReader) Read(p []byte) (int, error) {
+	if r.pos >= r.sealedSize {
+		return 0, io.EOF
+	}
+	sealedChunk := r.key.manifest.ChunkSize + e2eTagSize
+	chunk := r.pos / sealedChunk
+	if chunk != r.chunk {
+		start := chunk * r.key.manifest.ChunkSize
+		n := r.size - start
+		if n > r.key.manifest.ChunkSize {
+			n = r.key.manifest.ChunkSize
+		}
+		plaintext := make([]byte, n)
+		if read, err := r.src.ReadAt(plaintext, r.offset+start); int64(read) < n {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		index := r.offset/r.key.manifest.ChunkSize + chunk
+		r.buf = r.key.sealChunk(r.buf[:0], plaintext, index)
+		r.chunk = chunk
+	}
+	n := copy(p, r.buf[r.pos-chunk*sealedChunk:])
+	r.pos += int64(n)
+	return n, nil
+}
+
+// Seek - moves the read position in the encrypted part.
+func (r *sealReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.sealedSize
+	}
+	if offset < 0 {
+		return r.pos, ErrInvalidArgument("Negative position.")
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+// SealPart - returns part of the upload of m encrypted, as planned by
+// the PlanParts method of m, with the plaintext read from reader. The
+// reader is seekable, so requests sending it can be retried.
+func (e *PartEncryption) SealPart(m EncryptionManifest, reader io.ReaderAt, part PartState) (io.ReadSeeker, error) {
+	key, err := e.openManifest(m)
+	if err != nil {
+		return nil, err
+	}
+	if part.Offset%m.ChunkSize != 0 || part.Size < 0 || part.Offset+part.Size > m.Size {
+		return nil, ErrInvalidArgument(fmt.Sprintf("Part %d does not start at a chunk.", part.PartNumber))
+	}
+	return newSealReader(key, reader, part.Offset, part.Size), nil
+}
+
+// openReader - decrypts the chunks of an object starting at a chunk.
+type openReader struct {
+	key    *sessionKey
+	reader io.Reader
+	chunk  int64
+	skip   int64
+	sealed []byte
+	buf    []byte
+	err    error
+
+	// Chunk after the last one holding the range and plaintext bytes
+	// left to return, reader must hold the chunks up to it.
+	endChunk int64
+	left     int64
+}
+
+// Read - reads decrypted data.
+func (r *openReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.chunk >= r.endChunk {
+			return 0, io.EOF
+		}
+		size := r.key.manifest.ChunkSize + e2eTagSize
+		if r.chunk == r.key.manifest.chunks()-1 {
+			size = r.key.manifest.SealedSize() - r.key.manifest.SealedOffset(r.chunk*r.key.manifest.ChunkSize)
+		}
+		n, err := io.ReadFull(r.reader, r.sealed[:size])
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// The data was cut before the end of the range.
+			err = ErrDecryptionFailed
+		}
+		if err != nil {
+			r.err = err
+			continue
+		}
+		r.buf, err = r.key.openChunk(r.buf[:0], r.sealed[:n], r.chunk)
+		if err != nil {
+			r.err = err
+			continue
+		}
+		r.chunk++
+		if r.skip > 0 {
+			r.buf = r.buf[r.skip:]
+			r.skip = 0
+		}
+		if int64(len(r.buf)) > r.left {
+			r.buf = r.buf[:r.left]
+		}
+		r.left -= int64(len(r.buf))
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// DecryptReader - returns a reader decrypting length bytes of the
+// object described by objInfo, as returned by StatObject, read from
+// reader. A negative length reads up to the end of the object. The
+// decrypted data starts at plaintext byte offset, reader must hold the
+// object from SealedOffset(offset) of its manifest on, up to the end of
+// the chunk holding the last byte read. Chunks which do not decrypt and
+// data ending before them fail the read with ErrDecryptionFailed.
+func (e *PartEncryption) DecryptReader(objInfo ObjectInfo, reader io.Reader, offset, length int64) (io.Reader, error) {
+	m, err := ObjectManifest(objInfo)
+	if err != nil {
+		return nil, err
+	}
+	key, err := e.openManifest(m)
+	if err != nil {
+		return nil, ErrInvalidArgument("Object ‘" + objInfo.Key + "’: " + err.Error())
+	}
+	if offset < 0 || offset > m.Size {
+		return nil, ErrInvalidArgument(fmt.Sprintf("Offset must be between 0 and %d.", m.Size))
+	}
+	if length < 0 {
+		length = m.Size - offset
+	}
+	if length > m.Size-offset {
+		return nil, ErrInvalidArgument(fmt.Sprintf("Length must be at most %d.", m.Size-offset))
+	}
+	// Empty ranges read nothing, but the single chunk of an empty
+	// object, which only holds a tag, is read to authenticate it.
+	endChunk := (offset + length + m.ChunkSize - 1) / m.ChunkSize
+	switch {
+	case m.Size == 0:
+		endChunk = m.chunks()
+	case length == 0:
+		endChunk = offset / m.ChunkSize
+	}
+	return &openReader{
+		key:      key,
+		reader:   reader,
+		chunk:    offset / m.ChunkSize,
+		skip:     offset % m.ChunkSize,
+		sealed:   make([]byte, m.ChunkSize+e2eTagSize),
+		endChunk: endChunk,
+		left:     length,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package minio_ext
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+// sealObject - returns data encrypted as one part with e and the
+// object info StatObject returns for it.
+func sealObject(t *testing.T, e *PartEncryption, data []byte) ([]byte, ObjectInfo) {
+	m, err := e.NewManifest(int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := e.SealPart(m, bytes.NewReader(data), PartState{PartNumber: 1, Size: m.Size})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sealed, ObjectInfo{Key: "object", Size: int64(len(sealed)), Metadata: m.Metadata()}
+}
+
+func TestDecryptReader(t *testing.T) {
+	e, err := NewPartEncryption(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 2*e2eChunkSize+100)
+	rand.New(rand.NewSource(1)).Read(data)
+	sealed, objInfo := sealObject(t, e, data)
+	emptySealed, emptyInfo := sealObject(t, e, nil)
+	const sealedChunk = e2eChunkSize + e2eTagSize
+
+	testCases := []struct {
+		objInfo ObjectInfo
+		// Encrypted data read from.
+		sealed []byte
+		offset int64
+		length int64
+		// Expected plaintext range.
+		start, end int
+		shouldPass bool
+	}{
+		{objInfo, sealed, 0, -1, 0, len(data), true},
+		{objInfo, sealed[sealedChunk:], e2eChunkSize + 10, -1, e2eChunkSize + 10, len(data), true},
+		{objInfo, sealed[:sealedChunk], 100, 10, 100, 110, true},
+		{objInfo, sealed[:sealedChunk], 0, e2eChunkSize, 0, e2eChunkSize, true},
+		{objInfo, sealed[sealedChunk : 2*sealedChunk], e2eChunkSize, e2eChunkSize, e2eChunkSize, 2 * e2eChunkSize, true},
+		{objInfo, nil, int64(len(data)), -1, len(data), len(data), true},
+		{emptyInfo, emptySealed, 0, -1, 0, 0, true},
+		// Data cut at a chunk boundary before the end of the range.
+		{objInfo, sealed[:2*sealedChunk], 0, -1, 0, 0, false},
+		{objInfo, sealed[:sealedChunk], 0, e2eChunkSize + 1, 0, 0, false},
+		{emptyInfo, nil, 0, -1, 0, 0, false},
+		// Data cut within a chunk.
+		{objInfo, sealed[:sealedChunk-1], 0, 10, 0, 0, false},
+		{objInfo, sealed[:len(sealed)-1], 0, -1, 0, 0, false},
+	}
+	for i, testCase := range testCases {
+		reader, err := e.DecryptReader(testCase.objInfo, bytes.NewReader(testCase.sealed), testCase.offset, testCase.length)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i+1, err)
+		}
+		got, err := ioutil.ReadAll(reader)
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: expected to pass, failed with %v", i+1, err)
+			continue
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: expected to fail, passed", i+1)
+			continue
+		}
+		if err != nil {
+			if err != ErrDecryptionFailed {
+				t.Errorf("Test %d: expected ErrDecryptionFailed, got %v", i+1, err)
+			}
+			continue
+		}
+		if !bytes.Equal(got, data[testCase.start:testCase.end]) {
+			t.Errorf("Test %d: unexpected plaintext of %d bytes", i+1, len(got))
+		}
+	}
+
+	for i, bounds := range [][2]int64{{-1, -1}, {int64(len(data)) + 1, -1}, {10, int64(len(data))}} {
+		if _, err := e.DecryptReader(objInfo, bytes.NewReader(sealed), bounds[0], bounds[1]); err == nil {
+			t.Errorf("Test %d: expected offset %d and length %d refused", i+1, bounds[0], bounds[1])
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package minio_ext
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// minClockSkew - smallest offset of the server clock corrected, S3
+// allows 15 minutes.
+const minClockSkew = time.Second
+
+// now - returns the current time on the clock of the server, all
+// requests are signed with it.
+func (c Client) now() time.Time {
+	return time.Now().UTC().Add(c.clockSkew())
+}
+
+// clockSkew - returns the offset of the server clock to the local
+// clock, learnt from RequestTimeTooSkewed errors.
+func (c Client) clockSkew() time.Duration {
+	return time.Duration(atomic.LoadInt64(c.clockOffset))
+}
+
+// ClockSkew - returns the offset of the server clock to the local clock
+// requests and presigned URLs are corrected by, 0 until the server
+// rejected a request for a skewed clock. Requests signed with signature
+// V2 are not corrected, their presigned URLs are.
+func (c Client) ClockSkew() time.Duration {
+	return c.clockSkew()
+}
+
+// adjustClockSkew - records the offset of the server clock from the
+// Date header of resp, reports whether it changed so that a request
+// signed with the old offset is worth retrying.
+func (c Client) adjustClockSkew(resp *http.Response) bool {
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return false
+	}
+	skew := time.Until(serverTime)
+	if diff := skew - c.clockSkew(); diff < minClockSkew && diff > -minClockSkew {
+		return false
+	}
+	atomic.StoreInt64(c.clockOffset, int64(skew))
+	return true
+}
@@ -30,17 +30,17 @@ const MinPartSize = 1024 * 1024 * 64
 // maxPartsCount - maximum number of parts for a single multipart session.
 const MaxPartsCount = 10000
 
-// maxPartSize - maximum part size 5GiB for a single multipart upload
+// MaxPartSize - maximum part size 5GiB for a single multipart upload
 // operation.
-const maxPartSize = 1024 * 1024 * 1024 * 5
+const MaxPartSize int64 = 1024 * 1024 * 1024 * 5
 
 // maxSinglePutObjectSize - maximum size 5GiB of object per PUT
 // operation.
-const maxSinglePutObjectSize = 1024 * 1024 * 1024 * 5
+const maxSinglePutObjectSize int64 = 1024 * 1024 * 1024 * 5
 
 // maxMultipartPutObjectSize - maximum size 5TiB of object for
 // Multipart operation.
-const MaxMultipartPutObjectSize = 1024 * 1024 * 1024 * 1024 * 5
+const MaxMultipartPutObjectSize int64 = 1024 * 1024 * 1024 * 1024 * 5
 
 // unsignedPayload - value to be set to X-Amz-Content-Sha256 header when
 // we don't want to sign the request payload
@@ -58,5 +58,22 @@ const (
 // Storage class header constant.
 const amzStorageClass = "X-Amz-Storage-Class"
 
+// Object version header constant.
+const amzVersionID = "X-Amz-Version-Id"
+
+// Object lock header constants.
+const (
+	amzObjectLockMode            = "X-Amz-Object-Lock-Mode"
+	amzObjectLockRetainUntilDate = "X-Amz-Object-Lock-Retain-Until-Date"
+	amzObjectLockLegalHold       = "X-Amz-Object-Lock-Legal-Hold"
+)
+
 // Website redirect location header constant
 const amzWebsiteRedirectLocation = "X-Amz-Website-Redirect-Location"
+
+// SSE-C (server side encryption with customer provided keys) header constants.
+const (
+	amzSSECustomerAlgorithm = "X-Amz-Server-Side-Encryption-Customer-Algorithm"
+	amzSSECustomerKey       = "X-Amz-Server-Side-Encryption-Customer-Key"
+	amzSSECustomerKeyMD5    = "X-Amz-Server-Side-Encryption-Customer-Key-MD5"
+)
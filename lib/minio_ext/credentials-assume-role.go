@@ -0,0 +1,74 @@
+package minio_ext
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/credentials"
+)
+
+// AssumeRoleOptions - options for NewAssumeRole.
+type AssumeRoleOptions struct {
+	// Long-term keys requesting the temporary credentials, required.
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// Region of the STS endpoint, defaults to us-east-1.
+	Location string
+
+	// Lifetime of the temporary credentials, defaults to one hour.
+	Duration time.Duration
+
+	// Role to assume and name of the session, needed by AWS STS.
+	RoleARN         string
+	RoleSessionName string
+
+	// Client requesting the credentials, defaults to one using the
+	// DefaultTransport.
+	HTTPClient *http.Client
+}
+
+// AssumeRoleProvider - credentials.Provider of temporary credentials
+// requested with STS AssumeRole from AWS or MinIO. The credentials are
+// requested again shortly before they expire.
+type AssumeRoleProvider struct {
+	stsExpiry
+
+	endpoint string
+	opts     AssumeRoleOptions
+}
+
+// NewAssumeRole - returns a provider requesting temporary credentials
+// from the STS endpoint stsEndpoint, see WithAssumeRole.
+func NewAssumeRole(stsEndpoint string, opts AssumeRoleOptions) (*AssumeRoleProvider, error) {
+	httpClient, err := stsHTTPClient(stsEndpoint, opts.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+	if opts.AccessKeyID == "" || opts.SecretAccessKey == "" {
+		return nil, ErrInvalidArgument("AssumeRole needs an access key and a secret key.")
+	}
+	if opts.Location == "" {
+		opts.Location = "us-east-1"
+	}
+	opts.HTTPClient = httpClient
+	return &AssumeRoleProvider{endpoint: stsEndpoint, opts: opts}, nil
+}
+
+// Retrieve - implements credentials.Provider.
+func (p *AssumeRoleProvider) Retrieve() (credentials.Value, error) {
+	query := url.Values{}
+	query.Set("Action", "AssumeRole")
+	query.Set("Version", "2011-06-15")
+	query.Set("DurationSeconds", stsDuration(p.opts.Duration))
+	if p.opts.RoleARN != "" {
+		query.Set("RoleArn", p.opts.RoleARN)
+	}
+	if p.opts.RoleSessionName != "" {
+		query.Set("RoleSessionName", p.opts.RoleSessionName)
+	}
+	return p.retrieve(p.opts.HTTPClient, p.endpoint, query, func(req *http.Request) *http.Request {
+		return signV4ServiceAt(*req, p.opts.AccessKeyID, p.opts.SecretAccessKey, "", p.opts.Location, serviceSTS, time.Now())
+	})
+}
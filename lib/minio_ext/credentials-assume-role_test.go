@@ -0,0 +1,183 @@
+package minio_ext
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/credentials"
+)
+
+// stsServer - fake STS endpoint handing out numbered temporary
+// credentials valid for lifetime.
+type stsServer struct {
+	mutex    sync.Mutex
+	lifetime time.Duration
+	// failure answers every request when set.
+	failure *testResponse
+	// forms and headers of the requests received.
+	forms   []url.Values
+	headers []http.Header
+}
+
+// ServeHTTP - answers AssumeRole actions.
+func (s *stsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.forms = append(s.forms, r.Form)
+	s.headers = append(s.headers, r.Header)
+	if s.failure != nil {
+		s.failure.write(w)
+		return
+	}
+	action := r.Form.Get("Action")
+	n := len(s.forms)
+	fmt.Fprintf(w, `<%sResponse><%sResult><Credentials>
+<AccessKeyId>ACCESS%d</AccessKeyId><SecretAccessKey>SECRET%d</SecretAccessKey>
+<SessionToken>TOKEN%d</SessionToken><Expiration>%s</Expiration>
+</Credentials></%sResult></%sResponse>`,
+		action, action, n, n, n, time.Now().Add(s.lifetime).UTC().Format(time.RFC3339), action, action)
+}
+
+// requests - returns the number of requests received.
+func (s *stsServer) requests() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.forms)
+}
+
+func TestNewAssumeRole(t *testing.T) {
+	keys := AssumeRoleOptions{AccessKeyID: "access", SecretAccessKey: "secret"}
+	testCases := []struct {
+		endpoint   string
+		opts       AssumeRoleOptions
+		shouldPass bool
+	}{
+		{"https://sts.amazonaws.com", keys, true},
+		{"http://localhost:9000", keys, true},
+		{"sts.amazonaws.com", keys, false},
+		{"ftp://sts.amazonaws.com", keys, false},
+		{"https://", keys, false},
+		{"https://sts.amazonaws.com", AssumeRoleOptions{AccessKeyID: "access"}, false},
+		{"https://sts.amazonaws.com", AssumeRoleOptions{SecretAccessKey: "secret"}, false},
+	}
+	for i, testCase := range testCases {
+		p, err := NewAssumeRole(testCase.endpoint, testCase.opts)
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: expected to pass, failed with %v", i+1, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: expected to fail, passed", i+1)
+		}
+		if err == nil && (p.opts.Location != "us-east-1" || p.opts.HTTPClient == nil) {
+			t.Errorf("Test %d: expected the defaults set, got %+v", i+1, p.opts)
+		}
+	}
+}
+
+func TestAssumeRoleRetrieve(t *testing.T) {
+	sts := &stsServer{lifetime: 15 * time.Minute}
+	ts := httptest.NewServer(sts)
+	defer ts.Close()
+	p, err := NewAssumeRole(ts.URL, AssumeRoleOptions{
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		Location:        "eu-west-1",
+		Duration:        15 * time.Minute,
+		RoleARN:         "arn:aws:iam::123456789012:role/upload",
+		RoleSessionName: "session",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := credentials.New(p)
+
+	value, err := creds.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.AccessKeyID != "ACCESS1" || value.SecretAccessKey != "SECRET1" || value.SessionToken != "TOKEN1" || value.SignerType != credentials.SignatureV4 {
+		t.Errorf("Unexpected credentials %+v", value)
+	}
+	form, header := sts.forms[0], sts.headers[0]
+	if form.Get("Action") != "AssumeRole" || form.Get("DurationSeconds") != "900" ||
+		form.Get("RoleArn") != "arn:aws:iam::123456789012:role/upload" || form.Get("RoleSessionName") != "session" {
+		t.Errorf("Unexpected request %v", form)
+	}
+	if auth := header.Get("Authorization"); !strings.Contains(auth, "Credential=access/") || !strings.Contains(auth, "/eu-west-1/sts/aws4_request") {
+		t.Errorf("Expected the request signed for STS, got %q", auth)
+	}
+	if remaining := time.Until(p.Expiration()); remaining < 14*time.Minute || remaining > 15*time.Minute {
+		t.Errorf("Unexpected expiration in %v", remaining)
+	}
+
+	// The credentials are kept until shortly before they expire.
+	if _, err = creds.Get(); err != nil || sts.requests() != 1 {
+		t.Errorf("Expected the credentials kept, got %d requests, %v", sts.requests(), err)
+	}
+	p.CurrentTime = func() time.Time { return time.Now().Add(15*time.Minute - credentials.DefaultExpiryWindow) }
+	if value, err = creds.Get(); err != nil || sts.requests() != 2 || value.AccessKeyID != "ACCESS2" {
+		t.Errorf("Expected the credentials refreshed, got %+v after %d requests, %v", value, sts.requests(), err)
+	}
+
+	sts.failure = &testResponse{http.StatusForbidden, "AccessDenied", ""}
+	if _, err = p.Retrieve(); ToErrorResponse(err).Code != "AccessDenied" {
+		t.Errorf("Expected AccessDenied, got %v", err)
+	}
+}
+
+func TestAssumeRolePresignExpiry(t *testing.T) {
+	testCases := []struct {
+		lifetime   time.Duration
+		expires    time.Duration
+		shouldPass bool
+		maxExpires int64
+	}{
+		{time.Hour, time.Minute, true, 60},
+		{time.Hour, 7 * 24 * time.Hour, true, 3600},
+		// Credentials expiring now sign nothing.
+		{0, time.Minute, false, 0},
+	}
+	for i, testCase := range testCases {
+		sts := &stsServer{lifetime: testCase.lifetime}
+		ts := httptest.NewServer(sts)
+		p, err := NewAssumeRole(ts.URL, AssumeRoleOptions{AccessKeyID: "access", SecretAccessKey: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := New("s3.example.com", WithAssumeRole(p), WithRegion("us-east-1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		signedURL, err := c.GenUploadPartSignedUrl("upload", "bucket", "object", 1, 10, testCase.expires, "us-east-1")
+		ts.Close()
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: expected to pass, failed with %v", i+1, err)
+			continue
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: expected to fail, passed", i+1)
+		}
+		if err != nil {
+			continue
+		}
+		u, err := url.Parse(signedURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expires, _ := strconv.ParseInt(u.Query().Get("X-Amz-Expires"), 10, 64)
+		if expires > testCase.maxExpires || expires < testCase.maxExpires-5 {
+			t.Errorf("Test %d: expected expiry %d, got %d", i+1, testCase.maxExpires, expires)
+		}
+		if u.Query().Get("X-Amz-Security-Token") != "TOKEN1" {
+			t.Errorf("Test %d: expected the session token signed, got %s", i+1, signedURL)
+		}
+	}
+}
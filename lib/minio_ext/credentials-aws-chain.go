@@ -0,0 +1,235 @@
+package minio_ext
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/credentials"
+)
+
+// Endpoints of the EC2 instance metadata service and of the ECS task
+// metadata service.
+const (
+	ec2MetadataEndpoint = "http://169.254.169.254"
+	ecsMetadataEndpoint = "http://169.254.170.2"
+)
+
+// instanceRoleCredentials - credentials returned by the EC2 and ECS
+// metadata services.
+type instanceRoleCredentials struct {
+	Code            string
+	Message         string
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string
+	Token           string
+	Expiration      time.Time
+}
+
+// InstanceRoleProvider - credentials.Provider of the temporary
+// credentials of the ECS task role, when running in an ECS task, or of
+// the EC2 instance profile otherwise. EC2 metadata is read with IMDSv2
+// session tokens, falling back to IMDSv1 where tokens are disabled.
+type InstanceRoleProvider struct {
+	stsExpiry
+
+	client *http.Client
+}
+
+// NewInstanceRole - returns a provider reading the credentials from the
+// metadata services.
+func NewInstanceRole() *InstanceRoleProvider {
+	return &InstanceRoleProvider{
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Retrieve - implements credentials.Provider.
+func (p *InstanceRoleProvider) Retrieve() (credentials.Value, error) {
+	var creds instanceRoleCredentials
+	var err error
+	if os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" {
+		creds, err = p.ecsCredentials()
+	} else {
+		creds, err = p.ec2Credentials()
+	}
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	if creds.Code != "" && creds.Code != "Success" {
+		return credentials.Value{}, errors.New("instance role credentials: " + creds.Code + ": " + creds.Message)
+	}
+
+	p.expireAt(creds.Expiration)
+	return credentials.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.Token,
+		SignerType:      credentials.SignatureV4,
+	}, nil
+}
+
+// ecsCredentials - reads the credentials of the ECS task role.
+func (p *InstanceRoleProvider) ecsCredentials() (instanceRoleCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		endpoint = ecsMetadataEndpoint + uri
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return instanceRoleCredentials{}, err
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	var creds instanceRoleCredentials
+	err = p.getJSON(req, &creds)
+	return creds, err
+}
+
+// ec2Credentials - reads the credentials of the first role of the EC2
+// instance profile.
+func (p *InstanceRoleProvider) ec2Credentials() (instanceRoleCredentials, error) {
+	token := p.imdsToken()
+	rolesURL := ec2MetadataEndpoint + "/latest/meta-data/iam/security-credentials/"
+
+	req, err := p.imdsRequest(rolesURL, token)
+	if err != nil {
+		return instanceRoleCredentials{}, err
+	}
+	roles, err := p.get(req)
+	if err != nil {
+		return instanceRoleCredentials{}, err
+	}
+	role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+	if role == "" {
+		return instanceRoleCredentials{}, errors.New("instance role credentials: no role attached to the instance")
+	}
+
+	req, err = p.imdsRequest(rolesURL+role, token)
+	if err != nil {
+		return instanceRoleCredentials{}, err
+	}
+	var creds instanceRoleCredentials
+	err = p.getJSON(req, &creds)
+	return creds, err
+}
+
+// imdsToken - returns an IMDSv2 session token, empty where IMDSv2 is
+// not available.
+func (p *InstanceRoleProvider) imdsToken() string {
+	req, err := http.NewRequest(http.MethodPut, ec2MetadataEndpoint+"/latest/api/token", nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	token, err := p.get(req)
+	if err != nil {
+		return ""
+	}
+	return string(token)
+}
+
+// imdsRequest - returns a metadata request with the session token.
+func (p *InstanceRoleProvider) imdsRequest(u, token string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+	}
+	return req, nil
+}
+
+// get - returns the body of the response to req, fails unless the
+// response is 200 OK.
+func (p *InstanceRoleProvider) get(req *http.Request) ([]byte, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer closeResponse(resp)
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("instance role credentials: " + req.URL.Path + ": " + resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// getJSON - decodes the body of the response to req into v.
+func (p *InstanceRoleProvider) getJSON(req *http.Request, v interface{}) error {
+	body, err := p.get(req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// AWSChainProvider - credentials.Provider trying the environment, the
+// shared credentials file and the instance role in this order, like the
+// AWS SDKs. The first source with credentials is used until they expire.
+type AWSChainProvider struct {
+	providers []credentials.Provider
+
+	// mutex protects current.
+	mutex   sync.Mutex
+	current credentials.Provider
+}
+
+// NewAWSCredentialChain - returns the provider of the standard AWS
+// credential chain, see WithAWSCredentialChain.
+func NewAWSCredentialChain() *AWSChainProvider {
+	return &AWSChainProvider{
+		providers: []credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.FileAWSCredentials{},
+			NewInstanceRole(),
+		},
+	}
+}
+
+// Retrieve - implements credentials.Provider.
+func (c *AWSChainProvider) Retrieve() (credentials.Value, error) {
+	var errs []string
+	for _, provider := range c.providers {
+		value, err := provider.Retrieve()
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if value.AccessKeyID == "" || value.SecretAccessKey == "" {
+			continue
+		}
+		c.mutex.Lock()
+		c.current = provider
+		c.mutex.Unlock()
+		return value, nil
+	}
+	c.mutex.Lock()
+	c.current = nil
+	c.mutex.Unlock()
+	return credentials.Value{}, errors.New("no AWS credentials found: " + strings.Join(errs, "; "))
+}
+
+// IsExpired - implements credentials.Provider.
+func (c *AWSChainProvider) IsExpired() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.current == nil || c.current.IsExpired()
+}
+
+// Expiration - implements TemporaryCredentials, the zero time unless
+// the credentials come from the instance role.
+func (c *AWSChainProvider) Expiration() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if temporary, ok := c.current.(TemporaryCredentials); ok {
+		return temporary.Expiration()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,264 @@
+package minio_ext
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setenv - sets the environment variables in env, empty values unset
+// them, and restores them when the test ends.
+func setenv(t *testing.T, env map[string]string) {
+	for key, value := range env {
+		old, ok := os.LookupEnv(key)
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(key, old)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+		if value == "" {
+			os.Unsetenv(key)
+		} else {
+			os.Setenv(key, value)
+		}
+	}
+}
+
+// redirectTransport - sends all requests to the server at target.
+type redirectTransport struct {
+	target *url.URL
+}
+
+// RoundTrip - implements http.RoundTripper.
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = t.target.Scheme, t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// metadataServer - fake EC2 and ECS metadata service.
+type metadataServer struct {
+	mutex sync.Mutex
+	// IMDSv2 session tokens are handed out and required when set.
+	imdsV2 bool
+	// role attached to the instance, none when empty.
+	role string
+	// code of the credentials, Success when empty.
+	code     string
+	lifetime time.Duration
+	// ECS authorization header expected when set.
+	authorization string
+
+	retrieved int
+}
+
+// ServeHTTP - answers the metadata requests.
+func (s *metadataServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	const rolesPath = "/latest/meta-data/iam/security-credentials/"
+	switch {
+	case r.URL.Path == "/latest/api/token":
+		if !s.imdsV2 || r.Method != http.MethodPut || r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("imds-token"))
+		return
+	case strings.HasPrefix(r.URL.Path, rolesPath):
+		if s.imdsV2 && r.Header.Get("X-aws-ec2-metadata-token") != "imds-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		role := strings.TrimPrefix(r.URL.Path, rolesPath)
+		if role == "" {
+			w.Write([]byte(s.role + "\n"))
+			return
+		}
+		if role != s.role {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	case r.URL.Path == "/ecs/credentials":
+		if r.Header.Get("Authorization") != s.authorization {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.retrieved++
+	code := s.code
+	if code == "" {
+		code = "Success"
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"Code":            code,
+		"Message":         code,
+		"AccessKeyId":     "ROLE" + strings.Repeat("X", s.retrieved),
+		"SecretAccessKey": "secret",
+		"Token":           "token",
+		"Expiration":      time.Now().Add(s.lifetime).UTC().Format(time.RFC3339),
+	})
+}
+
+// newTestInstanceRole - returns a provider reading the metadata of the
+// server at serverURL.
+func newTestInstanceRole(t *testing.T, serverURL string) *InstanceRoleProvider {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewInstanceRole()
+	p.client.Transport = redirectTransport{u}
+	return p
+}
+
+func TestInstanceRoleEC2(t *testing.T) {
+	setenv(t, map[string]string{"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI": "", "AWS_CONTAINER_CREDENTIALS_FULL_URI": ""})
+	testCases := []struct {
+		name       string
+		server     *metadataServer
+		shouldPass bool
+	}{
+		{"imdsv2", &metadataServer{imdsV2: true, role: "upload", lifetime: time.Hour}, true},
+		{"imdsv1", &metadataServer{role: "upload", lifetime: time.Hour}, true},
+		{"no role", &metadataServer{imdsV2: true}, false},
+		{"failed", &metadataServer{role: "upload", code: "AssumeRoleUnauthorizedAccess"}, false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ts := httptest.NewServer(testCase.server)
+			defer ts.Close()
+			p := newTestInstanceRole(t, ts.URL)
+			value, err := p.Retrieve()
+			if err != nil && testCase.shouldPass {
+				t.Fatalf("Expected to pass, failed with %v", err)
+			}
+			if err == nil && !testCase.shouldPass {
+				t.Fatal("Expected to fail, passed")
+			}
+			if err != nil {
+				return
+			}
+			if value.AccessKeyID != "ROLEX" || value.SessionToken != "token" || p.IsExpired() {
+				t.Errorf("Unexpected credentials %+v", value)
+			}
+			if remaining := time.Until(p.Expiration()); remaining < 59*time.Minute {
+				t.Errorf("Unexpected expiration in %v", remaining)
+			}
+		})
+	}
+}
+
+func TestInstanceRoleECS(t *testing.T) {
+	server := &metadataServer{authorization: "ecs-token", lifetime: time.Hour}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	testCases := []struct {
+		relativeURI   string
+		fullURI       string
+		authorization string
+		shouldPass    bool
+	}{
+		{"/ecs/credentials", "", "ecs-token", true},
+		{"", ts.URL + "/ecs/credentials", "ecs-token", true},
+		{"/ecs/credentials", "", "", false},
+		{"/ecs/other", "", "ecs-token", false},
+	}
+	for i, testCase := range testCases {
+		setenv(t, map[string]string{
+			"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI": testCase.relativeURI,
+			"AWS_CONTAINER_CREDENTIALS_FULL_URI":     testCase.fullURI,
+			"AWS_CONTAINER_AUTHORIZATION_TOKEN":      testCase.authorization,
+		})
+		value, err := newTestInstanceRole(t, ts.URL).Retrieve()
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: expected to pass, failed with %v", i+1, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: expected to fail, passed", i+1)
+		}
+		if err == nil && !strings.HasPrefix(value.AccessKeyID, "ROLE") {
+			t.Errorf("Test %d: unexpected credentials %+v", i+1, value)
+		}
+	}
+}
+
+func TestAWSCredentialChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sharedFile := filepath.Join(dir, "credentials")
+	err = ioutil.WriteFile(sharedFile, []byte("[default]\naws_access_key_id = FILE\naws_secret_access_key = secret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &metadataServer{imdsV2: true, role: "upload", lifetime: time.Hour}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	setenv(t, map[string]string{
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI": "",
+		"AWS_CONTAINER_CREDENTIALS_FULL_URI":     "",
+		"AWS_ACCESS_KEY":                         "",
+		"AWS_SECRET_KEY":                         "",
+		"AWS_PROFILE":                            "",
+	})
+
+	testCases := []struct {
+		accessKey  string
+		sharedFile string
+		expected   string
+		temporary  bool
+	}{
+		{"ENV", sharedFile, "ENV", false},
+		{"", sharedFile, "FILE", false},
+		{"", filepath.Join(dir, "missing"), "ROLEX", true},
+	}
+	for i, testCase := range testCases {
+		secret := ""
+		if testCase.accessKey != "" {
+			secret = "secret"
+		}
+		setenv(t, map[string]string{
+			"AWS_ACCESS_KEY_ID":           testCase.accessKey,
+			"AWS_SECRET_ACCESS_KEY":       secret,
+			"AWS_SHARED_CREDENTIALS_FILE": testCase.sharedFile,
+		})
+		chain := NewAWSCredentialChain()
+		chain.providers[2] = newTestInstanceRole(t, ts.URL)
+		if !chain.IsExpired() {
+			t.Errorf("Test %d: expected no credentials before retrieving", i+1)
+		}
+		value, err := chain.Retrieve()
+		if err != nil || value.AccessKeyID != testCase.expected {
+			t.Errorf("Test %d: expected %s, got %+v, %v", i+1, testCase.expected, value, err)
+			continue
+		}
+		if chain.IsExpired() || chain.Expiration().IsZero() != !testCase.temporary {
+			t.Errorf("Test %d: unexpected expiry %v", i+1, chain.Expiration())
+		}
+	}
+
+	// Without any source the chain fails.
+	server.role = ""
+	chain := NewAWSCredentialChain()
+	chain.providers[2] = newTestInstanceRole(t, ts.URL)
+	if _, err = chain.Retrieve(); err == nil || !chain.IsExpired() {
+		t.Errorf("Expected no credentials found, got %v", err)
+	}
+}
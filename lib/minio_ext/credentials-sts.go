@@ -0,0 +1,151 @@
+package minio_ext
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/credentials"
+)
+
+// TemporaryCredentials - credentials.Provider of credentials expiring
+// at a known time, like those of NewAssumeRole and NewWebIdentity.
+type TemporaryCredentials interface {
+	credentials.Provider
+
+	// Expiration returns when the current credentials expire, the zero
+	// time before they were retrieved.
+	Expiration() time.Time
+}
+
+// stsResult - result of the STS actions.
+type stsResult struct {
+	Credentials struct {
+		AccessKeyID     string    `xml:"AccessKeyId"`
+		SecretAccessKey string    `xml:"SecretAccessKey"`
+		SessionToken    string    `xml:"SessionToken"`
+		Expiration      time.Time `xml:"Expiration"`
+	}
+}
+
+// stsResponse - response of the STS actions, the result element is
+// named after the action.
+type stsResponse struct {
+	AssumeRole  *stsResult `xml:"AssumeRoleResult"`
+	WebIdentity *stsResult `xml:"AssumeRoleWithWebIdentityResult"`
+}
+
+// stsExpiry - expiry of the credentials of an STS or instance role
+// provider.
+type stsExpiry struct {
+	credentials.Expiry
+
+	// mutex protects expiration.
+	mutex      sync.Mutex
+	expiration time.Time
+}
+
+// Expiration - implements TemporaryCredentials.
+func (e *stsExpiry) Expiration() time.Time {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.expiration
+}
+
+// expireAt - records that the retrieved credentials expire at
+// expiration, they are refreshed shortly before.
+func (e *stsExpiry) expireAt(expiration time.Time) {
+	e.mutex.Lock()
+	e.expiration = expiration
+	e.mutex.Unlock()
+	e.SetExpiration(expiration, credentials.DefaultExpiryWindow)
+}
+
+// stsHTTPClient - returns httpClient, a client using the DefaultTransport
+// for stsEndpoint when nil. Fails when stsEndpoint is not an http or
+// https URL.
+func stsHTTPClient(stsEndpoint string, httpClient *http.Client) (*http.Client, error) {
+	u, err := url.Parse(stsEndpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidArgument("STS endpoint ‘" + stsEndpoint + "’ is invalid.")
+	}
+	if httpClient != nil {
+		return httpClient, nil
+	}
+	transport, err := DefaultTransport(u.Scheme == "https")
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// stsDuration - the DurationSeconds parameter of duration, one hour
+// when zero.
+func stsDuration(duration time.Duration) string {
+	if duration <= 0 {
+		duration = time.Hour
+	}
+	return strconv.FormatInt(int64(duration/time.Second), 10)
+}
+
+// retrieve - posts the STS action query to stsEndpoint, signing it with
+// sign unless nil, and records the expiry of the returned credentials.
+func (e *stsExpiry) retrieve(httpClient *http.Client, stsEndpoint string, query url.Values, sign func(*http.Request) *http.Request) (credentials.Value, error) {
+	body := []byte(query.Encode())
+	u, err := url.Parse(stsEndpoint)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	u.Path = "/"
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if sign != nil {
+		req.Header.Set("X-Amz-Content-Sha256", sum256Hex(body))
+		req = sign(req)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	defer closeResponse(resp)
+	if resp.StatusCode != http.StatusOK {
+		return credentials.Value{}, httpRespToErrorResponse(resp, "", "")
+	}
+	var result stsResponse
+	if err = xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return credentials.Value{}, err
+	}
+
+	res := result.AssumeRole
+	if res == nil {
+		res = result.WebIdentity
+	}
+	if res == nil {
+		return credentials.Value{}, ErrInvalidArgument("STS response has no credentials.")
+	}
+	creds := res.Credentials
+	e.expireAt(creds.Expiration)
+	return credentials.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		SignerType:      credentials.SignatureV4,
+	}, nil
+}
+
+// credentialsExpiration - returns when the credentials of c expire, the
+// zero time when they do not.
+func (c Client) credentialsExpiration() time.Time {
+	if c.credsExpiration == nil {
+		return time.Time{}
+	}
+	return c.credsExpiration()
+}
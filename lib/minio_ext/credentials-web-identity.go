@@ -0,0 +1,92 @@
+package minio_ext
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/credentials"
+)
+
+// WebIdentityOptions - options for NewWebIdentity.
+type WebIdentityOptions struct {
+	// Returns the OIDC token exchanged for credentials, required. It is
+	// called on every refresh so rotated tokens are picked up.
+	Token func() (string, error)
+
+	// Lifetime of the temporary credentials, defaults to one hour.
+	Duration time.Duration
+
+	// Role to assume and name of the session, needed by AWS STS.
+	RoleARN         string
+	RoleSessionName string
+
+	// Client requesting the credentials, defaults to one using the
+	// DefaultTransport.
+	HTTPClient *http.Client
+}
+
+// WebIdentityTokenFile - returns a token source reading the token from
+// path, like the projected service account token of Kubernetes which
+// the kubelet rotates in place.
+func WebIdentityTokenFile(path string) func() (string, error) {
+	return func() (string, error) {
+		token, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(token)), nil
+	}
+}
+
+// WebIdentityProvider - credentials.Provider of temporary credentials
+// exchanged for an OIDC token with STS AssumeRoleWithWebIdentity from
+// AWS or MinIO, no static keys are needed. The credentials are
+// exchanged again shortly before they expire.
+type WebIdentityProvider struct {
+	stsExpiry
+
+	endpoint string
+	opts     WebIdentityOptions
+}
+
+// NewWebIdentity - returns a provider exchanging tokens at the STS
+// endpoint stsEndpoint, see WithWebIdentity.
+func NewWebIdentity(stsEndpoint string, opts WebIdentityOptions) (*WebIdentityProvider, error) {
+	httpClient, err := stsHTTPClient(stsEndpoint, opts.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Token == nil {
+		return nil, ErrInvalidArgument("Web identity needs a token source.")
+	}
+	opts.HTTPClient = httpClient
+	return &WebIdentityProvider{endpoint: stsEndpoint, opts: opts}, nil
+}
+
+// Retrieve - implements credentials.Provider.
+func (p *WebIdentityProvider) Retrieve() (credentials.Value, error) {
+	token, err := p.opts.Token()
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	if token == "" {
+		return credentials.Value{}, ErrInvalidArgument("Web identity token is empty.")
+	}
+
+	// The request is authenticated by the token, it is not signed.
+	query := url.Values{}
+	query.Set("Action", "AssumeRoleWithWebIdentity")
+	query.Set("Version", "2011-06-15")
+	query.Set("WebIdentityToken", token)
+	query.Set("DurationSeconds", stsDuration(p.opts.Duration))
+	if p.opts.RoleARN != "" {
+		query.Set("RoleArn", p.opts.RoleARN)
+	}
+	if p.opts.RoleSessionName != "" {
+		query.Set("RoleSessionName", p.opts.RoleSessionName)
+	}
+	return p.retrieve(p.opts.HTTPClient, p.endpoint, query, nil)
+}
@@ -0,0 +1,102 @@
+package minio_ext
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewWebIdentity(t *testing.T) {
+	token := func() (string, error) { return "token", nil }
+	testCases := []struct {
+		endpoint   string
+		token      func() (string, error)
+		shouldPass bool
+	}{
+		{"https://sts.amazonaws.com", token, true},
+		{"sts.amazonaws.com", token, false},
+		{"https://sts.amazonaws.com", nil, false},
+	}
+	for i, testCase := range testCases {
+		_, err := NewWebIdentity(testCase.endpoint, WebIdentityOptions{Token: testCase.token})
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: expected to pass, failed with %v", i+1, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: expected to fail, passed", i+1)
+		}
+	}
+}
+
+func TestWebIdentityRetrieve(t *testing.T) {
+	sts := &stsServer{lifetime: time.Hour}
+	ts := httptest.NewServer(sts)
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "web-identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+
+	p, err := NewWebIdentity(ts.URL, WebIdentityOptions{
+		Token:           WebIdentityTokenFile(tokenFile),
+		RoleARN:         "arn:aws:iam::123456789012:role/upload",
+		RoleSessionName: "pod",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The token file is read on every refresh, rotated tokens are sent.
+	testCases := []struct {
+		token      string
+		write      bool
+		shouldPass bool
+		sent       string
+	}{
+		{"", false, false, ""},
+		{"\n", true, false, ""},
+		{"token1\n", true, true, "token1"},
+		{"token2", true, true, "token2"},
+	}
+	for i, testCase := range testCases {
+		if testCase.write {
+			if err = ioutil.WriteFile(tokenFile, []byte(testCase.token), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		requests := sts.requests()
+		value, err := p.Retrieve()
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: expected to pass, failed with %v", i+1, err)
+			continue
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: expected to fail, passed", i+1)
+		}
+		if err != nil {
+			if sts.requests() != requests {
+				t.Errorf("Test %d: expected no exchange without token", i+1)
+			}
+			continue
+		}
+		form, header := sts.forms[requests], sts.headers[requests]
+		if form.Get("Action") != "AssumeRoleWithWebIdentity" || form.Get("WebIdentityToken") != testCase.sent ||
+			form.Get("DurationSeconds") != "3600" || form.Get("RoleArn") == "" || form.Get("RoleSessionName") != "pod" {
+			t.Errorf("Test %d: unexpected request %v", i+1, form)
+		}
+		if header.Get("Authorization") != "" {
+			t.Errorf("Test %d: expected the request not signed", i+1)
+		}
+		if value.AccessKeyID == "" || value.SessionToken == "" || p.IsExpired() {
+			t.Errorf("Test %d: unexpected credentials %+v", i+1, value)
+		}
+		if remaining := time.Until(p.Expiration()); remaining < 59*time.Minute {
+			t.Errorf("Test %d: unexpected expiration in %v", i+1, remaining)
+		}
+	}
+}
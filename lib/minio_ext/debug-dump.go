@@ -0,0 +1,160 @@
+package minio_ext
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// redactedQuery - query parameters of presigned URLs whose values are
+// redacted in traces.
+var redactedQuery = []string{"X-Amz-Signature", "X-Amz-Credential", "X-Amz-Security-Token", "Signature", "AWSAccessKeyId"}
+
+// redactedHeaders - headers whose values are redacted in traces, the
+// Authorization header only keeps its scope.
+var redactedHeaders = []string{
+	"X-Amz-Security-Token",
+	"X-Amz-Server-Side-Encryption-Customer-Key",
+	"X-Amz-Copy-Source-Server-Side-Encryption-Customer-Key",
+}
+
+// redactURL - returns u with the credentials and signatures of its query
+// redacted.
+func redactURL(u *url.URL) *url.URL {
+	redacted := *u
+	query := u.Query()
+	changed := false
+	for _, key := range redactedQuery {
+		if query.Get(key) != "" {
+			query.Set(key, "**REDACTED**")
+			changed = true
+		}
+	}
+	if changed {
+		redacted.RawQuery = query.Encode()
+	}
+	return &redacted
+}
+
+// redactRequest - returns a copy of req fit for traces, without
+// credentials, signatures and encryption keys in its headers and query.
+func redactRequest(req *http.Request) *http.Request {
+	redacted := *req
+	redacted.URL = redactURL(req.URL)
+	redacted.Header = req.Header.Clone()
+	if auth := redacted.Header.Get("Authorization"); auth != "" {
+		redacted.Header.Set("Authorization", redactSignature(auth))
+	}
+	for _, key := range redactedHeaders {
+		if redacted.Header.Get(key) != "" {
+			redacted.Header.Set(key, "**REDACTED**")
+		}
+	}
+	return &redacted
+}
+
+// RotatingFile - an io.WriteCloser writing to a file which is rotated
+// when it would grow beyond a size, path.1 keeps the previous content,
+// path.2 the one before and so on. Safe for concurrent use.
+type RotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	// mutex protects the fields below.
+	mutex sync.Mutex
+	file  *os.File
+	size  int64
+}
+
+// NewRotatingFile - opens path for appending, rotated after maxSize
+// bytes with at most maxFiles files kept, path included.
+func NewRotatingFile(path string, maxSize int64, maxFiles int) (*RotatingFile, error) {
+	if maxSize <= 0 {
+		return nil, ErrInvalidArgument("Maximum file size must be positive.")
+	}
+	if maxFiles < 1 {
+		return nil, ErrInvalidArgument("At least one file must be kept.")
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &RotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles, file: file, size: fi.Size()}, nil
+}
+
+// Write - implements io.Writer, p is never split across files.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate - shifts the kept files by one and starts an empty path.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	for i := f.maxFiles - 1; i > 0; i-- {
+		from := f.path
+		if i > 1 {
+			from += "." + strconv.Itoa(i-1)
+		}
+		if err := os.Rename(from, f.path+"."+strconv.Itoa(i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	f.file, f.size = file, 0
+	return nil
+}
+
+// Close - closes the current file.
+func (f *RotatingFile) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// DebugOn - traces all requests with DumpTrace to path, rotated after
+// maxSize bytes with at most maxFiles files kept. Signatures, credentials
+// and encryption keys are redacted, from headers as well as from the
+// query of presigned URLs, so the files can be shared when debugging
+// signature mismatches in the field. Close the returned file after
+// TraceOff.
+func (c *Client) DebugOn(path string, maxSize int64, maxFiles int) (io.Closer, error) {
+	file, err := NewRotatingFile(path, maxSize, maxFiles)
+	if err != nil {
+		return nil, err
+	}
+	c.SetTraceFormatter(DumpTrace)
+	c.TraceOn(file)
+	return file, nil
+}
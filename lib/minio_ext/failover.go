@@ -0,0 +1,142 @@
+package minio_ext
+
+import (
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// failoverCooldown - how long an endpoint which failed is skipped
+// while another endpoint is healthy.
+const failoverCooldown = 30 * time.Second
+
+// EndpointHealth - health of an endpoint of a client with failover
+// endpoints, see SetFailoverEndpoints.
+type EndpointHealth struct {
+	// Host, with optional port, of the endpoint.
+	Endpoint string
+
+	// Whether requests are sent to the endpoint.
+	Current bool
+
+	// Whether the last request sent to the endpoint connected.
+	Healthy bool
+
+	// Connection errors in a row, the last one and when it happened.
+	Failures    int
+	LastError   error
+	LastFailure time.Time
+}
+
+// endpointFailover - endpoints of a client in preference order and the
+// one requests are sent to, sticky until it fails.
+type endpointFailover struct {
+	sync.Mutex
+	endpoints []EndpointHealth
+	current   int
+}
+
+// SetFailoverEndpoints - sends requests to the next of endpoints, hosts
+// with optional port like the endpoint of the client, when the endpoint
+// in use fails to connect. Requests stay with an endpoint as long as it
+// connects, an endpoint which failed is tried again after 30 seconds at
+// the earliest. Amazon S3 and Google Cloud Storage endpoints cannot fail
+// over.
+func (c *Client) SetFailoverEndpoints(endpoints ...string) error {
+	if s3utils.IsAmazonEndpoint(*c.endpointURL) || s3utils.IsGoogleEndpoint(*c.endpointURL) {
+		return ErrInvalidArgument("Amazon S3 and Google Cloud Storage endpoints cannot fail over.")
+	}
+	failover := &endpointFailover{
+		endpoints: []EndpointHealth{{Endpoint: c.endpointURL.Host, Healthy: true}},
+	}
+	for _, endpoint := range endpoints {
+		endpointURL, err := getEndpointURL(endpoint, c.secure)
+		if err != nil {
+			return err
+		}
+		failover.endpoints = append(failover.endpoints, EndpointHealth{Endpoint: endpointURL.Host, Healthy: true})
+	}
+	if len(endpoints) == 0 {
+		failover = nil
+	}
+	c.failover = failover
+	return nil
+}
+
+// EndpointHealth - returns the health of the endpoints of the client in
+// preference order, only the endpoint of the client without failover
+// endpoints.
+func (c Client) EndpointHealth() []EndpointHealth {
+	if c.failover == nil {
+		return []EndpointHealth{{Endpoint: c.endpointURL.Host, Current: true, Healthy: true}}
+	}
+	f := c.failover
+	f.Lock()
+	defer f.Unlock()
+	endpoints := make([]EndpointHealth, len(f.endpoints))
+	copy(endpoints, f.endpoints)
+	endpoints[f.current].Current = true
+	return endpoints
+}
+
+// endpointHost - returns the host requests are sent to.
+func (c Client) endpointHost() string {
+	if c.failover == nil {
+		return c.endpointURL.Host
+	}
+	c.failover.Lock()
+	defer c.failover.Unlock()
+	return c.failover.endpoints[c.failover.current].Endpoint
+}
+
+// active - returns the index of the endpoint requests are sent to, -1
+// without failover endpoints.
+func (f *endpointFailover) active() int {
+	if f == nil {
+		return -1
+	}
+	f.Lock()
+	defer f.Unlock()
+	return f.current
+}
+
+// succeeded - records that endpoint connected.
+func (f *endpointFailover) succeeded(endpoint int) {
+	if f == nil || endpoint < 0 {
+		return
+	}
+	f.Lock()
+	defer f.Unlock()
+	f.endpoints[endpoint].Healthy = true
+	f.endpoints[endpoint].Failures = 0
+}
+
+// failed - records that endpoint failed to connect with err and moves
+// on to the next endpoint not failed in the last failoverCooldown,
+// returns whether requests are sent to another endpoint now.
+func (f *endpointFailover) failed(endpoint int, err error) bool {
+	if f == nil || endpoint < 0 {
+		return false
+	}
+	f.Lock()
+	defer f.Unlock()
+	now := time.Now()
+	health := &f.endpoints[endpoint]
+	health.Healthy = false
+	health.Failures++
+	health.LastError = err
+	health.LastFailure = now
+	if endpoint != f.current {
+		// Another request failed over already.
+		return true
+	}
+	for i := 1; i < len(f.endpoints); i++ {
+		next := (endpoint + i) % len(f.endpoints)
+		if f.endpoints[next].Healthy || now.Sub(f.endpoints[next].LastFailure) >= failoverCooldown {
+			f.current = next
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package minio_ext
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// closedServerHost - returns the host of a server which is gone, dials
+// to it are refused.
+func closedServerHost(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
+
+func TestEndpointFailoverFailed(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	now := time.Now()
+	testCases := []struct {
+		name      string
+		endpoints []EndpointHealth
+		current   int
+		endpoint  int
+		switched  bool
+		next      int
+	}{
+		{"next healthy", []EndpointHealth{{Healthy: true}, {Healthy: true}}, 0, 0, true, 1},
+		{"wraps around", []EndpointHealth{{Healthy: true}, {Healthy: true}, {Healthy: true}}, 2, 2, true, 0},
+		{"skips cooling down", []EndpointHealth{{Healthy: true}, {LastFailure: now}, {Healthy: true}}, 0, 0, true, 2},
+		{"cooled down", []EndpointHealth{{Healthy: true}, {LastFailure: now.Add(-failoverCooldown)}}, 0, 0, true, 1},
+		{"all cooling down", []EndpointHealth{{Healthy: true}, {LastFailure: now}}, 0, 0, false, 0},
+		{"failed over already", []EndpointHealth{{Healthy: true}, {Healthy: true}}, 1, 0, true, 1},
+		{"without failover", nil, 0, -1, false, 0},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var f *endpointFailover
+			if testCase.endpoints != nil {
+				f = &endpointFailover{endpoints: testCase.endpoints, current: testCase.current}
+			}
+			if switched := f.failed(testCase.endpoint, errRefused); switched != testCase.switched {
+				t.Errorf("Expected failed over %v, got %v", testCase.switched, switched)
+			}
+			if f == nil {
+				return
+			}
+			if f.current != testCase.next {
+				t.Errorf("Expected endpoint %d, got %d", testCase.next, f.current)
+			}
+			health := f.endpoints[testCase.endpoint]
+			if health.Healthy || health.Failures != 1 || health.LastError != errRefused {
+				t.Errorf("Expected the failure recorded, got %+v", health)
+			}
+			f.succeeded(testCase.endpoint)
+			if health = f.endpoints[testCase.endpoint]; !health.Healthy || health.Failures != 0 {
+				t.Errorf("Expected the success recorded, got %+v", health)
+			}
+		})
+	}
+}
+
+func TestSetFailoverEndpoints(t *testing.T) {
+	testCases := []struct {
+		endpoint   string
+		failover   []string
+		shouldPass bool
+		endpoints  int
+	}{
+		{"localhost:9000", []string{"localhost:9001", "localhost:9002"}, true, 3},
+		{"localhost:9000", nil, true, 1},
+		{"localhost:9000", []string{"localhost:9001/path"}, false, 0},
+		{"s3.amazonaws.com", []string{"localhost:9001"}, false, 0},
+		{"storage.googleapis.com", []string{"localhost:9001"}, false, 0},
+	}
+	for i, testCase := range testCases {
+		c, err := NewStatic(testCase.endpoint, exampleAccessKey, exampleSecretKey, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.SetFailoverEndpoints(testCase.failover...)
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: expected to pass, failed with %v", i+1, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: expected to fail, passed", i+1)
+		}
+		if err != nil {
+			continue
+		}
+		health := c.EndpointHealth()
+		if len(health) != testCase.endpoints || health[0].Endpoint != testCase.endpoint || !health[0].Current {
+			t.Errorf("Test %d: unexpected endpoints %+v", i+1, health)
+		}
+	}
+}
+
+func TestExecuteMethodFailover(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		failover []string
+		// Whether the request succeeds and the endpoint used after it.
+		shouldPass bool
+		current    int
+	}{
+		{"fails over", []string{serverURL.Host}, true, 1},
+		{"all down", []string{closedServerHost(t)}, false, 1},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			c, err := NewStatic(closedServerHost(t), exampleAccessKey, exampleSecretKey, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = c.SetFailoverEndpoints(testCase.failover...); err != nil {
+				t.Fatal(err)
+			}
+			// The backoff is skipped when failing over.
+			c.SetRetryPolicy(RequestRetryPolicy{MaxAttempts: 2, Unit: time.Minute, Cap: time.Minute, Backoff: ConstantBackoff{}})
+			var events []RetryEvent
+			c.SetRetryHook(func(event RetryEvent) {
+				events = append(events, event)
+			})
+
+			start := time.Now()
+			res, err := c.executeMethod(context.Background(), "GET", requestMetadata{
+				bucketName:       "bucket",
+				objectName:       "object",
+				bucketLocation:   "us-east-1",
+				contentSHA256Hex: emptySHA256Hex,
+			})
+			closeResponse(res)
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("Expected no backoff, took %s", elapsed)
+			}
+			if err != nil && testCase.shouldPass {
+				t.Fatalf("Expected to pass, failed with %v", err)
+			}
+			if err == nil && !testCase.shouldPass {
+				t.Fatal("Expected to fail, passed")
+			}
+
+			health := c.EndpointHealth()
+			if !health[testCase.current].Current {
+				t.Errorf("Expected endpoint %d in use, got %+v", testCase.current, health)
+			}
+			if health[0].Healthy || health[0].Failures != 1 || health[0].LastError == nil {
+				t.Errorf("Expected the first endpoint failed, got %+v", health[0])
+			}
+			if health[1].Healthy != testCase.shouldPass {
+				t.Errorf("Expected the second endpoint healthy %v, got %+v", testCase.shouldPass, health[1])
+			}
+			if len(events) == 0 || !events[0].Retry || events[0].Wait != 0 {
+				t.Errorf("Expected an immediate retry, got %+v", events)
+			}
+		})
+	}
+}
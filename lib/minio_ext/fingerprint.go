@@ -0,0 +1,121 @@
+package minio_ext
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// amzMetaFingerprint - user metadata header carrying the fingerprint
+// of uploaded objects.
+const amzMetaFingerprint = "X-Amz-Meta-Fingerprint"
+
+// amzMetaFingerprintObject - user metadata header of a fingerprint
+// marker carrying the key of the object with the fingerprint.
+const amzMetaFingerprintObject = "X-Amz-Meta-Fingerprint-Object"
+
+// FingerprintPrefix - prefix of the empty marker objects indexing the
+// objects of a bucket by fingerprint, so uploads of the same content
+// to another key are skipped as well. Markers are keyed by the sha256
+// and size of the content and name the key of the object.
+const FingerprintPrefix = ".fingerprints/"
+
+// FileFingerprint - identifies the content of a file by its size, md5
+// and sha256 sums.
+type FileFingerprint struct {
+	Size   int64
+	MD5    string // hex encoded md5sum
+	SHA256 string // hex encoded sha256sum
+}
+
+// String - returns the fingerprint as stored in object metadata.
+func (f FileFingerprint) String() string {
+	return fmt.Sprintf("%d-%s-%s", f.Size, f.MD5, f.SHA256)
+}
+
+// Fingerprint - computes the fingerprint of all data read from reader,
+// md5 and sha256 are calculated in a single streaming pass.
+func Fingerprint(reader io.Reader) (FileFingerprint, error) {
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), reader)
+	if err != nil {
+		return FileFingerprint{}, err
+	}
+	return FileFingerprint{
+		Size:   size,
+		MD5:    hex.EncodeToString(md5Hash.Sum(nil)),
+		SHA256: hex.EncodeToString(sha256Hash.Sum(nil)),
+	}, nil
+}
+
+// markerName - returns the key of the fingerprint marker of f.
+func (f FileFingerprint) markerName() string {
+	return FingerprintPrefix + f.SHA256 + "-" + strconv.FormatInt(f.Size, 10)
+}
+
+// findFingerprint - returns the object info of bucketName/objectName
+// when it exists with fingerprint fp, otherwise of the object of the
+// bucket the fingerprint marker of fp names, if it still has fp. Nil
+// when no object has the fingerprint.
+func (c *Client) findFingerprint(ctx context.Context, bucketName, objectName string, fp FileFingerprint) (*ObjectInfo, error) {
+	objInfo, err := c.statFingerprint(ctx, bucketName, objectName, fp)
+	if objInfo != nil || err != nil {
+		return objInfo, err
+	}
+	marker, err := c.statObject(ctx, bucketName, fp.markerName(), nil)
+	if err != nil {
+		if ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	indexed := marker.Metadata.Get(amzMetaFingerprintObject)
+	if indexed == "" || indexed == objectName {
+		return nil, nil
+	}
+	// A stale marker is overwritten by the next upload of the content.
+	return c.statFingerprint(ctx, bucketName, indexed, fp)
+}
+
+// statFingerprint - returns the object info of bucketName/objectName
+// when it exists with fingerprint fp, nil otherwise.
+func (c *Client) statFingerprint(ctx context.Context, bucketName, objectName string, fp FileFingerprint) (*ObjectInfo, error) {
+	objInfo, err := c.statObject(ctx, bucketName, objectName, nil)
+	if err != nil {
+		if ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if objInfo.Size != fp.Size || objInfo.UserMetadata["Fingerprint"] != fp.String() {
+		return nil, nil
+	}
+	return &objInfo, nil
+}
+
+// recordFingerprint - writes the fingerprint marker of fp naming
+// bucketName/objectName, replacing the marker of an earlier object.
+func (c *Client) recordFingerprint(ctx context.Context, bucketName, objectName string, fp FileFingerprint) error {
+	customHeader := make(http.Header)
+	customHeader.Set(amzMetaFingerprintObject, objectName)
+	resp, err := c.executeMethod(ctx, "PUT", requestMetadata{
+		bucketName:       bucketName,
+		objectName:       fp.markerName(),
+		customHeader:     customHeader,
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return httpRespToErrorResponse(resp, bucketName, fp.markerName())
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package minio_ext
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// hedgeSamples - number of recent latencies the hedge delay is computed
+// from, it is MinDelay until half of them are known.
+const hedgeSamples = 128
+
+// HedgePolicy - sends a second, hedge, request when a small idempotent
+// request, a HEAD request, a bucket location lookup or a part listing,
+// did not answer within a percentile of the recent latencies, and uses
+// whichever answers first. Cuts the long tail of flaky networks at the
+// price of a few more requests.
+type HedgePolicy struct {
+	// Percentile of the latencies after which the hedge is sent, for
+	// example 0.95, 0 disables hedging.
+	Percentile float64
+
+	// Shortest wait before the hedge, also used until enough latencies
+	// are known.
+	MinDelay time.Duration
+}
+
+// hedger - the hedge policy of a client and the recent latencies of
+// its hedgeable requests.
+type hedger struct {
+	policy HedgePolicy
+
+	// mutex protects the ring of latencies.
+	mutex     sync.Mutex
+	latencies [hedgeSamples]time.Duration
+	count     int
+}
+
+// SetHedging - sends hedge requests under policy, see HedgePolicy.
+func (c *Client) SetHedging(policy HedgePolicy) {
+	if policy.Percentile <= 0 {
+		c.hedger = nil
+		return
+	}
+	if policy.Percentile > 1 {
+		policy.Percentile = 1
+	}
+	c.hedger = &hedger{policy: policy}
+}
+
+// observe - records the latency of a hedgeable request.
+func (h *hedger) observe(latency time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.latencies[h.count%hedgeSamples] = latency
+	h.count++
+}
+
+// delay - returns the wait before the hedge request.
+func (h *hedger) delay() time.Duration {
+	h.mutex.Lock()
+	n := h.count
+	if n > hedgeSamples {
+		n = hedgeSamples
+	}
+	latencies := make([]time.Duration, n)
+	copy(latencies, h.latencies[:n])
+	h.mutex.Unlock()
+
+	if n < hedgeSamples/2 {
+		return h.policy.MinDelay
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	delay := latencies[int(float64(n-1)*h.policy.Percentile)]
+	if delay < h.policy.MinDelay {
+		delay = h.policy.MinDelay
+	}
+	return delay
+}
+
+// cancelOnClose - response body cancelling the context of its request
+// when closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close - closes the body and cancels the request.
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// doHedged - executes req like do, with a hedge request under the hedge
+// policy of c. req must not have a body.
+func (c Client) doHedged(req *http.Request) (*http.Response, error) {
+	h := c.hedger
+	if h == nil || (req.Body != nil && req.Body != http.NoBody) {
+		return c.do(req)
+	}
+
+	type result struct {
+		resp  *http.Response
+		err   error
+		index int
+	}
+	results := make(chan result, 2)
+	var cancels []context.CancelFunc
+	send := func() {
+		ctx, cancel := context.WithCancel(req.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			start := time.Now()
+			resp, err := c.do(req.Clone(ctx))
+			if err != nil {
+				cancel()
+			} else {
+				h.observe(time.Since(start))
+				resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			}
+			results <- result{resp, err, index}
+		}()
+	}
+
+	send()
+	timer := time.NewTimer(h.delay())
+	defer timer.Stop()
+
+	pending := 1
+	var r result
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			send()
+			pending++
+			continue
+		case r = <-results:
+			pending--
+		}
+		if r.err == nil {
+			break
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// Abort the slower request, the winner is cancelled when its body
+	// is closed.
+	for i, cancel := range cancels {
+		if i != r.index {
+			cancel()
+		}
+	}
+	for i := 0; i < pending; i++ {
+		go func() {
+			if loser := <-results; loser.err == nil {
+				closeResponse(loser.resp)
+			}
+		}()
+	}
+	return r.resp, nil
+}
@@ -0,0 +1,161 @@
+package minio_ext
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgerDelay(t *testing.T) {
+	testCases := []struct {
+		policy  HedgePolicy
+		samples int
+		delay   time.Duration
+	}{
+		// MinDelay until half of the samples are known.
+		{HedgePolicy{Percentile: 0.5, MinDelay: 5 * time.Millisecond}, 0, 5 * time.Millisecond},
+		{HedgePolicy{Percentile: 0.5, MinDelay: 5 * time.Millisecond}, hedgeSamples/2 - 1, 5 * time.Millisecond},
+		{HedgePolicy{Percentile: 0.5, MinDelay: 5 * time.Millisecond}, hedgeSamples / 2, 32 * time.Millisecond},
+		{HedgePolicy{Percentile: 0.5}, hedgeSamples, 64 * time.Millisecond},
+		{HedgePolicy{Percentile: 1}, hedgeSamples, 128 * time.Millisecond},
+		// Only the recent samples count.
+		{HedgePolicy{Percentile: 1}, 2 * hedgeSamples, 256 * time.Millisecond},
+		{HedgePolicy{Percentile: 0.5, MinDelay: time.Second}, hedgeSamples, time.Second},
+	}
+	for i, testCase := range testCases {
+		h := &hedger{policy: testCase.policy}
+		for j := 1; j <= testCase.samples; j++ {
+			h.observe(time.Duration(j) * time.Millisecond)
+		}
+		if delay := h.delay(); delay != testCase.delay {
+			t.Errorf("Test %d: expected delay %s, got %s", i+1, testCase.delay, delay)
+		}
+	}
+}
+
+func TestSetHedging(t *testing.T) {
+	testCases := []struct {
+		policy     HedgePolicy
+		enabled    bool
+		percentile float64
+	}{
+		{HedgePolicy{}, false, 0},
+		{HedgePolicy{Percentile: -1}, false, 0},
+		{HedgePolicy{Percentile: 0.95}, true, 0.95},
+		{HedgePolicy{Percentile: 2}, true, 1},
+	}
+	for i, testCase := range testCases {
+		c := &Client{}
+		c.SetHedging(testCase.policy)
+		if (c.hedger != nil) != testCase.enabled {
+			t.Errorf("Test %d: expected hedging %v", i+1, testCase.enabled)
+			continue
+		}
+		if c.hedger != nil && c.hedger.policy.Percentile != testCase.percentile {
+			t.Errorf("Test %d: expected percentile %v, got %v", i+1, testCase.percentile, c.hedger.policy.Percentile)
+		}
+	}
+}
+
+func TestDoHedged(t *testing.T) {
+	testCases := []struct {
+		name string
+		// Whether the client hedges, whether the request has a body and
+		// how long the first request takes to answer.
+		hedging bool
+		body    bool
+		first   time.Duration
+		// Requests received and the answer used.
+		requests int32
+		answer   string
+	}{
+		{"fast", true, false, 0, 1, "1"},
+		{"slow", true, false, time.Minute, 2, "2"},
+		{"disabled", false, false, 50 * time.Millisecond, 1, "1"},
+		{"body", true, true, 50 * time.Millisecond, 1, "1"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var requests int32
+			cancelled := make(chan struct{}, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&requests, 1)
+				if n == 1 && testCase.first > 0 {
+					select {
+					case <-time.After(testCase.first):
+					case <-r.Context().Done():
+						// The hedge answered first.
+						cancelled <- struct{}{}
+						return
+					}
+				}
+				w.Write([]byte{byte('0' + n)})
+			}))
+			defer server.Close()
+			c := newTestClient(t, server.URL)
+			if testCase.hedging {
+				c.SetHedging(HedgePolicy{Percentile: 0.95, MinDelay: 20 * time.Millisecond})
+			}
+
+			var req *http.Request
+			var err error
+			if testCase.body {
+				req, err = http.NewRequest("PUT", server.URL, strings.NewReader("body"))
+			} else {
+				req, err = http.NewRequest("GET", server.URL, nil)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Now()
+			resp, err := c.doHedged(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			answer, err := ioutil.ReadAll(resp.Body)
+			closeResponse(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(answer) != testCase.answer {
+				t.Errorf("Expected answer %s, got %s", testCase.answer, answer)
+			}
+			if n := atomic.LoadInt32(&requests); n != testCase.requests {
+				t.Errorf("Expected %d requests, got %d", testCase.requests, n)
+			}
+			if testCase.first == time.Minute {
+				if elapsed := time.Since(start); elapsed > 10*time.Second {
+					t.Errorf("Expected the hedge to answer, took %s", elapsed)
+				}
+				// The slower request is aborted.
+				select {
+				case <-cancelled:
+				case <-time.After(10 * time.Second):
+					t.Error("Expected the first request to be cancelled")
+				}
+			}
+		})
+	}
+}
+
+func TestDoHedgedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serverURL := server.URL
+	server.Close()
+
+	// Both requests fail, the error is returned.
+	c := newTestClient(t, serverURL)
+	c.SetHedging(HedgePolicy{Percentile: 0.95, MinDelay: time.Millisecond})
+	req, err := http.NewRequest("GET", serverURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := c.doHedged(req); err == nil {
+		closeResponse(resp)
+		t.Fatal("Expected the request to fail")
+	}
+}
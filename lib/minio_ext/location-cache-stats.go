@@ -0,0 +1,72 @@
+package minio_ext
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// defaultBucketLocationNegativeTTL - how long failed bucket location
+// lookups are cached by default.
+const defaultBucketLocationNegativeTTL = 10 * time.Second
+
+// LocationCacheStats - counters of the bucket location cache of a
+// client, for monitoring.
+type LocationCacheStats struct {
+	// Lookups answered from memory.
+	Hits uint64
+
+	// Lookups asking the location store or the server.
+	Misses uint64
+
+	// Lookups answered with the cached NoSuchBucket error of a failed
+	// lookup.
+	NegativeHits uint64
+}
+
+// LocationCacheStats - returns the counters of the bucket location
+// cache since the client was created.
+func (c *Client) LocationCacheStats() LocationCacheStats {
+	return LocationCacheStats{
+		Hits:         atomic.LoadUint64(&c.bucketLocCache.hits),
+		Misses:       atomic.LoadUint64(&c.bucketLocCache.misses),
+		NegativeHits: atomic.LoadUint64(&c.bucketLocCache.negativeHits),
+	}
+}
+
+// SetBucketLocationNegativeTTL - caches bucket location lookups of
+// buckets which do not exist for ttl, ten seconds by default, not at
+// all when 0. Presigning for a missing bucket then fails without
+// asking the server every time.
+func (c *Client) SetBucketLocationNegativeTTL(ttl time.Duration) {
+	c.bucketLocCache.Lock()
+	defer c.bucketLocCache.Unlock()
+	c.bucketLocCache.negativeTTL = ttl
+}
+
+// failed - returns the cached error of a failed lookup of bucketName,
+// nil when there is none.
+func (r *bucketLocationCache) failed(bucketName string) error {
+	r.RLock()
+	defer r.RUnlock()
+	entry, ok := r.items[bucketName]
+	if !ok || entry.err == nil || time.Now().After(entry.expires) {
+		return nil
+	}
+	return entry.err
+}
+
+// setFailed - caches err of a failed lookup of bucketName when the
+// server answered that the bucket does not exist. Other errors, like
+// SlowDown or AccessDenied, may be gone with the next lookup and are
+// not cached.
+func (r *bucketLocationCache) setFailed(bucketName string, err error) {
+	if errResp, ok := err.(ErrorResponse); !ok || errResp.Code != "NoSuchBucket" {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	if r.negativeTTL <= 0 {
+		return
+	}
+	r.items[bucketName] = bucketLocationEntry{err: err, expires: time.Now().Add(r.negativeTTL)}
+}
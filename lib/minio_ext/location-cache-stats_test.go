@@ -0,0 +1,100 @@
+package minio_ext
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBucketLocationNegativeCache(t *testing.T) {
+	testCases := []struct {
+		name        string
+		response    testResponse
+		negativeTTL time.Duration
+		wait        time.Duration
+		// Requests to the server for two lookups and the stats after
+		// them.
+		requests int32
+		stats    LocationCacheStats
+		code     string
+	}{
+		{"location", testResponse{status: http.StatusOK}, time.Minute, 0, 1, LocationCacheStats{Hits: 1, Misses: 1}, ""},
+		{"no such bucket", testResponse{http.StatusNotFound, "NoSuchBucket", ""}, time.Minute, 0, 1, LocationCacheStats{Misses: 1, NegativeHits: 1}, "NoSuchBucket"},
+		{"negative expired", testResponse{http.StatusNotFound, "NoSuchBucket", ""}, 10 * time.Millisecond, 20 * time.Millisecond, 2, LocationCacheStats{Misses: 2}, "NoSuchBucket"},
+		{"negative disabled", testResponse{http.StatusNotFound, "NoSuchBucket", ""}, 0, 0, 2, LocationCacheStats{Misses: 2}, "NoSuchBucket"},
+		{"slow down", testResponse{http.StatusServiceUnavailable, "SlowDown", ""}, time.Minute, 0, 2, LocationCacheStats{Misses: 2}, "SlowDown"},
+		{"internal error", testResponse{http.StatusInternalServerError, "InternalError", ""}, time.Minute, 0, 2, LocationCacheStats{Misses: 2}, "InternalError"},
+		{"forbidden", testResponse{http.StatusForbidden, "SignatureDoesNotMatch", ""}, time.Minute, 0, 2, LocationCacheStats{Misses: 2}, "SignatureDoesNotMatch"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				if _, ok := r.URL.Query()["location"]; !ok {
+					t.Errorf("Unexpected request %s", r.URL)
+				}
+				if testCase.response.code == "" {
+					w.Write([]byte("<LocationConstraint>eu-west-1</LocationConstraint>"))
+					return
+				}
+				testCase.response.write(w)
+			}))
+			defer server.Close()
+			c := newTestClient(t, server.URL)
+			c.SetBucketLocationNegativeTTL(testCase.negativeTTL)
+
+			for i := 0; i < 2; i++ {
+				if i > 0 {
+					time.Sleep(testCase.wait)
+				}
+				location, err := c.getBucketLocation(context.Background(), "bucket")
+				if code := ToErrorResponse(err).Code; code != testCase.code {
+					t.Errorf("Lookup %d: expected code %q, got %v", i+1, testCase.code, err)
+				}
+				if err == nil && location != "eu-west-1" {
+					t.Errorf("Lookup %d: expected location eu-west-1, got %s", i+1, location)
+				}
+			}
+			if n := atomic.LoadInt32(&requests); n != testCase.requests {
+				t.Errorf("Expected %d requests, got %d", testCase.requests, n)
+			}
+			if stats := c.LocationCacheStats(); stats != testCase.stats {
+				t.Errorf("Expected stats %+v, got %+v", testCase.stats, stats)
+			}
+			if _, ok := c.ExportBucketLocations()["bucket"]; ok != (testCase.code == "") {
+				t.Errorf("Expected exported location %v", testCase.code == "")
+			}
+		})
+	}
+}
+
+func TestBucketLocationNegativeCacheInvalidate(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			testResponse{http.StatusNotFound, "NoSuchBucket", ""}.write(w)
+			return
+		}
+		w.Write([]byte("<LocationConstraint></LocationConstraint>"))
+	}))
+	defer server.Close()
+	c := newTestClient(t, server.URL)
+
+	// The bucket is created after a failed lookup, invalidating its
+	// location forgets the failure.
+	if _, err := c.getBucketLocation(context.Background(), "bucket"); ToErrorResponse(err).Code != "NoSuchBucket" {
+		t.Fatalf("Expected NoSuchBucket, got %v", err)
+	}
+	c.InvalidateBucketLocation("bucket")
+	location, err := c.getBucketLocation(context.Background(), "bucket")
+	if err != nil || location != "us-east-1" {
+		t.Fatalf("Expected location us-east-1, got %s, %v", location, err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Expected 2 requests, got %d", n)
+	}
+}
@@ -0,0 +1,194 @@
+package minio_ext
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LocationStore - persists bucket locations beyond the memory cache of
+// a client, for example in a file or in Redis, so processes sharing it
+// do not look every bucket up again on startup. Locations are a cache,
+// errors of the store are ignored. Implementations must be safe for
+// concurrent use.
+type LocationStore interface {
+	// Load returns the location of bucketName, ok is false when it is
+	// unknown or expired.
+	Load(bucketName string) (location string, ok bool, err error)
+
+	// Save stores the location of bucketName for ttl, forever when 0.
+	Save(bucketName, location string, ttl time.Duration) error
+
+	// Delete removes the location of bucketName, if any.
+	Delete(bucketName string) error
+}
+
+// SetLocationStore - persists the bucket locations looked up in store
+// and looks locations up in store before asking the server. Locations
+// are kept in memory as well, for the bucket location TTL.
+func (c *Client) SetLocationStore(store LocationStore) {
+	c.bucketLocCache.Lock()
+	defer c.bucketLocCache.Unlock()
+	c.bucketLocCache.store = store
+}
+
+// loadOrFetch - returns the location of bucketName from the store, or
+// from fetch saving it to the store, and caches it in memory.
+func (r *bucketLocationCache) loadOrFetch(bucketName string, fetch func() (string, error)) (string, error) {
+	r.RLock()
+	store := r.store
+	r.RUnlock()
+	if store != nil {
+		if location, ok, err := store.Load(bucketName); err == nil && ok {
+			r.Set(bucketName, location)
+			return location, nil
+		}
+	}
+
+	location, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	r.Save(bucketName, location)
+	return location, nil
+}
+
+// Save - Caches the location of a bucket and saves it to the location
+// store.
+func (r *bucketLocationCache) Save(bucketName, location string) {
+	r.Set(bucketName, location)
+	r.RLock()
+	store, ttl := r.store, r.ttl
+	r.RUnlock()
+	if store != nil {
+		store.Save(bucketName, location, ttl)
+	}
+}
+
+// fileLocation - a location in the file of a FileLocationStore.
+type fileLocation struct {
+	Location string    `json:"location"`
+	Expires  time.Time `json:"expires"`
+}
+
+// FileLocationStore - LocationStore keeping all locations in one JSON
+// file, shared by the processes of a host. The file is read on every
+// Load and rewritten on every change, concurrent writers of other
+// processes may drop each other's changes, those locations are looked
+// up again.
+type FileLocationStore struct {
+	path string
+
+	// mutex serializes the changes of this process.
+	mutex sync.Mutex
+}
+
+// NewFileLocationStore - returns a location store in the file path, the
+// directory of path is created when missing.
+func NewFileLocationStore(path string) (*FileLocationStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	return &FileLocationStore{path: path}, nil
+}
+
+// read - returns the locations of the file, none when it is missing.
+func (s *FileLocationStore) read() (map[string]fileLocation, error) {
+	locations := make(map[string]fileLocation)
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return locations, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &locations); err != nil {
+		// A corrupt cache is started over.
+		return make(map[string]fileLocation), nil
+	}
+	return locations, nil
+}
+
+// update - applies change to the locations of the file, dropping the
+// expired ones.
+func (s *FileLocationStore) update(change func(map[string]fileLocation)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	locations, err := s.read()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for bucketName, location := range locations {
+		if !location.Expires.IsZero() && now.After(location.Expires) {
+			delete(locations, bucketName)
+		}
+	}
+	change(locations)
+	data, err := json.Marshal(locations)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// Load - implements LocationStore.
+func (s *FileLocationStore) Load(bucketName string) (string, bool, error) {
+	locations, err := s.read()
+	if err != nil {
+		return "", false, err
+	}
+	location, ok := locations[bucketName]
+	if !ok || (!location.Expires.IsZero() && time.Now().After(location.Expires)) {
+		return "", false, nil
+	}
+	return location.Location, true, nil
+}
+
+// Save - implements LocationStore.
+func (s *FileLocationStore) Save(bucketName, location string, ttl time.Duration) error {
+	entry := fileLocation{Location: location}
+	if ttl > 0 {
+		entry.Expires = time.Now().Add(ttl)
+	}
+	return s.update(func(locations map[string]fileLocation) {
+		locations[bucketName] = entry
+	})
+}
+
+// Delete - implements LocationStore.
+func (s *FileLocationStore) Delete(bucketName string) error {
+	return s.update(func(locations map[string]fileLocation) {
+		delete(locations, bucketName)
+	})
+}
+
+// PreloadBucketLocations - caches locations, bucket name to location,
+// for example a snapshot of ExportBucketLocations taken at shutdown, so
+// a fixed set of buckets is not looked up after boot. Preloaded
+// locations expire with the bucket location TTL like looked up ones.
+func (c *Client) PreloadBucketLocations(locations map[string]string) {
+	for bucketName, location := range locations {
+		c.bucketLocCache.Set(bucketName, location)
+	}
+}
+
+// ExportBucketLocations - returns the cached locations which did not
+// expire, bucket name to location.
+func (c *Client) ExportBucketLocations() map[string]string {
+	r := c.bucketLocCache
+	r.RLock()
+	defer r.RUnlock()
+	now := time.Now()
+	locations := make(map[string]string, len(r.items))
+	for bucketName, entry := range r.items {
+		if entry.err != nil || (!entry.expires.IsZero() && now.After(entry.expires)) {
+			continue
+		}
+		locations[bucketName] = entry.location
+	}
+	return locations
+}
@@ -0,0 +1,178 @@
+package minio_ext
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// prometheusLatencyBuckets - upper bounds in seconds of the buckets of
+// the request latency histogram.
+var prometheusLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+// latencyHistogram - a histogram of request latencies.
+type latencyHistogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// PrometheusMetrics - Metrics kept in memory and served in the
+// Prometheus text format, mount it on the metrics endpoint:
+//
+//	metrics := minio_ext.NewPrometheusMetrics("minio_client")
+//	http.Handle("/metrics", metrics)
+//	client, err := minio_ext.New(endpoint, minio_ext.WithMetrics(metrics))
+type PrometheusMetrics struct {
+	namespace string
+
+	// mutex protects the series below.
+	mutex          sync.Mutex
+	requests       map[[2]string]uint64
+	latencies      map[string]*latencyHistogram
+	retries        map[[2]string]uint64
+	presigns       map[string]uint64
+	uploadedBytes  int64
+	activeSessions int64
+}
+
+// NewPrometheusMetrics - returns empty metrics whose names start with
+// namespace and an underscore.
+func NewPrometheusMetrics(namespace string) *PrometheusMetrics {
+	return &PrometheusMetrics{
+		namespace: namespace,
+		requests:  make(map[[2]string]uint64),
+		latencies: make(map[string]*latencyHistogram),
+		retries:   make(map[[2]string]uint64),
+		presigns:  make(map[string]uint64),
+	}
+}
+
+// ObserveRequest - implements Metrics.
+func (m *PrometheusMetrics) ObserveRequest(operation string, status int, latency time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.requests[[2]string{operation, strconv.Itoa(status)}]++
+	h, ok := m.latencies[operation]
+	if !ok {
+		h = &latencyHistogram{counts: make([]uint64, len(prometheusLatencyBuckets))}
+		m.latencies[operation] = h
+	}
+	seconds := latency.Seconds()
+	for i, bound := range prometheusLatencyBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// ObserveRetry - implements Metrics.
+func (m *PrometheusMetrics) ObserveRetry(operation, code string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.retries[[2]string{operation, code}]++
+}
+
+// AddUploadedBytes - implements Metrics.
+func (m *PrometheusMetrics) AddUploadedBytes(n int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.uploadedBytes += n
+}
+
+// AddActiveSessions - implements Metrics.
+func (m *PrometheusMetrics) AddActiveSessions(delta int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.activeSessions += int64(delta)
+}
+
+// ObservePresign - implements Metrics.
+func (m *PrometheusMetrics) ObservePresign(operation string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.presigns[operation]++
+}
+
+// ServeHTTP - writes all metrics in the Prometheus text format.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	name := m.namespace + "_requests_total"
+	fmt.Fprintf(out, "# HELP %s Requests sent, by operation and HTTP status, 0 without response.\n# TYPE %s counter\n", name, name)
+	for _, key := range sortedPairs(m.requests) {
+		fmt.Fprintf(out, "%s{operation=%s,status=%s} %d\n", name, promLabel(key[0]), promLabel(key[1]), m.requests[key])
+	}
+
+	name = m.namespace + "_request_duration_seconds"
+	fmt.Fprintf(out, "# HELP %s Latency of the requests, by operation.\n# TYPE %s histogram\n", name, name)
+	operations := make([]string, 0, len(m.latencies))
+	for operation := range m.latencies {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+	for _, operation := range operations {
+		h := m.latencies[operation]
+		for i, bound := range prometheusLatencyBuckets {
+			fmt.Fprintf(out, "%s_bucket{operation=%s,le=\"%s\"} %d\n", name, promLabel(operation), strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(out, "%s_bucket{operation=%s,le=\"+Inf\"} %d\n", name, promLabel(operation), h.count)
+		fmt.Fprintf(out, "%s_sum{operation=%s} %g\n", name, promLabel(operation), h.sum)
+		fmt.Fprintf(out, "%s_count{operation=%s} %d\n", name, promLabel(operation), h.count)
+	}
+
+	name = m.namespace + "_retries_total"
+	fmt.Fprintf(out, "# HELP %s Retried attempts, by operation and S3 error code, empty for network errors.\n# TYPE %s counter\n", name, name)
+	for _, key := range sortedPairs(m.retries) {
+		fmt.Fprintf(out, "%s{operation=%s,code=%s} %d\n", name, promLabel(key[0]), promLabel(key[1]), m.retries[key])
+	}
+
+	name = m.namespace + "_uploaded_bytes_total"
+	fmt.Fprintf(out, "# HELP %s Payload bytes of successful upload requests.\n# TYPE %s counter\n%s %d\n", name, name, name, m.uploadedBytes)
+
+	name = m.namespace + "_active_sessions"
+	fmt.Fprintf(out, "# HELP %s Upload sessions, download sessions and upload streams transferring.\n# TYPE %s gauge\n%s %d\n", name, name, name, m.activeSessions)
+
+	name = m.namespace + "_presigns_total"
+	fmt.Fprintf(out, "# HELP %s Presigned URLs and POST policies issued, by operation.\n# TYPE %s counter\n", name, name)
+	presigned := make([]string, 0, len(m.presigns))
+	for operation := range m.presigns {
+		presigned = append(presigned, operation)
+	}
+	sort.Strings(presigned)
+	for _, operation := range presigned {
+		fmt.Fprintf(out, "%s{operation=%s} %d\n", name, promLabel(operation), m.presigns[operation])
+	}
+}
+
+// sortedPairs - returns the keys of series sorted.
+func sortedPairs(series map[[2]string]uint64) [][2]string {
+	keys := make([][2]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
+
+// promLabel - returns value quoted as a Prometheus label value.
+func promLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
@@ -0,0 +1,200 @@
+package minio_ext
+
+import (
+	"net/http"
+	"time"
+)
+
+// Metrics - receives the measurements of a client, adapters forward
+// them to Prometheus, see PrometheusMetrics, StatsD or OpenTelemetry.
+// Operations are S3 API names like "PutObject" or "UploadPart".
+// Implementations must be safe for concurrent use and must not block.
+type Metrics interface {
+	// ObserveRequest is called after every attempt of a request with
+	// its HTTP status, 0 when there is no response, and its latency.
+	ObserveRequest(operation string, status int, latency time.Duration)
+
+	// ObserveRetry is called for every retried attempt with its S3
+	// error code, empty for network errors.
+	ObserveRetry(operation, code string)
+
+	// AddUploadedBytes is called with the payload of every successful
+	// upload request.
+	AddUploadedBytes(n int64)
+
+	// AddActiveSessions is called with 1 when an upload session, a
+	// download session or an upload stream starts transferring and
+	// with -1 when it stops.
+	AddActiveSessions(delta int)
+
+	// ObservePresign is called for every presigned URL or POST policy
+	// issued.
+	ObservePresign(operation string)
+}
+
+// SetMetrics - reports the measurements of the client to metrics, none
+// when nil.
+func (c *Client) SetMetrics(metrics Metrics) {
+	c.metrics = metrics
+}
+
+// observeRequest - reports an attempt of a request with metadata which
+// answered res, nil when it failed, after latency.
+func (c Client) observeRequest(method string, metadata requestMetadata, res *http.Response, latency time.Duration) {
+	status := 0
+	if res != nil {
+		status = res.StatusCode
+	}
+	succeeded := status >= 200 && status < 300
+	uploaded := succeeded && (method == "PUT" || method == "POST") && metadata.contentLength > 0
+
+	operation := operationName(method, metadata)
+	var bytes int64
+	if uploaded {
+		bytes = metadata.contentLength
+	} else if succeeded && method == "GET" && res.ContentLength > 0 {
+		bytes = res.ContentLength
+	}
+	c.operationStats.observe(operation, status == 0 || status >= 400, latency, bytes)
+
+	if c.metrics == nil {
+		return
+	}
+	c.metrics.ObserveRequest(operation, status, latency)
+	if uploaded {
+		c.metrics.AddUploadedBytes(metadata.contentLength)
+	}
+}
+
+// observeRetry - reports a retried attempt of a request with metadata.
+func (c Client) observeRetry(method string, metadata requestMetadata, code string) {
+	if c.metrics != nil {
+		c.metrics.ObserveRetry(operationName(method, metadata), code)
+	}
+}
+
+// observePresign - reports an issued presigned URL or POST policy which
+// took latency, its bucket location lookup included.
+func (c Client) observePresign(operation string, latency time.Duration) {
+	c.operationStats.observe("Presign"+operation, false, latency, 0)
+	if c.metrics != nil {
+		c.metrics.ObservePresign(operation)
+	}
+}
+
+// trackSession - reports a transferring session, the returned function
+// reports its end.
+func (c Client) trackSession() func() {
+	if c.metrics == nil {
+		return func() {}
+	}
+	c.metrics.AddActiveSessions(1)
+	return func() {
+		c.metrics.AddActiveSessions(-1)
+	}
+}
+
+// operationName - returns the S3 API name of a request with metadata.
+func operationName(method string, metadata requestMetadata) string {
+	q := metadata.queryValues
+	has := func(key string) bool {
+		_, ok := q[key]
+		return ok
+	}
+	copySource := metadata.customHeader.Get("X-Amz-Copy-Source") != ""
+
+	switch {
+	case metadata.bucketName == "":
+		return "ListBuckets"
+	case has("location"):
+		return "GetBucketLocation"
+	case has("cors"):
+		switch method {
+		case "GET":
+			return "GetBucketCors"
+		case "PUT":
+			return "PutBucketCors"
+		case "DELETE":
+			return "DeleteBucketCors"
+		}
+	case has("lifecycle"):
+		switch method {
+		case "GET":
+			return "GetBucketLifecycle"
+		case "PUT":
+			return "PutBucketLifecycle"
+		case "DELETE":
+			return "DeleteBucketLifecycle"
+		}
+	case metadata.objectName == "":
+		switch method {
+		case "GET":
+			if has("uploads") {
+				return "ListMultipartUploads"
+			}
+			return "ListObjects"
+		case "PUT":
+			return "MakeBucket"
+		case "HEAD":
+			return "BucketExists"
+		case "DELETE":
+			return "RemoveBucket"
+		case "POST":
+			if has("delete") {
+				return "RemoveObjects"
+			}
+		}
+	case has("uploads") && method == "POST":
+		return "NewMultipartUpload"
+	case has("uploadId"):
+		switch method {
+		case "PUT":
+			if copySource {
+				return "UploadPartCopy"
+			}
+			return "UploadPart"
+		case "GET":
+			return "ListParts"
+		case "POST":
+			return "CompleteMultipartUpload"
+		case "DELETE":
+			return "AbortMultipartUpload"
+		}
+	case has("attributes"):
+		return "GetObjectAttributes"
+	case has("retention"):
+		if method == "PUT" {
+			return "PutObjectRetention"
+		}
+		return "GetObjectRetention"
+	case has("legal-hold"):
+		if method == "PUT" {
+			return "PutObjectLegalHold"
+		}
+		return "GetObjectLegalHold"
+	case has("tagging"):
+		switch method {
+		case "GET":
+			return "GetObjectTagging"
+		case "PUT":
+			return "PutObjectTagging"
+		case "DELETE":
+			return "DeleteObjectTagging"
+		}
+	default:
+		switch method {
+		case "GET":
+			return "GetObject"
+		case "PUT":
+			if copySource {
+				return "CopyObject"
+			}
+			return "PutObject"
+		case "HEAD":
+			return "StatObject"
+		case "DELETE":
+			return "RemoveObject"
+		}
+	}
+	return method
+}
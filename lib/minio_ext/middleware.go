@@ -0,0 +1,37 @@
+package minio_ext
+
+import "net/http"
+
+// RoundTripFunc - sends a signed S3 request and returns its response,
+// like http.RoundTripper.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware - wraps the sending of every S3 request, to add headers,
+// audit, inject faults or retry on its own terms:
+//
+//	client.Use(func(next minio_ext.RoundTripFunc) minio_ext.RoundTripFunc {
+//		return func(req *http.Request) (*http.Response, error) {
+//			log.Println(req.Method, req.URL)
+//			return next(req)
+//		}
+//	})
+//
+// Every attempt, hedge request and bucket location lookup goes through
+// the chain after signing, headers changed must not be signed ones.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// Use - appends middleware to the chain of the client, the first one
+// added sees the requests first and the responses last.
+func (c *Client) Use(middleware ...Middleware) {
+	chain := make([]Middleware, 0, len(c.middleware)+len(middleware))
+	c.middleware = append(append(chain, c.middleware...), middleware...)
+}
+
+// roundTrip - sends req through the middleware chain to the HTTP client.
+func (c Client) roundTrip(req *http.Request) (*http.Response, error) {
+	send := RoundTripFunc(c.httpClient.Do)
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		send = c.middleware[i](send)
+	}
+	return send(req)
+}
@@ -41,6 +41,9 @@ type ObjectInfo struct {
 	// The class of storage used to store the object.
 	StorageClass string `json:"storageClass"`
 
+	// Version of the object, empty in unversioned buckets.
+	VersionID string `json:"versionId,omitempty"`
+
 	// Error
 	Err error `json:"-"`
 }
@@ -0,0 +1,136 @@
+package minio_ext
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// operationStatsWindow - period the latencies and rates of
+	// OperationStats are computed over.
+	operationStatsWindow = time.Minute
+
+	// operationStatsSamples - most recent requests kept per operation,
+	// busier operations are computed over a shorter period.
+	operationStatsSamples = 1024
+)
+
+// OperationStats - latencies and throughput of the requests of an
+// operation for health endpoints, computed over the last minute unless
+// noted otherwise, durations are encoded in nanoseconds in JSON.
+type OperationStats struct {
+	// Requests and failed requests since the client was created.
+	Total  uint64 `json:"total"`
+	Errors uint64 `json:"errors"`
+
+	// Requests of the period and the period they were counted over.
+	Count  int           `json:"count"`
+	Period time.Duration `json:"period"`
+
+	// Latency percentiles.
+	P50 time.Duration `json:"p50"`
+	P95 time.Duration `json:"p95"`
+	P99 time.Duration `json:"p99"`
+
+	// Requests and payload bytes, sent or received, per second.
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	BytesPerSecond    float64 `json:"bytesPerSecond"`
+}
+
+// operationSample - a request of an operation.
+type operationSample struct {
+	at      time.Time
+	latency time.Duration
+	bytes   int64
+}
+
+// operationSeries - the recent requests of an operation in a ring.
+type operationSeries struct {
+	samples [operationStatsSamples]operationSample
+	count   int
+	errors  uint64
+}
+
+// operationCounters - the OperationStats of a client by operation.
+type operationCounters struct {
+	sync.Mutex
+	series map[string]*operationSeries
+}
+
+// newOperationCounters - returns counters without operations.
+func newOperationCounters() *operationCounters {
+	return &operationCounters{series: make(map[string]*operationSeries)}
+}
+
+// observe - records a request of operation which took latency and
+// transferred bytes of payload.
+func (o *operationCounters) observe(operation string, failed bool, latency time.Duration, bytes int64) {
+	if o == nil {
+		return
+	}
+	o.Lock()
+	defer o.Unlock()
+	s, ok := o.series[operation]
+	if !ok {
+		s = &operationSeries{}
+		o.series[operation] = s
+	}
+	s.samples[s.count%operationStatsSamples] = operationSample{at: time.Now(), latency: latency, bytes: bytes}
+	s.count++
+	if failed {
+		s.errors++
+	}
+}
+
+// stats - computes the OperationStats of s at now.
+func (s *operationSeries) stats(now time.Time) OperationStats {
+	stats := OperationStats{Total: uint64(s.count), Errors: s.errors, Period: operationStatsWindow}
+	n := s.count
+	if n > operationStatsSamples {
+		n = operationStatsSamples
+	}
+	var latencies []time.Duration
+	var bytes int64
+	for _, sample := range s.samples[:n] {
+		if now.Sub(sample.at) > operationStatsWindow {
+			continue
+		}
+		latencies = append(latencies, sample.latency)
+		bytes += sample.bytes
+	}
+	// A full ring may not reach back the whole window.
+	if n == operationStatsSamples {
+		if oldest := now.Sub(s.samples[s.count%operationStatsSamples].at); oldest > 0 && oldest < stats.Period {
+			stats.Period = oldest
+		}
+	}
+	if len(latencies) == 0 {
+		return stats
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) time.Duration {
+		return latencies[int(float64(len(latencies)-1)*p)]
+	}
+	stats.Count = len(latencies)
+	stats.P50, stats.P95, stats.P99 = percentile(0.5), percentile(0.95), percentile(0.99)
+	stats.RequestsPerSecond = float64(stats.Count) / stats.Period.Seconds()
+	stats.BytesPerSecond = float64(bytes) / stats.Period.Seconds()
+	return stats
+}
+
+// Stats - returns the latencies and throughput of the requests of the
+// client by S3 operation, like "UploadPart" or "CompleteMultipartUpload",
+// and of presigning by "Presign" and the presigned operation, like
+// "PresignUploadPart" or "PresignPostPolicy". Meant for the health
+// endpoint of the application, no metrics system needed.
+func (c *Client) Stats() map[string]OperationStats {
+	c.operationStats.Lock()
+	defer c.operationStats.Unlock()
+	now := time.Now()
+	stats := make(map[string]OperationStats, len(c.operationStats.series))
+	for operation, s := range c.operationStats.series {
+		stats[operation] = s.stats(now)
+	}
+	return stats
+}
@@ -0,0 +1,217 @@
+package minio_ext
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// adaptivePartDuration - adaptive part sizing aims at parts taking
+// about this long to upload.
+const adaptivePartDuration = 10 * time.Second
+
+// partSpec - position of a part in the source.
+type partSpec struct {
+	PartNumber int
+	Offset     int64
+	Size       int64
+}
+
+// partPlanner - splits a source into parts. In fixed mode all parts but
+// the last have the same size and are planned upfront, in adaptive mode
+// parts are planned one by one and their size follows the measured
+// throughput. Either way the source never needs more than MaxPartsCount
+// parts.
+type partPlanner struct {
+	// mutex protects all fields below.
+	mutex sync.Mutex
+
+	size     int64
+	partSize int64
+	adaptive bool
+
+	// align rounds parts up to a multiple of it, 0 for none. Set
+	// before parts are planned, for the chunks of encrypted uploads.
+	align int64
+
+	// plan holds the planned parts, indexed by part number - 1.
+	plan    []partSpec
+	planned int64
+}
+
+// newPartPlanner - returns a planner for size bytes starting with
+// parts of partSize bytes.
+func newPartPlanner(size, partSize int64, adaptive bool) *partPlanner {
+	p := &partPlanner{
+		size:     size,
+		partSize: partSize,
+		adaptive: adaptive,
+	}
+	if size == 0 {
+		// An empty source is uploaded as a single empty part.
+		p.plan = append(p.plan, partSpec{PartNumber: 1})
+		return p
+	}
+	if !adaptive {
+		for p.planned < p.size {
+			p.planNext()
+		}
+	}
+	return p
+}
+
+// PlanParts - returns the fixed part plan of an upload of size bytes
+// with parts of partSize bytes, for clients uploading the parts
+// themselves. With partSize 0 the smallest part size fitting size into
+// MaxPartsCount parts is used.
+func PlanParts(size, partSize int64) ([]PartState, error) {
+	if size < 0 || size > MaxMultipartPutObjectSize {
+		return nil, ErrInvalidArgument(fmt.Sprintf("Size must be between 0 and %d.", MaxMultipartPutObjectSize))
+	}
+	if partSize == 0 {
+		partSize = optimalPartSize(size)
+	}
+	if partSize < absMinPartSize || partSize > MaxPartSize {
+		return nil, ErrInvalidArgument(fmt.Sprintf("Part size must be between %d and %d.", absMinPartSize, MaxPartSize))
+	}
+	if partsCount(size, partSize) > MaxPartsCount {
+		return nil, ErrInvalidArgument(fmt.Sprintf("Part size %d results in more than %d parts.", partSize, MaxPartsCount))
+	}
+	p := newPartPlanner(size, partSize, false)
+	var plan []PartState
+	for _, spec := range p.parts() {
+		plan = append(plan, PartState{PartNumber: spec.PartNumber, Offset: spec.Offset, Size: spec.Size})
+	}
+	return plan, nil
+}
+
+// restorePartPlanner - returns a planner continuing plan, the parts
+// planned by an earlier planner for the same source.
+func restorePartPlanner(size, partSize int64, adaptive bool, plan []partSpec) (*partPlanner, error) {
+	if len(plan) == 0 {
+		return newPartPlanner(size, partSize, adaptive), nil
+	}
+	p := &partPlanner{
+		size:     size,
+		partSize: partSize,
+		adaptive: adaptive,
+	}
+	if len(plan) > MaxPartsCount {
+		return nil, ErrInvalidArgument(fmt.Sprintf("Plan has more than %d parts.", MaxPartsCount))
+	}
+	for i, spec := range plan {
+		if spec.PartNumber != i+1 || spec.Offset != p.planned || spec.Size < 0 || spec.Size > MaxPartSize || spec.Size > size-spec.Offset {
+			return nil, ErrInvalidArgument(fmt.Sprintf("Invalid plan for part %d.", spec.PartNumber))
+		}
+		p.plan = append(p.plan, spec)
+		p.planned += spec.Size
+	}
+	for !adaptive && p.planned < p.size {
+		p.planNext()
+	}
+	return p, nil
+}
+
+// planNext - appends the next part to the plan, caller must hold the mutex.
+func (p *partPlanner) planNext() partSpec {
+	remaining := p.size - p.planned
+	partSize := p.partSize
+
+	// Never let the remaining parts exceed the part count limit.
+	if remainingParts := int64(MaxPartsCount - len(p.plan)); remainingParts > 0 {
+		if minSize := (remaining + remainingParts - 1) / remainingParts; minSize > partSize {
+			partSize = minSize
+		}
+	}
+	if p.align > 0 && partSize%p.align != 0 {
+		partSize += p.align - partSize%p.align
+	}
+	if partSize > remaining {
+		partSize = remaining
+	}
+
+	spec := partSpec{
+		PartNumber: len(p.plan) + 1,
+		Offset:     p.planned,
+		Size:       partSize,
+	}
+	p.plan = append(p.plan, spec)
+	p.planned += partSize
+	return spec
+}
+
+// next - plans and returns a new part, false once the whole source is
+// planned.
+func (p *partPlanner) next() (partSpec, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.planned >= p.size {
+		return partSpec{}, false
+	}
+	return p.planNext(), true
+}
+
+// complete - reports whether the whole source is planned.
+func (p *partPlanner) complete() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.planned >= p.size
+}
+
+// parts - returns all planned parts.
+func (p *partPlanner) parts() []partSpec {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	parts := make([]partSpec, len(p.plan))
+	copy(parts, p.plan)
+	return parts
+}
+
+// settings - returns the current part size and mode.
+func (p *partPlanner) settings() (partSize int64, adaptive bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.partSize, p.adaptive
+}
+
+// observe - feeds the outcome of a part upload into adaptive sizing,
+// parts grow while uploads are quick and shrink when they are slow or
+// needed retries.
+func (p *partPlanner) observe(size int64, elapsed time.Duration, attempts int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.adaptive || size <= 0 {
+		return
+	}
+
+	partSize := p.partSize
+	if attempts > 1 {
+		partSize /= 2
+	} else if elapsed > 0 {
+		// Size which would have taken adaptivePartDuration at the
+		// measured throughput, changing at most by factor two at once.
+		// Compared as float, very short uploads would overflow int64.
+		ideal := float64(size) / elapsed.Seconds() * adaptivePartDuration.Seconds()
+		switch {
+		case ideal > 2*float64(partSize):
+			partSize *= 2
+		case ideal < float64(partSize)/2:
+			partSize /= 2
+		default:
+			partSize = int64(ideal)
+		}
+	}
+
+	// Round to MiB and keep within the part size limits.
+	partSize = partSize / (1 << 20) * (1 << 20)
+	if partSize < absMinPartSize {
+		partSize = absMinPartSize
+	}
+	if partSize > MaxPartSize {
+		partSize = MaxPartSize
+	}
+	if p.align > 0 && partSize > maxEncryptedPartSize {
+		partSize = maxEncryptedPartSize
+	}
+	p.partSize = partSize
+}
@@ -0,0 +1,182 @@
+package minio_ext
+
+import (
+	"testing"
+	"time"
+)
+
+// checkPlan - returns an error message when plan does not cover size
+// bytes in order within the part limits, "" otherwise.
+func checkPlan(plan []partSpec, size int64) string {
+	if len(plan) == 0 || len(plan) > MaxPartsCount {
+		return "unexpected number of parts"
+	}
+	var offset int64
+	for i, spec := range plan {
+		if spec.PartNumber != i+1 || spec.Offset != offset || spec.Size > MaxPartSize {
+			return "unexpected part"
+		}
+		if i < len(plan)-1 && spec.Size < absMinPartSize {
+			return "part too small"
+		}
+		offset += spec.Size
+	}
+	if offset != size {
+		return "plan does not cover the source"
+	}
+	return ""
+}
+
+func TestNewPartPlanner(t *testing.T) {
+	testCases := []struct {
+		size     int64
+		partSize int64
+		parts    int
+		lastSize int64
+	}{
+		{0, absMinPartSize, 1, 0},
+		{1, absMinPartSize, 1, 1},
+		{absMinPartSize, absMinPartSize, 1, absMinPartSize},
+		{absMinPartSize + 1, absMinPartSize, 2, 1},
+		{MaxMultipartPutObjectSize, optimalPartSize(MaxMultipartPutObjectSize), 9987, 230 << 20},
+		{MaxMultipartPutObjectSize, MaxPartSize, 1024, MaxPartSize},
+		// Parts grow so the source fits into MaxPartsCount parts.
+		{MaxMultipartPutObjectSize, absMinPartSize, MaxPartsCount, 549755813},
+	}
+	for i, testCase := range testCases {
+		plan := newPartPlanner(testCase.size, testCase.partSize, false).parts()
+		if msg := checkPlan(plan, testCase.size); msg != "" {
+			t.Errorf("Test %d: %s", i+1, msg)
+			continue
+		}
+		if len(plan) != testCase.parts || plan[len(plan)-1].Size != testCase.lastSize {
+			t.Errorf("Test %d: expected %d parts ending with %d bytes, got %d parts ending with %d bytes",
+				i+1, testCase.parts, testCase.lastSize, len(plan), plan[len(plan)-1].Size)
+		}
+	}
+}
+
+func TestPlanParts(t *testing.T) {
+	testCases := []struct {
+		size       int64
+		partSize   int64
+		shouldPass bool
+		parts      int
+		lastSize   int64
+	}{
+		{0, 0, true, 1, 0},
+		{1, 0, true, 1, 1},
+		{absMinPartSize, 0, true, 1, absMinPartSize},
+		{absMinPartSize + 1, absMinPartSize, true, 2, 1},
+		{MaxMultipartPutObjectSize, 0, true, 9987, 230 << 20},
+		{MaxMultipartPutObjectSize, MaxPartSize, true, 1024, MaxPartSize},
+		{MaxMultipartPutObjectSize + 1, 0, false, 0, 0},
+		{-1, 0, false, 0, 0},
+		{MaxMultipartPutObjectSize, absMinPartSize, false, 0, 0},
+		{100, absMinPartSize - 1, false, 0, 0},
+		{100, MaxPartSize + 1, false, 0, 0},
+	}
+	for i, testCase := range testCases {
+		plan, err := PlanParts(testCase.size, testCase.partSize)
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: expected to pass, failed with %v", i+1, err)
+			continue
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: expected to fail, passed", i+1)
+			continue
+		}
+		if err != nil {
+			if ToErrorResponse(err).Code != "InvalidArgument" {
+				t.Errorf("Test %d: expected InvalidArgument, got %v", i+1, err)
+			}
+			continue
+		}
+		specs := make([]partSpec, 0, len(plan))
+		for _, part := range plan {
+			specs = append(specs, partSpec(part))
+		}
+		if msg := checkPlan(specs, testCase.size); msg != "" {
+			t.Errorf("Test %d: %s", i+1, msg)
+		}
+		if len(plan) != testCase.parts || plan[len(plan)-1].Size != testCase.lastSize {
+			t.Errorf("Test %d: expected %d parts ending with %d bytes, got %d parts ending with %d bytes",
+				i+1, testCase.parts, testCase.lastSize, len(plan), plan[len(plan)-1].Size)
+		}
+	}
+}
+
+func TestRestorePartPlanner(t *testing.T) {
+	const partSize = absMinPartSize
+	testCases := []struct {
+		size       int64
+		adaptive   bool
+		plan       []partSpec
+		shouldPass bool
+		parts      int
+	}{
+		{3 * partSize, false, nil, true, 3},
+		{3 * partSize, false, []partSpec{{1, 0, partSize}}, true, 3},
+		// Adaptive plans are continued part by part.
+		{3 * partSize, true, []partSpec{{1, 0, partSize}}, true, 1},
+		{3 * partSize, false, []partSpec{{1, 0, 2 * partSize}, {2, 2 * partSize, partSize}}, true, 2},
+		{3 * partSize, false, []partSpec{{2, 0, partSize}}, false, 0},
+		{3 * partSize, false, []partSpec{{1, 0, partSize}, {2, partSize + 1, partSize}}, false, 0},
+		{3 * partSize, false, []partSpec{{1, 0, 4 * partSize}}, false, 0},
+		{3 * partSize, false, []partSpec{{1, 0, -1}}, false, 0},
+		{MaxMultipartPutObjectSize, false, []partSpec{{1, 0, MaxPartSize + 1}}, false, 0},
+	}
+	for i, testCase := range testCases {
+		p, err := restorePartPlanner(testCase.size, partSize, testCase.adaptive, testCase.plan)
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: expected to pass, failed with %v", i+1, err)
+			continue
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: expected to fail, passed", i+1)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if parts := p.parts(); len(parts) != testCase.parts {
+			t.Errorf("Test %d: expected %d parts, got %d", i+1, testCase.parts, len(parts))
+		}
+		for _, ok := p.next(); ok; _, ok = p.next() {
+		}
+		if msg := checkPlan(p.parts(), testCase.size); msg != "" || !p.complete() {
+			t.Errorf("Test %d: %s", i+1, msg)
+		}
+	}
+}
+
+func TestAdaptivePartPlanner(t *testing.T) {
+	testCases := []struct {
+		name string
+		size int64
+		// Upload time and attempts reported for every part.
+		elapsed  time.Duration
+		attempts int
+	}{
+		// Parts grow to MaxPartSize.
+		{"fast", MaxMultipartPutObjectSize, time.Nanosecond, 1},
+		// Parts shrink to the minimum but the part count limit wins.
+		{"slow", MaxMultipartPutObjectSize, time.Hour, 1},
+		{"retried", MaxMultipartPutObjectSize, time.Second, 3},
+		{"steady", 100 * absMinPartSize, adaptivePartDuration, 1},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			p := newPartPlanner(testCase.size, absMinPartSize, true)
+			for spec, ok := p.next(); ok; spec, ok = p.next() {
+				p.observe(spec.Size, testCase.elapsed, testCase.attempts)
+			}
+			if msg := checkPlan(p.parts(), testCase.size); msg != "" || !p.complete() {
+				t.Errorf("%s, %d parts planned", msg, len(p.parts()))
+			}
+			if partSize, adaptive := p.settings(); !adaptive || partSize < absMinPartSize || partSize > MaxPartSize {
+				t.Errorf("Unexpected part size %d", partSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package minio_ext
+
+import (
+	"context"
+	"sync"
+)
+
+// Typical upload priorities, any positive priority may be used.
+const (
+	PriorityBackground  = 1
+	PriorityNormal      = 4
+	PriorityInteractive = 16
+)
+
+// PartScheduler - shares a fixed number of part upload slots between
+// all sessions using it. Free slots go to the waiting sessions in
+// weighted fair order, a session with priority 16 gets sixteen times
+// as many slots as one with priority 1 while both are waiting, so
+// interactive uploads overtake background uploads at the next part.
+// Parts already uploading are never interrupted.
+type PartScheduler struct {
+	// mutex protects all fields below.
+	mutex sync.Mutex
+
+	free    int
+	waiting []*schedulerWaiter
+
+	// vtime is the virtual time of the last granted slot, seq orders
+	// waiters with the same virtual finish time.
+	vtime float64
+	seq   uint64
+}
+
+// schedulerFlow - per session state of a scheduler, protected by the
+// scheduler mutex.
+type schedulerFlow struct {
+	finish float64
+}
+
+// schedulerWaiter - a session waiting for a slot.
+type schedulerWaiter struct {
+	tag   float64
+	seq   uint64
+	ready chan struct{}
+}
+
+// NewPartScheduler - returns a scheduler with slots parallel part
+// uploads, at least one.
+func NewPartScheduler(slots int) *PartScheduler {
+	if slots <= 0 {
+		slots = 1
+	}
+	return &PartScheduler{free: slots}
+}
+
+// acquire - waits for a free slot for flow with priority, returns the
+// error of ctx when it is done first.
+func (p *PartScheduler) acquire(ctx context.Context, flow *schedulerFlow, priority int) error {
+	if priority <= 0 {
+		priority = 1
+	}
+
+	p.mutex.Lock()
+	start := flow.finish
+	if p.vtime > start {
+		start = p.vtime
+	}
+	p.seq++
+	w := &schedulerWaiter{
+		tag:   start + 1/float64(priority),
+		seq:   p.seq,
+		ready: make(chan struct{}),
+	}
+	flow.finish = w.tag
+	p.waiting = append(p.waiting, w)
+	p.dispatch()
+	p.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for i, waiting := range p.waiting {
+		if waiting == w {
+			p.waiting = append(p.waiting[:i], p.waiting[i+1:]...)
+			return ctx.Err()
+		}
+	}
+	// Granted meanwhile, hand the slot on.
+	p.free++
+	p.dispatch()
+	return ctx.Err()
+}
+
+// release - returns a slot taken by acquire.
+func (p *PartScheduler) release() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.free++
+	p.dispatch()
+}
+
+// dispatch - grants free slots to the waiters with the smallest
+// virtual finish time, caller must hold the mutex.
+func (p *PartScheduler) dispatch() {
+	for p.free > 0 && len(p.waiting) > 0 {
+		next := 0
+		for i, w := range p.waiting {
+			best := p.waiting[next]
+			if w.tag < best.tag || w.tag == best.tag && w.seq < best.seq {
+				next = i
+			}
+		}
+		w := p.waiting[next]
+		p.waiting = append(p.waiting[:next], p.waiting[next+1:]...)
+		p.free--
+		p.vtime = w.tag
+		close(w.ready)
+	}
+}
@@ -0,0 +1,106 @@
+package minio_ext
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+)
+
+// PayloadSigning - whether signature V4 requests sign their payload.
+type PayloadSigning int
+
+// Different payload signing policies.
+const (
+	// Payloads are signed when their SHA-256 is known, streamed with
+	// chunk signatures over http and unsigned otherwise. Presigned URLs
+	// do not sign their payload.
+	PayloadSigningAuto PayloadSigning = iota
+
+	// Payloads are never signed, X-Amz-Content-Sha256 is always
+	// UNSIGNED-PAYLOAD.
+	PayloadSigningUnsigned
+
+	// Payloads are always signed with their SHA-256, computed from the
+	// body when not known. Bodies must be seekable, presigned PUT URLs
+	// need the SHA-256 of the payload, see GenUploadPartSignedUrlSHA256.
+	// For hardened gateways rejecting unsigned payloads.
+	PayloadSigningSigned
+)
+
+// SetPayloadSigning - sets the payload signing policy of signature V4
+// requests and presigned URLs, PayloadSigningAuto by default.
+func (c *Client) SetPayloadSigning(policy PayloadSigning) {
+	c.payloadSigning = policy
+}
+
+// payloadSHA256 - returns the X-Amz-Content-Sha256 of a request with
+// metadata under the payload signing policy of c.
+func (c Client) payloadSHA256(metadata requestMetadata) (string, error) {
+	switch c.payloadSigning {
+	case PayloadSigningUnsigned:
+		return unsignedPayload, nil
+	case PayloadSigningSigned:
+		if metadata.contentSHA256Hex != "" && metadata.contentSHA256Hex != unsignedPayload {
+			return metadata.contentSHA256Hex, nil
+		}
+		return bodySHA256(metadata.contentBody, metadata.contentLength)
+	}
+	if metadata.contentSHA256Hex != "" {
+		return metadata.contentSHA256Hex, nil
+	}
+	return unsignedPayload, nil
+}
+
+// bodySHA256 - returns the hex encoded SHA-256 of the length bytes of
+// body, which is left at its position.
+func bodySHA256(body io.Reader, length int64) (string, error) {
+	if body == nil || length == 0 {
+		return emptySHA256Hex, nil
+	}
+	seeker, ok := body.(io.Seeker)
+	if !ok || length < 0 {
+		return "", ErrInvalidArgument("Signed payloads need a seekable body of known length.")
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	n, err := io.Copy(hash, io.LimitReader(body, length))
+	if err != nil {
+		return "", err
+	}
+	if n != length {
+		return "", ErrInvalidArgument("Body is shorter than its content length.")
+	}
+	if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// GenUploadPartSignedUrlSHA256 - same as GenUploadPartSignedUrl with the
+// hex encoded SHA-256 of the part in the signature, needed with
+// PayloadSigningSigned. The returned headers are signed and must be sent
+// with the part PUT.
+func (c Client) GenUploadPartSignedUrlSHA256(uploadID string, bucketName string, objectName string, partNumber int, size int64, expires time.Duration, bucketLocation string, sha256Hex string) (string, http.Header, error) {
+	return c.GenUploadPartSignedUrlSHA256WithContext(context.Background(), uploadID, bucketName, objectName, partNumber, size, expires, bucketLocation, sha256Hex)
+}
+
+// GenUploadPartSignedUrlSHA256WithContext - same as GenUploadPartSignedUrlSHA256, ctx cancels the lookup
+// of the bucket location.
+func (c Client) GenUploadPartSignedUrlSHA256WithContext(ctx context.Context, uploadID string, bucketName string, objectName string, partNumber int, size int64, expires time.Duration, bucketLocation string, sha256Hex string) (string, http.Header, error) {
+	if sum, err := hex.DecodeString(sha256Hex); err != nil || len(sum) != sha256.Size {
+		return "", nil, ErrInvalidArgument("X-Amz-Content-Sha256 must be a hex encoded SHA-256.")
+	}
+	customHeader := make(http.Header)
+	customHeader.Set("X-Amz-Content-Sha256", sha256Hex)
+	signedUrl, err := c.genUploadPartSignedUrl(ctx, uploadID, bucketName, objectName, partNumber, size, expires, bucketLocation, customHeader)
+	if err != nil {
+		return "", nil, err
+	}
+	return signedUrl, customHeader, nil
+}
@@ -0,0 +1,140 @@
+package minio_ext
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter - token bucket limiting upload throughput in bytes per
+// second. A limiter may be shared by many sessions and its limit can be
+// changed at any time, a rate of zero or less disables limiting.
+type RateLimiter struct {
+	// mutex protects all fields below.
+	mutex sync.Mutex
+
+	rate   int64 // bytes per second.
+	burst  int64 // maximum bytes taken at once.
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter - returns a limiter allowing bytesPerSec bytes per
+// second with bursts of up to burst bytes, burst defaults to one
+// second worth of bytes.
+func NewRateLimiter(bytesPerSec, burst int64) *RateLimiter {
+	l := &RateLimiter{}
+	l.SetLimit(bytesPerSec, burst)
+	return l
+}
+
+// SetLimit - changes rate and burst of the limiter, takes effect for
+// all readers using the limiter immediately.
+func (l *RateLimiter) SetLimit(bytesPerSec, burst int64) {
+	if burst <= 0 {
+		burst = bytesPerSec
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.rate = bytesPerSec
+	l.burst = burst
+	l.last = time.Now()
+	if l.tokens > float64(burst) {
+		l.tokens = float64(burst)
+	}
+}
+
+// Limit - returns the current rate and burst of the limiter.
+func (l *RateLimiter) Limit() (bytesPerSec, burst int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.rate, l.burst
+}
+
+// maxChunk - returns the largest read size which should be taken
+// from the limiter at once.
+func (l *RateLimiter) maxChunk() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.burst
+}
+
+// waitN - blocks until n bytes may be transferred or ctx is done.
+func (l *RateLimiter) waitN(ctx context.Context, n int) error {
+	l.mutex.Lock()
+	if l.rate <= 0 {
+		l.mutex.Unlock()
+		return nil
+	}
+
+	// Refill tokens for the time elapsed since the last call.
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * float64(l.rate)
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.last = now
+
+	// Reserve n tokens, going into debt makes later callers wait
+	// for this reservation as well.
+	l.tokens -= float64(n)
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / float64(l.rate) * float64(time.Second))
+	}
+	l.mutex.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limitedReader - throttles reads of a seekable reader by a
+// RateLimiter, seeking is passed through so requests stay retryable.
+type limitedReader struct {
+	ctx     context.Context
+	reader  io.ReadSeeker
+	limiter *RateLimiter
+}
+
+// newLimitedReader - wraps reader with limiter, returns reader as is
+// when limiter is nil.
+func newLimitedReader(ctx context.Context, reader io.ReadSeeker, limiter *RateLimiter) io.ReadSeeker {
+	if limiter == nil {
+		return reader
+	}
+	return &limitedReader{
+		ctx:     ctx,
+		reader:  reader,
+		limiter: limiter,
+	}
+}
+
+// Read - reads at most burst bytes and waits for the limiter.
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if chunk := r.limiter.maxChunk(); chunk > 0 && int64(len(p)) > chunk {
+		p = p[:chunk]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if werr := r.limiter.waitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// Seek - seeks the underlying reader.
+func (r *limitedReader) Seek(offset int64, whence int) (int64, error) {
+	return r.reader.Seek(offset, whence)
+}
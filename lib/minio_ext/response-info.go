@@ -0,0 +1,53 @@
+package minio_ext
+
+import (
+	"context"
+	"net/http"
+)
+
+// deploymentIDHeader - header with the id of the MinIO deployment which
+// answered a request.
+const deploymentIDHeader = "x-minio-deployment-id"
+
+// ResponseInfo - identifiers of a response of the server, to be quoted
+// in support requests. ErrorResponse carries the same for failures.
+type ResponseInfo struct {
+	StatusCode int
+
+	// x-amz-request-id and x-amz-id-2 of the response.
+	RequestID string
+	HostID    string
+
+	// Id of the MinIO deployment which answered, empty for other
+	// servers.
+	DeploymentID string
+
+	// Location of the bucket if the server reported it.
+	Region string
+}
+
+// responseInfoKey - context key of the ResponseInfo recording responses.
+type responseInfoKey struct{}
+
+// WithResponseInfo - returns a copy of ctx recording the identifiers of
+// the responses of calls taking it in info, the last response of a call
+// wins. Calls taking the context must not run concurrently.
+func WithResponseInfo(ctx context.Context, info *ResponseInfo) context.Context {
+	return context.WithValue(ctx, responseInfoKey{}, info)
+}
+
+// recordResponse - records res in the ResponseInfo of ctx, if any.
+func recordResponse(ctx context.Context, res *http.Response) {
+	if res == nil {
+		return
+	}
+	if info, ok := ctx.Value(responseInfoKey{}).(*ResponseInfo); ok && info != nil {
+		*info = ResponseInfo{
+			StatusCode:   res.StatusCode,
+			RequestID:    res.Header.Get("x-amz-request-id"),
+			HostID:       res.Header.Get("x-amz-id-2"),
+			DeploymentID: res.Header.Get(deploymentIDHeader),
+			Region:       res.Header.Get("x-amz-bucket-region"),
+		}
+	}
+}
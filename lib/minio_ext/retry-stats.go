@@ -0,0 +1,60 @@
+package minio_ext
+
+import (
+	"sync"
+	"time"
+)
+
+// RetryStats - counters of the failed attempts of the requests of a
+// client, for alerting on retry storms.
+type RetryStats struct {
+	// Failed attempts which were retried.
+	Retries uint64
+
+	// Requests which failed on their last attempt or gave up before
+	// their deadline.
+	Exhausted uint64
+
+	// Failed attempts by S3 error code, network errors under the empty
+	// code.
+	Codes map[string]uint64
+
+	// Total wait before retries.
+	Wait time.Duration
+}
+
+// retryCounters - the RetryStats of a client.
+type retryCounters struct {
+	sync.Mutex
+	stats RetryStats
+}
+
+// newRetryCounters - returns zero counters.
+func newRetryCounters() *retryCounters {
+	return &retryCounters{stats: RetryStats{Codes: make(map[string]uint64)}}
+}
+
+// record - counts the failed attempt of event.
+func (r *retryCounters) record(event RetryEvent) {
+	r.Lock()
+	defer r.Unlock()
+	if event.Retry {
+		r.stats.Retries++
+		r.stats.Wait += event.Wait
+	} else {
+		r.stats.Exhausted++
+	}
+	r.stats.Codes[event.Code]++
+}
+
+// RetryStats - returns the retry counters since the client was created.
+func (c *Client) RetryStats() RetryStats {
+	c.retryStats.Lock()
+	defer c.retryStats.Unlock()
+	stats := c.retryStats.stats
+	stats.Codes = make(map[string]uint64, len(c.retryStats.stats.Codes))
+	for code, n := range c.retryStats.stats.Codes {
+		stats.Codes[code] = n
+	}
+	return stats
+}
@@ -18,10 +18,17 @@
 package minio_ext
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -42,47 +49,158 @@ const DefaultRetryUnit = time.Second
 // this maximum time duration.
 const DefaultRetryCap = time.Second * 30
 
-// newRetryTimer creates a timer with exponentially increasing
-// delays until the maximum retry attempts are reached.
-func (c Client) newRetryTimer(maxRetry int, unit time.Duration, cap time.Duration, jitter float64, doneCh chan struct{}) <-chan int {
-	attemptCh := make(chan int)
-
-	// computes the exponential backoff duration according to
-	// https://www.awsarchitectureblog.com/2015/03/backoff.html
-	exponentialBackoffWait := func(attempt int) time.Duration {
-		// normalize jitter to the range [0, 1.0]
-		if jitter < NoJitter {
-			jitter = NoJitter
-		}
-		if jitter > MaxJitter {
-			jitter = MaxJitter
-		}
+// RequestRetryPolicy - controls how often a failed request is retried
+// by the client. Zero fields keep the setting of the client, or
+// MaxRetry, DefaultRetryUnit and DefaultRetryCap.
+type RequestRetryPolicy struct {
+	// Maximum number of attempts per request, 1 disables retries.
+	MaxAttempts int
 
-		//sleep = random_between(0, min(cap, base * 2 ** attempt))
-		sleep := unit * time.Duration(1<<uint(attempt))
-		if sleep > cap {
-			sleep = cap
-		}
-		if jitter != NoJitter {
-			sleep -= time.Duration(c.random.Float64() * float64(sleep) * jitter)
+	// Backoff unit and maximum wait between two attempts.
+	Unit time.Duration
+	Cap  time.Duration
+
+	// Waits between two attempts, exponential with full jitter by
+	// default.
+	Backoff Backoff
+}
+
+// override - returns p with the fields set in o replaced.
+func (p RequestRetryPolicy) override(o RequestRetryPolicy) RequestRetryPolicy {
+	if o.MaxAttempts > 0 {
+		p.MaxAttempts = o.MaxAttempts
+	}
+	if o.Unit > 0 {
+		p.Unit = o.Unit
+	}
+	if o.Cap > 0 {
+		p.Cap = o.Cap
+	}
+	if o.Backoff != nil {
+		p.Backoff = o.Backoff
+	}
+	return p
+}
+
+// SetRetryPolicy - sets how often failed requests are retried, for
+// example a single attempt on latency sensitive paths and many for bulk
+// uploads. Calls may override it with WithRequestRetry.
+func (c *Client) SetRetryPolicy(policy RequestRetryPolicy) {
+	c.retryPolicy = policy
+}
+
+// retryPolicyFor - returns the retry policy of a request with metadata.
+func (c Client) retryPolicyFor(metadata requestMetadata) RequestRetryPolicy {
+	policy := RequestRetryPolicy{MaxAttempts: MaxRetry, Unit: DefaultRetryUnit, Cap: DefaultRetryCap}
+	return policy.override(c.retryPolicy).override(metadata.retryPolicy)
+}
+
+// retryTimer - counts the attempts of a call and waits before every
+// attempt but the first with delays computed by backoff, as
+// executeMethod does.
+type retryTimer struct {
+	maxRetry  int
+	unit, cap time.Duration
+	backoff   Backoff
+
+	attempts int
+	wait     time.Duration
+}
+
+// newRetryTimer - returns a timer of maxRetry attempts, backoff
+// defaults to ExponentialBackoff with MaxJitter.
+func newRetryTimer(maxRetry int, unit time.Duration, cap time.Duration, backoff Backoff) *retryTimer {
+	if backoff == nil {
+		backoff = ExponentialBackoff{Jitter: MaxJitter}
+	}
+	return &retryTimer{maxRetry: maxRetry, unit: unit, cap: cap, backoff: backoff}
+}
+
+// next - waits before the next attempt, reports false when maxRetry
+// attempts were made. The wait ends early when ctx is done, callers
+// check ctx before the attempt.
+func (t *retryTimer) next(ctx context.Context) bool {
+	if t.attempts >= t.maxRetry {
+		return false
+	}
+	if t.attempts > 0 {
+		t.wait = t.backoff.Wait(t.attempts-1, t.wait, t.unit, t.cap)
+		sleepContext(ctx, t.wait)
+	}
+	t.attempts++
+	return true
+}
+
+// RetryDeadlineError - returned when the context of a request expires
+// before its next attempt, matches context.DeadlineExceeded with
+// errors.Is. Err is the error of the last attempt.
+type RetryDeadlineError struct {
+	Attempts int
+	Wait     time.Duration
+	Err      error
+}
+
+// Error - Returns the aborted retries as string.
+func (e RetryDeadlineError) Error() string {
+	return fmt.Sprintf("context deadline exceeded before attempt %d in %s, last attempt failed: %v", e.Attempts+1, e.Wait, e.Err)
+}
+
+// Unwrap - returns context.DeadlineExceeded.
+func (e RetryDeadlineError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// retryAfterDelay - returns the wait asked by the Retry-After header of
+// a 503 Service Unavailable or 429 Too Many Requests response, given in
+// seconds or as an HTTP date.
+func retryAfterDelay(res *http.Response) (time.Duration, bool) {
+	if res.StatusCode != http.StatusServiceUnavailable && res.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	value := strings.TrimSpace(res.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
 		}
-		return sleep
-	}
-
-	go func() {
-		defer close(attemptCh)
-		for i := 0; i < maxRetry; i++ {
-			select {
-			// Attempts start from 1.
-			case attemptCh <- i + 1:
-			case <-doneCh:
-				// Stop the routine.
-				return
-			}
-			time.Sleep(exponentialBackoffWait(i))
+		if seconds > math.MaxInt64/int64(time.Second) {
+			seconds = math.MaxInt64 / int64(time.Second)
 		}
-	}()
-	return attemptCh
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	delay := time.Until(date)
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true
+}
+
+// sleepContext - waits for d, fails early with the error of ctx when it
+// is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isConnectionReset - reports whether err means that the server closed
+// the connection before it answered.
+func isConnectionReset(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
 }
 
 // isHTTPReqErrorRetryable - is http requests error retryable, such
@@ -94,10 +212,10 @@ func isHTTPReqErrorRetryable(err error) bool {
 	switch e := err.(type) {
 	case *url.Error:
 		switch e.Err.(type) {
-		case *net.DNSError, *net.OpError, net.UnknownNetworkError:
+		case *net.DNSError, *net.OpError, net.UnknownNetworkError, stallError:
 			return true
 		}
-		if strings.Contains(err.Error(), "Connection closed by foreign host") {
+		if errors.Is(e.Err, ErrConnectionReset) {
 			return true
 		} else if strings.Contains(err.Error(), "net/http: TLS handshake timeout") {
 			// If error is - tlsHandshakeTimeoutError, retry.
@@ -108,6 +226,9 @@ func isHTTPReqErrorRetryable(err error) bool {
 		} else if strings.Contains(err.Error(), "connection timed out") {
 			// If err is a net.Dial timeout, retry.
 			return true
+		} else if strings.Contains(err.Error(), "net/http: timeout awaiting response headers") {
+			// If the response header timeout expired, retry.
+			return true
 		} else if strings.Contains(err.Error(), "net/http: HTTP/1.x transport connection broken") {
 			// If error is transport connection broken, retry.
 			return true
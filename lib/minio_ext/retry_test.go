@@ -0,0 +1,288 @@
+package minio_ext
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sequenceServer - test server sending responses in order, the last one
+// once all were sent. Returns the server and the number of requests it
+// received.
+func sequenceServer(responses ...testResponse) (*httptest.Server, func() int) {
+	var mutex sync.Mutex
+	var count int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		response := responses[len(responses)-1]
+		if count < len(responses) {
+			response = responses[count]
+		}
+		count++
+		mutex.Unlock()
+		response.write(w)
+	}))
+	return server, func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return count
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	testCases := []struct {
+		attempt int
+		unit    time.Duration
+		cap     time.Duration
+		wait    time.Duration
+	}{
+		{0, time.Second, 30 * time.Second, time.Second},
+		{1, time.Second, 30 * time.Second, 2 * time.Second},
+		{4, time.Second, 30 * time.Second, 16 * time.Second},
+		{5, time.Second, 30 * time.Second, 30 * time.Second},
+		{31, time.Second, 30 * time.Second, 30 * time.Second},
+		{32, time.Second, 30 * time.Second, 30 * time.Second},
+		{100, time.Millisecond, time.Hour, time.Hour},
+		{0, time.Minute, time.Second, time.Second},
+	}
+	for i, testCase := range testCases {
+		if wait := (ExponentialBackoff{Jitter: NoJitter}).Wait(testCase.attempt, 0, testCase.unit, testCase.cap); wait != testCase.wait {
+			t.Errorf("Test %d: expected wait %s, got %s", i+1, testCase.wait, wait)
+		}
+		// Jitter subtracts up to all of the wait, out of range jitter
+		// is clamped.
+		for _, jitter := range []float64{0.5, MaxJitter, 2} {
+			wait := (ExponentialBackoff{Jitter: jitter}).Wait(testCase.attempt, 0, testCase.unit, testCase.cap)
+			if wait < 0 || wait > testCase.wait {
+				t.Errorf("Test %d: wait %s with jitter %v out of [0, %s]", i+1, wait, jitter, testCase.wait)
+			}
+		}
+	}
+}
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	testCases := []struct {
+		previous time.Duration
+		unit     time.Duration
+		cap      time.Duration
+		min      time.Duration
+		max      time.Duration
+	}{
+		{0, time.Second, 30 * time.Second, time.Second, 3 * time.Second},
+		{4 * time.Second, time.Second, 30 * time.Second, time.Second, 12 * time.Second},
+		{20 * time.Second, time.Second, 30 * time.Second, time.Second, 30 * time.Second},
+		{1 << 62, time.Second, time.Minute, time.Second, time.Minute},
+		{0, time.Minute, time.Second, time.Second, time.Second},
+	}
+	for i, testCase := range testCases {
+		for j := 0; j < 100; j++ {
+			wait := DecorrelatedJitterBackoff{}.Wait(j, testCase.previous, testCase.unit, testCase.cap)
+			if wait < testCase.min || wait > testCase.max {
+				t.Fatalf("Test %d: wait %s out of [%s, %s]", i+1, wait, testCase.min, testCase.max)
+			}
+		}
+	}
+}
+
+func TestConstantBackoff(t *testing.T) {
+	testCases := []struct {
+		unit time.Duration
+		cap  time.Duration
+		wait time.Duration
+	}{
+		{time.Second, time.Minute, time.Second},
+		{time.Minute, time.Second, time.Second},
+	}
+	for i, testCase := range testCases {
+		for attempt := 0; attempt < 3; attempt++ {
+			if wait := (ConstantBackoff{}).Wait(attempt, time.Hour, testCase.unit, testCase.cap); wait != testCase.wait {
+				t.Errorf("Test %d: expected wait %s, got %s", i+1, testCase.wait, wait)
+			}
+		}
+	}
+}
+
+func TestRetryTimer(t *testing.T) {
+	testCases := []struct {
+		maxRetry int
+		unit     time.Duration
+		canceled bool
+		// Least time the attempts take, waiting between them.
+		elapsed time.Duration
+	}{
+		{0, time.Hour, false, 0},
+		{1, time.Hour, false, 0},
+		{3, 10 * time.Millisecond, false, 20 * time.Millisecond},
+		// Waits end when the context is done.
+		{3, time.Hour, true, 0},
+	}
+	for i, testCase := range testCases {
+		ctx, cancel := context.WithCancel(context.Background())
+		if testCase.canceled {
+			cancel()
+		}
+		start := time.Now()
+		var attempts int
+		for timer := newRetryTimer(testCase.maxRetry, testCase.unit, testCase.unit, ConstantBackoff{}); timer.next(ctx); {
+			attempts++
+		}
+		cancel()
+		elapsed := time.Since(start)
+		if attempts != testCase.maxRetry {
+			t.Errorf("Test %d: expected %d attempts, got %d", i+1, testCase.maxRetry, attempts)
+		}
+		if elapsed < testCase.elapsed || elapsed > testCase.elapsed+time.Minute {
+			t.Errorf("Test %d: unexpected attempts in %s", i+1, elapsed)
+		}
+	}
+}
+
+func TestRetryPolicyFor(t *testing.T) {
+	testCases := []struct {
+		client   RequestRetryPolicy
+		call     RequestRetryPolicy
+		expected RequestRetryPolicy
+	}{
+		{RequestRetryPolicy{}, RequestRetryPolicy{}, RequestRetryPolicy{MaxAttempts: MaxRetry, Unit: DefaultRetryUnit, Cap: DefaultRetryCap}},
+		{RequestRetryPolicy{MaxAttempts: 3}, RequestRetryPolicy{}, RequestRetryPolicy{MaxAttempts: 3, Unit: DefaultRetryUnit, Cap: DefaultRetryCap}},
+		{RequestRetryPolicy{MaxAttempts: 3, Unit: time.Millisecond}, RequestRetryPolicy{MaxAttempts: 1}, RequestRetryPolicy{MaxAttempts: 1, Unit: time.Millisecond, Cap: DefaultRetryCap}},
+		{RequestRetryPolicy{Cap: time.Minute}, RequestRetryPolicy{Unit: time.Millisecond}, RequestRetryPolicy{MaxAttempts: MaxRetry, Unit: time.Millisecond, Cap: time.Minute}},
+		{RequestRetryPolicy{Cap: time.Minute}, RequestRetryPolicy{Backoff: ConstantBackoff{}}, RequestRetryPolicy{MaxAttempts: MaxRetry, Unit: DefaultRetryUnit, Cap: time.Minute, Backoff: ConstantBackoff{}}},
+	}
+	for i, testCase := range testCases {
+		c := Client{retryPolicy: testCase.client}
+		if policy := c.retryPolicyFor(requestMetadata{retryPolicy: testCase.call}); policy != testCase.expected {
+			t.Errorf("Test %d: expected policy %+v, got %+v", i+1, testCase.expected, policy)
+		}
+	}
+}
+
+func TestRetryAfterDelay(t *testing.T) {
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	testCases := []struct {
+		status int
+		value  string
+		min    time.Duration
+		max    time.Duration
+		ok     bool
+	}{
+		{http.StatusServiceUnavailable, "2", 2 * time.Second, 2 * time.Second, true},
+		{http.StatusTooManyRequests, " 0 ", 0, 0, true},
+		{http.StatusServiceUnavailable, date, 59 * time.Minute, time.Hour, true},
+		{http.StatusServiceUnavailable, "Mon, 02 Jan 2006 15:04:05 GMT", 0, 0, true},
+		{http.StatusServiceUnavailable, "99999999999999999999", 0, 0, false},
+		{http.StatusServiceUnavailable, "9223372036854775807", time.Duration(1<<63 - 1).Truncate(time.Second), time.Duration(1<<63 - 1), true},
+		{http.StatusServiceUnavailable, "-1", 0, 0, false},
+		{http.StatusServiceUnavailable, "soon", 0, 0, false},
+		{http.StatusServiceUnavailable, "", 0, 0, false},
+		{http.StatusInternalServerError, "2", 0, 0, false},
+	}
+	for i, testCase := range testCases {
+		res := &http.Response{StatusCode: testCase.status, Header: http.Header{}}
+		res.Header.Set("Retry-After", testCase.value)
+		delay, ok := retryAfterDelay(res)
+		if ok != testCase.ok {
+			t.Errorf("Test %d: expected ok %v, got %v", i+1, testCase.ok, ok)
+			continue
+		}
+		if delay < testCase.min || delay > testCase.max {
+			t.Errorf("Test %d: delay %s out of [%s, %s]", i+1, delay, testCase.min, testCase.max)
+		}
+	}
+}
+
+func TestExecuteMethodRetry(t *testing.T) {
+	testCases := []struct {
+		name      string
+		responses []testResponse
+		call      RequestRetryPolicy
+		status    int
+		attempts  int
+		// Retry of the events passed to the retry hook.
+		events []bool
+	}{
+		{"success", []testResponse{{status: http.StatusOK}}, RequestRetryPolicy{}, http.StatusOK, 1, nil},
+		{"slow down", []testResponse{{http.StatusServiceUnavailable, "SlowDown", ""}, {status: http.StatusOK}}, RequestRetryPolicy{}, http.StatusOK, 2, []bool{true}},
+		{"bad gateway", []testResponse{{status: http.StatusBadGateway}, {status: http.StatusOK}}, RequestRetryPolicy{}, http.StatusOK, 2, []bool{true}},
+		{"exhausted", []testResponse{{http.StatusInternalServerError, "InternalError", ""}}, RequestRetryPolicy{}, http.StatusInternalServerError, 3, []bool{true, true, false}},
+		{"call policy", []testResponse{{http.StatusInternalServerError, "InternalError", ""}}, RequestRetryPolicy{MaxAttempts: 1}, http.StatusInternalServerError, 1, []bool{false}},
+		{"not retryable", []testResponse{{http.StatusNotFound, "NoSuchKey", ""}}, RequestRetryPolicy{}, http.StatusNotFound, 1, nil},
+		{"retry after", []testResponse{{http.StatusServiceUnavailable, "SlowDown", "0"}, {status: http.StatusOK}}, RequestRetryPolicy{}, http.StatusOK, 2, []bool{true}},
+		{"retry after cap", []testResponse{{http.StatusServiceUnavailable, "SlowDown", "60"}, {status: http.StatusOK}}, RequestRetryPolicy{}, http.StatusServiceUnavailable, 1, nil},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server, requests := sequenceServer(testCase.responses...)
+			defer server.Close()
+			c := newTestClient(t, server.URL)
+			c.SetRetryPolicy(RequestRetryPolicy{MaxAttempts: 3, Unit: time.Millisecond, Cap: 10 * time.Millisecond})
+			var events []RetryEvent
+			c.SetRetryHook(func(event RetryEvent) {
+				events = append(events, event)
+			})
+
+			ctx := WithRequestRetry(context.Background(), testCase.call)
+			res, err := c.executeMethod(ctx, "GET", requestMetadata{
+				bucketName:       "bucket",
+				objectName:       "object",
+				bucketLocation:   "us-east-1",
+				contentSHA256Hex: emptySHA256Hex,
+			})
+			if err != nil {
+				t.Fatalf("Expected a response, failed with %v", err)
+			}
+			closeResponse(res)
+			if res.StatusCode != testCase.status {
+				t.Errorf("Expected status %d, got %d", testCase.status, res.StatusCode)
+			}
+			if requests() != testCase.attempts {
+				t.Errorf("Expected %d attempts, got %d", testCase.attempts, requests())
+			}
+			if len(events) != len(testCase.events) {
+				t.Fatalf("Expected %d retry events, got %+v", len(testCase.events), events)
+			}
+			for i, event := range events {
+				if event.Attempt != i+1 || event.Retry != testCase.events[i] || event.BucketName != "bucket" || event.ObjectName != "object" {
+					t.Errorf("Unexpected retry event %+v", event)
+				}
+				if event.Retry && event.Wait > 10*time.Millisecond {
+					t.Errorf("Expected a wait within the cap, got %s", event.Wait)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteMethodRetryDeadline(t *testing.T) {
+	server, requests := sequenceServer(testResponse{http.StatusServiceUnavailable, "SlowDown", ""})
+	defer server.Close()
+	c := newTestClient(t, server.URL)
+	c.SetRetryPolicy(RequestRetryPolicy{MaxAttempts: 5, Unit: time.Minute, Cap: time.Minute, Backoff: ConstantBackoff{}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := c.executeMethod(ctx, "GET", requestMetadata{
+		bucketName:       "bucket",
+		objectName:       "object",
+		bucketLocation:   "us-east-1",
+		contentSHA256Hex: emptySHA256Hex,
+	})
+	var deadlineErr RetryDeadlineError
+	if !errors.As(err, &deadlineErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected RetryDeadlineError, got %v", err)
+	}
+	if deadlineErr.Attempts != 1 || deadlineErr.Wait != time.Minute || ToErrorResponse(deadlineErr.Err).Code != "SlowDown" {
+		t.Errorf("Unexpected error %+v", deadlineErr)
+	}
+	if requests() != 1 {
+		t.Errorf("Expected a single attempt, got %d", requests())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected to give up at once, took %s", elapsed)
+	}
+}
@@ -0,0 +1,122 @@
+package minio_ext
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// sseCustomerKeyLen - SSE-C keys are always 256 bit AES keys.
+const sseCustomerKeyLen = 32
+
+// SSECustomerKey holds a customer provided encryption key (SSE-C).
+type SSECustomerKey struct {
+	key [sseCustomerKeyLen]byte
+}
+
+// NewSSECustomerKey - returns a new SSE-C key, the key must be exactly
+// 32 bytes long.
+func NewSSECustomerKey(key []byte) (SSECustomerKey, error) {
+	var sseKey SSECustomerKey
+	if len(key) != sseCustomerKeyLen {
+		return sseKey, ErrInvalidArgument(fmt.Sprintf("SSE-C key must be %d bytes long.", sseCustomerKeyLen))
+	}
+	copy(sseKey.key[:], key)
+	return sseKey, nil
+}
+
+// keyMD5Base64 returns the base64 encoded md5sum of the key.
+func (k SSECustomerKey) keyMD5Base64() string {
+	sum := md5.Sum(k.key[:])
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// marshal - sets the SSE-C headers for the key on h.
+func (k SSECustomerKey) marshal(h http.Header) {
+	h.Set(amzSSECustomerAlgorithm, "AES256")
+	h.Set(amzSSECustomerKey, base64.StdEncoding.EncodeToString(k.key[:]))
+	h.Set(amzSSECustomerKeyMD5, k.keyMD5Base64())
+}
+
+// ErrSSECustomerKeyChanged - SSE-C key differs from the one the
+// multipart upload was started with.
+func ErrSSECustomerKeyChanged(uploadID string) error {
+	return ErrorResponse{
+		StatusCode: http.StatusBadRequest,
+		Code:       "InvalidArgument",
+		Message:    "The SSE-C key does not match the key used by multipart upload ‘" + uploadID + "’, the same key must be used for every part.",
+	}
+}
+
+// sseCustomerSession - SSE-C headers derived once for a multipart upload.
+type sseCustomerSession struct {
+	keyMD5 string
+	header http.Header
+}
+
+// sseCustomerSessionCache - Provides simple mechanism to hold the SSE-C
+// headers of in progress multipart uploads in memory, S3 requires
+// the same key on every part and on complete.
+type sseCustomerSessionCache struct {
+	// mutex is used for handling the concurrent
+	// read/write requests for cache.
+	sync.Mutex
+
+	// items holds the SSE-C sessions by upload id.
+	items map[string]sseCustomerSession
+}
+
+// newSSECustomerSessionCache - Provides a new SSE-C session cache to be
+// used internally with the client object.
+func newSSECustomerSessionCache() *sseCustomerSessionCache {
+	return &sseCustomerSessionCache{
+		items: make(map[string]sseCustomerSession),
+	}
+}
+
+// headers - returns the cached SSE-C headers for uploadID, deriving them
+// on first use. Fails if key is not the key the upload started with.
+func (r *sseCustomerSessionCache) headers(uploadID string, key SSECustomerKey) (http.Header, error) {
+	keyMD5 := key.keyMD5Base64()
+
+	r.Lock()
+	defer r.Unlock()
+	session, ok := r.items[uploadID]
+	if !ok {
+		session = sseCustomerSession{
+			keyMD5: keyMD5,
+			header: make(http.Header),
+		}
+		key.marshal(session.header)
+		r.items[uploadID] = session
+	}
+	if session.keyMD5 != keyMD5 {
+		return nil, ErrSSECustomerKeyChanged(uploadID)
+	}
+
+	// Hand out a copy, callers are free to add their own headers.
+	header := make(http.Header, len(session.header))
+	for k, v := range session.header {
+		header[k] = v
+	}
+	return header, nil
+}
+
+// Delete - Deletes the SSE-C session of uploadID.
+func (r *sseCustomerSessionCache) Delete(uploadID string) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.items, uploadID)
+}
+
+// SSECustomerHeaders - returns the SSE-C headers which have to be sent
+// with every part of multipart upload uploadID. The headers are derived
+// once per upload, a different key for the same upload is rejected.
+func (c Client) SSECustomerHeaders(uploadID string, key SSECustomerKey) (http.Header, error) {
+	if uploadID == "" {
+		return nil, errInvalidArgument(ErrInvalidUploadID)
+	}
+	return c.sseCSessions.headers(uploadID, key)
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"oss/lib/minio_ext"
+)
+
+// ContentIndex - objects by their owner and the MD5 and size of their
+// content, uploads declaring content already stored by the same owner
+// are answered with the stored object instead of being uploaded again.
+// Owners are the "user:<sub>" quota subjects of the token claims, empty
+// for requests without token. Implementations must be safe for
+// concurrent use.
+type ContentIndex interface {
+	// Lookup returns the objects of owner recorded with md5, hex
+	// encoded, and size, none when there are none.
+	Lookup(owner, md5 string, size int64) ([]Destination, error)
+
+	// Record adds dest of owner with md5 and size to the index.
+	Record(owner, md5 string, size int64, dest Destination) error
+
+	// Remove removes dest of owner with md5 and size from the index,
+	// for objects gone from the bucket.
+	Remove(owner, md5 string, size int64, dest Destination) error
+}
+
+// MemoryContentIndex - ContentIndex keeping the objects in memory, the
+// index is lost on restart.
+type MemoryContentIndex struct {
+	// mutex protects objects.
+	mutex   sync.Mutex
+	objects map[string][]Destination
+}
+
+// NewMemoryContentIndex - returns an empty content index.
+func NewMemoryContentIndex() *MemoryContentIndex {
+	return &MemoryContentIndex{objects: make(map[string][]Destination)}
+}
+
+// contentKey - returns the key of content of owner with md5 and size.
+func contentKey(owner, md5 string, size int64) string {
+	return owner + "/" + strings.ToLower(md5) + "-" + strconv.FormatInt(size, 10)
+}
+
+// Lookup - implements ContentIndex.
+func (m *MemoryContentIndex) Lookup(owner, md5 string, size int64) ([]Destination, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	objects := m.objects[contentKey(owner, md5, size)]
+	return append([]Destination(nil), objects...), nil
+}
+
+// Record - implements ContentIndex.
+func (m *MemoryContentIndex) Record(owner, md5 string, size int64, dest Destination) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	key := contentKey(owner, md5, size)
+	for _, object := range m.objects[key] {
+		if object == dest {
+			return nil
+		}
+	}
+	m.objects[key] = append(m.objects[key], dest)
+	return nil
+}
+
+// Remove - implements ContentIndex.
+func (m *MemoryContentIndex) Remove(owner, md5 string, size int64, dest Destination) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	key := contentKey(owner, md5, size)
+	objects := m.objects[key]
+	for i, object := range objects {
+		if object == dest {
+			objects = append(objects[:i], objects[i+1:]...)
+			break
+		}
+	}
+	if len(objects) == 0 {
+		delete(m.objects, key)
+	} else {
+		m.objects[key] = objects
+	}
+	return nil
+}
+
+// validMD5 - reports whether s is a hex encoded MD5.
+func validMD5(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == 16
+}
+
+// contentOwner - returns the owner in the index of requests with
+// claims, the user subject, and whether they are deduplicated at all.
+// Requests without token share the empty owner and are only
+// deduplicated with an Authorize check, which is run on every object
+// found for them.
+func (h *Handler) contentOwner(claims *TokenClaims) (string, bool) {
+	if claims == nil {
+		return "", h.opts.Authorize != nil
+	}
+	if claims.UserID == "" {
+		return "", false
+	}
+	return userSubject + claims.UserID, true
+}
+
+// findContent - returns an object of the caller in the bucket of dest
+// with content md5 and size the request may read, nil when there is
+// none. Objects gone from the bucket are removed from the index.
+func (h *Handler) findContent(r *http.Request, dest Destination, md5 string, size int64) (*Destination, error) {
+	owner, ok := h.contentOwner(ClaimsFromContext(r.Context()))
+	if !ok {
+		return nil, nil
+	}
+	objects, err := h.opts.Index.Lookup(owner, md5, size)
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range objects {
+		if object.BucketName != dest.BucketName {
+			continue
+		}
+		if h.authorize(r, object.BucketName, object.ObjectName, size) != nil {
+			continue
+		}
+		info, err := h.client.StatObject(r.Context(), object.BucketName, object.ObjectName)
+		if err != nil {
+			if minio_ext.ToErrorResponse(err).Code != "NoSuchKey" {
+				return nil, err
+			}
+			h.opts.Index.Remove(owner, md5, size, object)
+			continue
+		}
+		if info.Size != size {
+			h.opts.Index.Remove(owner, md5, size, object)
+			continue
+		}
+		found := object
+		return &found, nil
+	}
+	return nil, nil
+}
+
+// indexContent - reads the completed object back and records it for
+// the owner of the completing request in the index when its MD5 is md5,
+// so clients cannot record content they did not upload. Runs in the
+// background, the object may be large.
+func (h *Handler) indexContent(obj CompletedObject, md5 string) {
+	if h.opts.Index == nil || md5 == "" {
+		return
+	}
+	owner, ok := h.contentOwner(obj.Claims)
+	if !ok {
+		return
+	}
+	go func() {
+		sum, err := objectChecksum(context.Background(), h.client, obj.BucketName, obj.ObjectName, "MD5")
+		if err != nil || !strings.EqualFold(hex.EncodeToString(sum), md5) {
+			return
+		}
+		h.opts.Index.Record(owner, md5, obj.Size, Destination{BucketName: obj.BucketName, ObjectName: obj.ObjectName})
+	}()
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryContentIndex(t *testing.T) {
+	index := NewMemoryContentIndex()
+	a := Destination{BucketName: "bucket", ObjectName: "a"}
+	b := Destination{BucketName: "bucket", ObjectName: "b"}
+	index.Record("user:alice", "ABCD", 1, a)
+	index.Record("user:alice", "abcd", 1, b)
+	index.Record("user:alice", "abcd", 1, a)
+	index.Record("user:alice", "abcd", 2, b)
+	index.Record("user:bob", "abcd", 2, a)
+
+	testCases := []struct {
+		owner   string
+		md5     string
+		size    int64
+		objects []Destination
+	}{
+		{"user:alice", "abcd", 1, []Destination{a, b}},
+		{"user:alice", "AbCd", 1, []Destination{a, b}},
+		{"user:alice", "abcd", 2, []Destination{b}},
+		{"user:alice", "abcd", 3, nil},
+		{"user:alice", "ef01", 1, nil},
+		{"user:bob", "abcd", 1, nil},
+		{"user:bob", "abcd", 2, []Destination{a}},
+		{"", "abcd", 1, nil},
+	}
+	for i, testCase := range testCases {
+		if objects, err := index.Lookup(testCase.owner, testCase.md5, testCase.size); err != nil || !reflect.DeepEqual(objects, testCase.objects) {
+			t.Errorf("Test %d: expected %v, got %v %v", i+1, testCase.objects, objects, err)
+		}
+	}
+
+	index.Remove("user:alice", "abcd", 1, a)
+	index.Remove("user:alice", "abcd", 2, b)
+	index.Remove("user:alice", "abcd", 2, b)
+	if objects, _ := index.Lookup("user:alice", "abcd", 1); !reflect.DeepEqual(objects, []Destination{b}) {
+		t.Errorf("Expected b left, got %v", objects)
+	}
+	if objects, _ := index.Lookup("user:alice", "abcd", 2); objects != nil {
+		t.Errorf("Expected nothing left, got %v", objects)
+	}
+	if objects, _ := index.Lookup("user:bob", "abcd", 2); !reflect.DeepEqual(objects, []Destination{a}) {
+		t.Errorf("Expected the object of another owner kept, got %v", objects)
+	}
+}
+
+// uploadObject - uploads data as name through h declaring md5, prepare
+// changes the requests before they are sent.
+func uploadObject(t *testing.T, h http.Handler, name string, data []byte, md5 string, prepare func(r *http.Request)) {
+	var initRes initResponse
+	rec := serveRequest(t, h, "POST", "/uploads", initRequest{Name: name, Size: int64(len(data)), MD5: md5}, &initRes, prepare)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the upload of %s initiated, got %d %s", name, rec.Code, rec.Body)
+	}
+	var partsRes partsResponse
+	serveRequest(t, h, "GET", "/uploads/"+initRes.UploadID+"/parts", nil, &partsRes, prepare)
+	putPart(t, partsRes.Parts[0].URL, data)
+	if rec = serveRequest(t, h, "POST", "/uploads/"+initRes.UploadID+"/complete", nil, nil, prepare); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the upload of %s completed, got %d %s", name, rec.Code, rec.Body)
+	}
+}
+
+// waitFor - polls cond until it holds or a few seconds passed.
+func waitFor(cond func() bool) bool {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return true
+		}
+	}
+	return false
+}
+
+func TestHandlerDedup(t *testing.T) {
+	index := NewMemoryContentIndex()
+	h, s3 := newTestHandler(t, Options{
+		Index: index,
+		Authorize: func(r *http.Request, objectName string) error {
+			if r.Header.Get("X-Guest") != "" && !strings.HasPrefix(objectName, "guest/") {
+				return errors.New("guests only read guest objects")
+			}
+			return nil
+		},
+	})
+	data := []byte("0123456789abcdef")
+	sum := etagOf(data)
+	recorded := func(owner, md5, objectName string) bool {
+		objects, _ := index.Lookup(owner, md5, int64(len(data)))
+		for _, object := range objects {
+			if object.ObjectName == objectName {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Requests without token share the empty owner.
+	uploadObject(t, h, "first", data, sum, nil)
+	if !waitFor(func() bool { return recorded("", sum, "first") }) {
+		t.Fatal("Expected the object recorded")
+	}
+	// Content not matching the declared MD5 is not recorded.
+	reads := s3.count("get")
+	uploadObject(t, h, "false", data, etagOf([]byte("other")), nil)
+	if !waitFor(func() bool { return s3.count("get") > reads }) {
+		t.Fatal("Expected the object read back")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if recorded("", etagOf([]byte("other")), "false") {
+		t.Error("Expected the false MD5 not recorded")
+	}
+
+	testCases := []struct {
+		name     string
+		md5      string
+		size     int64
+		guest    bool
+		status   int
+		existing string
+	}{
+		{"copy", sum, int64(len(data)), false, http.StatusOK, "first"},
+		{"upper", strings.ToUpper(sum), int64(len(data)), false, http.StatusOK, "first"},
+		{"larger", sum, int64(len(data)) + 1, false, http.StatusCreated, ""},
+		{"undeclared", "", int64(len(data)), false, http.StatusCreated, ""},
+		{"guest/copy", sum, int64(len(data)), true, http.StatusCreated, ""},
+		{"invalid", "abc", int64(len(data)), false, http.StatusBadRequest, ""},
+	}
+	for i, testCase := range testCases {
+		initiated := s3.count("initiate")
+		var initRes initResponse
+		req := initRequest{Name: testCase.name, Size: testCase.size, MD5: testCase.md5}
+		rec := serveRequest(t, h, "POST", "/uploads", req, &initRes, func(r *http.Request) {
+			if testCase.guest {
+				r.Header.Set("X-Guest", "1")
+			}
+		})
+		if rec.Code != testCase.status {
+			t.Errorf("Test %d: expected %d, got %d %s", i+1, testCase.status, rec.Code, rec.Body)
+			continue
+		}
+		if testCase.existing == "" {
+			continue
+		}
+		if !initRes.Existing || initRes.ObjectName != testCase.existing || initRes.UploadID != "" || s3.count("initiate") != initiated {
+			t.Errorf("Test %d: expected the stored object, got %+v", i+1, initRes)
+		}
+	}
+
+	// Objects gone from the bucket are removed from the index.
+	s3.remove("/bucket/first")
+	var initRes initResponse
+	if rec := serve(t, h, "POST", "/uploads", initRequest{Name: "again", Size: int64(len(data)), MD5: sum}, &initRes); rec.Code != http.StatusCreated || initRes.Existing {
+		t.Fatalf("Expected a new upload, got %d %+v", rec.Code, initRes)
+	}
+	if recorded("", sum, "first") {
+		t.Error("Expected the removed object forgotten")
+	}
+
+	// Content uploaded with a token is only found for the same user.
+	secret := []byte("secret")
+	handler := RequireToken(secret, h)
+	alice := bearer(t, secret, TokenClaims{UserID: "alice", BucketName: "bucket", ObjectName: "/"})
+	uploadObject(t, handler, "alice/first", data, sum, alice)
+	if !waitFor(func() bool { return recorded(userSubject+"alice", sum, "alice/first") }) {
+		t.Fatal("Expected the object recorded for its user")
+	}
+	owners := []struct {
+		name     string
+		prepare  func(r *http.Request)
+		existing bool
+	}{
+		{"alice", alice, true},
+		{"bob", bearer(t, secret, TokenClaims{UserID: "bob", BucketName: "bucket", ObjectName: "/"}), false},
+		{"no subject", bearer(t, secret, TokenClaims{BucketName: "bucket", ObjectName: "/"}), false},
+	}
+	for i, owner := range owners {
+		var initRes initResponse
+		req := initRequest{Name: owner.name + "/copy", Size: int64(len(data)), MD5: sum}
+		rec := serveRequest(t, handler, "POST", "/uploads", req, &initRes, owner.prepare)
+		if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+			t.Errorf("Owner %d: unexpected answer %d %s", i+1, rec.Code, rec.Body)
+			continue
+		}
+		if initRes.Existing != owner.existing || (owner.existing && initRes.ObjectName != "alice/first") {
+			t.Errorf("Owner %d: expected existing %v, got %+v", i+1, owner.existing, initRes)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"oss/lib/minio_ext"
+
+	"golang.org/x/net/websocket"
+)
+
+// States of progress events.
+const (
+	EventUploading = "uploading"
+	EventCompleted = "completed"
+	EventAborted   = "aborted"
+	EventExpired   = "expired"
+	EventRejected  = "rejected"
+)
+
+// progressEvent - server verified progress of an upload, the bytes
+// count only parts the object storage confirms.
+type progressEvent struct {
+	UploadID       string `json:"uploadId"`
+	State          string `json:"state"`
+	Size           int64  `json:"size"`
+	ConfirmedBytes int64  `json:"confirmedBytes"`
+	PartsTotal     int    `json:"partsTotal"`
+	PartsDone      int    `json:"partsDone"`
+	ETag           string `json:"etag,omitempty"`
+}
+
+// eventHub - delivers the final events of completed and aborted
+// uploads to the progress streams of the upload.
+type eventHub struct {
+	// mutex protects subs.
+	mutex sync.Mutex
+	subs  map[string]map[chan progressEvent]struct{}
+}
+
+// subscribe - returns a channel receiving the final event of uploadID.
+func (e *eventHub) subscribe(uploadID string) chan progressEvent {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.subs == nil {
+		e.subs = make(map[string]map[chan progressEvent]struct{})
+	}
+	if e.subs[uploadID] == nil {
+		e.subs[uploadID] = make(map[chan progressEvent]struct{})
+	}
+	ch := make(chan progressEvent, 1)
+	e.subs[uploadID][ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe - stops delivery to ch.
+func (e *eventHub) unsubscribe(uploadID string, ch chan progressEvent) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	delete(e.subs[uploadID], ch)
+	if len(e.subs[uploadID]) == 0 {
+		delete(e.subs, uploadID)
+	}
+}
+
+// publish - delivers ev to the subscribers of its upload.
+func (e *eventHub) publish(ev progressEvent) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for ch := range e.subs[ev.UploadID] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// progress - returns the progress of state as listed by the object
+// storage.
+func (h *Handler) progress(ctx context.Context, state *minio_ext.UploadState) (progressEvent, error) {
+	ev := progressEvent{
+		UploadID:   state.UploadID,
+		State:      EventUploading,
+		Size:       state.Size,
+		PartsTotal: len(state.Parts),
+	}
+	partsInfo, err := h.uploaded(ctx, state)
+	if err != nil {
+		return ev, err
+	}
+	for _, spec := range state.Parts {
+		if _, ok := partsInfo[spec.PartNumber]; ok {
+			ev.PartsDone++
+			ev.ConfirmedBytes += spec.Size
+		}
+	}
+	return ev, nil
+}
+
+// watch - sends progress events of upload uploadID every time more
+// parts are confirmed until the upload is completed, aborted or gone
+// or ctx is done, polling every interval. keepalive is called when
+// nothing changed.
+func (h *Handler) watch(ctx context.Context, state *minio_ext.UploadState, interval time.Duration, send func(progressEvent) error, keepalive func() error) {
+	finished := h.hub.subscribe(state.UploadID)
+	defer h.hub.unsubscribe(state.UploadID, finished)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *progressEvent
+	for {
+		ev, err := h.progress(ctx, state)
+		if err != nil {
+			if !minio_ext.IsUploadExpired(err) {
+				return
+			}
+			// Gone on the server, completed or aborted meanwhile if
+			// the final event is there.
+			select {
+			case ev = <-finished:
+			default:
+				ev.State = EventExpired
+			}
+			send(ev)
+			return
+		}
+		if last == nil || ev != *last {
+			err = send(ev)
+			last = &ev
+		} else if keepalive != nil {
+			err = keepalive()
+		}
+		if err != nil {
+			return
+		}
+
+		select {
+		case ev = <-finished:
+			send(ev)
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// events - GET /uploads/{id}/events, streams progress events as
+// Server-Sent Events, or as JSON messages over a WebSocket when the
+// request asks for an upgrade.
+func (h *Handler) events(w http.ResponseWriter, r *http.Request, uploadID string) {
+	state, err := h.load(r, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		websocket.Handler(func(ws *websocket.Conn) {
+			defer ws.Close()
+			// The connection is hijacked, a closed connection is only
+			// noticed by reading from it.
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				io.Copy(ioutil.Discard, ws)
+				cancel()
+			}()
+			h.watch(ctx, state, h.opts.EventInterval, func(ev progressEvent) error {
+				return websocket.JSON.Send(ws, ev)
+			}, nil)
+		}).ServeHTTP(w, r)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, httpError{status: http.StatusNotImplemented, code: "NotImplemented", message: "Streaming is not supported."})
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	h.watch(r.Context(), state, h.opts.EventInterval, func(ev progressEvent) error {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.State, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}, func() error {
+		// Comment line keeping proxies from closing an idle stream.
+		if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+}
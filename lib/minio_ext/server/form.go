@@ -0,0 +1,87 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"oss/lib/minio_ext"
+)
+
+// maxFormSize - largest object S3 accepts in a single POST.
+const maxFormSize = 5 * 1024 * 1024 * 1024
+
+// formRequest - body of POST /uploads/form.
+type formRequest struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// formResponse - answer of POST /uploads/form, the browser posts the
+// fields and then the file to the URL.
+type formResponse struct {
+	URL        string            `json:"url"`
+	Fields     map[string]string `json:"fields"`
+	BucketName string            `json:"bucket"`
+	ObjectName string            `json:"object"`
+	MaxSize    int64             `json:"maxSize"`
+}
+
+// form - POST /uploads/form, returns a presigned POST policy for a
+// single-shot upload of a small file. The content-length-range of the
+// policy is the least of MaxSize and the limit of the upload token.
+// The server is not told when a form upload completes, so the quota
+// could not be reconciled, requests accounted against a quota are
+// refused and have to initiate a multipart upload.
+func (h *Handler) form(w http.ResponseWriter, r *http.Request) {
+	var req formRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeError(w, errBadRequest("MalformedJSON", "Malformed request body: "+err.Error()))
+		return
+	}
+	dest, err := h.opts.Router.Route(r.Context(), req.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err = h.authorize(r, dest.BucketName, dest.ObjectName, 0); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	maxSize := int64(maxFormSize)
+	if h.opts.MaxSize > 0 && h.opts.MaxSize < maxSize {
+		maxSize = h.opts.MaxSize
+	}
+	if claims := ClaimsFromContext(r.Context()); claims != nil && claims.MaxSize > 0 && claims.MaxSize < maxSize {
+		maxSize = claims.MaxSize
+	}
+	if len(h.quotaSubjects(r.Context())) > 0 {
+		writeError(w, errForbidden("Form uploads are not available with quotas, initiate a multipart upload."))
+		return
+	}
+
+	if err := h.ensureBucket(r.Context(), dest.BucketName); err != nil {
+		writeError(w, err)
+		return
+	}
+	u, fields, err := h.client.PresignedPostPolicyWithContext(r.Context(), minio_ext.PostPolicy{
+		BucketName:  dest.BucketName,
+		Key:         dest.ObjectName,
+		MaxSize:     maxSize,
+		ContentType: req.ContentType,
+		Expires:     h.opts.Expires,
+		Location:    h.location(dest.BucketName),
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, formResponse{
+		URL:        u.String(),
+		Fields:     fields,
+		BucketName: dest.BucketName,
+		ObjectName: dest.ObjectName,
+		MaxSize:    maxSize,
+	})
+}
@@ -0,0 +1,382 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"oss/lib/minio_ext"
+	"oss/lib/minio_ext/server/uploadpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcService - implements uploadpb.UploadServiceServer on the uploads
+// of a Handler, see Handler.GRPC.
+type grpcService struct {
+	uploadpb.UnimplementedUploadServiceServer
+	*Handler
+}
+
+// GRPC - returns the gRPC service serving the same uploads as h, for
+// backends not speaking HTTP. Register it on a grpc.Server with
+// uploadpb.RegisterUploadServiceServer. Protect the service with the
+// interceptors of TokenInterceptors or the Authorize check of the
+// options, which is passed a request carrying the method, peer address
+// and metadata of the call. Like requests, calls are served without
+// token claims unless the interceptors require them.
+// A Limiter wrapping h does not see the calls, limit them with its
+// Interceptors as well.
+func (h *Handler) GRPC() uploadpb.UploadServiceServer {
+	return grpcService{Handler: h}
+}
+
+// grpcError - maps err onto a gRPC status error.
+func grpcError(err error) error {
+	code := codes.Internal
+	switch e := err.(type) {
+	case httpError:
+		switch e.status {
+		case http.StatusBadRequest:
+			code = codes.InvalidArgument
+		case http.StatusUnauthorized:
+			code = codes.Unauthenticated
+		case http.StatusForbidden:
+			code = codes.PermissionDenied
+		case http.StatusNotFound:
+			code = codes.NotFound
+		case http.StatusConflict:
+			code = codes.FailedPrecondition
+		case http.StatusTooManyRequests:
+			code = codes.ResourceExhausted
+		case http.StatusUnprocessableEntity:
+			code = codes.Aborted
+		}
+	case minio_ext.ErrorResponse:
+		switch {
+		case e.Code == "InvalidArgument" || e.StatusCode == http.StatusBadRequest:
+			code = codes.InvalidArgument
+		case e.StatusCode == http.StatusForbidden:
+			code = codes.PermissionDenied
+		case e.StatusCode == http.StatusNotFound:
+			code = codes.NotFound
+		}
+	}
+	if minio_ext.IsUploadExpired(err) {
+		code = codes.NotFound
+	}
+	return status.Error(code, err.Error())
+}
+
+// load - returns the state of upload uploadID after checking the token
+// claims in ctx against its object.
+func (s grpcService) load(ctx context.Context, uploadID string) (*minio_ext.UploadState, error) {
+	state, err := s.lookup(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.authorize(ctx, state.BucketName, state.ObjectName, state.Size); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// authorize - checks the object and size against the token claims in
+// ctx, if any, and runs the Authorize check of the options on the call.
+func (s grpcService) authorize(ctx context.Context, bucketName, objectName string, size int64) error {
+	if err := s.authorizeClaims(ctx, bucketName, objectName, size); err != nil {
+		return err
+	}
+	if s.opts.Authorize == nil {
+		return nil
+	}
+	r, err := grpcRequest(ctx)
+	if err != nil {
+		return err
+	}
+	if err = s.opts.Authorize(r, objectName); err != nil {
+		return errForbidden(err.Error())
+	}
+	return nil
+}
+
+// grpcRequest - returns a request standing for the call of ctx for the
+// Authorize check: a POST to the full method name with the metadata as
+// headers, from the address of the peer.
+func grpcRequest(ctx context.Context) (*http.Request, error) {
+	method, _ := grpc.Method(ctx)
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, method, nil)
+	if err != nil {
+		return nil, err
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, values := range md {
+		for _, v := range values {
+			r.Header.Add(k, v)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.RemoteAddr = p.Addr.String()
+	}
+	return r, nil
+}
+
+// InitUpload - initiates an upload and returns its part plan.
+func (s grpcService) InitUpload(ctx context.Context, req *uploadpb.InitUploadRequest) (*uploadpb.InitUploadResponse, error) {
+	dest, err := s.opts.Router.Route(ctx, req.Name)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if err = s.authorize(ctx, dest.BucketName, dest.ObjectName, req.Size); err != nil {
+		return nil, grpcError(err)
+	}
+	state, err := s.initiateUpload(ctx, dest, req.Size, req.ContentType, "", nil)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	res := &uploadpb.InitUploadResponse{
+		UploadId: state.UploadID,
+		Bucket:   state.BucketName,
+		Object:   state.ObjectName,
+		Size:     state.Size,
+		PartSize: state.PartSize,
+	}
+	for _, spec := range state.Parts {
+		res.Parts = append(res.Parts, pbPart(spec))
+	}
+	return res, nil
+}
+
+// GetPartURLs - returns presigned URLs of the missing parts and the
+// uploaded parts, of the requested parts only when some are requested.
+func (s grpcService) GetPartURLs(ctx context.Context, req *uploadpb.GetPartURLsRequest) (*uploadpb.GetPartURLsResponse, error) {
+	state, err := s.load(ctx, req.UploadId)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	var wanted map[int]bool
+	if len(req.PartNumbers) > 0 {
+		wanted = make(map[int]bool)
+		for _, partNumber := range req.PartNumbers {
+			if partNumber < 1 || int(partNumber) > len(state.Parts) {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid part number %d", partNumber)
+			}
+			wanted[int(partNumber)] = true
+		}
+	}
+	partsInfo, err := s.uploaded(ctx, state)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	parts, uploaded, err := s.signParts(ctx, state, partsInfo, wanted)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	res := &uploadpb.GetPartURLsResponse{ExpiresInSeconds: int64(s.opts.Expires.Seconds())}
+	for _, part := range parts {
+		res.Parts = append(res.Parts, &uploadpb.PartURL{Part: pbPart(part.PartState), Url: part.URL})
+	}
+	for _, part := range uploaded {
+		res.Uploaded = append(res.Uploaded, &uploadpb.UploadedPart{PartNumber: int32(part.PartNumber), Size: part.Size, Etag: part.ETag})
+	}
+	return res, nil
+}
+
+// ReportPart - checks a part the client uploaded against the listing
+// of the object storage.
+func (s grpcService) ReportPart(ctx context.Context, req *uploadpb.ReportPartRequest) (*uploadpb.ReportPartResponse, error) {
+	if req.Part == nil {
+		return nil, status.Error(codes.InvalidArgument, "part is missing")
+	}
+	state, err := s.load(ctx, req.UploadId)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	partsInfo, err := s.client.ListObjectPartsWithContext(ctx, state.BucketName, state.ObjectName, state.UploadID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	res := &uploadpb.ReportPartResponse{}
+	if part, ok := partsInfo[int(req.Part.PartNumber)]; ok {
+		res.ServerPart = &uploadpb.UploadedPart{PartNumber: int32(part.PartNumber), Size: part.Size, Etag: part.ETag}
+		res.Confirmed = part.Size == req.Part.Size && normalizeETag(part.ETag) == normalizeETag(req.Part.Etag)
+	}
+	return res, nil
+}
+
+// Complete - verifies the parts and completes the upload, mismatching
+// parts fail with FailedPrecondition.
+func (s grpcService) Complete(ctx context.Context, req *uploadpb.CompleteRequest) (*uploadpb.CompleteResponse, error) {
+	state, err := s.load(ctx, req.UploadId)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	var reported []reportedPart
+	for _, part := range req.Parts {
+		reported = append(reported, reportedPart{PartNumber: int(part.PartNumber), Size: part.Size, ETag: part.Etag})
+	}
+	res, parts, err := s.verifyParts(ctx, state, reported)
+	if err != nil {
+		if minio_ext.IsUploadExpired(err) {
+			s.forget(ctx, state)
+		}
+		return nil, grpcError(err)
+	}
+	if !res.OK {
+		var mismatches []string
+		for _, m := range res.Mismatches {
+			mismatches = append(mismatches, m.Reason+":"+strconv.Itoa(m.PartNumber))
+		}
+		return nil, status.Error(codes.FailedPrecondition, "parts do not match the uploaded parts: "+strings.Join(mismatches, ","))
+	}
+	obj, flags, err := s.completeUpload(ctx, state, parts)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	completed := &uploadpb.CompleteResponse{Bucket: obj.BucketName, Object: obj.ObjectName, Etag: obj.ETag}
+	for _, flag := range flags {
+		completed.Flags = append(completed.Flags, &uploadpb.HookFailure{Hook: flag.Hook, Message: flag.Message})
+	}
+	return completed, nil
+}
+
+// Abort - aborts the upload and removes its parts.
+func (s grpcService) Abort(ctx context.Context, req *uploadpb.AbortRequest) (*uploadpb.AbortResponse, error) {
+	state, err := s.load(ctx, req.UploadId)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if err = s.abortUpload(ctx, state); err != nil {
+		return nil, grpcError(err)
+	}
+	return &uploadpb.AbortResponse{}, nil
+}
+
+// WatchProgress - streams server verified progress of the upload,
+// polling every EventInterval or every second when it is not set.
+func (s grpcService) WatchProgress(req *uploadpb.WatchProgressRequest, stream uploadpb.UploadService_WatchProgressServer) error {
+	state, err := s.load(stream.Context(), req.UploadId)
+	if err != nil {
+		return grpcError(err)
+	}
+	interval := s.opts.EventInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	s.watch(stream.Context(), state, interval, func(ev progressEvent) error {
+		return stream.Send(&uploadpb.ProgressEvent{
+			UploadId:       ev.UploadID,
+			State:          ev.State,
+			Size:           ev.Size,
+			ConfirmedBytes: ev.ConfirmedBytes,
+			PartsTotal:     int32(ev.PartsTotal),
+			PartsDone:      int32(ev.PartsDone),
+			Etag:           ev.ETag,
+		})
+	}, nil)
+	return nil
+}
+
+// pbPart - converts a planned part.
+func pbPart(spec minio_ext.PartState) *uploadpb.Part {
+	return &uploadpb.Part{PartNumber: int32(spec.PartNumber), Offset: spec.Offset, Size: spec.Size}
+}
+
+// TokenInterceptors - interceptors rejecting calls without a valid
+// upload token signed with secret with Unauthenticated, the token is
+// taken from the "authorization: Bearer" metadata. The service only
+// serves uploads of the bucket, key and size the token allows.
+func TokenInterceptors(secret []byte) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	verify := func(ctx context.Context) (context.Context, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var token string
+		for _, auth := range md.Get("authorization") {
+			if strings.HasPrefix(auth, "Bearer ") {
+				token = strings.TrimPrefix(auth, "Bearer ")
+			}
+		}
+		if token == "" {
+			return nil, status.Error(codes.Unauthenticated, ErrTokenMissing.Error())
+		}
+		claims, err := ParseToken(secret, token, time.Now())
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return context.WithValue(ctx, claimsKey{}, claims), nil
+	}
+
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := verify(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := verify(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, claimsStream{ServerStream: ss, ctx: ctx})
+	}
+	return unary, stream
+}
+
+// Interceptors - interceptors limiting the calls of the gRPC service as
+// Wrap limits HTTP requests, keyed on the request the Authorize check
+// is passed. Calls over the rate are refused with ResourceExhausted and
+// a retry-after header. Chain them after TokenInterceptors to limit per
+// token subject:
+//
+//	grpc.ChainUnaryInterceptor(tokenUnary, limitUnary)
+func (l *Limiter) Interceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	limit := func(ctx context.Context, setHeader func(metadata.MD) error) (context.Context, error) {
+		r, err := grpcRequest(ctx)
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		key := l.limits.Key(r)
+		if wait := l.take(key, time.Now()); wait > 0 {
+			setHeader(metadata.Pairs("retry-after", retryAfterSeconds(wait)))
+			return nil, grpcError(errTooManyRequests("Request rate limit exceeded.", wait))
+		}
+		return context.WithValue(ctx, limitKey{}, &limitScope{limiter: l, key: key}), nil
+	}
+
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := limit(ctx, func(md metadata.MD) error {
+			return grpc.SetHeader(ctx, md)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := limit(ss.Context(), ss.SetHeader)
+		if err != nil {
+			return err
+		}
+		return handler(srv, claimsStream{ServerStream: ss, ctx: ctx})
+	}
+	return unary, stream
+}
+
+// claimsStream - server stream carrying the verified token claims or
+// the limit scope in its context.
+type claimsStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context - returns the context with the claims.
+func (s claimsStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"oss/lib/minio_ext/server/uploadpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCAuthorize(t *testing.T) {
+	refuse := func(r *http.Request, objectName string) error {
+		if objectName == "private" {
+			return errors.New("private object")
+		}
+		return nil
+	}
+	testCases := []struct {
+		authorize func(r *http.Request, objectName string) error
+		claims    *TokenClaims
+		name      string
+		// Expected answers of both transports.
+		status int
+		code   codes.Code
+	}{
+		// Without check and token both transports serve the upload.
+		{nil, nil, "file", http.StatusCreated, codes.OK},
+		{refuse, nil, "file", http.StatusCreated, codes.OK},
+		{refuse, nil, "private", http.StatusForbidden, codes.PermissionDenied},
+		{nil, &TokenClaims{BucketName: "bucket", ObjectName: "file"}, "file", http.StatusCreated, codes.OK},
+		{nil, &TokenClaims{BucketName: "bucket", ObjectName: "file"}, "other", http.StatusForbidden, codes.PermissionDenied},
+	}
+	for i, testCase := range testCases {
+		h, _ := newTestHandler(t, Options{Authorize: testCase.authorize})
+		ctx := context.Background()
+		if testCase.claims != nil {
+			ctx = context.WithValue(ctx, claimsKey{}, testCase.claims)
+		}
+		rec := serveRequest(t, h, "POST", "/uploads", initRequest{Name: testCase.name, Size: 1}, nil, func(r *http.Request) {
+			*r = *r.WithContext(ctx)
+		})
+		if rec.Code != testCase.status {
+			t.Errorf("Test %d: expected %d, got %d %s", i+1, testCase.status, rec.Code, rec.Body)
+		}
+		_, err := h.GRPC().InitUpload(ctx, &uploadpb.InitUploadRequest{Name: testCase.name, Size: 1})
+		if code := status.Code(err); code != testCase.code {
+			t.Errorf("Test %d: expected %v, got %v", i+1, testCase.code, err)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package server
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultActiveTTL - default time after which an upload neither
+// completed nor aborted stops counting as active.
+const defaultActiveTTL = 24 * time.Hour
+
+// activeRetryAfter - Retry-After of requests rejected for too many
+// active uploads, the client has to finish an upload first.
+const activeRetryAfter = 30 * time.Second
+
+// Limits - settings of a Limiter.
+type Limits struct {
+	// Requests per second allowed per client and the burst on top of
+	// it, requests are not limited when Rate is 0. Burst defaults to
+	// one second worth of requests.
+	Rate  float64
+	Burst int
+
+	// Uploads a client may have initiated and neither completed nor
+	// aborted, not capped when 0.
+	MaxActiveUploads int
+
+	// Uploads stop counting as active after ActiveTTL, in case they are
+	// left behind or expire, 24 hours by default.
+	ActiveTTL time.Duration
+
+	// Key identifying the client of a request, ClientKey by default.
+	Key func(r *http.Request) string
+}
+
+// Limiter - middleware protecting the object storage from abusive
+// clients by limiting the request rate and the active uploads of every
+// client, requests over the limits are answered with 429 Too Many
+// Requests and a Retry-After header. Put it behind RequireToken to
+// limit per token subject instead of per IP:
+//
+//	RequireToken(secret, limiter.Wrap(handler))
+//
+// A Limiter may wrap the Handler and its Uppy endpoints at once. Calls of
+// the gRPC service bypass Wrap, serve it with the Interceptors of the
+// same Limiter so clients share their limits over both transports.
+type Limiter struct {
+	limits Limits
+
+	// mutex protects all fields below.
+	mutex   sync.Mutex
+	buckets map[string]*requestBucket
+	swept   time.Time
+	uploads map[string]activeUpload
+	active  map[string]int
+}
+
+// requestBucket - token bucket of the requests of a client.
+type requestBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// activeUpload - an upload counted for its client.
+type activeUpload struct {
+	key   string
+	since time.Time
+}
+
+// limitScope - the limiter and client of a request, see Limiter.Wrap.
+type limitScope struct {
+	limiter *Limiter
+	key     string
+}
+
+// limitKey - context key of the limit scope.
+type limitKey struct{}
+
+// NewLimiter - returns a limiter enforcing limits.
+func NewLimiter(limits Limits) *Limiter {
+	if limits.Burst <= 0 {
+		limits.Burst = int(math.Ceil(limits.Rate))
+	}
+	if limits.ActiveTTL <= 0 {
+		limits.ActiveTTL = defaultActiveTTL
+	}
+	if limits.Key == nil {
+		limits.Key = ClientKey
+	}
+	return &Limiter{
+		limits:  limits,
+		buckets: make(map[string]*requestBucket),
+		uploads: make(map[string]activeUpload),
+		active:  make(map[string]int),
+	}
+}
+
+// ClientKey - returns the token subject of the request, or its remote
+// IP when it has no token claims. Proxies forwarding requests need a
+// Key of their own.
+func ClientKey(r *http.Request) string {
+	if claims := ClaimsFromContext(r.Context()); claims != nil && claims.UserID != "" {
+		return "user:" + claims.UserID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// Wrap - returns next limited by l.
+func (l *Limiter) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := l.limits.Key(r)
+		if wait := l.take(key, time.Now()); wait > 0 {
+			writeError(w, errTooManyRequests("Request rate limit exceeded.", wait))
+			return
+		}
+		scope := &limitScope{limiter: l, key: key}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), limitKey{}, scope)))
+	})
+}
+
+// take - takes a request of key from its bucket, returns how long to
+// wait when the bucket is empty.
+func (l *Limiter) take(key string, now time.Time) time.Duration {
+	if l.limits.Rate <= 0 {
+		return 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	burst := float64(l.limits.Burst)
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &requestBucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.limits.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / l.limits.Rate * float64(time.Second))
+}
+
+// sweep - forgets the buckets refilled completely and the active
+// uploads older than ActiveTTL, at most once a minute.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	if l.limits.Rate > 0 {
+		full := time.Duration(float64(l.limits.Burst) / l.limits.Rate * float64(time.Second))
+		for key, b := range l.buckets {
+			if now.Sub(b.last) >= full {
+				delete(l.buckets, key)
+			}
+		}
+	}
+	for uploadID, upload := range l.uploads {
+		if now.Sub(upload.since) >= l.limits.ActiveTTL {
+			l.forget(uploadID)
+		}
+	}
+}
+
+// reserve - reserves an active upload of key, returns false when key
+// has MaxActiveUploads uploads.
+func (l *Limiter) reserve(key string, now time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.sweep(now)
+	if l.limits.MaxActiveUploads > 0 && l.active[key] >= l.limits.MaxActiveUploads {
+		return false
+	}
+	l.active[key]++
+	return true
+}
+
+// commit - turns the reservation of key into the active upload
+// uploadID, or cancels it when uploadID is empty.
+func (l *Limiter) commit(key, uploadID string, now time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if uploadID == "" {
+		l.decrement(key)
+		return
+	}
+	l.uploads[uploadID] = activeUpload{key: key, since: now}
+}
+
+// release - stops counting upload uploadID.
+func (l *Limiter) release(uploadID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.forget(uploadID)
+}
+
+// forget - removes upload uploadID, mutex must be held.
+func (l *Limiter) forget(uploadID string) {
+	upload, ok := l.uploads[uploadID]
+	if !ok {
+		return
+	}
+	delete(l.uploads, uploadID)
+	l.decrement(upload.key)
+}
+
+// decrement - decrements the active uploads of key, mutex must be held.
+func (l *Limiter) decrement(key string) {
+	if l.active[key] <= 1 {
+		delete(l.active, key)
+		return
+	}
+	l.active[key]--
+}
+
+// reserveUpload - reserves an active upload for the client of ctx when
+// the request went through a Limiter. The returned function must be
+// called with the id of the initiated upload, or with an empty id when
+// initiating failed.
+func reserveUpload(ctx context.Context) (func(uploadID string), error) {
+	scope, _ := ctx.Value(limitKey{}).(*limitScope)
+	if scope == nil {
+		return func(string) {}, nil
+	}
+	if !scope.limiter.reserve(scope.key, time.Now()) {
+		return nil, errTooManyRequests("Too many active uploads, complete or abort an upload first.", activeRetryAfter)
+	}
+	return func(uploadID string) {
+		scope.limiter.commit(scope.key, uploadID, time.Now())
+	}, nil
+}
+
+// releaseUpload - stops counting upload uploadID as active when the
+// request went through a Limiter.
+func releaseUpload(ctx context.Context, uploadID string) {
+	if scope, _ := ctx.Value(limitKey{}).(*limitScope); scope != nil {
+		scope.limiter.release(uploadID)
+	}
+}
+
+// errTooManyRequests - request over a limit, to be retried after wait.
+func errTooManyRequests(message string, wait time.Duration) error {
+	return httpError{status: http.StatusTooManyRequests, code: "SlowDown", message: message, retryAfter: wait}
+}
+
+// retryAfterSeconds - value of the Retry-After header for wait, at
+// least a second.
+func retryAfterSeconds(wait time.Duration) string {
+	seconds := int64(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestLimiterTake(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	testCases := []struct {
+		limits Limits
+		// Offsets of the requests from start and the expected waits.
+		offsets []time.Duration
+		waits   []time.Duration
+	}{
+		{Limits{}, []time.Duration{0, 0, 0}, []time.Duration{0, 0, 0}},
+		{Limits{Rate: 2}, []time.Duration{0, 0, 0}, []time.Duration{0, 0, 500 * time.Millisecond}},
+		{Limits{Rate: 2}, []time.Duration{0, 0, 250 * time.Millisecond, 500 * time.Millisecond}, []time.Duration{0, 0, 250 * time.Millisecond, 0}},
+		{Limits{Rate: 1, Burst: 3}, []time.Duration{0, 0, 0, 0}, []time.Duration{0, 0, 0, time.Second}},
+		// Buckets refill up to the burst.
+		{Limits{Rate: 1, Burst: 1}, []time.Duration{0, time.Hour, time.Hour}, []time.Duration{0, 0, time.Second}},
+	}
+	for i, testCase := range testCases {
+		l := NewLimiter(testCase.limits)
+		for j, offset := range testCase.offsets {
+			if wait := l.take("client", start.Add(offset)); wait != testCase.waits[j] {
+				t.Errorf("Test %d: request %d expected to wait %v, got %v", i+1, j+1, testCase.waits[j], wait)
+			}
+		}
+		// Other clients have buckets of their own.
+		if wait := l.take("other", start); wait != 0 {
+			t.Errorf("Test %d: expected another client served, got %v", i+1, wait)
+		}
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	testCases := []struct {
+		remoteAddr string
+		claims     *TokenClaims
+		key        string
+	}{
+		{"192.0.2.1:1234", nil, "ip:192.0.2.1"},
+		{"[2001:db8::1]:1234", nil, "ip:2001:db8::1"},
+		{"pipe", nil, "ip:pipe"},
+		{"192.0.2.1:1234", &TokenClaims{UserID: "user"}, "user:user"},
+		{"192.0.2.1:1234", &TokenClaims{}, "ip:192.0.2.1"},
+	}
+	for i, testCase := range testCases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = testCase.remoteAddr
+		if testCase.claims != nil {
+			r = r.WithContext(context.WithValue(r.Context(), claimsKey{}, testCase.claims))
+		}
+		if key := ClientKey(r); key != testCase.key {
+			t.Errorf("Test %d: expected %q, got %q", i+1, testCase.key, key)
+		}
+	}
+}
+
+// from - returns a function setting the remote address of requests.
+func from(remoteAddr string) func(r *http.Request) {
+	return func(r *http.Request) {
+		r.RemoteAddr = remoteAddr
+	}
+}
+
+func TestLimiterWrapRate(t *testing.T) {
+	h, _ := newTestHandler(t, Options{})
+	handler := NewLimiter(Limits{Rate: 1}).Wrap(h)
+	testCases := []struct {
+		remoteAddr string
+		status     int
+		retryAfter string
+	}{
+		{"192.0.2.1:1", http.StatusNotFound, ""},
+		{"192.0.2.1:2", http.StatusTooManyRequests, "1"},
+		{"192.0.2.2:1", http.StatusNotFound, ""},
+	}
+	for i, testCase := range testCases {
+		var errRes errorBody
+		rec := serveRequest(t, handler, "GET", "/uploads/unknown/parts", nil, &errRes, from(testCase.remoteAddr))
+		if rec.Code != testCase.status || rec.Header().Get("Retry-After") != testCase.retryAfter {
+			t.Errorf("Test %d: expected %d with Retry-After %q, got %d with %q", i+1, testCase.status, testCase.retryAfter, rec.Code, rec.Header().Get("Retry-After"))
+		}
+		if testCase.status == http.StatusTooManyRequests && errRes.Code != "SlowDown" {
+			t.Errorf("Test %d: expected SlowDown, got %s", i+1, errRes.Code)
+		}
+	}
+}
+
+func TestLimiterWrapActiveUploads(t *testing.T) {
+	h, _ := newTestHandler(t, Options{})
+	limiter := NewLimiter(Limits{MaxActiveUploads: 1})
+	handler := limiter.Wrap(h)
+	uppy := limiter.Wrap(h.Uppy())
+	first, second := from("192.0.2.1:1"), from("192.0.2.2:1")
+
+	initiate := func(prepare func(r *http.Request), name string) (*httptest.ResponseRecorder, initResponse) {
+		var initRes initResponse
+		rec := serveRequest(t, handler, "POST", "/uploads", initRequest{Name: name, Size: 1}, &initRes, prepare)
+		return rec, initRes
+	}
+	rec, upload := initiate(first, "a")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the upload initiated, got %d %s", rec.Code, rec.Body)
+	}
+	if rec, _ = initiate(first, "b"); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" {
+		t.Fatalf("Expected a second upload refused, got %d", rec.Code)
+	}
+	if rec = serveRequest(t, uppy, "POST", "/s3/multipart", uppyCreateRequest{Filename: "b"}, nil, first); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected a second Uppy upload refused, got %d", rec.Code)
+	}
+	if rec, _ = initiate(second, "c"); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected another client served, got %d", rec.Code)
+	}
+	// Refused requests do not count.
+	if rec, _ = initiate(first, ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected an invalid upload refused, got %d", rec.Code)
+	}
+
+	// Aborting the upload frees its slot.
+	if rec = serveRequest(t, handler, "DELETE", "/uploads/"+upload.UploadID, nil, nil, first); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected the upload aborted, got %d", rec.Code)
+	}
+	var created map[string]string
+	if rec = serveRequest(t, uppy, "POST", "/s3/multipart", uppyCreateRequest{Filename: "b"}, &created, first); rec.Code != http.StatusOK {
+		t.Fatalf("Expected an upload after the abort, got %d %s", rec.Code, rec.Body)
+	}
+	if rec = serveRequest(t, uppy, "DELETE", "/s3/multipart/"+created["uploadId"]+"?key=b", nil, nil, first); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the Uppy upload aborted, got %d", rec.Code)
+	}
+	if rec, _ = initiate(first, "d"); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected an upload after the Uppy abort, got %d", rec.Code)
+	}
+
+	// Uploads left behind stop counting after ActiveTTL.
+	limiter.mutex.Lock()
+	limiter.limits.ActiveTTL = time.Nanosecond
+	limiter.swept = time.Time{}
+	limiter.mutex.Unlock()
+	if rec, _ = initiate(first, "e"); rec.Code != http.StatusCreated {
+		t.Errorf("Expected an upload after the TTL, got %d", rec.Code)
+	}
+}
+
+// testStream - server stream of a call, only its context and headers
+// are used.
+type testStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+// Context - returns the context of the call.
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+// SetHeader - records the header sent.
+func (s *testStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestLimiterInterceptors(t *testing.T) {
+	h, _ := newTestHandler(t, Options{})
+	limiter := NewLimiter(Limits{Rate: 1})
+	handler := limiter.Wrap(h)
+	unary, stream := limiter.Interceptors()
+	call := func(addr string) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 1}})
+	}
+
+	// Calls share the limits of HTTP requests of the same client.
+	if rec := serveRequest(t, handler, "GET", "/uploads/unknown/parts", nil, nil, from("192.0.2.1:1")); rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected the request served, got %d", rec.Code)
+	}
+	testCases := []struct {
+		addr   string
+		stream bool
+		code   codes.Code
+	}{
+		{"192.0.2.1", false, codes.ResourceExhausted},
+		{"192.0.2.1", true, codes.ResourceExhausted},
+		{"192.0.2.2", false, codes.OK},
+		{"192.0.2.3", true, codes.OK},
+		{"192.0.2.3", true, codes.ResourceExhausted},
+	}
+	for i, testCase := range testCases {
+		var scoped bool
+		next := func(ctx context.Context) {
+			scoped = ctx.Value(limitKey{}) != nil
+		}
+		var err error
+		ss := &testStream{ctx: call(testCase.addr)}
+		if testCase.stream {
+			err = stream(nil, ss, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+				next(ss.Context())
+				return nil
+			})
+		} else {
+			_, err = unary(ss.ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				next(ctx)
+				return nil, nil
+			})
+		}
+		if code := status.Code(err); code != testCase.code {
+			t.Errorf("Test %d: expected %v, got %v", i+1, testCase.code, err)
+		}
+		if scoped != (testCase.code == codes.OK) {
+			t.Errorf("Test %d: expected the limit scope passed on with served calls only", i+1)
+		}
+		if testCase.stream && testCase.code != codes.OK && len(ss.header.Get("retry-after")) != 1 {
+			t.Errorf("Test %d: expected a retry-after header, got %v", i+1, ss.header)
+		}
+	}
+}
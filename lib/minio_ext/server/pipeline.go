@@ -0,0 +1,267 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"oss/lib/minio_ext"
+)
+
+// defaultHookTimeout - default time a hook may take.
+const defaultHookTimeout = 30 * time.Second
+
+// FailurePolicy - what happens to an object when a hook fails.
+type FailurePolicy int
+
+// Failure policies of hooks.
+const (
+	// FailFlag keeps the object, the failure is reported with the
+	// completion and to OnFlag, the next hooks run.
+	FailFlag FailurePolicy = iota
+	// FailDelete deletes the object and rejects the upload with 422
+	// Unprocessable Entity, the next hooks do not run.
+	FailDelete
+)
+
+// CompletedObject - an object completed by the Handler on its way
+// through the hooks. Hooks moving the object update ObjectName.
+type CompletedObject struct {
+	BucketName string `json:"bucket"`
+	ObjectName string `json:"object"`
+	UploadID   string `json:"uploadId"`
+	Size       int64  `json:"size"`
+	ETag       string `json:"etag"`
+
+	// Claims of the upload token of the completing request, nil
+	// without token.
+	Claims *TokenClaims `json:"claims,omitempty"`
+}
+
+// Hook - a processing step run after an upload completed and before
+// the completion is answered, such as a virus scan or a thumbnail
+// trigger.
+type Hook struct {
+	Name string
+	Run  func(ctx context.Context, client *minio_ext.Client, obj *CompletedObject) error
+
+	// Time the hook may take, 30 seconds by default.
+	Timeout time.Duration
+
+	// Failure policy, FailFlag by default.
+	OnFailure FailurePolicy
+}
+
+// HookFailure - a failed hook of a completed upload.
+type HookFailure struct {
+	Hook    string `json:"hook"`
+	Message string `json:"message"`
+}
+
+// process - runs the hooks on obj in order, returns the flags raised
+// and an error when a hook rejected the object, which is deleted then.
+// Hooks get their own deadline, a client going away does not cut them.
+func (h *Handler) process(obj *CompletedObject) ([]HookFailure, error) {
+	var flags []HookFailure
+	for _, hook := range h.opts.Hooks {
+		timeout := hook.Timeout
+		if timeout <= 0 {
+			timeout = defaultHookTimeout
+		}
+		// Objects the hooks write are notified with the completion.
+		ctx, cancel := context.WithTimeout(minio_ext.WithoutCompletionWebhook(context.Background()), timeout)
+		err := hook.Run(ctx, h.client, obj)
+		cancel()
+		if err == nil {
+			continue
+		}
+
+		failure := HookFailure{Hook: hook.Name, Message: err.Error()}
+		if hook.OnFailure == FailDelete {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err = deleteObject(ctx, h.client, obj.BucketName, obj.ObjectName); err != nil {
+				failure.Message += ", deleting the object failed: " + err.Error()
+			}
+			return flags, httpError{
+				status:  http.StatusUnprocessableEntity,
+				code:    "UploadRejected",
+				message: "Upload rejected by " + hook.Name + ": " + failure.Message,
+			}
+		}
+		flags = append(flags, failure)
+		if h.opts.OnFlag != nil {
+			h.opts.OnFlag(*obj, failure)
+		}
+	}
+	return flags, nil
+}
+
+// deleteObject - deletes an object, a missing object is no error.
+func deleteObject(ctx context.Context, client *minio_ext.Client, bucketName, objectName string) error {
+	resp, err := client.Execute(ctx, http.MethodDelete, minio_ext.RequestSpec{BucketName: bucketName, ObjectName: objectName})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("delete answered %s", resp.Status)
+	}
+	return nil
+}
+
+// HTTPHook - hook posting the completed object as JSON to url, for
+// virus scanners or thumbnail services. Answers other than 2xx fail
+// the hook.
+func HTTPHook(name, url string, timeout time.Duration, onFailure FailurePolicy) Hook {
+	return Hook{
+		Name:      name,
+		Timeout:   timeout,
+		OnFailure: onFailure,
+		Run: func(ctx context.Context, client *minio_ext.Client, obj *CompletedObject) error {
+			body, err := json.Marshal(obj)
+			if err != nil {
+				return err
+			}
+			req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+				return fmt.Errorf("%s answered %s: %s", name, resp.Status, strings.TrimSpace(string(msg)))
+			}
+			return nil
+		},
+	}
+}
+
+// ChecksumHook - hook reading the object back and comparing its
+// checksum of algorithm, "MD5" or one of the minio_ext checksum
+// algorithms, with the hex or base64 encoded checksum expected returns
+// for it. Objects expected returns no checksum for pass.
+func ChecksumHook(algorithm string, expected func(obj CompletedObject) string, timeout time.Duration, onFailure FailurePolicy) Hook {
+	return Hook{
+		Name:      "checksum",
+		Timeout:   timeout,
+		OnFailure: onFailure,
+		Run: func(ctx context.Context, client *minio_ext.Client, obj *CompletedObject) error {
+			want := expected(*obj)
+			if want == "" {
+				return nil
+			}
+			got, err := objectChecksum(ctx, client, obj.BucketName, obj.ObjectName, algorithm)
+			if err != nil {
+				return err
+			}
+			if !strings.EqualFold(want, hex.EncodeToString(got)) && want != base64.StdEncoding.EncodeToString(got) {
+				return fmt.Errorf("%s checksum %s does not match the expected %s", algorithm, hex.EncodeToString(got), want)
+			}
+			return nil
+		},
+	}
+}
+
+// objectChecksum - reads an object and returns its checksum of
+// algorithm, see checksumHash.
+func objectChecksum(ctx context.Context, client *minio_ext.Client, bucketName, objectName, algorithm string) ([]byte, error) {
+	sum := checksumHash(algorithm)
+	if sum == nil {
+		return nil, fmt.Errorf("unknown checksum algorithm %s", algorithm)
+	}
+	resp, err := client.Execute(ctx, http.MethodGet, minio_ext.RequestSpec{BucketName: bucketName, ObjectName: objectName})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reading the object answered %s", resp.Status)
+	}
+	if _, err = io.Copy(sum, resp.Body); err != nil {
+		return nil, err
+	}
+	return sum.Sum(nil), nil
+}
+
+// checksumHash - returns a hash computing checksums of algorithm, nil
+// for unknown algorithms.
+func checksumHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case "MD5":
+		return md5.New()
+	case minio_ext.ChecksumSHA256:
+		return sha256.New()
+	case minio_ext.ChecksumSHA1:
+		return sha1.New()
+	case minio_ext.ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case minio_ext.ChecksumCRC32:
+		return crc32.NewIEEE()
+	}
+	return nil
+}
+
+// maxCopySize - largest object copied in a single request, larger
+// objects are copied part by part.
+const maxCopySize = 5 * 1024 * 1024 * 1024
+
+// MoveHook - hook moving the object within its bucket to the key
+// target returns for it, for example from a staging prefix to its final
+// place. Objects target returns no key or their own key for stay. The
+// copy is pinned to the ETag of the object and the source is removed
+// after the copy, later hooks and a failure policy see the moved
+// object. Objects larger than
+// 5 GiB are copied part by part with ComposeObject and lose their
+// metadata.
+func MoveHook(target func(obj CompletedObject) string, timeout time.Duration, onFailure FailurePolicy) Hook {
+	return Hook{
+		Name:      "move",
+		Timeout:   timeout,
+		OnFailure: onFailure,
+		Run: func(ctx context.Context, client *minio_ext.Client, obj *CompletedObject) error {
+			objectName := target(*obj)
+			if objectName == "" || objectName == obj.ObjectName {
+				return nil
+			}
+			var etag string
+			var err error
+			if obj.Size > maxCopySize {
+				etag, err = client.ComposeObject(ctx, obj.BucketName, objectName, []minio_ext.ComposeSource{
+					{BucketName: obj.BucketName, ObjectName: obj.ObjectName},
+				}, minio_ext.ComposeOptions{})
+			} else {
+				etag, err = client.CopyObject(ctx, obj.BucketName, objectName, obj.BucketName, obj.ObjectName, minio_ext.CopyObjectOptions{
+					MatchETag: "\"" + obj.ETag + "\"",
+				})
+			}
+			if err != nil {
+				return err
+			}
+			source := obj.ObjectName
+			obj.ObjectName, obj.ETag = objectName, strings.Trim(etag, "\"")
+			if err = client.RemoveObject(ctx, obj.BucketName, source); err != nil {
+				return fmt.Errorf("moved from %s, removing the source failed: %v", source, err)
+			}
+			return nil
+		},
+	}
+}
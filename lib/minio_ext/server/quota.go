@@ -0,0 +1,225 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"oss/lib/minio_ext"
+)
+
+// QuotaStore - bytes used and allowed per quota subject, subjects are
+// "user:<sub>" and "tenant:<tenant>" of the token claims. The declared
+// size of an upload is reserved when it is initiated and corrected to
+// the size of the object after completion. Implementations must be safe
+// for concurrent use.
+type QuotaStore interface {
+	// Reserve adds n bytes to the usage of subject unless that exceeds
+	// its limit, returns the bytes remaining before the reservation and
+	// whether it was made. Unlimited subjects return -1.
+	Reserve(subject string, n int64) (remaining int64, ok bool, err error)
+
+	// Adjust adds delta, negative to give bytes back, to the usage of
+	// subject regardless of its limit.
+	Adjust(subject string, delta int64) error
+
+	// Remaining returns the bytes subject may still upload, -1 when
+	// it is unlimited.
+	Remaining(subject string) (int64, error)
+}
+
+// MemoryQuotaStore - QuotaStore keeping the usage in memory, usage is
+// lost on restart. Limits are set per subject or default per kind.
+type MemoryQuotaStore struct {
+	// Limits of subjects without a limit of their own, 0 for unlimited.
+	UserLimit   int64
+	TenantLimit int64
+
+	// mutex protects limits and used.
+	mutex  sync.Mutex
+	limits map[string]int64
+	used   map[string]int64
+}
+
+// NewMemoryQuotaStore - returns a store limiting every user to
+// userLimit and every tenant to tenantLimit bytes, 0 for unlimited.
+func NewMemoryQuotaStore(userLimit, tenantLimit int64) *MemoryQuotaStore {
+	return &MemoryQuotaStore{
+		UserLimit:   userLimit,
+		TenantLimit: tenantLimit,
+		limits:      make(map[string]int64),
+		used:        make(map[string]int64),
+	}
+}
+
+// SetLimit - sets the limit of subject, 0 for unlimited.
+func (m *MemoryQuotaStore) SetLimit(subject string, limit int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.limits[subject] = limit
+}
+
+// SetUsage - sets the usage of subject, for example from the sizes of
+// its objects at startup.
+func (m *MemoryQuotaStore) SetUsage(subject string, used int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.used[subject] = used
+}
+
+// limit - returns the limit of subject, mutex must be held.
+func (m *MemoryQuotaStore) limit(subject string) int64 {
+	if limit, ok := m.limits[subject]; ok {
+		return limit
+	}
+	switch {
+	case strings.HasPrefix(subject, userSubject):
+		return m.UserLimit
+	case strings.HasPrefix(subject, tenantSubject):
+		return m.TenantLimit
+	}
+	return 0
+}
+
+// remaining - returns the bytes left for subject, mutex must be held.
+func (m *MemoryQuotaStore) remaining(subject string) int64 {
+	limit := m.limit(subject)
+	if limit <= 0 {
+		return -1
+	}
+	if remaining := limit - m.used[subject]; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Reserve - implements QuotaStore.
+func (m *MemoryQuotaStore) Reserve(subject string, n int64) (int64, bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	remaining := m.remaining(subject)
+	if remaining >= 0 && n > remaining {
+		return remaining, false, nil
+	}
+	m.used[subject] += n
+	return remaining, true, nil
+}
+
+// Adjust - implements QuotaStore.
+func (m *MemoryQuotaStore) Adjust(subject string, delta int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.used[subject] += delta
+	if m.used[subject] <= 0 {
+		delete(m.used, subject)
+	}
+	return nil
+}
+
+// Remaining - implements QuotaStore.
+func (m *MemoryQuotaStore) Remaining(subject string) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.remaining(subject), nil
+}
+
+// Prefixes of quota subjects.
+const (
+	userSubject   = "user:"
+	tenantSubject = "tenant:"
+)
+
+// quotaSubjects - returns the quota subjects of the token claims in
+// ctx, none without claims or quota store.
+func (h *Handler) quotaSubjects(ctx context.Context) []string {
+	claims := ClaimsFromContext(ctx)
+	if h.opts.Quotas == nil || claims == nil {
+		return nil
+	}
+	var subjects []string
+	if claims.UserID != "" {
+		subjects = append(subjects, userSubject+claims.UserID)
+	}
+	if claims.TenantID != "" {
+		subjects = append(subjects, tenantSubject+claims.TenantID)
+	}
+	return subjects
+}
+
+// reserveQuota - reserves size bytes for all subjects, nothing is
+// reserved when a subject has not enough quota left.
+func (h *Handler) reserveQuota(subjects []string, size int64) error {
+	for i, subject := range subjects {
+		remaining, ok, err := h.opts.Quotas.Reserve(subject, size)
+		if err == nil && ok {
+			continue
+		}
+		for _, reserved := range subjects[:i] {
+			h.opts.Quotas.Adjust(reserved, -size)
+		}
+		if err != nil {
+			return err
+		}
+		return httpError{
+			status:  http.StatusForbidden,
+			code:    "QuotaExceeded",
+			message: fmt.Sprintf("Upload size %d exceeds the remaining quota of %d bytes of %s.", size, remaining, subject),
+		}
+	}
+	return nil
+}
+
+// adjustQuota - adds delta to the usage of all subjects.
+func (h *Handler) adjustQuota(subjects []string, delta int64) {
+	if delta == 0 || h.opts.Quotas == nil {
+		return
+	}
+	for _, subject := range subjects {
+		h.opts.Quotas.Adjust(subject, delta)
+	}
+}
+
+// reconcileQuota - corrects the reservation of reserved bytes for the
+// subjects to the size of the completed object. The reservation is kept
+// when the object can not be read.
+func (h *Handler) reconcileQuota(ctx context.Context, subjects []string, bucketName, objectName string, reserved int64) {
+	if len(subjects) == 0 || h.opts.Quotas == nil {
+		return
+	}
+	info, err := h.client.StatObject(ctx, bucketName, objectName)
+	if err != nil {
+		return
+	}
+	h.adjustQuota(subjects, info.Size-reserved)
+}
+
+// removeState - removes the state of upload uploadID and reports
+// whether this call removed it, see minio_ext.StateRemover. States of
+// other stores are loaded first, which only serializes the removals of
+// this handler.
+func (h *Handler) removeState(uploadID string) (bool, error) {
+	if remover, ok := h.opts.States.(minio_ext.StateRemover); ok {
+		return remover.Remove(uploadID)
+	}
+	h.removeMutex.Lock()
+	defer h.removeMutex.Unlock()
+	state, err := h.opts.States.Load(uploadID)
+	if err != nil || state == nil {
+		return false, err
+	}
+	return true, h.opts.States.Delete(uploadID)
+}
+
+// forget - forgets the state of an upload gone on the server, stops
+// counting it as active and gives its reserved quota back. Requests
+// finding the upload gone concurrently give it back once. Only requests
+// changing the upload forget it, polling requests may race with its
+// completion.
+func (h *Handler) forget(ctx context.Context, state *minio_ext.UploadState) {
+	if removed, _ := h.removeState(state.UploadID); removed {
+		releaseUpload(ctx, state.UploadID)
+		h.adjustQuota(state.QuotaSubjects, -state.Size)
+	}
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryQuotaStore(t *testing.T) {
+	store := NewMemoryQuotaStore(100, 0)
+	store.SetLimit("user:big", 1000)
+	store.SetLimit("tenant:small", 10)
+	store.SetUsage("user:used", 90)
+
+	testCases := []struct {
+		subject   string
+		n         int64
+		remaining int64
+		ok        bool
+		after     int64
+	}{
+		{"user:a", 60, 100, true, 40},
+		{"user:a", 41, 40, false, 40},
+		{"user:a", 40, 40, true, 0},
+		{"user:big", 500, 1000, true, 500},
+		{"user:used", 20, 10, false, 10},
+		{"tenant:any", 1 << 40, -1, true, -1},
+		{"tenant:small", 10, 10, true, 0},
+		{"other", 1, -1, true, -1},
+	}
+	for i, testCase := range testCases {
+		remaining, ok, err := store.Reserve(testCase.subject, testCase.n)
+		if err != nil || remaining != testCase.remaining || ok != testCase.ok {
+			t.Errorf("Test %d: expected %d %v, got %d %v %v", i+1, testCase.remaining, testCase.ok, remaining, ok, err)
+		}
+		if after, _ := store.Remaining(testCase.subject); after != testCase.after {
+			t.Errorf("Test %d: expected %d remaining, got %d", i+1, testCase.after, after)
+		}
+	}
+
+	// Adjustments ignore the limit and never go below zero usage.
+	store.Adjust("user:a", 50)
+	if remaining, _ := store.Remaining("user:a"); remaining != 0 {
+		t.Errorf("Expected the quota used up, got %d", remaining)
+	}
+	store.Adjust("user:a", -1000)
+	if remaining, _ := store.Remaining("user:a"); remaining != 100 {
+		t.Errorf("Expected the quota given back, got %d", remaining)
+	}
+}
+
+// bearer - returns a function sending a token of claims signed with
+// secret, valid for an hour.
+func bearer(t *testing.T, secret []byte, claims TokenClaims) func(r *http.Request) {
+	claims.ExpiresAt = time.Now().Add(time.Hour).Unix()
+	token, err := SignToken(secret, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func TestHandlerQuota(t *testing.T) {
+	secret := []byte("secret")
+	quotas := NewMemoryQuotaStore(100, 150)
+	h, s3 := newTestHandler(t, Options{Quotas: quotas})
+	handler := RequireToken(secret, h)
+	alice := bearer(t, secret, TokenClaims{UserID: "alice", TenantID: "acme", BucketName: "bucket", ObjectName: "alice/"})
+	bob := bearer(t, secret, TokenClaims{UserID: "bob", TenantID: "acme", BucketName: "bucket", ObjectName: "bob/"})
+	remaining := func(subject string) int64 {
+		remaining, _ := quotas.Remaining(subject)
+		return remaining
+	}
+	initiate := func(prepare func(r *http.Request), name string, size int64) (*httptest.ResponseRecorder, initResponse) {
+		var initRes initResponse
+		rec := serveRequest(t, handler, "POST", "/uploads", initRequest{Name: name, Size: size}, &initRes, prepare)
+		return rec, initRes
+	}
+
+	rec, first := initiate(alice, "alice/first", 60)
+	if rec.Code != http.StatusCreated || remaining("user:alice") != 40 || remaining("tenant:acme") != 90 {
+		t.Fatalf("Expected 60 bytes reserved, got %d, %d and %d left", rec.Code, remaining("user:alice"), remaining("tenant:acme"))
+	}
+	var errRes errorBody
+	rec = serveRequest(t, handler, "POST", "/uploads", initRequest{Name: "alice/second", Size: 41}, &errRes, alice)
+	if rec.Code != http.StatusForbidden || errRes.Code != "QuotaExceeded" {
+		t.Fatalf("Expected the user quota exceeded, got %d %s", rec.Code, errRes.Code)
+	}
+	// The user quota is given back when the tenant quota is exceeded.
+	if rec, _ = initiate(bob, "bob/first", 91); rec.Code != http.StatusForbidden || remaining("user:bob") != 100 {
+		t.Fatalf("Expected the tenant quota exceeded, got %d and %d left", rec.Code, remaining("user:bob"))
+	}
+	rec, second := initiate(bob, "bob/second", 16)
+	if rec.Code != http.StatusCreated || remaining("tenant:acme") != 74 {
+		t.Fatalf("Expected 16 bytes reserved, got %d and %d left", rec.Code, remaining("tenant:acme"))
+	}
+
+	// Aborted and expired uploads give their reservation back.
+	if rec = serveRequest(t, handler, "DELETE", "/uploads/"+first.UploadID, nil, nil, alice); rec.Code != http.StatusNoContent || remaining("user:alice") != 100 {
+		t.Fatalf("Expected the quota given back on abort, got %d and %d left", rec.Code, remaining("user:alice"))
+	}
+	rec, third := initiate(alice, "alice/third", 30)
+	s3.expire(third.UploadID)
+	// Polling keeps the reservation, it may race with the completion.
+	if rec = serveRequest(t, handler, "GET", "/uploads/"+third.UploadID+"/parts", nil, nil, alice); rec.Code != http.StatusGone || remaining("user:alice") != 70 {
+		t.Fatalf("Expected the quota kept when polling, got %d and %d left", rec.Code, remaining("user:alice"))
+	}
+	// Completions racing on the expired upload give it back once.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveRequest(t, handler, "POST", "/uploads/"+third.UploadID+"/complete", nil, nil, alice)
+		}()
+	}
+	wg.Wait()
+	if remaining("user:alice") != 100 {
+		t.Fatalf("Expected the quota given back once on expiry, got %d left", remaining("user:alice"))
+	}
+
+	// Completed uploads keep the size of the object.
+	var partsRes partsResponse
+	serveRequest(t, handler, "GET", "/uploads/"+second.UploadID+"/parts", nil, &partsRes, bob)
+	putPart(t, partsRes.Parts[0].URL, make([]byte, 16))
+	if rec = serveRequest(t, handler, "POST", "/uploads/"+second.UploadID+"/complete", nil, nil, bob); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the upload completed, got %d %s", rec.Code, rec.Body)
+	}
+	if remaining("user:bob") != 84 || remaining("tenant:acme") != 134 {
+		t.Errorf("Expected 16 bytes used, got %d and %d left", remaining("user:bob"), remaining("tenant:acme"))
+	}
+
+	// Uploads without token are not accounted.
+	if rec, _ = initiate(nil, "anonymous", 1000); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the token required, got %d", rec.Code)
+	}
+	if rec = serve(t, h, "POST", "/uploads", initRequest{Name: "anonymous", Size: 1000}, nil); rec.Code != http.StatusCreated || remaining("tenant:acme") != 134 {
+		t.Errorf("Expected an upload without quota, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"oss/lib/minio_ext"
+)
+
+// DefaultKeyTemplate - key template of TemplateRouter when none is set.
+const DefaultKeyTemplate = "{yyyy}/{mm}/{dd}/{uuid}_{filename}"
+
+// maxFilenameRunes - longest file name kept in generated keys.
+const maxFilenameRunes = 128
+
+// Destination - bucket and object key of an upload.
+type Destination struct {
+	BucketName string
+	ObjectName string
+}
+
+// Router - picks the bucket and object key of new uploads from the file
+// name sent by the browser, for example per tenant with the token
+// claims of ctx, see ClaimsFromContext. A returned httpError keeps its
+// status, other errors are answered with 500.
+type Router interface {
+	Route(ctx context.Context, name string) (Destination, error)
+}
+
+// RouterFunc - adapter to use an ordinary function as Router.
+type RouterFunc func(ctx context.Context, name string) (Destination, error)
+
+// Route - calls f(ctx, name).
+func (f RouterFunc) Route(ctx context.Context, name string) (Destination, error) {
+	return f(ctx, name)
+}
+
+// prefixRouter - the Router of handlers without one, uploads go to
+// bucketName under prefix with the name sent by the browser.
+type prefixRouter struct {
+	bucketName string
+	prefix     string
+}
+
+// Route - returns the cleaned name below the prefix.
+func (p prefixRouter) Route(ctx context.Context, name string) (Destination, error) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return Destination{}, errBadRequest("InvalidArgument", "Object name cannot be empty.")
+	}
+	return Destination{BucketName: p.bucketName, ObjectName: p.prefix + name}, nil
+}
+
+// TemplateRouter - Router filling bucket and key templates with the
+// token claims, the date and a random id. Placeholders:
+//
+//	{tenant}    tenant of the token claims
+//	{user}      subject of the token claims
+//	{yyyy} {mm} {dd}
+//	            UTC date of the upload
+//	{uuid}      random id, unique per upload
+//	{filename}  base name sent by the browser, unsafe characters replaced
+//
+// For example "tenant-{tenant}" and "{yyyy}/{mm}/{dd}/{uuid}_{filename}".
+// Keys never collide: a key template without {uuid} gets the id in
+// front of its last element. Uploads needing claims the request has no
+// token for are rejected with 403.
+type TemplateRouter struct {
+	// Bucket template, the bucket names must be valid S3 bucket names.
+	Bucket string
+
+	// Key template, DefaultKeyTemplate when empty.
+	Key string
+}
+
+// Route - fills the templates for name.
+func (t TemplateRouter) Route(ctx context.Context, name string) (Destination, error) {
+	if t.Bucket == "" {
+		return Destination{}, minio_ext.ErrInvalidArgument("Bucket template cannot be empty.")
+	}
+	filename := sanitizeFilename(name)
+	if filename == "" {
+		return Destination{}, errBadRequest("InvalidArgument", "Object name cannot be empty.")
+	}
+	id, err := randomID()
+	if err != nil {
+		return Destination{}, err
+	}
+
+	keyTemplate := t.Key
+	if keyTemplate == "" {
+		keyTemplate = DefaultKeyTemplate
+	}
+	if !strings.Contains(keyTemplate, "{uuid}") {
+		dir, last := path.Split(keyTemplate)
+		keyTemplate = dir + "{uuid}_" + last
+	}
+
+	claims := ClaimsFromContext(ctx)
+	now := time.Now().UTC()
+	values := map[string]string{
+		"yyyy":     now.Format("2006"),
+		"mm":       now.Format("01"),
+		"dd":       now.Format("02"),
+		"uuid":     id,
+		"filename": filename,
+	}
+	if claims != nil {
+		values["tenant"] = claims.TenantID
+		values["user"] = claims.UserID
+	}
+
+	bucketName, err := expandTemplate(t.Bucket, values)
+	if err != nil {
+		return Destination{}, err
+	}
+	objectName, err := expandTemplate(keyTemplate, values)
+	if err != nil {
+		return Destination{}, err
+	}
+	return Destination{BucketName: bucketName, ObjectName: strings.TrimPrefix(path.Clean("/"+objectName), "/")}, nil
+}
+
+// expandTemplate - replaces the placeholders of template with values,
+// claims missing or unsafe for a key are rejected.
+func expandTemplate(template string, values map[string]string) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			b.WriteString(template)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", errBadRequest("InvalidArgument", "Unterminated placeholder in ‘"+template+"’.")
+		}
+		placeholder := template[start+1 : start+end]
+		value, ok := values[placeholder]
+		switch {
+		case !ok && (placeholder == "tenant" || placeholder == "user"):
+			return "", errForbidden("Upload token required for the ‘" + placeholder + "’ of the upload path.")
+		case !ok:
+			return "", errBadRequest("InvalidArgument", "Unknown placeholder ‘{"+placeholder+"}’.")
+		case value == "" || strings.ContainsAny(value, "/\\") || value == "." || value == "..":
+			return "", errForbidden("Upload token has no usable ‘" + placeholder + "’ for the upload path.")
+		}
+		b.WriteString(template[:start])
+		b.WriteString(value)
+		template = template[start+end+1:]
+	}
+}
+
+// sanitizeFilename - returns the base name of name with characters
+// unsafe in keys and URLs replaced by "_", cut to maxFilenameRunes.
+func sanitizeFilename(name string) string {
+	name = path.Base(path.Clean("/" + strings.Replace(name, "\\", "/", -1)))
+	if name == "/" || name == "." || name == ".." {
+		return ""
+	}
+	var runes []rune
+	for _, r := range name {
+		if len(runes) == maxFilenameRunes {
+			break
+		}
+		if unicode.IsControl(r) || strings.ContainsRune("#?%&{}<>*\"|:^`~[]", r) || unicode.IsSpace(r) && r != ' ' {
+			r = '_'
+		}
+		runes = append(runes, r)
+	}
+	return string(runes)
+}
+
+// randomID - returns 16 random bytes in hex.
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
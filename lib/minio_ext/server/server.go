@@ -0,0 +1,414 @@
+// Package server - embeddable net/http backend for browser breakpoint
+// uploads. The browser asks the handler for a part plan and presigned
+// part URLs, PUTs the parts straight to the object storage and asks the
+// handler to complete the upload, an interrupted upload is resumed by
+// asking for the parts again.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"oss/lib/minio_ext"
+)
+
+// defaultExpires - default lifetime of presigned part URLs.
+const defaultExpires = time.Hour
+
+// maxExpires - longest lifetime of presigned URLs allowed by S3.
+const maxExpires = 7 * 24 * time.Hour
+
+// maxRequestBody - largest JSON request body accepted.
+const maxRequestBody = 64 * 1024
+
+// Options - settings of a Handler.
+type Options struct {
+	// Bucket all uploads go to and its location, the location may be
+	// empty for the default region.
+	BucketName string
+	Location   string
+
+	// Prefix prepended to the object names sent by the browser.
+	Prefix string
+
+	// Router picking bucket and key of every upload, for example per
+	// tenant, BucketName and Prefix are not used when set. The location
+	// of other buckets than BucketName is looked up.
+	Router Router
+
+	// CreateBuckets creates the buckets uploads are routed to on first
+	// use, in Location, for example the per tenant buckets of a
+	// TemplateRouter. Buckets are only created for authorized uploads.
+	CreateBuckets bool
+
+	// Store of the upload states keyed by upload id, uploads are kept
+	// in memory and lost on restart when nil.
+	States minio_ext.StateStore
+
+	// Part size of new uploads, 0 picks the smallest part size fitting
+	// the upload into MaxPartsCount parts.
+	PartSize int64
+
+	// Largest upload accepted, 0 for no limit beyond the S3 limit.
+	MaxSize int64
+
+	// Quotas of the users and tenants of the upload tokens, uploads
+	// without token are not accounted. Form uploads are refused for
+	// accounted requests. No quotas when nil.
+	Quotas QuotaStore
+
+	// Index of the stored content by owner and MD5, uploads declaring
+	// content the same user already stored in their bucket are
+	// answered with the stored object. Requests without token are only
+	// deduplicated with an Authorize check. Completed uploads are read
+	// back to verify their declared MD5 before they are recorded. No
+	// deduplication when nil.
+	Index ContentIndex
+
+	// Lifetime of presigned part URLs, an hour by default and at most
+	// seven days.
+	Expires time.Duration
+
+	// Age at which the bucket lifecycle aborts incomplete uploads,
+	// reported as expiry in the upload status, 0 when unknown.
+	AbortAfter time.Duration
+
+	// Relay accepts parts on PUT /uploads/{id}/parts/{n} and forwards
+	// them to the bucket, for browsers that cannot reach the storage.
+	// Parts are checked against their declared checksums first.
+	Relay bool
+
+	// Polling interval of the progress events endpoint, the endpoint
+	// is disabled when 0.
+	EventInterval time.Duration
+
+	// Hooks run in order on every completed upload, OnFlag is called
+	// for hooks failing with FailFlag, both optional.
+	Hooks  []Hook
+	OnFlag func(obj CompletedObject, failure HookFailure)
+
+	// Optional check of every request against the object it touches,
+	// a returned error is answered with 403 Forbidden. gRPC calls are
+	// passed as requests, see Handler.GRPC. Requests and calls without
+	// token are served on both transports when there is no check,
+	// wrap the handler in RequireToken and the gRPC service in
+	// TokenInterceptors to require one.
+	Authorize func(r *http.Request, objectName string) error
+}
+
+// Handler - http.Handler serving the upload flow, mount it with
+// http.StripPrefix to serve it below a path:
+//
+//	POST   /uploads                 initiate an upload, returns its part plan
+//	POST   /uploads/form            presigned POST policy for small files
+//	GET    /uploads/{id}            status of the upload
+//	GET    /uploads/{id}/parts      presigned part URLs and uploaded parts
+//	GET    /uploads/{id}/parts/{n}  whether part n is uploaded
+//	PUT    /uploads/{id}/parts/{n}  relay part n to the bucket, with Relay set
+//	POST   /uploads/{id}/verify     compare reported and uploaded parts
+//	POST   /uploads/{id}/complete   verify and complete the upload
+//	DELETE /uploads/{id}            abort the upload
+//	GET    /uploads/{id}/events     progress events, with EventInterval set
+//
+// Wrapped in RequireToken only the uploads allowed by the upload token
+// of the request are served.
+type Handler struct {
+	client *minio_ext.Client
+	opts   Options
+	hub    eventHub
+
+	// Buckets known to exist, with CreateBuckets set.
+	buckets sync.Map
+
+	// removeMutex serializes the removals of states from stores which
+	// are no minio_ext.StateRemover.
+	removeMutex sync.Mutex
+}
+
+// New - returns a handler uploading into opts.BucketName, or where
+// opts.Router routes the uploads to, with client.
+// Uploads completed by the handler notify the completion webhook of
+// client once the hooks processed them, see
+// minio_ext.Client.SetCompletionWebhook. Rejected uploads are not
+// notified.
+func New(client *minio_ext.Client, opts Options) (*Handler, error) {
+	if client == nil {
+		return nil, minio_ext.ErrInvalidArgument("Client cannot be nil.")
+	}
+	if opts.BucketName == "" && opts.Router == nil {
+		return nil, minio_ext.ErrInvalidArgument("Bucket name cannot be empty.")
+	}
+	if opts.Router == nil {
+		opts.Router = prefixRouter{bucketName: opts.BucketName, prefix: opts.Prefix}
+	}
+	if opts.Expires <= 0 {
+		opts.Expires = defaultExpires
+	}
+	if opts.Expires > maxExpires {
+		opts.Expires = maxExpires
+	}
+	if opts.States == nil {
+		opts.States = newMemoryStore()
+	}
+	return &Handler{client: client, opts: opts}, nil
+}
+
+// ServeHTTP - routes a request to its endpoint.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if segments[0] != "uploads" || len(segments) > 4 || len(segments) == 4 && segments[2] != "parts" {
+		writeError(w, errNotFound("NotFound", "No such endpoint."))
+		return
+	}
+
+	switch {
+	case len(segments) == 1:
+		if allowMethod(w, r, http.MethodPost) {
+			h.initiate(w, r)
+		}
+	case len(segments) == 2 && segments[1] == "form":
+		if allowMethod(w, r, http.MethodPost) {
+			h.form(w, r)
+		}
+	case len(segments) == 2 && r.Method == http.MethodGet:
+		h.status(w, r, segments[1])
+	case len(segments) == 2:
+		if allowMethod(w, r, http.MethodDelete) {
+			h.abort(w, r, segments[1])
+		}
+	case len(segments) == 4 && r.Method == http.MethodPut && h.opts.Relay:
+		h.relay(w, r, segments[1], segments[3])
+	case len(segments) == 4:
+		if allowMethod(w, r, http.MethodGet) {
+			h.part(w, r, segments[1], segments[3])
+		}
+	case segments[2] == "parts":
+		if allowMethod(w, r, http.MethodGet) {
+			h.parts(w, r, segments[1])
+		}
+	case segments[2] == "complete":
+		if allowMethod(w, r, http.MethodPost) {
+			h.complete(w, r, segments[1])
+		}
+	case segments[2] == "verify":
+		if allowMethod(w, r, http.MethodPost) {
+			h.verify(w, r, segments[1])
+		}
+	case segments[2] == "events" && h.opts.EventInterval > 0:
+		if allowMethod(w, r, http.MethodGet) {
+			h.events(w, r, segments[1])
+		}
+	default:
+		writeError(w, errNotFound("NotFound", "No such endpoint."))
+	}
+}
+
+// allowMethod - answers requests with another method than method with
+// 405 Method Not Allowed.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, httpError{
+		status:  http.StatusMethodNotAllowed,
+		code:    "MethodNotAllowed",
+		message: "Method " + r.Method + " is not allowed.",
+	})
+	return false
+}
+
+// location - returns the location of bucketName for presigning, empty
+// to look it up.
+func (h *Handler) location(bucketName string) string {
+	if bucketName == h.opts.BucketName {
+		return h.opts.Location
+	}
+	return ""
+}
+
+// ensureBucket - creates bucketName in Location unless it exists, with
+// CreateBuckets set.
+func (h *Handler) ensureBucket(ctx context.Context, bucketName string) error {
+	if !h.opts.CreateBuckets {
+		return nil
+	}
+	if _, ok := h.buckets.Load(bucketName); ok {
+		return nil
+	}
+	exists, err := h.client.BucketExists(ctx, bucketName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		err = h.client.MakeBucket(ctx, bucketName, h.opts.Location)
+		// Another instance may have created it in the meantime.
+		if err != nil && minio_ext.ToErrorResponse(err).Code != "BucketAlreadyOwnedByYou" {
+			return err
+		}
+	}
+	h.buckets.Store(bucketName, struct{}{})
+	return nil
+}
+
+// authorize - checks the object and size against the token claims of
+// the request, if any, and runs the Authorize check of the options.
+func (h *Handler) authorize(r *http.Request, bucketName, objectName string, size int64) error {
+	if err := h.authorizeClaims(r.Context(), bucketName, objectName, size); err != nil {
+		return err
+	}
+	if h.opts.Authorize == nil {
+		return nil
+	}
+	if err := h.opts.Authorize(r, objectName); err != nil {
+		return errForbidden(err.Error())
+	}
+	return nil
+}
+
+// authorizeClaims - checks the object and size against the token claims
+// in ctx, if any.
+func (h *Handler) authorizeClaims(ctx context.Context, bucketName, objectName string, size int64) error {
+	claims := ClaimsFromContext(ctx)
+	if claims == nil {
+		return nil
+	}
+	if !claims.allows(bucketName, objectName) {
+		return errForbidden("Upload token is not valid for ‘" + objectName + "’.")
+	}
+	if claims.MaxSize > 0 && size > claims.MaxSize {
+		return errForbidden(fmt.Sprintf("Upload size %d exceeds the limit of the upload token.", size))
+	}
+	return nil
+}
+
+// httpError - error answered with its status and code, with a
+// Retry-After header when retryAfter is set.
+type httpError struct {
+	status     int
+	code       string
+	message    string
+	retryAfter time.Duration
+}
+
+// Error - Returns HTTP error string.
+func (e httpError) Error() string {
+	return e.message
+}
+
+// errBadRequest - malformed request.
+func errBadRequest(code, message string) error {
+	return httpError{status: http.StatusBadRequest, code: code, message: message}
+}
+
+// errForbidden - request not allowed.
+func errForbidden(message string) error {
+	return httpError{status: http.StatusForbidden, code: "AccessDenied", message: message}
+}
+
+// errNotFound - unknown endpoint or upload.
+func errNotFound(code, message string) error {
+	return httpError{status: http.StatusNotFound, code: code, message: message}
+}
+
+// errorBody - JSON body of error responses.
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	// Identifiers of the failed response of the object storage, for
+	// support requests.
+	RequestID    string `json:"requestId,omitempty"`
+	HostID       string `json:"hostId,omitempty"`
+	DeploymentID string `json:"deploymentId,omitempty"`
+}
+
+// writeError - answers err, errors of the object storage keep their
+// status when they have one.
+func writeError(w http.ResponseWriter, err error) {
+	status, code := http.StatusInternalServerError, "InternalError"
+	body := errorBody{}
+	switch e := err.(type) {
+	case httpError:
+		status, code = e.status, e.code
+		if e.retryAfter > 0 {
+			w.Header().Set("Retry-After", retryAfterSeconds(e.retryAfter))
+		}
+	case minio_ext.ErrorResponse:
+		code = e.Code
+		if e.StatusCode != 0 {
+			status = e.StatusCode
+		}
+		if e.Code == "InvalidArgument" {
+			status = http.StatusBadRequest
+		}
+		// Errors of the client carry no response.
+		if e.RequestID != "minio" {
+			body.RequestID, body.HostID, body.DeploymentID = e.RequestID, e.HostID, e.DeploymentID
+		}
+	}
+	if minio_ext.IsUploadExpired(err) {
+		status, code = http.StatusGone, "NoSuchUpload"
+	}
+	body.Code, body.Message = code, err.Error()
+	writeJSON(w, status, body)
+}
+
+// writeJSON - answers v as JSON with status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// memoryStore - StateStore keeping states in memory.
+type memoryStore struct {
+	// mutex protects states.
+	mutex  sync.Mutex
+	states map[string]minio_ext.UploadState
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{states: make(map[string]minio_ext.UploadState)}
+}
+
+// Load - returns the state stored under key, nil when there is none.
+func (m *memoryStore) Load(key string) (*minio_ext.UploadState, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	state, ok := m.states[key]
+	if !ok {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+// Save - stores state under key.
+func (m *memoryStore) Save(key string, state minio_ext.UploadState) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.states[key] = state
+	return nil
+}
+
+// Delete - removes the state stored under key.
+func (m *memoryStore) Delete(key string) error {
+	_, err := m.Remove(key)
+	return err
+}
+
+// Remove - removes the state stored under key and reports whether
+// there was one.
+func (m *memoryStore) Remove(key string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	_, ok := m.states[key]
+	delete(m.states, key)
+	return ok, nil
+}
@@ -0,0 +1,567 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"oss/lib/minio_ext"
+)
+
+// testPartSize - smallest part size accepted by S3.
+const testPartSize = 5 << 20
+
+// s3Server - fake object storage answering the multipart upload API,
+// signatures are not checked.
+type s3Server struct {
+	mutex   sync.Mutex
+	nextID  int
+	uploads map[string]map[int][]byte
+	objects map[string][]byte
+
+	// requests counts the requests by operation.
+	requests map[string]int
+}
+
+// newS3Server - returns an empty object storage.
+func newS3Server() *s3Server {
+	return &s3Server{
+		uploads:  make(map[string]map[int][]byte),
+		objects:  make(map[string][]byte),
+		requests: make(map[string]int),
+	}
+}
+
+// count - returns the number of requests of operation.
+func (s *s3Server) count(operation string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests[operation]
+}
+
+// object - returns the data of the object stored under path.
+func (s *s3Server) object(path string) ([]byte, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	data, ok := s.objects[path]
+	return data, ok
+}
+
+// remove - removes the object stored under path.
+func (s *s3Server) remove(path string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.objects, path)
+}
+
+// expire - aborts uploadID as a lifecycle rule would.
+func (s *s3Server) expire(uploadID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.uploads, uploadID)
+}
+
+// s3Error - sends an S3 error response.
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+// writeXML - sends v as XML with status 200.
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(v)
+}
+
+// etagOf - returns the S3 ETag of a part holding data.
+func etagOf(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// listPartsResult - answer of the fake to ListParts.
+type listPartsResult struct {
+	XMLName              xml.Name `xml:"ListPartsResult"`
+	NextPartNumberMarker int
+	IsTruncated          bool
+	Parts                []listedPart `xml:"Part"`
+}
+
+// listedPart - a part of listPartsResult.
+type listedPart struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
+
+// completeRequest - body of CompleteMultipartUpload.
+type completeRequest struct {
+	Parts []struct {
+		PartNumber int
+		ETag       string
+	} `xml:"Part"`
+}
+
+// completeResult - answer of the fake to CompleteMultipartUpload.
+type completeResult struct {
+	XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+	Bucket  string
+	Key     string
+	ETag    string
+}
+
+// ServeHTTP - answers the multipart upload API.
+func (s *s3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		w.Write([]byte("<LocationConstraint></LocationConstraint>"))
+		return
+	}
+	if (r.Method == "HEAD" || r.Method == "GET") && len(query) == 0 {
+		s.read(w, r)
+		return
+	}
+	_, initiate := query["uploads"]
+	uploadID := query.Get("uploadId")
+	var operation string
+	switch {
+	case r.Method == "POST" && initiate:
+		operation = "initiate"
+	case r.Method == "PUT" && uploadID != "":
+		operation = "part"
+	case r.Method == "GET" && uploadID != "":
+		operation = "list"
+	case r.Method == "POST" && uploadID != "":
+		operation = "complete"
+	case r.Method == "DELETE" && uploadID != "":
+		operation = "abort"
+	default:
+		s3Error(w, http.StatusNotImplemented, "NotImplemented")
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests[operation]++
+	parts, ok := s.uploads[uploadID]
+	if operation != "initiate" && !ok {
+		s3Error(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+
+	switch operation {
+	case "initiate":
+		s.nextID++
+		uploadID = "upload-" + strconv.Itoa(s.nextID)
+		s.uploads[uploadID] = make(map[int][]byte)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", uploadID)
+	case "part":
+		partNumber, err := strconv.Atoi(query.Get("partNumber"))
+		if err != nil || partNumber < 1 {
+			s3Error(w, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+		data, err := readBody(r)
+		if err != nil {
+			s3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		parts[partNumber] = data
+		w.Header().Set("ETag", "\""+etagOf(data)+"\"")
+	case "list":
+		s.listParts(w, r, parts)
+	case "complete":
+		s.complete(w, r, uploadID, parts)
+	case "abort":
+		delete(s.uploads, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// read - answers HEAD and GET requests of stored objects.
+func (s *s3Server) read(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.requests[strings.ToLower(r.Method)]++
+	data, ok := s.objects[r.URL.Path]
+	s.mutex.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("ETag", "\""+etagOf(data)+"\"")
+	w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+	if r.Method == "GET" {
+		w.Write(data)
+	}
+}
+
+// readBody - returns the payload of a PUT, aws-chunked bodies are
+// decoded.
+func readBody(r *http.Request) ([]byte, error) {
+	var buf bytes.Buffer
+	if r.Header.Get("X-Amz-Content-Sha256") != "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+		_, err := io.Copy(&buf, r.Body)
+		return buf.Bytes(), err
+	}
+	reader := bufio.NewReader(r.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.ParseInt(strings.SplitN(line, ";", 2)[0], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return buf.Bytes(), nil
+		}
+		if _, err = io.CopyN(&buf, reader, size); err != nil {
+			return nil, err
+		}
+		if _, err = reader.Discard(2); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// listParts - lists up to max-parts parts after part-number-marker.
+func (s *s3Server) listParts(w http.ResponseWriter, r *http.Request, parts map[int][]byte) {
+	marker, _ := strconv.Atoi(r.URL.Query().Get("part-number-marker"))
+	maxParts, _ := strconv.Atoi(r.URL.Query().Get("max-parts"))
+	var numbers []int
+	for number := range parts {
+		if number > marker {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+	var result listPartsResult
+	for _, number := range numbers {
+		if maxParts > 0 && len(result.Parts) == maxParts {
+			result.IsTruncated = true
+			break
+		}
+		data := parts[number]
+		result.Parts = append(result.Parts, listedPart{PartNumber: number, ETag: "\"" + etagOf(data) + "\"", Size: int64(len(data))})
+		result.NextPartNumberMarker = number
+	}
+	writeXML(w, result)
+}
+
+// complete - checks the parts like S3 does and stores the object.
+func (s *s3Server) complete(w http.ResponseWriter, r *http.Request, uploadID string, parts map[int][]byte) {
+	var request completeRequest
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Parts) == 0 {
+		s3Error(w, http.StatusBadRequest, "MalformedXML")
+		return
+	}
+	var object []byte
+	for i, completed := range request.Parts {
+		data, ok := parts[completed.PartNumber]
+		if !ok || etagOf(data) != strings.Trim(completed.ETag, "\"") {
+			s3Error(w, http.StatusBadRequest, "InvalidPart")
+			return
+		}
+		if i < len(request.Parts)-1 && len(data) < testPartSize {
+			s3Error(w, http.StatusBadRequest, "EntityTooSmall")
+			return
+		}
+		object = append(object, data...)
+	}
+	s.objects[r.URL.Path] = object
+	delete(s.uploads, uploadID)
+	path := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	writeXML(w, completeResult{Bucket: path[0], Key: path[1], ETag: "\"" + etagOf(object) + "\""})
+}
+
+// newTestHandler - returns a handler of opts uploading into bucket
+// "bucket" of a fake object storage.
+func newTestHandler(t *testing.T, opts Options) (*Handler, *s3Server) {
+	s3 := newS3Server()
+	ts := httptest.NewServer(s3)
+	t.Cleanup(ts.Close)
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := minio_ext.NewStatic(u.Host, "access", "secret1234", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.BucketName == "" {
+		opts.BucketName = "bucket"
+	}
+	h, err := New(client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h, s3
+}
+
+// serve - sends a request to h and decodes its JSON answer into v,
+// when v is not nil.
+func serve(t *testing.T, h http.Handler, method, target string, body interface{}, v interface{}) *httptest.ResponseRecorder {
+	return serveRequest(t, h, method, target, body, v, nil)
+}
+
+// serveRequest - same as serve, prepare changes the request before it
+// is sent.
+func serveRequest(t *testing.T, h http.Handler, method, target string, body interface{}, v interface{}, prepare func(r *http.Request)) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if s, ok := body.(string); ok {
+		reader = strings.NewReader(s)
+	} else if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	r := httptest.NewRequest(method, target, reader)
+	if prepare != nil {
+		prepare(r)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if v != nil && rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: malformed answer %q", method, target, rec.Body.String())
+		}
+	}
+	return rec
+}
+
+// putPart - PUTs data to a presigned part URL.
+func putPart(t *testing.T, signedURL string, data []byte) {
+	req, err := http.NewRequest(http.MethodPut, signedURL, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Part PUT failed with %s", resp.Status)
+	}
+}
+
+func TestHandlerUpload(t *testing.T) {
+	h, s3 := newTestHandler(t, Options{Prefix: "incoming/", PartSize: testPartSize})
+	data := bytes.Repeat([]byte("0123456789abcdef"), (2*testPartSize+16)/16)
+
+	var initRes initResponse
+	rec := serve(t, h, "POST", "/uploads", initRequest{Name: "../dir/file.bin", Size: int64(len(data))}, &initRes)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the upload initiated, got %d %s", rec.Code, rec.Body)
+	}
+	if initRes.ObjectName != "incoming/dir/file.bin" || initRes.PartSize != testPartSize || len(initRes.Parts) != 3 {
+		t.Fatalf("Unexpected upload %+v", initRes)
+	}
+	partsPath := "/uploads/" + initRes.UploadID + "/parts"
+	completePath := "/uploads/" + initRes.UploadID + "/complete"
+
+	var partsRes partsResponse
+	serve(t, h, "GET", partsPath, nil, &partsRes)
+	if len(partsRes.Parts) != 3 || len(partsRes.Uploaded) != 0 || partsRes.ExpiresIn != 3600 {
+		t.Fatalf("Unexpected parts %+v", partsRes)
+	}
+	first := partsRes.Parts[0]
+	putPart(t, first.URL, data[first.Offset:first.Offset+first.Size])
+
+	// The uploaded part is reported, only the missing ones are signed.
+	partsRes = partsResponse{}
+	serve(t, h, "GET", partsPath, nil, &partsRes)
+	if len(partsRes.Parts) != 2 || len(partsRes.Uploaded) != 1 || partsRes.Uploaded[0].PartNumber != 1 {
+		t.Fatalf("Unexpected parts after the first part %+v", partsRes)
+	}
+	var errRes errorBody
+	if rec = serve(t, h, "POST", completePath, nil, &errRes); rec.Code != http.StatusConflict || errRes.Code != "PartsMismatch" {
+		t.Fatalf("Expected an incomplete upload, got %d %+v", rec.Code, errRes)
+	}
+
+	// Parts of wrong size are sent again.
+	for _, part := range partsRes.Parts {
+		putPart(t, part.URL, data[part.Offset:part.Offset+part.Size-1])
+	}
+	partsRes = partsResponse{}
+	serve(t, h, "GET", partsPath, nil, &partsRes)
+	if len(partsRes.Parts) != 2 || len(partsRes.Uploaded) != 1 {
+		t.Fatalf("Unexpected parts after wrong sized parts %+v", partsRes)
+	}
+	for _, part := range partsRes.Parts {
+		putPart(t, part.URL, data[part.Offset:part.Offset+part.Size])
+	}
+
+	// Asked for parts are listed, uploaded parts are not signed again.
+	partsRes = partsResponse{}
+	serve(t, h, "GET", partsPath+"?partNumbers=1,3", nil, &partsRes)
+	if len(partsRes.Parts) != 0 || len(partsRes.Uploaded) != 2 || partsRes.Uploaded[1].PartNumber != 3 {
+		t.Fatalf("Unexpected parts asked for %+v", partsRes)
+	}
+
+	var completeRes completeResponse
+	if rec = serve(t, h, "POST", completePath, nil, &completeRes); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the upload completed, got %d %s", rec.Code, rec.Body)
+	}
+	if object, ok := s3.object("/bucket/incoming/dir/file.bin"); !ok || !bytes.Equal(object, data) {
+		t.Fatal("Expected the object stored")
+	}
+	if completeRes.ETag != etagOf(data) || completeRes.ObjectName != "incoming/dir/file.bin" {
+		t.Errorf("Unexpected completion %+v", completeRes)
+	}
+	if rec = serve(t, h, "GET", partsPath, nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the upload forgotten, got %d", rec.Code)
+	}
+}
+
+func TestHandlerWebhook(t *testing.T) {
+	rename := Hook{Name: "rename", Run: func(ctx context.Context, client *minio_ext.Client, obj *CompletedObject) error {
+		obj.ObjectName = "renamed"
+		return nil
+	}}
+	reject := Hook{Name: "reject", OnFailure: FailDelete, Run: func(ctx context.Context, client *minio_ext.Client, obj *CompletedObject) error {
+		return errors.New("infected")
+	}}
+	testCases := []struct {
+		name  string
+		hooks []Hook
+		// Keys of the events posted.
+		keys []string
+	}{
+		{"plain", nil, []string{"file"}},
+		{"renamed", []Hook{rename}, []string{"renamed"}},
+		{"rejected", []Hook{reject}, nil},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			var keys []string
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var event minio_ext.CompletionEvent
+				json.NewDecoder(r.Body).Decode(&event)
+				mutex.Lock()
+				keys = append(keys, event.Key)
+				mutex.Unlock()
+			}))
+			defer receiver.Close()
+			h, _ := newTestHandler(t, Options{Hooks: testCase.hooks})
+			h.client.SetCompletionWebhook(&minio_ext.CompletionWebhook{URLs: []string{receiver.URL}})
+
+			var initRes initResponse
+			serve(t, h, "POST", "/uploads", initRequest{Name: "file", Size: 1}, &initRes)
+			var partsRes partsResponse
+			serve(t, h, "GET", "/uploads/"+initRes.UploadID+"/parts", nil, &partsRes)
+			putPart(t, partsRes.Parts[0].URL, []byte("x"))
+			serve(t, h, "POST", "/uploads/"+initRes.UploadID+"/complete", nil, nil)
+			h.client.WaitCompletionWebhooks()
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if !reflect.DeepEqual(keys, testCase.keys) {
+				t.Errorf("Expected events for %v, got %v", testCase.keys, keys)
+			}
+		})
+	}
+}
+
+func TestHandlerAbort(t *testing.T) {
+	h, s3 := newTestHandler(t, Options{})
+	testCases := []struct {
+		name   string
+		expire bool
+	}{
+		{"aborted", false},
+		// An upload gone on the server is forgotten all the same.
+		{"expired", true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var initRes initResponse
+			serve(t, h, "POST", "/uploads", initRequest{Name: testCase.name, Size: 1}, &initRes)
+			if testCase.expire {
+				s3.expire(initRes.UploadID)
+			}
+			if rec := serve(t, h, "DELETE", "/uploads/"+initRes.UploadID, nil, nil); rec.Code != http.StatusNoContent {
+				t.Fatalf("Expected the upload aborted, got %d %s", rec.Code, rec.Body)
+			}
+			if state, _ := h.opts.States.Load(initRes.UploadID); state != nil {
+				t.Error("Expected the upload forgotten")
+			}
+		})
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	denied := fmt.Errorf("denied")
+	h, s3 := newTestHandler(t, Options{
+		MaxSize: 1 << 30,
+		Authorize: func(r *http.Request, objectName string) error {
+			if strings.HasPrefix(objectName, "private/") {
+				return denied
+			}
+			return nil
+		},
+	})
+	var initRes initResponse
+	serve(t, h, "POST", "/uploads", initRequest{Name: "expired", Size: 1}, &initRes)
+	s3.expire(initRes.UploadID)
+
+	testCases := []struct {
+		method string
+		target string
+		body   interface{}
+		status int
+		code   string
+	}{
+		{"GET", "/objects", nil, http.StatusNotFound, "NotFound"},
+		{"GET", "/uploads/id/complete/1", nil, http.StatusNotFound, "NotFound"},
+		{"GET", "/uploads/id/other", nil, http.StatusNotFound, "NotFound"},
+		{"GET", "/uploads", nil, http.StatusMethodNotAllowed, "MethodNotAllowed"},
+		{"POST", "/uploads/id/parts", nil, http.StatusMethodNotAllowed, "MethodNotAllowed"},
+		{"POST", "/uploads", "{", http.StatusBadRequest, "MalformedJSON"},
+		{"POST", "/uploads", initRequest{Name: "/", Size: 1}, http.StatusBadRequest, "InvalidArgument"},
+		{"POST", "/uploads", initRequest{Name: "big", Size: 1<<30 + 1}, http.StatusBadRequest, "EntityTooLarge"},
+		{"POST", "/uploads", initRequest{Name: "negative", Size: -1}, http.StatusBadRequest, "InvalidArgument"},
+		{"POST", "/uploads", initRequest{Name: "private/file", Size: 1}, http.StatusForbidden, "AccessDenied"},
+		{"GET", "/uploads/unknown/parts", nil, http.StatusNotFound, "NoSuchUpload"},
+		{"GET", "/uploads/unknown/parts/1", nil, http.StatusNotFound, "NoSuchUpload"},
+		{"POST", "/uploads/unknown/complete", nil, http.StatusNotFound, "NoSuchUpload"},
+		{"DELETE", "/uploads/unknown", nil, http.StatusNotFound, "NoSuchUpload"},
+		{"GET", "/uploads/" + initRes.UploadID + "/parts", nil, http.StatusGone, "NoSuchUpload"},
+		{"POST", "/uploads/" + initRes.UploadID + "/verify", nil, http.StatusGone, "NoSuchUpload"},
+		{"POST", "/uploads/" + initRes.UploadID + "/complete", nil, http.StatusGone, "NoSuchUpload"},
+	}
+	for i, testCase := range testCases {
+		var errRes errorBody
+		rec := serve(t, h, testCase.method, testCase.target, testCase.body, &errRes)
+		if rec.Code != testCase.status || errRes.Code != testCase.code {
+			t.Errorf("Test %d: expected %d %s, got %d %s", i+1, testCase.status, testCase.code, rec.Code, errRes.Code)
+		}
+	}
+	if state, _ := h.opts.States.Load(initRes.UploadID); state != nil {
+		t.Error("Expected the expired upload forgotten")
+	}
+}
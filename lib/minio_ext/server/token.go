@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// tokenHeader - JOSE header of upload tokens, tokens are HS256 JWTs.
+const tokenHeader = `{"alg":"HS256","typ":"JWT"}`
+
+// TokenClaims - claims of a signed upload token, issued by the
+// application to a user allowed to upload one object.
+type TokenClaims struct {
+	UserID     string `json:"sub"`
+	BucketName string `json:"bucket"`
+	// Tenant of the user, for routing uploads, optional.
+	TenantID string `json:"tenant,omitempty"`
+	// Object key the token is valid for, a key ending in "/" allows
+	// every key below it and "/" every key of the bucket, as needed for
+	// keys picked by a Router.
+	ObjectName string `json:"key"`
+	// Largest upload allowed, 0 for no limit.
+	MaxSize int64 `json:"maxSize,omitempty"`
+	// Expiry as Unix time in seconds.
+	ExpiresAt int64 `json:"exp"`
+}
+
+// allows - reports whether the claims allow uploading objectName to
+// bucketName.
+func (c TokenClaims) allows(bucketName, objectName string) bool {
+	if c.BucketName != bucketName {
+		return false
+	}
+	if c.ObjectName == "/" {
+		return true
+	}
+	if strings.HasSuffix(c.ObjectName, "/") {
+		return strings.HasPrefix(objectName, c.ObjectName)
+	}
+	return objectName == c.ObjectName
+}
+
+// Token errors.
+var (
+	ErrTokenMissing   = errors.New("upload token is missing")
+	ErrTokenMalformed = errors.New("upload token is malformed")
+	ErrTokenSignature = errors.New("upload token signature is invalid")
+	ErrTokenExpired   = errors.New("upload token has expired")
+)
+
+// SignToken - returns claims as an HS256 JWT signed with secret.
+func SignToken(secret []byte, claims TokenClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := encodeSegment([]byte(tokenHeader)) + "." + encodeSegment(payload)
+	return signed + "." + encodeSegment(tokenSignature(secret, signed)), nil
+}
+
+// ParseToken - verifies token with secret and returns its claims,
+// tokens expired at now are rejected.
+func ParseToken(secret []byte, token string, now time.Time) (*TokenClaims, error) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return nil, ErrTokenMalformed
+	}
+	header, err := decodeSegment(segments[0])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	var jose struct {
+		Alg string `json:"alg"`
+	}
+	if err = json.Unmarshal(header, &jose); err != nil || jose.Alg != "HS256" {
+		return nil, ErrTokenMalformed
+	}
+	signature, err := decodeSegment(segments[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if !hmac.Equal(signature, tokenSignature(secret, segments[0]+"."+segments[1])) {
+		return nil, ErrTokenSignature
+	}
+	payload, err := decodeSegment(segments[1])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	var claims TokenClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if claims.ExpiresAt == 0 || now.Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+	return &claims, nil
+}
+
+// tokenSignature - returns the HMAC-SHA256 of signed.
+func tokenSignature(secret []byte, signed string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return mac.Sum(nil)
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// claimsKey - context key of the verified token claims.
+type claimsKey struct{}
+
+// ClaimsFromContext - returns the claims verified by RequireToken, nil
+// when the request went through without a token.
+func ClaimsFromContext(ctx context.Context) *TokenClaims {
+	claims, _ := ctx.Value(claimsKey{}).(*TokenClaims)
+	return claims
+}
+
+// RequireToken - middleware rejecting requests without a valid upload
+// token signed with secret with 401 Unauthorized. The token is taken
+// from the "Authorization: Bearer" header or the token query parameter.
+// A Handler behind it only serves uploads of the bucket, key and size
+// the token allows.
+func RequireToken(secret []byte, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if token == "" {
+			writeError(w, errUnauthorized(ErrTokenMissing))
+			return
+		}
+		claims, err := ParseToken(secret, token, time.Now())
+		if err != nil {
+			writeError(w, errUnauthorized(err))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+	})
+}
+
+// errUnauthorized - missing or invalid token.
+func errUnauthorized(err error) error {
+	return httpError{status: http.StatusUnauthorized, code: "InvalidToken", message: err.Error()}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseToken(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1600000000, 0)
+	claims := TokenClaims{UserID: "user", BucketName: "bucket", ObjectName: "dir/", MaxSize: 10, ExpiresAt: now.Unix() + 60}
+	valid, err := SignToken(secret, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, _ := SignToken(secret, TokenClaims{ExpiresAt: now.Unix()})
+	noExpiry, _ := SignToken(secret, TokenClaims{UserID: "user"})
+	segments := strings.Split(valid, ".")
+	tampered, _ := SignToken(secret, TokenClaims{UserID: "admin", ExpiresAt: now.Unix() + 60})
+	tampered = segments[0] + "." + strings.Split(tampered, ".")[1] + "." + segments[2]
+	unsigned := encodeSegment([]byte(`{"alg":"none"}`)) + "." + segments[1] + "."
+
+	testCases := []struct {
+		secret []byte
+		token  string
+		err    error
+	}{
+		{secret, valid, nil},
+		{[]byte("other"), valid, ErrTokenSignature},
+		{secret, tampered, ErrTokenSignature},
+		{secret, expired, ErrTokenExpired},
+		{secret, noExpiry, ErrTokenExpired},
+		{secret, unsigned, ErrTokenMalformed},
+		{secret, "", ErrTokenMalformed},
+		{secret, segments[0] + "." + segments[1], ErrTokenMalformed},
+		{secret, segments[0] + ".!." + segments[2], ErrTokenSignature},
+		{secret, "!." + segments[1] + "." + segments[2], ErrTokenMalformed},
+	}
+	for i, testCase := range testCases {
+		parsed, err := ParseToken(testCase.secret, testCase.token, now)
+		if err != testCase.err {
+			t.Errorf("Test %d: expected %v, got %v", i+1, testCase.err, err)
+			continue
+		}
+		if err == nil && *parsed != claims {
+			t.Errorf("Test %d: expected %+v, got %+v", i+1, claims, *parsed)
+		}
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	secret := []byte("secret")
+	h, _ := newTestHandler(t, Options{})
+	handler := RequireToken(secret, h)
+	token := func(objectName string, maxSize int64) string {
+		token, err := SignToken(secret, TokenClaims{
+			UserID:     "user",
+			BucketName: "bucket",
+			ObjectName: objectName,
+			MaxSize:    maxSize,
+			ExpiresAt:  time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	testCases := []struct {
+		name          string
+		authorization string
+		query         string
+		size          int64
+		status        int
+		code          string
+	}{
+		{"file", "Bearer " + token("file", 0), "", 1, http.StatusCreated, ""},
+		{"dir/file", "", "?token=" + token("dir/", 10), 10, http.StatusCreated, ""},
+		{"file", "", "", 1, http.StatusUnauthorized, "InvalidToken"},
+		{"file", "Basic " + token("file", 0), "", 1, http.StatusUnauthorized, "InvalidToken"},
+		{"file", "Bearer invalid", "", 1, http.StatusUnauthorized, "InvalidToken"},
+		{"other", "Bearer " + token("file", 0), "", 1, http.StatusForbidden, "AccessDenied"},
+		{"dir", "Bearer " + token("dir/", 0), "", 1, http.StatusForbidden, "AccessDenied"},
+		{"dir/file", "Bearer " + token("dir/", 10), "", 11, http.StatusForbidden, "AccessDenied"},
+	}
+	for i, testCase := range testCases {
+		var errRes errorBody
+		req := initRequest{Name: testCase.name, Size: testCase.size}
+		rec := serveRequest(t, handler, "POST", "/uploads"+testCase.query, req, &errRes, func(r *http.Request) {
+			if testCase.authorization != "" {
+				r.Header.Set("Authorization", testCase.authorization)
+			}
+		})
+		if rec.Code != testCase.status || errRes.Code != testCase.code {
+			t.Errorf("Test %d: expected %d %s, got %d %s", i+1, testCase.status, testCase.code, rec.Code, errRes.Code)
+		}
+	}
+
+	// Uploads of other objects are refused with the token.
+	var initRes initResponse
+	serve(t, h, "POST", "/uploads", initRequest{Name: "private", Size: 1}, &initRes)
+	rec := serveRequest(t, handler, "GET", "/uploads/"+initRes.UploadID+"/parts", nil, nil, func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+token("file", 0))
+	})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected the upload refused, got %d", rec.Code)
+	}
+}
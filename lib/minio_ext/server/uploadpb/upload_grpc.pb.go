@@ -0,0 +1,329 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: upload.proto
+
+package uploadpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// UploadServiceClient is the client API for UploadService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UploadServiceClient interface {
+	// Initiates an upload and returns its part plan.
+	InitUpload(ctx context.Context, in *InitUploadRequest, opts ...grpc.CallOption) (*InitUploadResponse, error)
+	// Returns presigned URLs of the missing parts and the parts already
+	// uploaded, of the requested parts only when some are requested.
+	GetPartURLs(ctx context.Context, in *GetPartURLsRequest, opts ...grpc.CallOption) (*GetPartURLsResponse, error)
+	// Checks a part the client uploaded against the object storage.
+	ReportPart(ctx context.Context, in *ReportPartRequest, opts ...grpc.CallOption) (*ReportPartResponse, error)
+	// Verifies the parts and completes the upload.
+	Complete(ctx context.Context, in *CompleteRequest, opts ...grpc.CallOption) (*CompleteResponse, error)
+	// Aborts the upload and removes its parts.
+	Abort(ctx context.Context, in *AbortRequest, opts ...grpc.CallOption) (*AbortResponse, error)
+	// Streams server verified progress until the upload is completed,
+	// aborted or gone.
+	WatchProgress(ctx context.Context, in *WatchProgressRequest, opts ...grpc.CallOption) (UploadService_WatchProgressClient, error)
+}
+
+type uploadServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUploadServiceClient(cc grpc.ClientConnInterface) UploadServiceClient {
+	return &uploadServiceClient{cc}
+}
+
+func (c *uploadServiceClient) InitUpload(ctx context.Context, in *InitUploadRequest, opts ...grpc.CallOption) (*InitUploadResponse, error) {
+	out := new(InitUploadResponse)
+	err := c.cc.Invoke(ctx, "/minio_ext.upload.v1.UploadService/InitUpload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uploadServiceClient) GetPartURLs(ctx context.Context, in *GetPartURLsRequest, opts ...grpc.CallOption) (*GetPartURLsResponse, error) {
+	out := new(GetPartURLsResponse)
+	err := c.cc.Invoke(ctx, "/minio_ext.upload.v1.UploadService/GetPartURLs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uploadServiceClient) ReportPart(ctx context.Context, in *ReportPartRequest, opts ...grpc.CallOption) (*ReportPartResponse, error) {
+	out := new(ReportPartResponse)
+	err := c.cc.Invoke(ctx, "/minio_ext.upload.v1.UploadService/ReportPart", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uploadServiceClient) Complete(ctx context.Context, in *CompleteRequest, opts ...grpc.CallOption) (*CompleteResponse, error) {
+	out := new(CompleteResponse)
+	err := c.cc.Invoke(ctx, "/minio_ext.upload.v1.UploadService/Complete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uploadServiceClient) Abort(ctx context.Context, in *AbortRequest, opts ...grpc.CallOption) (*AbortResponse, error) {
+	out := new(AbortResponse)
+	err := c.cc.Invoke(ctx, "/minio_ext.upload.v1.UploadService/Abort", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uploadServiceClient) WatchProgress(ctx context.Context, in *WatchProgressRequest, opts ...grpc.CallOption) (UploadService_WatchProgressClient, error) {
+	stream, err := c.cc.NewStream(ctx, &UploadService_ServiceDesc.Streams[0], "/minio_ext.upload.v1.UploadService/WatchProgress", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &uploadServiceWatchProgressClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type UploadService_WatchProgressClient interface {
+	Recv() (*ProgressEvent, error)
+	grpc.ClientStream
+}
+
+type uploadServiceWatchProgressClient struct {
+	grpc.ClientStream
+}
+
+func (x *uploadServiceWatchProgressClient) Recv() (*ProgressEvent, error) {
+	m := new(ProgressEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// UploadServiceServer is the server API for UploadService service.
+// All implementations must embed UnimplementedUploadServiceServer
+// for forward compatibility
+type UploadServiceServer interface {
+	// Initiates an upload and returns its part plan.
+	InitUpload(context.Context, *InitUploadRequest) (*InitUploadResponse, error)
+	// Returns presigned URLs of the missing parts and the parts already
+	// uploaded, of the requested parts only when some are requested.
+	GetPartURLs(context.Context, *GetPartURLsRequest) (*GetPartURLsResponse, error)
+	// Checks a part the client uploaded against the object storage.
+	ReportPart(context.Context, *ReportPartRequest) (*ReportPartResponse, error)
+	// Verifies the parts and completes the upload.
+	Complete(context.Context, *CompleteRequest) (*CompleteResponse, error)
+	// Aborts the upload and removes its parts.
+	Abort(context.Context, *AbortRequest) (*AbortResponse, error)
+	// Streams server verified progress until the upload is completed,
+	// aborted or gone.
+	WatchProgress(*WatchProgressRequest, UploadService_WatchProgressServer) error
+	mustEmbedUnimplementedUploadServiceServer()
+}
+
+// UnimplementedUploadServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedUploadServiceServer struct {
+}
+
+func (UnimplementedUploadServiceServer) InitUpload(context.Context, *InitUploadRequest) (*InitUploadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InitUpload not implemented")
+}
+func (UnimplementedUploadServiceServer) GetPartURLs(context.Context, *GetPartURLsRequest) (*GetPartURLsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPartURLs not implemented")
+}
+func (UnimplementedUploadServiceServer) ReportPart(context.Context, *ReportPartRequest) (*ReportPartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportPart not implemented")
+}
+func (UnimplementedUploadServiceServer) Complete(context.Context, *CompleteRequest) (*CompleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Complete not implemented")
+}
+func (UnimplementedUploadServiceServer) Abort(context.Context, *AbortRequest) (*AbortResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Abort not implemented")
+}
+func (UnimplementedUploadServiceServer) WatchProgress(*WatchProgressRequest, UploadService_WatchProgressServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchProgress not implemented")
+}
+func (UnimplementedUploadServiceServer) mustEmbedUnimplementedUploadServiceServer() {}
+
+// UnsafeUploadServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UploadServiceServer will
+// result in compilation errors.
+type UnsafeUploadServiceServer interface {
+	mustEmbedUnimplementedUploadServiceServer()
+}
+
+func RegisterUploadServiceServer(s grpc.ServiceRegistrar, srv UploadServiceServer) {
+	s.RegisterService(&UploadService_ServiceDesc, srv)
+}
+
+func _UploadService_InitUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InitUploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UploadServiceServer).InitUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/minio_ext.upload.v1.UploadService/InitUpload",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UploadServiceServer).InitUpload(ctx, req.(*InitUploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UploadService_GetPartURLs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPartURLsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UploadServiceServer).GetPartURLs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/minio_ext.upload.v1.UploadService/GetPartURLs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UploadServiceServer).GetPartURLs(ctx, req.(*GetPartURLsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UploadService_ReportPart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportPartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UploadServiceServer).ReportPart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/minio_ext.upload.v1.UploadService/ReportPart",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UploadServiceServer).ReportPart(ctx, req.(*ReportPartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UploadService_Complete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UploadServiceServer).Complete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/minio_ext.upload.v1.UploadService/Complete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UploadServiceServer).Complete(ctx, req.(*CompleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UploadService_Abort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AbortRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UploadServiceServer).Abort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/minio_ext.upload.v1.UploadService/Abort",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UploadServiceServer).Abort(ctx, req.(*AbortRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UploadService_WatchProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchProgressRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UploadServiceServer).WatchProgress(m, &uploadServiceWatchProgressServer{stream})
+}
+
+type UploadService_WatchProgressServer interface {
+	Send(*ProgressEvent) error
+	grpc.ServerStream
+}
+
+type uploadServiceWatchProgressServer struct {
+	grpc.ServerStream
+}
+
+func (x *uploadServiceWatchProgressServer) Send(m *ProgressEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// UploadService_ServiceDesc is the grpc.ServiceDesc for UploadService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var UploadService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "minio_ext.upload.v1.UploadService",
+	HandlerType: (*UploadServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "InitUpload",
+			Handler:    _UploadService_InitUpload_Handler,
+		},
+		{
+			MethodName: "GetPartURLs",
+			Handler:    _UploadService_GetPartURLs_Handler,
+		},
+		{
+			MethodName: "ReportPart",
+			Handler:    _UploadService_ReportPart_Handler,
+		},
+		{
+			MethodName: "Complete",
+			Handler:    _UploadService_Complete_Handler,
+		},
+		{
+			MethodName: "Abort",
+			Handler:    _UploadService_Abort_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchProgress",
+			Handler:       _UploadService_WatchProgress_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "upload.proto",
+}
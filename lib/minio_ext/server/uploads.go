@@ -0,0 +1,549 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"oss/lib/minio_ext"
+)
+
+// initRequest - body of POST /uploads.
+type initRequest struct {
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"contentType,omitempty"`
+
+	// Optional hex encoded MD5 of the file, with a content index the
+	// upload is skipped when the content is already stored.
+	MD5 string `json:"md5,omitempty"`
+
+	// Optional manifest of an end to end encrypted upload of Size
+	// bytes of plaintext, see minio_ext.EncryptionManifest. The parts
+	// are planned on the plaintext and sent encrypted, the manifest is
+	// stored with the object and returned with the part URLs.
+	Encryption *minio_ext.EncryptionManifest `json:"encryption,omitempty"`
+}
+
+// initResponse - answer of POST /uploads, the browser uploads the
+// parts of the plan. With Existing set the content is already stored
+// as the object, there is no upload and no plan.
+type initResponse struct {
+	UploadID   string                `json:"uploadId,omitempty"`
+	BucketName string                `json:"bucket"`
+	ObjectName string                `json:"object"`
+	Size       int64                 `json:"size"`
+	PartSize   int64                 `json:"partSize,omitempty"`
+	Parts      []minio_ext.PartState `json:"parts,omitempty"`
+	Existing   bool                  `json:"existing,omitempty"`
+}
+
+// partURL - a planned part with the presigned URL to PUT it to, and
+// the size of the part once encrypted for encrypted uploads.
+type partURL struct {
+	minio_ext.PartState
+	URL      string `json:"url"`
+	SentSize int64  `json:"sentSize,omitempty"`
+}
+
+// uploadedPart - a part of the plan already uploaded.
+type uploadedPart struct {
+	PartNumber int    `json:"partNumber"`
+	Size       int64  `json:"size"`
+	ETag       string `json:"etag"`
+}
+
+// partsResponse - answer of GET /uploads/{id}/parts, with the manifest
+// of encrypted uploads so a client resuming the upload recovers its
+// sealed key.
+type partsResponse struct {
+	UploadID   string                        `json:"uploadId"`
+	ExpiresIn  int64                         `json:"expiresIn"`
+	Parts      []partURL                     `json:"parts"`
+	Uploaded   []uploadedPart                `json:"uploaded"`
+	Encryption *minio_ext.EncryptionManifest `json:"encryption,omitempty"`
+}
+
+// completeResponse - answer of POST /uploads/{id}/complete, the object
+// is where the hooks left it.
+type completeResponse struct {
+	BucketName string        `json:"bucket"`
+	ObjectName string        `json:"object"`
+	ETag       string        `json:"etag"`
+	Flags      []HookFailure `json:"flags,omitempty"`
+}
+
+// initiate - POST /uploads, initiates a multipart upload and plans its
+// parts. Uploads declaring an MD5 found in the content index are
+// answered with the stored object, the upload is skipped and no quota
+// is reserved. Encrypted uploads are never deduplicated, the stored
+// object is sealed with another data key. The handler relays and
+// stores encrypted parts without being able to decrypt them.
+func (h *Handler) initiate(w http.ResponseWriter, r *http.Request) {
+	var req initRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeError(w, errBadRequest("MalformedJSON", "Malformed request body: "+err.Error()))
+		return
+	}
+	dest, err := h.opts.Router.Route(r.Context(), req.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err = h.authorize(r, dest.BucketName, dest.ObjectName, req.Size); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.MD5 != "" && !validMD5(req.MD5) {
+		writeError(w, errBadRequest("InvalidDigest", "Invalid MD5 ‘"+req.MD5+"’."))
+		return
+	}
+	if req.Encryption != nil {
+		if err = req.Encryption.Validate(); err != nil {
+			writeError(w, err)
+			return
+		}
+		if req.Encryption.Size != req.Size {
+			writeError(w, errBadRequest("InvalidArgument", "Encryption manifest size does not match the upload size."))
+			return
+		}
+	}
+	if req.MD5 != "" && h.opts.Index != nil && req.Encryption == nil {
+		existing, err := h.findContent(r, dest, req.MD5, req.Size)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if existing != nil {
+			writeJSON(w, http.StatusOK, initResponse{
+				BucketName: existing.BucketName,
+				ObjectName: existing.ObjectName,
+				Size:       req.Size,
+				Existing:   true,
+			})
+			return
+		}
+	}
+	state, err := h.initiateUpload(r.Context(), dest, req.Size, req.ContentType, req.MD5, req.Encryption)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, initResponse{
+		UploadID:   state.UploadID,
+		BucketName: state.BucketName,
+		ObjectName: state.ObjectName,
+		Size:       state.Size,
+		PartSize:   state.PartSize,
+		Parts:      state.Parts,
+	})
+}
+
+// initiateUpload - plans and initiates an upload of size bytes to dest
+// and saves its state, md5 is the declared MD5 of the content or empty
+// and encryption the manifest of an encrypted upload or nil.
+func (h *Handler) initiateUpload(ctx context.Context, dest Destination, size int64, contentType, md5 string, encryption *minio_ext.EncryptionManifest) (minio_ext.UploadState, error) {
+	if h.opts.MaxSize > 0 && size > h.opts.MaxSize {
+		return minio_ext.UploadState{}, errBadRequest("EntityTooLarge", fmt.Sprintf("Upload size %d exceeds the limit of %d.", size, h.opts.MaxSize))
+	}
+	var plan []minio_ext.PartState
+	var err error
+	if encryption != nil {
+		plan, err = encryption.PlanParts(h.opts.PartSize)
+	} else {
+		plan, err = minio_ext.PlanParts(size, h.opts.PartSize)
+	}
+	if err != nil {
+		return minio_ext.UploadState{}, err
+	}
+	if err = h.ensureBucket(ctx, dest.BucketName); err != nil {
+		return minio_ext.UploadState{}, err
+	}
+	commit, err := reserveUpload(ctx)
+	if err != nil {
+		return minio_ext.UploadState{}, err
+	}
+	var initiated string
+	defer func() { commit(initiated) }()
+	subjects := h.quotaSubjects(ctx)
+	if err = h.reserveQuota(subjects, size); err != nil {
+		return minio_ext.UploadState{}, err
+	}
+	defer func() {
+		if initiated == "" {
+			h.adjustQuota(subjects, -size)
+		}
+	}()
+
+	customHeader := make(http.Header)
+	if encryption != nil {
+		customHeader = encryption.Metadata()
+	}
+	if contentType != "" {
+		customHeader.Set("Content-Type", contentType)
+	}
+	uploadID, err := h.client.NewMultipartUpload(ctx, dest.BucketName, dest.ObjectName, customHeader)
+	if err != nil {
+		return minio_ext.UploadState{}, err
+	}
+
+	state := minio_ext.UploadState{
+		BucketName: dest.BucketName,
+		ObjectName: dest.ObjectName,
+		UploadID:   uploadID,
+		Initiated:  time.Now().UTC(),
+		Size:       size,
+		PartSize:   plan[0].Size,
+		Parts:      plan,
+
+		Encryption:    encryption,
+		QuotaSubjects: subjects,
+		ContentMD5:    strings.ToLower(md5),
+	}
+	if err = h.opts.States.Save(uploadID, state); err != nil {
+		h.client.AbortMultipartUpload(ctx, dest.BucketName, dest.ObjectName, uploadID)
+		return minio_ext.UploadState{}, err
+	}
+	initiated = uploadID
+	return state, nil
+}
+
+// lookup - returns the state of upload uploadID.
+func (h *Handler) lookup(uploadID string) (*minio_ext.UploadState, error) {
+	state, err := h.opts.States.Load(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.Stream {
+		// Uploads started by Uppy have no plan and are only served
+		// by the Uppy endpoints.
+		return nil, errNotFound("NoSuchUpload", "Upload ‘"+uploadID+"’ does not exist.")
+	}
+	return state, nil
+}
+
+// load - returns the state of upload uploadID after authorizing the
+// request against its object.
+func (h *Handler) load(r *http.Request, uploadID string) (*minio_ext.UploadState, error) {
+	state, err := h.lookup(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if err = h.authorize(r, state.BucketName, state.ObjectName, state.Size); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// uploaded - lists the parts of state uploaded with the planned size.
+func (h *Handler) uploaded(ctx context.Context, state *minio_ext.UploadState) (map[int]minio_ext.ObjectPart, error) {
+	partsInfo, err := h.client.ListObjectPartsWithContext(ctx, state.BucketName, state.ObjectName, state.UploadID)
+	if err != nil {
+		return nil, err
+	}
+	for _, spec := range state.Parts {
+		if part, ok := partsInfo[spec.PartNumber]; ok && part.Size != state.SentPart(spec).Size {
+			// Uploaded with a wrong size, the part has to be sent again.
+			delete(partsInfo, spec.PartNumber)
+		}
+	}
+	return partsInfo, nil
+}
+
+// parts - GET /uploads/{id}/parts, returns the uploaded parts with
+// their ETags and presigned URLs of the missing ones. The partNumbers
+// query parameter, a comma separated list, limits the answer to these
+// parts, requested parts already uploaded get no URL.
+func (h *Handler) parts(w http.ResponseWriter, r *http.Request, uploadID string) {
+	state, err := h.load(r, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	partsInfo, err := h.uploaded(r.Context(), state)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var wanted map[int]bool
+	if list := r.URL.Query().Get("partNumbers"); list != "" {
+		wanted = make(map[int]bool)
+		for _, s := range strings.Split(list, ",") {
+			partNumber, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || partNumber < 1 || partNumber > len(state.Parts) {
+				writeError(w, errBadRequest("InvalidPartNumber", "Invalid part number ‘"+s+"’."))
+				return
+			}
+			wanted[partNumber] = true
+		}
+	}
+
+	parts, uploaded, err := h.signParts(r.Context(), state, partsInfo, wanted)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, partsResponse{
+		UploadID:   state.UploadID,
+		ExpiresIn:  int64(h.opts.Expires.Seconds()),
+		Parts:      parts,
+		Uploaded:   uploaded,
+		Encryption: state.Encryption,
+	})
+}
+
+// signParts - returns the presigned URLs of the missing parts of state
+// and the uploaded parts, only of the wanted parts unless wanted is nil.
+// Only missing parts are signed.
+func (h *Handler) signParts(ctx context.Context, state *minio_ext.UploadState, partsInfo map[int]minio_ext.ObjectPart, wanted map[int]bool) ([]partURL, []uploadedPart, error) {
+	parts := []partURL{}
+	uploaded := []uploadedPart{}
+	for _, spec := range state.Parts {
+		if wanted != nil && !wanted[spec.PartNumber] {
+			continue
+		}
+		if part, done := partsInfo[spec.PartNumber]; done {
+			uploaded = append(uploaded, uploadedPart{PartNumber: part.PartNumber, Size: part.Size, ETag: part.ETag})
+			continue
+		}
+		sent := state.SentPart(spec)
+		signedUrl, err := h.client.GenUploadPartSignedUrlWithContext(ctx, state.UploadID, state.BucketName, state.ObjectName, spec.PartNumber, sent.Size, h.opts.Expires, h.location(state.BucketName))
+		if err != nil {
+			return nil, nil, err
+		}
+		part := partURL{PartState: spec, URL: signedUrl}
+		if state.Encryption != nil {
+			part.SentSize = sent.Size
+		}
+		parts = append(parts, part)
+	}
+	return parts, uploaded, nil
+}
+
+// part - GET /uploads/{id}/parts/{n}, checks a single part without
+// listing all parts of the upload.
+func (h *Handler) part(w http.ResponseWriter, r *http.Request, uploadID, number string) {
+	state, err := h.load(r, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	partNumber, err := strconv.Atoi(number)
+	if err != nil || partNumber < 1 || partNumber > len(state.Parts) {
+		writeError(w, errBadRequest("InvalidPartNumber", "Invalid part number ‘"+number+"’."))
+		return
+	}
+	uploaded, err := h.client.IsPartUploadedWithContext(r.Context(), state.BucketName, state.ObjectName, state.UploadID, partNumber, state.SentPart(state.Parts[partNumber-1]).Size)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"partNumber": partNumber, "uploaded": uploaded})
+}
+
+// relay - PUT /uploads/{id}/parts/{n}, forwards the body as part n. The
+// body must have the planned size of the part, the sent size of
+// encrypted parts, which are forwarded as the ciphertext they are, and
+// declare its checksum in Content-MD5 or X-Amz-Checksum-Crc32c, base64
+// encoded. A part not matching its checksum is answered with 400
+// ChunkChecksumMismatch and not forwarded, the browser sends just this
+// part again.
+func (h *Handler) relay(w http.ResponseWriter, r *http.Request, uploadID, number string) {
+	state, err := h.load(r, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	partNumber, err := strconv.Atoi(number)
+	if err != nil || partNumber < 1 || partNumber > len(state.Parts) {
+		writeError(w, errBadRequest("InvalidPartNumber", "Invalid part number ‘"+number+"’."))
+		return
+	}
+	size := state.SentPart(state.Parts[partNumber-1]).Size
+	if r.ContentLength != size {
+		writeError(w, errBadRequest("InvalidPartSize", fmt.Sprintf("Part %d must have %d bytes.", partNumber, size)))
+		return
+	}
+	declared := minio_ext.PartChecksums{
+		MD5:    r.Header.Get("Content-MD5"),
+		CRC32C: r.Header.Get("X-Amz-Checksum-Crc32c"),
+	}
+	if declared.MD5 == "" && declared.CRC32C == "" {
+		writeError(w, errBadRequest("MissingChecksum", "Content-MD5 or X-Amz-Checksum-Crc32c is required."))
+		return
+	}
+
+	part, err := h.client.PutObjectPart(r.Context(), state.BucketName, state.ObjectName, state.UploadID,
+		partNumber, r.Body, size, declared)
+	if err != nil {
+		if minio_ext.IsUploadExpired(err) {
+			h.forget(r.Context(), state)
+		}
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"partNumber": part.PartNumber, "etag": part.ETag, "size": part.Size})
+}
+
+// complete - POST /uploads/{id}/complete, completes the upload once all
+// planned parts are uploaded. Parts reported in the body must match the
+// uploaded parts, otherwise the differences are answered with 409
+// Conflict and the upload is left as it is.
+func (h *Handler) complete(w http.ResponseWriter, r *http.Request, uploadID string) {
+	state, err := h.load(r, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	req, err := readVerifyRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, parts, err := h.verifyParts(r.Context(), state, req.Parts)
+	if err != nil {
+		if minio_ext.IsUploadExpired(err) {
+			h.forget(r.Context(), state)
+		}
+		writeError(w, err)
+		return
+	}
+	if !res.OK {
+		writeJSON(w, http.StatusConflict, verificationError{
+			errorBody: errorBody{
+				Code:    "PartsMismatch",
+				Message: fmt.Sprintf("%d of %d parts do not match the uploaded parts.", len(res.Mismatches), res.PartsTotal),
+			},
+			Verification: res,
+		})
+		return
+	}
+
+	obj, flags, err := h.completeUpload(r.Context(), state, parts)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, completeResponse{
+		BucketName: obj.BucketName,
+		ObjectName: obj.ObjectName,
+		ETag:       obj.ETag,
+		Flags:      flags,
+	})
+}
+
+// completeUpload - completes the upload of state with parts, runs the
+// hooks, forgets the upload and notifies its progress streams and the
+// completion webhook of the client. Returns
+// the object and the flags raised by the hooks.
+func (h *Handler) completeUpload(ctx context.Context, state *minio_ext.UploadState, parts []minio_ext.CompletePart) (CompletedObject, []HookFailure, error) {
+	// The webhook is notified of the object the hooks leave.
+	etag, err := h.client.CompleteMultipartUploadWithContext(minio_ext.WithoutCompletionWebhook(ctx), state.BucketName, state.ObjectName, state.UploadID, parts, nil)
+	if err != nil {
+		return CompletedObject{}, nil, err
+	}
+	// The quota is settled by the request removing the state, another
+	// one may have found the upload gone meanwhile.
+	removed, _ := h.removeState(state.UploadID)
+	releaseUpload(ctx, state.UploadID)
+
+	obj := CompletedObject{
+		BucketName: state.BucketName,
+		ObjectName: state.ObjectName,
+		UploadID:   state.UploadID,
+		Size:       state.Size,
+		ETag:       strings.Trim(etag, "\""),
+		Claims:     ClaimsFromContext(ctx),
+	}
+	flags, err := h.process(&obj)
+	if err != nil {
+		if removed {
+			h.adjustQuota(state.QuotaSubjects, -state.Size)
+		}
+		h.hub.publish(progressEvent{
+			UploadID:   state.UploadID,
+			State:      EventRejected,
+			Size:       state.Size,
+			PartsTotal: len(state.Parts),
+		})
+		return obj, flags, err
+	}
+	if removed {
+		h.reconcileQuota(ctx, state.QuotaSubjects, obj.BucketName, obj.ObjectName, state.Size)
+	}
+	h.indexContent(obj, state.ContentMD5)
+	h.client.NotifyCompleted(obj.BucketName, obj.ObjectName, obj.UploadID, obj.ETag)
+	h.hub.publish(progressEvent{
+		UploadID:       state.UploadID,
+		State:          EventCompleted,
+		Size:           state.Size,
+		ConfirmedBytes: state.Size,
+		PartsTotal:     len(state.Parts),
+		PartsDone:      len(state.Parts),
+		ETag:           obj.ETag,
+	})
+	return obj, flags, nil
+}
+
+// status - GET /uploads/{id}, returns the declared size, the confirmed
+// bytes, the missing parts and the expiry of the upload and whether it
+// can be completed.
+func (h *Handler) status(w http.ResponseWriter, r *http.Request, uploadID string) {
+	state, err := h.load(r, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	status, err := h.client.UploadStatusWithContext(r.Context(), *state, h.opts.AbortAfter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// abort - DELETE /uploads/{id}, aborts the upload and removes its
+// uploaded parts.
+func (h *Handler) abort(w http.ResponseWriter, r *http.Request, uploadID string) {
+	state, err := h.load(r, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err = h.abortUpload(r.Context(), state); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// abortUpload - aborts the upload of state, forgets it and notifies its
+// progress streams. An upload already gone is no error.
+func (h *Handler) abortUpload(ctx context.Context, state *minio_ext.UploadState) error {
+	err := h.client.AbortMultipartUpload(ctx, state.BucketName, state.ObjectName, state.UploadID)
+	if err != nil && !minio_ext.IsUploadExpired(err) {
+		return err
+	}
+	removed, err := h.removeState(state.UploadID)
+	if err != nil {
+		return err
+	}
+	releaseUpload(ctx, state.UploadID)
+	if removed {
+		h.adjustQuota(state.QuotaSubjects, -state.Size)
+	}
+	h.hub.publish(progressEvent{
+		UploadID:   state.UploadID,
+		State:      EventAborted,
+		Size:       state.Size,
+		PartsTotal: len(state.Parts),
+	})
+	return nil
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// md5Base64 - returns the Content-MD5 of data.
+func md5Base64(data []byte) string {
+	sum := md5.Sum(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// crc32cBase64 - returns the X-Amz-Checksum-Crc32c of data.
+func crc32cBase64(data []byte) string {
+	sum := make([]byte, 4)
+	binary.BigEndian.PutUint32(sum, crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+	return base64.StdEncoding.EncodeToString(sum)
+}
+
+func TestHandlerRelay(t *testing.T) {
+	h, s3 := newTestHandler(t, Options{PartSize: testPartSize, Relay: true})
+	data := append(bytes.Repeat([]byte{1}, testPartSize), bytes.Repeat([]byte{2}, 16)...)
+	first, second := data[:testPartSize], data[testPartSize:]
+	var initRes initResponse
+	serve(t, h, "POST", "/uploads", initRequest{Name: "relayed", Size: int64(len(data))}, &initRes)
+	partsPath := "/uploads/" + initRes.UploadID + "/parts/"
+
+	testCases := []struct {
+		partNumber string
+		body       []byte
+		md5        string
+		crc32c     string
+		status     int
+		code       string
+	}{
+		{"1", first, md5Base64(second), "", http.StatusBadRequest, "ChunkChecksumMismatch"},
+		{"2", second, "", crc32cBase64(first), http.StatusBadRequest, "ChunkChecksumMismatch"},
+		{"2", second, md5Base64(second), crc32cBase64(first), http.StatusBadRequest, "ChunkChecksumMismatch"},
+		{"2", second, "", "", http.StatusBadRequest, "MissingChecksum"},
+		{"2", first, md5Base64(first), "", http.StatusBadRequest, "InvalidPartSize"},
+		{"3", second, md5Base64(second), "", http.StatusBadRequest, "InvalidPartNumber"},
+		{"1", first, md5Base64(first), "", http.StatusOK, ""},
+		{"2", second, "", crc32cBase64(second), http.StatusOK, ""},
+	}
+	for i, testCase := range testCases {
+		parts := s3.count("part")
+		var res struct {
+			errorBody
+			PartNumber int    `json:"partNumber"`
+			ETag       string `json:"etag"`
+			Size       int64  `json:"size"`
+		}
+		rec := serveRequest(t, h, "PUT", partsPath+testCase.partNumber, string(testCase.body), &res, func(r *http.Request) {
+			if testCase.md5 != "" {
+				r.Header.Set("Content-MD5", testCase.md5)
+			}
+			if testCase.crc32c != "" {
+				r.Header.Set("X-Amz-Checksum-Crc32c", testCase.crc32c)
+			}
+		})
+		if rec.Code != testCase.status || res.Code != testCase.code {
+			t.Errorf("Test %d: expected %d %s, got %d %s", i+1, testCase.status, testCase.code, rec.Code, res.Code)
+			continue
+		}
+		// Parts are only forwarded once their checksums match.
+		forwarded := s3.count("part") - parts
+		if testCase.status != http.StatusOK {
+			if forwarded != 0 {
+				t.Errorf("Test %d: expected the part not forwarded", i+1)
+			}
+			continue
+		}
+		if forwarded != 1 || strconv.Itoa(res.PartNumber) != testCase.partNumber ||
+			res.Size != int64(len(testCase.body)) || res.ETag != etagOf(testCase.body) {
+			t.Errorf("Test %d: unexpected part %+v", i+1, res)
+		}
+	}
+
+	if rec := serve(t, h, "POST", "/uploads/"+initRes.UploadID+"/complete", nil, nil); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the upload completed, got %d %s", rec.Code, rec.Body)
+	}
+	if object, ok := s3.object("/bucket/relayed"); !ok || !bytes.Equal(object, data) {
+		t.Error("Expected the relayed object stored")
+	}
+
+	// Without Relay parts are not accepted.
+	h.opts.Relay = false
+	if rec := serve(t, h, "PUT", partsPath+"1", string(first), nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected relaying refused, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,351 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"oss/lib/minio_ext"
+)
+
+// uppyHandler - serves the endpoints of Uppy's AwsS3Multipart plugin,
+// see Handler.Uppy.
+type uppyHandler struct {
+	*Handler
+}
+
+// Uppy - returns a handler serving the same uploads with the requests
+// and responses of the Companion endpoints Uppy's AwsS3Multipart plugin
+// talks to, set the companionUrl of the plugin to where it is mounted:
+//
+//	POST   /s3/multipart                         createMultipartUpload
+//	GET    /s3/multipart/{id}?key=               listParts
+//	GET    /s3/multipart/{id}/batch?key=&partNumbers=
+//	                                             prepareUploadParts
+//	GET    /s3/multipart/{id}/{partNumber}?key=  signPart
+//	POST   /s3/multipart/{id}/complete?key=      completeMultipartUpload
+//	DELETE /s3/multipart/{id}?key=               abortMultipartUpload
+//
+// Uppy plans the parts itself, the size limits are checked when the
+// upload is completed.
+func (h *Handler) Uppy() http.Handler {
+	return uppyHandler{h}
+}
+
+// uppyCreateRequest - body of createMultipartUpload.
+type uppyCreateRequest struct {
+	Filename string            `json:"filename"`
+	Type     string            `json:"type"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// uppyPart - a part in the shape of S3 responses, as Uppy expects it.
+type uppyPart struct {
+	PartNumber int
+	Size       int64 `json:",omitempty"`
+	ETag       string
+}
+
+// ServeHTTP - routes a request to its endpoint.
+func (u uppyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) < 2 || len(segments) > 4 || segments[0] != "s3" || segments[1] != "multipart" {
+		writeError(w, errNotFound("NotFound", "No such endpoint."))
+		return
+	}
+
+	switch {
+	case len(segments) == 2:
+		if allowMethod(w, r, http.MethodPost) {
+			u.create(w, r)
+		}
+	case len(segments) == 3 && r.Method == http.MethodDelete:
+		u.abort(w, r, segments[2])
+	case len(segments) == 3:
+		if allowMethod(w, r, http.MethodGet) {
+			u.listParts(w, r, segments[2])
+		}
+	case segments[3] == "complete":
+		if allowMethod(w, r, http.MethodPost) {
+			u.complete(w, r, segments[2])
+		}
+	case segments[3] == "batch":
+		if allowMethod(w, r, http.MethodGet) {
+			u.signParts(w, r, segments[2], r.URL.Query().Get("partNumbers"), true)
+		}
+	default:
+		if allowMethod(w, r, http.MethodGet) {
+			u.signParts(w, r, segments[2], segments[3], false)
+		}
+	}
+}
+
+// create - createMultipartUpload, answers the upload id and key.
+func (u uppyHandler) create(w http.ResponseWriter, r *http.Request) {
+	var req uppyCreateRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeError(w, errBadRequest("MalformedJSON", "Malformed request body: "+err.Error()))
+		return
+	}
+	dest, err := u.opts.Router.Route(r.Context(), req.Filename)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err = u.authorize(r, dest.BucketName, dest.ObjectName, 0); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	commit, err := reserveUpload(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var initiated string
+	defer func() { commit(initiated) }()
+
+	customHeader := make(http.Header)
+	if req.Type != "" {
+		customHeader.Set("Content-Type", req.Type)
+	}
+	for k, v := range req.Metadata {
+		customHeader.Set("X-Amz-Meta-"+k, v)
+	}
+	uploadID, err := u.client.NewMultipartUpload(r.Context(), dest.BucketName, dest.ObjectName, customHeader)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// Parts are planned by Uppy, the state has no plan.
+	state := minio_ext.UploadState{
+		BucketName: dest.BucketName,
+		ObjectName: dest.ObjectName,
+		UploadID:   uploadID,
+		Initiated:  time.Now().UTC(),
+		Stream:     true,
+	}
+	if err = u.opts.States.Save(uploadID, state); err != nil {
+		u.client.AbortMultipartUpload(r.Context(), dest.BucketName, dest.ObjectName, uploadID)
+		writeError(w, err)
+		return
+	}
+	initiated = uploadID
+	writeJSON(w, http.StatusOK, map[string]string{"uploadId": uploadID, "key": dest.ObjectName})
+}
+
+// load - returns the state of an upload started by create, the key
+// query parameter must name its object.
+func (u uppyHandler) load(r *http.Request, uploadID string) (*minio_ext.UploadState, error) {
+	state, err := u.opts.States.Load(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || !state.Stream || state.ObjectName != r.URL.Query().Get("key") {
+		return nil, errNotFound("NoSuchUpload", "Upload ‘"+uploadID+"’ does not exist.")
+	}
+	if err = u.authorize(r, state.BucketName, state.ObjectName, 0); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// listParts - answers the uploaded parts sorted by part number.
+func (u uppyHandler) listParts(w http.ResponseWriter, r *http.Request, uploadID string) {
+	state, err := u.load(r, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	partsInfo, err := u.client.ListObjectPartsWithContext(r.Context(), state.BucketName, state.ObjectName, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	parts := []uppyPart{}
+	for _, part := range partsInfo {
+		parts = append(parts, uppyPart{PartNumber: part.PartNumber, Size: part.Size, ETag: part.ETag})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	writeJSON(w, http.StatusOK, parts)
+}
+
+// signParts - signPart and prepareUploadParts, answers presigned URLs of
+// the comma separated part numbers in list.
+func (u uppyHandler) signParts(w http.ResponseWriter, r *http.Request, uploadID, list string, batch bool) {
+	state, err := u.load(r, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	numbers := strings.Split(list, ",")
+	if !batch && len(numbers) != 1 {
+		writeError(w, errBadRequest("InvalidPartNumber", "Invalid part number ‘"+list+"’."))
+		return
+	}
+	urls := make(map[string]string)
+	var signedUrl string
+	for _, s := range numbers {
+		partNumber, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || partNumber < 1 || partNumber > minio_ext.MaxPartsCount {
+			writeError(w, errBadRequest("InvalidPartNumber", "Invalid part number ‘"+s+"’."))
+			return
+		}
+		// Presigned part URLs do not sign the content length.
+		signedUrl, err = u.client.GenUploadPartSignedUrlWithContext(r.Context(), uploadID, state.BucketName, state.ObjectName, partNumber, 0, u.opts.Expires, u.location(state.BucketName))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		urls[strconv.Itoa(partNumber)] = signedUrl
+	}
+
+	expires := int64(u.opts.Expires.Seconds())
+	if batch {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"presignedUrls": urls, "expires": expires})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"url": signedUrl, "expires": expires})
+}
+
+// complete - completeMultipartUpload, completes the upload with the
+// parts in the body after checking them against the uploaded parts,
+// answers the location of the object.
+func (u uppyHandler) complete(w http.ResponseWriter, r *http.Request, uploadID string) {
+	state, err := u.load(r, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var req struct {
+		Parts []uppyPart `json:"parts"`
+	}
+	if err = json.NewDecoder(io.LimitReader(r.Body, maxRequestBody*16)).Decode(&req); err != nil {
+		writeError(w, errBadRequest("MalformedJSON", "Malformed request body: "+err.Error()))
+		return
+	}
+	if len(req.Parts) == 0 {
+		writeError(w, errBadRequest("InvalidArgument", "No parts to complete the upload with."))
+		return
+	}
+	partsInfo, err := u.client.ListObjectPartsWithContext(r.Context(), state.BucketName, state.ObjectName, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	res := verification{UploadID: uploadID, PartsTotal: len(req.Parts), Mismatches: []partMismatch{}}
+	var parts []minio_ext.CompletePart
+	var size int64
+	for _, reported := range req.Parts {
+		part, ok := partsInfo[reported.PartNumber]
+		switch {
+		case !ok:
+			res.Mismatches = append(res.Mismatches, partMismatch{PartNumber: reported.PartNumber, Reason: MismatchMissing, ETag: reported.ETag})
+		case normalizeETag(reported.ETag) != normalizeETag(part.ETag):
+			res.Mismatches = append(res.Mismatches, partMismatch{PartNumber: reported.PartNumber, Reason: MismatchETag, ETag: reported.ETag, ServerETag: part.ETag})
+		default:
+			res.PartsUploaded++
+			size += part.Size
+			parts = append(parts, minio_ext.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+		}
+	}
+	if len(res.Mismatches) > 0 {
+		writeJSON(w, http.StatusConflict, verificationError{
+			errorBody: errorBody{
+				Code:    "PartsMismatch",
+				Message: fmt.Sprintf("%d of %d parts do not match the uploaded parts.", len(res.Mismatches), res.PartsTotal),
+			},
+			Verification: res,
+		})
+		return
+	}
+	if u.opts.MaxSize > 0 && size > u.opts.MaxSize {
+		writeError(w, errBadRequest("EntityTooLarge", fmt.Sprintf("Upload size %d exceeds the limit of %d.", size, u.opts.MaxSize)))
+		return
+	}
+	if err = u.authorize(r, state.BucketName, state.ObjectName, size); err != nil {
+		writeError(w, err)
+		return
+	}
+	// Uppy declares no size, the quota is checked with the parts.
+	subjects := u.quotaSubjects(r.Context())
+	if err = u.reserveQuota(subjects, size); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	etag, err := u.client.CompleteMultipartUploadWithContext(minio_ext.WithoutCompletionWebhook(r.Context()), state.BucketName, state.ObjectName, uploadID, parts, nil)
+	if err != nil {
+		u.adjustQuota(subjects, -size)
+		writeError(w, err)
+		return
+	}
+	u.opts.States.Delete(uploadID)
+	releaseUpload(r.Context(), uploadID)
+
+	obj := CompletedObject{
+		BucketName: state.BucketName,
+		ObjectName: state.ObjectName,
+		UploadID:   uploadID,
+		Size:       size,
+		ETag:       strings.Trim(etag, "\""),
+		Claims:     ClaimsFromContext(r.Context()),
+	}
+	flags, err := u.process(&obj)
+	if err != nil {
+		u.adjustQuota(subjects, -size)
+		u.hub.publish(progressEvent{UploadID: uploadID, State: EventRejected, Size: size, PartsTotal: len(parts)})
+		writeError(w, err)
+		return
+	}
+	u.reconcileQuota(r.Context(), subjects, obj.BucketName, obj.ObjectName, size)
+	u.client.NotifyCompleted(obj.BucketName, obj.ObjectName, obj.UploadID, obj.ETag)
+	u.hub.publish(progressEvent{
+		UploadID:       uploadID,
+		State:          EventCompleted,
+		Size:           size,
+		ConfirmedBytes: size,
+		PartsTotal:     len(parts),
+		PartsDone:      len(parts),
+		ETag:           obj.ETag,
+	})
+
+	location, err := u.client.ObjectURLWithContext(r.Context(), obj.BucketName, obj.ObjectName)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	completed := map[string]interface{}{"location": location.String()}
+	if len(flags) > 0 {
+		completed["flags"] = flags
+	}
+	writeJSON(w, http.StatusOK, completed)
+}
+
+// abort - abortMultipartUpload.
+func (u uppyHandler) abort(w http.ResponseWriter, r *http.Request, uploadID string) {
+	state, err := u.load(r, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	err = u.client.AbortMultipartUpload(r.Context(), state.BucketName, state.ObjectName, uploadID)
+	if err != nil && !minio_ext.IsUploadExpired(err) {
+		writeError(w, err)
+		return
+	}
+	if err = u.opts.States.Delete(uploadID); err != nil {
+		writeError(w, err)
+		return
+	}
+	releaseUpload(r.Context(), uploadID)
+	u.hub.publish(progressEvent{UploadID: uploadID, State: EventAborted})
+	writeJSON(w, http.StatusOK, struct{}{})
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestUppyUpload(t *testing.T) {
+	h, s3 := newTestHandler(t, Options{Prefix: "uppy/"})
+	uppy := h.Uppy()
+	data := append(bytes.Repeat([]byte{1}, testPartSize), bytes.Repeat([]byte{2}, testPartSize+16)...)
+	chunks := [][]byte{data[:testPartSize], data[testPartSize : 2*testPartSize], data[2*testPartSize:]}
+
+	var created map[string]string
+	rec := serve(t, uppy, "POST", "/s3/multipart", uppyCreateRequest{Filename: "photo.jpg", Type: "image/jpeg"}, &created)
+	if rec.Code != http.StatusOK || created["key"] != "uppy/photo.jpg" || created["uploadId"] == "" {
+		t.Fatalf("Unexpected upload %d %v", rec.Code, created)
+	}
+	base := "/s3/multipart/" + created["uploadId"]
+	key := "?key=" + url.QueryEscape(created["key"])
+
+	var signed struct {
+		URL     string `json:"url"`
+		Expires int64  `json:"expires"`
+	}
+	serve(t, uppy, "GET", base+"/1"+key, nil, &signed)
+	if signed.URL == "" || signed.Expires != 3600 {
+		t.Fatalf("Unexpected signed part %+v", signed)
+	}
+	putPart(t, signed.URL, chunks[0])
+	var batch struct {
+		PresignedUrls map[string]string `json:"presignedUrls"`
+	}
+	serve(t, uppy, "GET", base+"/batch"+key+"&partNumbers=2,3", nil, &batch)
+	if len(batch.PresignedUrls) != 2 {
+		t.Fatalf("Unexpected signed batch %+v", batch)
+	}
+	for number, signedURL := range batch.PresignedUrls {
+		partNumber, _ := strconv.Atoi(number)
+		putPart(t, signedURL, chunks[partNumber-1])
+	}
+
+	var parts []uppyPart
+	serve(t, uppy, "GET", base+key, nil, &parts)
+	if len(parts) != 3 || parts[0].PartNumber != 1 || parts[2].PartNumber != 3 || parts[2].Size != 16 {
+		t.Fatalf("Unexpected parts %+v", parts)
+	}
+
+	testCases := []struct {
+		parts  []uppyPart
+		status int
+		code   string
+	}{
+		{nil, http.StatusBadRequest, "InvalidArgument"},
+		{[]uppyPart{parts[0], {PartNumber: 2, ETag: parts[0].ETag}, parts[2]}, http.StatusConflict, "PartsMismatch"},
+		{[]uppyPart{parts[0], parts[1], parts[2], {PartNumber: 4, ETag: "x"}}, http.StatusConflict, "PartsMismatch"},
+	}
+	for i, testCase := range testCases {
+		var errRes errorBody
+		body := map[string][]uppyPart{"parts": testCase.parts}
+		if rec = serve(t, uppy, "POST", base+"/complete"+key, body, &errRes); rec.Code != testCase.status || errRes.Code != testCase.code {
+			t.Errorf("Test %d: expected %d %s, got %d %s", i+1, testCase.status, testCase.code, rec.Code, errRes.Code)
+		}
+	}
+
+	var completed map[string]string
+	rec = serve(t, uppy, "POST", base+"/complete"+key, map[string][]uppyPart{"parts": parts}, &completed)
+	if rec.Code != http.StatusOK || completed["location"] == "" {
+		t.Fatalf("Expected the upload completed, got %d %s", rec.Code, rec.Body)
+	}
+	if object, ok := s3.object("/bucket/uppy/photo.jpg"); !ok || !bytes.Equal(object, data) {
+		t.Error("Expected the object stored")
+	}
+	if state, _ := h.opts.States.Load(created["uploadId"]); state != nil {
+		t.Error("Expected the upload forgotten")
+	}
+}
+
+func TestUppyErrors(t *testing.T) {
+	h, s3 := newTestHandler(t, Options{MaxSize: 10})
+	uppy := h.Uppy()
+	var created map[string]string
+	serve(t, uppy, "POST", "/s3/multipart", uppyCreateRequest{Filename: "file"}, &created)
+	base := "/s3/multipart/" + created["uploadId"]
+	var signed struct {
+		URL string `json:"url"`
+	}
+	serve(t, uppy, "GET", base+"/1?key=file", nil, &signed)
+	putPart(t, signed.URL, make([]byte, 11))
+	var parts []uppyPart
+	serve(t, uppy, "GET", base+"?key=file", nil, &parts)
+
+	// Uploads of the plain handler are not served to Uppy.
+	var initRes initResponse
+	serve(t, h, "POST", "/uploads", initRequest{Name: "plain", Size: 1}, &initRes)
+
+	testCases := []struct {
+		method string
+		target string
+		body   interface{}
+		status int
+		code   string
+	}{
+		{"GET", "/s3/other", nil, http.StatusNotFound, "NotFound"},
+		{"GET", "/s3/multipart", nil, http.StatusMethodNotAllowed, "MethodNotAllowed"},
+		{"POST", "/s3/multipart", "{", http.StatusBadRequest, "MalformedJSON"},
+		{"POST", "/s3/multipart", uppyCreateRequest{}, http.StatusBadRequest, "InvalidArgument"},
+		{"GET", base + "?key=other", nil, http.StatusNotFound, "NoSuchUpload"},
+		{"GET", "/s3/multipart/" + initRes.UploadID + "?key=plain", nil, http.StatusNotFound, "NoSuchUpload"},
+		{"GET", base + "/0?key=file", nil, http.StatusBadRequest, "InvalidPartNumber"},
+		{"GET", base + "/1,2?key=file", nil, http.StatusBadRequest, "InvalidPartNumber"},
+		{"GET", base + "/batch?key=file&partNumbers=1,x", nil, http.StatusBadRequest, "InvalidPartNumber"},
+		{"POST", base + "/complete?key=file", "{", http.StatusBadRequest, "MalformedJSON"},
+		{"POST", base + "/complete?key=file", map[string][]uppyPart{"parts": parts}, http.StatusBadRequest, "EntityTooLarge"},
+	}
+	for i, testCase := range testCases {
+		var errRes errorBody
+		rec := serve(t, uppy, testCase.method, testCase.target, testCase.body, &errRes)
+		if rec.Code != testCase.status || errRes.Code != testCase.code {
+			t.Errorf("Test %d: expected %d %s, got %d %s", i+1, testCase.status, testCase.code, rec.Code, errRes.Code)
+		}
+	}
+
+	if rec := serve(t, uppy, "DELETE", base+"?key=file", nil, nil); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the upload aborted, got %d %s", rec.Code, rec.Body)
+	}
+	if state, _ := h.opts.States.Load(created["uploadId"]); state != nil || s3.count("abort") != 1 {
+		t.Error("Expected the upload aborted and forgotten")
+	}
+}
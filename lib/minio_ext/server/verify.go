@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"oss/lib/minio_ext"
+)
+
+// Reasons of part mismatches.
+const (
+	MismatchMissing    = "missing"
+	MismatchSize       = "size"
+	MismatchETag       = "etag"
+	MismatchUnplanned  = "unplanned"
+	MismatchUnreported = "unreported"
+)
+
+// reportedPart - a part the browser reports as uploaded.
+type reportedPart struct {
+	PartNumber int    `json:"partNumber"`
+	Size       int64  `json:"size"`
+	ETag       string `json:"etag"`
+}
+
+// verifyRequest - body of POST /uploads/{id}/verify and
+// POST /uploads/{id}/complete, both accept an empty body.
+type verifyRequest struct {
+	Parts []reportedPart `json:"parts"`
+}
+
+// partMismatch - a part differing between the plan, the browser report
+// and the object storage.
+type partMismatch struct {
+	PartNumber int    `json:"partNumber"`
+	Reason     string `json:"reason"`
+	Size       int64  `json:"size,omitempty"`
+	ServerSize int64  `json:"serverSize,omitempty"`
+	ETag       string `json:"etag,omitempty"`
+	ServerETag string `json:"serverEtag,omitempty"`
+}
+
+// verification - result of comparing an upload against the listing of
+// its parts.
+type verification struct {
+	UploadID      string         `json:"uploadId"`
+	OK            bool           `json:"ok"`
+	PartsTotal    int            `json:"partsTotal"`
+	PartsUploaded int            `json:"partsUploaded"`
+	Mismatches    []partMismatch `json:"mismatches"`
+}
+
+// verificationError - body of 409 answers of complete.
+type verificationError struct {
+	errorBody
+	Verification verification `json:"verification"`
+}
+
+// readVerifyRequest - decodes an optional verify request body.
+func readVerifyRequest(r *http.Request) (verifyRequest, error) {
+	var req verifyRequest
+	err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBody*16)).Decode(&req)
+	if err != nil && err != io.EOF {
+		return req, errBadRequest("MalformedJSON", "Malformed request body: "+err.Error())
+	}
+	return req, nil
+}
+
+// normalizeETag - returns etag without quotes in lower case.
+func normalizeETag(etag string) string {
+	return strings.ToLower(strings.Trim(etag, "\""))
+}
+
+// verifyParts - compares the plan of state and the parts reported by
+// the browser, if any, with the parts ListObjectParts returns. The
+// parts to complete the upload with are returned when all match.
+func (h *Handler) verifyParts(ctx context.Context, state *minio_ext.UploadState, reported []reportedPart) (verification, []minio_ext.CompletePart, error) {
+	res := verification{
+		UploadID:   state.UploadID,
+		PartsTotal: len(state.Parts),
+		Mismatches: []partMismatch{},
+	}
+	partsInfo, err := h.client.ListObjectPartsWithContext(ctx, state.BucketName, state.ObjectName, state.UploadID)
+	if err != nil {
+		return res, nil, err
+	}
+
+	reports := make(map[int]reportedPart)
+	for _, part := range reported {
+		if part.PartNumber < 1 || part.PartNumber > len(state.Parts) {
+			res.Mismatches = append(res.Mismatches, partMismatch{
+				PartNumber: part.PartNumber,
+				Reason:     MismatchUnplanned,
+				Size:       part.Size,
+				ETag:       part.ETag,
+			})
+			continue
+		}
+		reports[part.PartNumber] = part
+	}
+
+	var parts []minio_ext.CompletePart
+	for _, spec := range state.Parts {
+		part, uploaded := partsInfo[spec.PartNumber]
+		report, isReported := reports[spec.PartNumber]
+		sent := state.SentPart(spec)
+		mismatch := partMismatch{
+			PartNumber: spec.PartNumber,
+			Size:       sent.Size,
+			ServerSize: part.Size,
+			ServerETag: part.ETag,
+		}
+		switch {
+		case !uploaded:
+			mismatch.Reason = MismatchMissing
+		case part.Size != sent.Size:
+			mismatch.Reason = MismatchSize
+		case len(reported) > 0 && !isReported:
+			mismatch.Reason = MismatchUnreported
+		case isReported && report.Size != part.Size:
+			mismatch.Reason = MismatchSize
+			mismatch.Size = report.Size
+		case isReported && normalizeETag(report.ETag) != normalizeETag(part.ETag):
+			mismatch.Reason = MismatchETag
+			mismatch.ETag = report.ETag
+		}
+		if uploaded {
+			res.PartsUploaded++
+		}
+		if mismatch.Reason != "" {
+			res.Mismatches = append(res.Mismatches, mismatch)
+			continue
+		}
+		parts = append(parts, minio_ext.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	sort.Slice(res.Mismatches, func(i, j int) bool {
+		return res.Mismatches[i].PartNumber < res.Mismatches[j].PartNumber
+	})
+	res.OK = len(res.Mismatches) == 0
+	if !res.OK {
+		return res, nil, nil
+	}
+	return res, parts, nil
+}
+
+// verify - POST /uploads/{id}/verify, returns the differences between
+// the plan, the parts in the body and the uploaded parts without
+// completing the upload.
+func (h *Handler) verify(w http.ResponseWriter, r *http.Request, uploadID string) {
+	state, err := h.load(r, uploadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	req, err := readVerifyRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, _, err := h.verifyParts(r.Context(), state, req.Parts)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// startUpload - initiates an upload of data and PUTs the parts for
+// which put returns the data to send, nil to skip the part.
+func startUpload(t *testing.T, h http.Handler, name string, data []byte, put func(part partURL, data []byte) []byte) initResponse {
+	var initRes initResponse
+	if rec := serve(t, h, "POST", "/uploads", initRequest{Name: name, Size: int64(len(data))}, &initRes); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the upload initiated, got %d %s", rec.Code, rec.Body)
+	}
+	var partsRes partsResponse
+	serve(t, h, "GET", "/uploads/"+initRes.UploadID+"/parts", nil, &partsRes)
+	for _, part := range partsRes.Parts {
+		if body := put(part, data[part.Offset:part.Offset+part.Size]); body != nil {
+			putPart(t, part.URL, body)
+		}
+	}
+	return initRes
+}
+
+func TestHandlerVerify(t *testing.T) {
+	h, s3 := newTestHandler(t, Options{PartSize: testPartSize})
+	data := append(bytes.Repeat([]byte{1}, testPartSize), bytes.Repeat([]byte{2}, testPartSize+16)...)
+	etag := func(partNumber int) string {
+		offset := (partNumber - 1) * testPartSize
+		end := offset + testPartSize
+		if end > len(data) {
+			end = len(data)
+		}
+		return etagOf(data[offset:end])
+	}
+	uploaded := startUpload(t, h, "uploaded", data, func(part partURL, data []byte) []byte {
+		return data
+	})
+	broken := startUpload(t, h, "broken", data, func(part partURL, data []byte) []byte {
+		switch part.PartNumber {
+		case 2:
+			return data[1:]
+		case 3:
+			return nil
+		}
+		return data
+	})
+	all := []reportedPart{
+		{1, testPartSize, "\"" + strings.ToUpper(etag(1)) + "\""},
+		{2, testPartSize, etag(2)},
+		{3, 16, etag(3)},
+	}
+
+	testCases := []struct {
+		uploadID   string
+		reported   []reportedPart
+		uploads    int
+		mismatches []partMismatch
+	}{
+		{uploaded.UploadID, nil, 3, []partMismatch{}},
+		{uploaded.UploadID, all, 3, []partMismatch{}},
+		{uploaded.UploadID, all[:2], 3, []partMismatch{
+			{PartNumber: 3, Reason: MismatchUnreported, Size: 16, ServerSize: 16, ServerETag: etag(3)},
+		}},
+		{uploaded.UploadID, []reportedPart{{1, testPartSize, etag(2)}, all[1], {3, 15, etag(3)}, {4, 1, "x"}}, 3, []partMismatch{
+			{PartNumber: 1, Reason: MismatchETag, Size: testPartSize, ServerSize: testPartSize, ETag: etag(2), ServerETag: etag(1)},
+			{PartNumber: 3, Reason: MismatchSize, Size: 15, ServerSize: 16, ServerETag: etag(3)},
+			{PartNumber: 4, Reason: MismatchUnplanned, Size: 1, ETag: "x"},
+		}},
+		{broken.UploadID, nil, 2, []partMismatch{
+			{PartNumber: 2, Reason: MismatchSize, Size: testPartSize, ServerSize: testPartSize - 1, ServerETag: etagOf(data[testPartSize+1 : 2*testPartSize])},
+			{PartNumber: 3, Reason: MismatchMissing, Size: 16},
+		}},
+	}
+	for i, testCase := range testCases {
+		var res verification
+		rec := serve(t, h, "POST", "/uploads/"+testCase.uploadID+"/verify", verifyRequest{Parts: testCase.reported}, &res)
+		if rec.Code != http.StatusOK {
+			t.Errorf("Test %d: expected 200, got %d %s", i+1, rec.Code, rec.Body)
+			continue
+		}
+		if res.OK != (len(testCase.mismatches) == 0) || res.PartsTotal != 3 || res.PartsUploaded != testCase.uploads {
+			t.Errorf("Test %d: unexpected verification %+v", i+1, res)
+		}
+		if !reflect.DeepEqual(res.Mismatches, testCase.mismatches) {
+			t.Errorf("Test %d: expected mismatches %+v, got %+v", i+1, testCase.mismatches, res.Mismatches)
+		}
+	}
+
+	// A mismatching report leaves the upload as it is.
+	var errRes verificationError
+	completePath := "/uploads/" + uploaded.UploadID + "/complete"
+	rec := serve(t, h, "POST", completePath, verifyRequest{Parts: all[:2]}, &errRes)
+	if rec.Code != http.StatusConflict || errRes.Code != "PartsMismatch" || len(errRes.Verification.Mismatches) != 1 {
+		t.Fatalf("Expected a mismatch, got %d %+v", rec.Code, errRes)
+	}
+	if s3.count("complete") != 0 {
+		t.Fatal("Expected the upload not completed")
+	}
+	if rec = serve(t, h, "POST", completePath, verifyRequest{Parts: all}, nil); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the upload completed, got %d %s", rec.Code, rec.Body)
+	}
+	if rec = serve(t, h, "POST", "/uploads/"+broken.UploadID+"/verify", "{", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a malformed body refused, got %d", rec.Code)
+	}
+}
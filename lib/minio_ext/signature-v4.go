@@ -0,0 +1,240 @@
+package minio_ext
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/pkg/s3utils"
+)
+
+// Signature V4 signing of s3signer with the time passed in, so that
+// requests can be signed with the clock of the server, see clockSkew.
+
+// yyyymmdd - date format of the signature V4 scope.
+const yyyymmdd = "20060102"
+
+// v4IgnoredHeaders - headers not signed, see s3signer.
+var v4IgnoredHeaders = map[string]bool{
+	"Authorization":  true,
+	"Content-Type":   true,
+	"Content-Length": true,
+	"User-Agent":     true,
+}
+
+// v4PresignIgnoredHeaders - headers not signed in presigned URLs,
+// Content-Type is only set when the caller asks to sign it.
+var v4PresignIgnoredHeaders = map[string]bool{
+	"Authorization":  true,
+	"Content-Length": true,
+	"User-Agent":     true,
+}
+
+// sumHMAC - hmac-sha256 of data with key.
+func sumHMAC(key []byte, data []byte) []byte {
+	hash := hmac.New(sha256.New, key)
+	hash.Write(data)
+	return hash.Sum(nil)
+}
+
+// Services signed, S3 and STS for temporary credentials.
+const (
+	serviceS3  = "s3"
+	serviceSTS = "sts"
+)
+
+// signingKeyScope - identifies a derived signing key, by the SHA-256 of
+// the secret so that the cache does not keep secrets in memory.
+type signingKeyScope struct {
+	secret   [sha256.Size]byte
+	date     string
+	location string
+	service  string
+}
+
+// maxSigningKeys - most signing keys cached, the cache is emptied when
+// it is full. Keys change daily, few are in use at any time.
+const maxSigningKeys = 256
+
+// signingKeys - cache of the derived signing keys, batch presigning
+// would otherwise spend most of its time deriving the same key.
+var signingKeys = struct {
+	// mutex protects keys.
+	mutex sync.Mutex
+	keys  map[signingKeyScope][]byte
+}{keys: make(map[signingKeyScope][]byte)}
+
+// getSigningKey - hmac seed to calculate the final signature.
+func getSigningKey(secret, loc, service string, t time.Time) []byte {
+	scope := signingKeyScope{secret: sha256.Sum256([]byte(secret)), date: t.Format(yyyymmdd), location: loc, service: service}
+	signingKeys.mutex.Lock()
+	key, ok := signingKeys.keys[scope]
+	signingKeys.mutex.Unlock()
+	if ok {
+		return key
+	}
+
+	date := sumHMAC([]byte("AWS4"+secret), []byte(scope.date))
+	location := sumHMAC(date, []byte(loc))
+	signed := sumHMAC(location, []byte(service))
+	key = sumHMAC(signed, []byte("aws4_request"))
+
+	signingKeys.mutex.Lock()
+	if len(signingKeys.keys) >= maxSigningKeys {
+		signingKeys.keys = make(map[signingKeyScope][]byte)
+	}
+	signingKeys.keys[scope] = key
+	signingKeys.mutex.Unlock()
+	return key
+}
+
+// getScope - date, region and service of a signature.
+func getScope(location, service string, t time.Time) string {
+	return strings.Join([]string{t.Format(yyyymmdd), location, service, "aws4_request"}, "/")
+}
+
+// getCredential - the credential of a signature.
+func getCredential(accessKeyID, location, service string, t time.Time) string {
+	return accessKeyID + "/" + getScope(location, service, t)
+}
+
+// getHostAddr - returns the Host header if set, the host of the URL
+// otherwise.
+func getHostAddr(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// getSignedHeaders - lexically sorted, semicolon separated lowercase
+// names of the headers of req not in ignored.
+func getSignedHeaders(req http.Request, ignored map[string]bool) string {
+	var headers []string
+	for k := range req.Header {
+		if ignored[http.CanonicalHeaderKey(k)] {
+			continue
+		}
+		headers = append(headers, strings.ToLower(k))
+	}
+	headers = append(headers, "host")
+	sort.Strings(headers)
+	return strings.Join(headers, ";")
+}
+
+// getCanonicalHeaders - the headers of req not in ignored in canonical
+// form.
+func getCanonicalHeaders(req http.Request, ignored map[string]bool) string {
+	var headers []string
+	vals := make(map[string][]string)
+	for k, vv := range req.Header {
+		if ignored[http.CanonicalHeaderKey(k)] {
+			continue
+		}
+		headers = append(headers, strings.ToLower(k))
+		vals[strings.ToLower(k)] = vv
+	}
+	headers = append(headers, "host")
+	sort.Strings(headers)
+
+	var buf bytes.Buffer
+	for _, k := range headers {
+		buf.WriteString(k)
+		buf.WriteByte(':')
+		if k == "host" {
+			buf.WriteString(getHostAddr(&req))
+		}
+		for idx, v := range vals[k] {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(v)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// getCanonicalRequest - the canonical request of req signing the
+// headers not in ignored.
+func getCanonicalRequest(req http.Request, ignored map[string]bool) string {
+	req.URL.RawQuery = strings.Replace(req.URL.Query().Encode(), "+", "%20", -1)
+	hashedPayload := req.Header.Get("X-Amz-Content-Sha256")
+	if hashedPayload == "" {
+		// Presign does not have a payload, use S3 recommended value.
+		hashedPayload = unsignedPayload
+	}
+	return strings.Join([]string{
+		req.Method,
+		s3utils.EncodePath(req.URL.Path),
+		req.URL.RawQuery,
+		getCanonicalHeaders(req, ignored),
+		getSignedHeaders(req, ignored),
+		hashedPayload,
+	}, "\n")
+}
+
+// getStringToSignV4 - the string to sign of a canonical request.
+func getStringToSignV4(t time.Time, location, service, canonicalRequest string) string {
+	return signV4Algorithm + "\n" + t.Format(iso8601DateFormat) + "\n" +
+		getScope(location, service, t) + "\n" + sum256Hex([]byte(canonicalRequest))
+}
+
+// signV4At - s3signer.SignV4 signing at time t.
+func signV4At(req http.Request, accessKeyID, secretAccessKey, sessionToken, location string, t time.Time) *http.Request {
+	return signV4ServiceAt(req, accessKeyID, secretAccessKey, sessionToken, location, serviceS3, t)
+}
+
+// signV4ServiceAt - signs req for service at time t.
+func signV4ServiceAt(req http.Request, accessKeyID, secretAccessKey, sessionToken, location, service string, t time.Time) *http.Request {
+	// Signature calculation is not needed for anonymous credentials.
+	if accessKeyID == "" || secretAccessKey == "" {
+		return &req
+	}
+	t = t.UTC()
+	req.Header.Set("X-Amz-Date", t.Format(iso8601DateFormat))
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	stringToSign := getStringToSignV4(t, location, service, getCanonicalRequest(req, v4IgnoredHeaders))
+	signature := hex.EncodeToString(sumHMAC(getSigningKey(secretAccessKey, location, service, t), []byte(stringToSign)))
+	req.Header.Set("Authorization", strings.Join([]string{
+		signV4Algorithm + " Credential=" + getCredential(accessKeyID, location, service, t),
+		"SignedHeaders=" + getSignedHeaders(req, v4IgnoredHeaders),
+		"Signature=" + signature,
+	}, ", "))
+	return &req
+}
+
+// preSignV4At - s3signer.PreSignV4 presigning at time t, the URL
+// expires expires seconds after t.
+func preSignV4At(req http.Request, accessKeyID, secretAccessKey, sessionToken, location string, expires int64, t time.Time) *http.Request {
+	// Presign is not needed for anonymous credentials.
+	if accessKeyID == "" || secretAccessKey == "" {
+		return &req
+	}
+	t = t.UTC()
+	query := req.URL.Query()
+	query.Set("X-Amz-Algorithm", signV4Algorithm)
+	query.Set("X-Amz-Date", t.Format(iso8601DateFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(expires, 10))
+	query.Set("X-Amz-SignedHeaders", getSignedHeaders(req, v4PresignIgnoredHeaders))
+	query.Set("X-Amz-Credential", getCredential(accessKeyID, location, serviceS3, t))
+	if sessionToken != "" {
+		query.Set("X-Amz-Security-Token", sessionToken)
+	}
+	req.URL.RawQuery = query.Encode()
+
+	stringToSign := getStringToSignV4(t, location, serviceS3, getCanonicalRequest(req, v4PresignIgnoredHeaders))
+	signature := hex.EncodeToString(sumHMAC(getSigningKey(secretAccessKey, location, serviceS3, t), []byte(stringToSign)))
+	req.URL.RawQuery += "&X-Amz-Signature=" + signature
+	return &req
+}
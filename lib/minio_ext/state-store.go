@@ -0,0 +1,228 @@
+package minio_ext
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// StateStore - persists upload session states so uploads can be
+// resumed after a restart. Keys are chosen by the caller, usually
+// bucket and object name of the upload.
+type StateStore interface {
+	// Load returns the state stored under key, nil when there is none.
+	Load(key string) (*UploadState, error)
+	// Save stores state under key, replacing any previous state.
+	Save(key string, state UploadState) error
+	// Delete removes the state stored under key, if any.
+	Delete(key string) error
+}
+
+// StateRemover - optional interface of a StateStore reporting whether
+// a state was there when it was removed. Of several concurrent removals
+// of the same state exactly one reports it removed, callers releasing
+// resources held by the upload, like reserved quota, release them once.
+type StateRemover interface {
+	// Remove removes the state stored under key and reports whether
+	// there was one.
+	Remove(key string) (bool, error)
+}
+
+// CompletedPart - a part known to be uploaded, as recorded in a
+// PartJournal.
+type CompletedPart struct {
+	PartNumber int    `json:"partNumber"`
+	Size       int64  `json:"size"`
+	ETag       string `json:"etag"`
+
+	// Hex encoded MD5 of the part data, empty when it was not computed.
+	MD5 string `json:"md5,omitempty"`
+}
+
+// PartJournal - optional interface of a StateStore recording completed
+// parts in a write ahead journal. UploadFile appends every part as soon
+// as it is uploaded, Load returns states with the journaled parts of
+// their upload id merged into Completed and Save starts a new journal.
+type PartJournal interface {
+	// AppendPart durably records part of upload uploadID under key
+	// before it returns.
+	AppendPart(key, uploadID string, part CompletedPart) error
+}
+
+// journalEntry - a line of the journal of FileStateStore.
+type journalEntry struct {
+	UploadID string `json:"uploadId"`
+	CompletedPart
+}
+
+// FileStateStore - StateStore keeping one JSON file per key in a
+// directory, completed parts are journaled in a second file per key.
+type FileStateStore struct {
+	dir string
+
+	// mutex serializes journal appends.
+	mutex sync.Mutex
+}
+
+// NewFileStateStore - returns a file state store in dir, dir is
+// created when missing.
+func NewFileStateStore(dir string) (*FileStateStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStateStore{dir: dir}, nil
+}
+
+// path - returns the file holding the state of key, keys are hashed so
+// any key maps to a valid file name.
+func (s *FileStateStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// journalPath - returns the journal file of key.
+func (s *FileStateStore) journalPath(key string) string {
+	return s.path(key) + "l"
+}
+
+// Load - implements StateStore.
+func (s *FileStateStore) Load(key string) (*UploadState, error) {
+	data, err := ioutil.ReadFile(s.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var state UploadState
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	journal, err := ioutil.ReadFile(s.journalPath(key))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(journal))
+	for scanner.Scan() {
+		var entry journalEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || entry.UploadID != state.UploadID {
+			// Torn write of a crash or a previous upload.
+			continue
+		}
+		state.Completed = mergeCompleted(state.Completed, entry.CompletedPart)
+	}
+	return &state, nil
+}
+
+// mergeCompleted - adds part to parts, replacing a part with the same
+// number.
+func mergeCompleted(parts []CompletedPart, part CompletedPart) []CompletedPart {
+	for i := range parts {
+		if parts[i].PartNumber == part.PartNumber {
+			parts[i] = part
+			return parts
+		}
+	}
+	return append(parts, part)
+}
+
+// Save - implements StateStore, the journal is dropped as state holds
+// all completed parts.
+func (s *FileStateStore) Save(key string, state UploadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err = writeFileAtomic(s.path(key), data); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	err = os.Remove(s.journalPath(key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Delete - implements StateStore.
+func (s *FileStateStore) Delete(key string) error {
+	for _, filePath := range []string{s.path(key), s.journalPath(key)} {
+		err := os.Remove(filePath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove - implements StateRemover.
+func (s *FileStateStore) Remove(key string) (bool, error) {
+	err := os.Remove(s.path(key))
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	removed := err == nil
+	if err = os.Remove(s.journalPath(key)); err != nil && !os.IsNotExist(err) {
+		return removed, err
+	}
+	return removed, nil
+}
+
+// AppendPart - implements PartJournal.
+func (s *FileStateStore) AppendPart(key, uploadID string, part CompletedPart) error {
+	data, err := json.Marshal(journalEntry{UploadID: uploadID, CompletedPart: part})
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	file, err := os.OpenFile(s.journalPath(key), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(data, '\n')); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeFileAtomic - writes data to a temporary file next to filePath
+// and renames it into place, so a crash never leaves a truncated file
+// behind.
+func writeFileAtomic(filePath string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filePath), "."+filepath.Base(filePath)+"-")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), filePath); err != nil {
+		return err
+	}
+	// Make the rename durable, not supported everywhere.
+	if dir, err := os.Open(filepath.Dir(filePath)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
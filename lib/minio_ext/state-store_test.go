@@ -0,0 +1,36 @@
+package minio_ext
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestFileStateStoreRemove(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewFileStateStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Save("key", UploadState{UploadID: "upload"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.AppendPart("key", "upload", CompletedPart{PartNumber: 1, ETag: "etag", Size: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the first removal finds the state.
+	for i, expected := range []bool{true, false} {
+		removed, err := store.Remove("key")
+		if err != nil || removed != expected {
+			t.Errorf("Test %d: expected %v, got %v, %v", i+1, expected, removed, err)
+		}
+	}
+	if _, err = os.Stat(store.journalPath("key")); !os.IsNotExist(err) {
+		t.Errorf("Expected the journal removed, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package minio_ext
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// HTTPTrace - a traced request and its response, signatures,
+// credentials and encryption keys in the headers and the query of the
+// request are redacted.
+type HTTPTrace struct {
+	Request  *http.Request
+	Response *http.Response
+
+	// Time the request was sent and the time until the response
+	// headers arrived.
+	Start    time.Time
+	Duration time.Duration
+
+	// Most bytes of a body a formatter may write, 0 for no limit.
+	MaxBody int64
+}
+
+// TraceFormatter - writes a traced request to w. The body of the
+// response must be left readable.
+type TraceFormatter func(w io.Writer, trace HTTPTrace) error
+
+// SetTraceFormatter - formats the traces of TraceOn with format,
+// DumpTrace when nil.
+func (c *Client) SetTraceFormatter(format TraceFormatter) {
+	c.traceFormatter = format
+}
+
+// SetTraceLimits - traces one in sampleEvery successful requests, all
+// when 0 or 1, failed requests are always traced. Bodies are cut after
+// maxBody bytes, 0 for no limit. Keeps tracing affordable in production.
+func (c *Client) SetTraceLimits(sampleEvery int, maxBody int64) {
+	c.traceSampleEvery = sampleEvery
+	c.traceMaxBody = maxBody
+}
+
+// traceSampled - reports whether the request of resp is traced.
+func (c Client) traceSampled(resp *http.Response) bool {
+	if c.traceSampleEvery <= 1 || resp.StatusCode >= http.StatusMultipleChoices {
+		return true
+	}
+	return (atomic.AddUint64(c.traceCount, 1)-1)%uint64(c.traceSampleEvery) == 0
+}
+
+// DumpTrace - TraceFormatter writing the raw request headers and
+// response, the response body of failed requests included up to
+// MaxBody bytes.
+func DumpTrace(w io.Writer, trace HTTPTrace) error {
+	req, resp := trace.Request, trace.Response
+
+	// Starts http dump.
+	_, err := fmt.Fprintln(w, "---------START-HTTP---------")
+	if err != nil {
+		return err
+	}
+
+	// Only display request header.
+	reqTrace, err := httputil.DumpRequestOut(req, false)
+	if err != nil {
+		return err
+	}
+
+	// Write request to trace output.
+	_, err = fmt.Fprint(w, string(reqTrace))
+	if err != nil {
+		return err
+	}
+
+	// Only display response header.
+	var respTrace []byte
+
+	// For errors we make sure to dump response body as well.
+	if resp.StatusCode != http.StatusOK &&
+		resp.StatusCode != http.StatusPartialContent &&
+		resp.StatusCode != http.StatusNoContent {
+		if trace.MaxBody > 0 {
+			respTrace, err = dumpResponseHead(resp, trace.MaxBody)
+		} else {
+			respTrace, err = httputil.DumpResponse(resp, true)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		respTrace, err = httputil.DumpResponse(resp, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Write response to trace output.
+	_, err = fmt.Fprint(w, strings.TrimSuffix(string(respTrace), "\r\n"))
+	if err != nil {
+		return err
+	}
+
+	// Ends the http dump.
+	_, err = fmt.Fprintln(w, "---------END-HTTP---------")
+	return err
+}
+
+// dumpResponseHead - dumps resp with the first maxBody bytes of its
+// body, the body stays readable in full.
+func dumpResponseHead(resp *http.Response, maxBody int64) ([]byte, error) {
+	dump, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		return nil, err
+	}
+	head, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBody+1))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	if int64(len(head)) > maxBody {
+		return append(append(dump, head[:maxBody]...), fmt.Sprintf("\r\n... body cut after %d bytes", maxBody)...), nil
+	}
+	return append(dump, head...), nil
+}
+
+// jsonTrace - a line written by JSONTrace.
+type jsonTrace struct {
+	Time          time.Time `json:"time"`
+	Method        string    `json:"method"`
+	URL           string    `json:"url"`
+	Status        int       `json:"status"`
+	DurationMs    float64   `json:"durationMs"`
+	RequestID     string    `json:"requestId,omitempty"`
+	HostID        string    `json:"hostId,omitempty"`
+	Authorization string    `json:"authorization,omitempty"`
+	RequestSize   int64     `json:"requestSize"`
+	ResponseSize  int64     `json:"responseSize"`
+}
+
+// JSONTrace - TraceFormatter writing a JSON line per request with
+// method, URL, status, duration and request id, for log pipelines like
+// ELK or Loki. Signatures of presigned URLs are redacted.
+func JSONTrace(w io.Writer, trace HTTPTrace) error {
+	req, resp := trace.Request, trace.Response
+	u := redactURL(req.URL)
+
+	line, err := json.Marshal(jsonTrace{
+		Time:          trace.Start.UTC(),
+		Method:        req.Method,
+		URL:           u.String(),
+		Status:        resp.StatusCode,
+		DurationMs:    float64(trace.Duration) / float64(time.Millisecond),
+		RequestID:     resp.Header.Get("x-amz-request-id"),
+		HostID:        resp.Header.Get("x-amz-id-2"),
+		Authorization: req.Header.Get("Authorization"),
+		RequestSize:   req.ContentLength,
+		ResponseSize:  resp.ContentLength,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,132 @@
+package minio_ext
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName - instrumentation name of the spans of the client.
+const tracerName = "oss/lib/minio_ext"
+
+// Attributes of the spans of the client.
+const (
+	attrBucket     = attribute.Key("s3.bucket")
+	attrKey        = attribute.Key("s3.key")
+	attrUploadID   = attribute.Key("s3.upload_id")
+	attrPartNumber = attribute.Key("s3.part_number")
+	attrBytes      = attribute.Key("s3.bytes")
+	attrAttempts   = attribute.Key("s3.attempts")
+	attrMethod     = attribute.Key("http.method")
+	attrStatusCode = attribute.Key("http.status_code")
+)
+
+// SetTracerProvider - traces requests, bucket location lookups,
+// presigning and upload sessions with spans of provider, the global
+// OpenTelemetry provider by default. The trace context is sent to the
+// server in the headers of the global OpenTelemetry propagator.
+func (c *Client) SetTracerProvider(provider trace.TracerProvider) {
+	c.tracerProvider = provider
+}
+
+// startSpan - starts a span of kind named name, a child of the span of
+// ctx if any.
+func (c Client) startSpan(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	provider := c.tracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// endSpan - ends span, failed with err unless it is nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// endRequestSpan - ends the span of a request which answered res, an
+// error response fails the span as well.
+func endRequestSpan(span trace.Span, res *http.Response, err error) {
+	if res != nil {
+		span.SetAttributes(attrStatusCode.Int(res.StatusCode))
+		if err == nil && res.StatusCode >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, res.Status)
+		}
+	}
+	endSpan(span, err)
+}
+
+// objectAttributes - returns the span attributes of
+// bucketName/objectName, empty names are left out.
+func objectAttributes(bucketName, objectName string) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if bucketName != "" {
+		attrs = append(attrs, attrBucket.String(bucketName))
+	}
+	if objectName != "" {
+		attrs = append(attrs, attrKey.String(objectName))
+	}
+	return attrs
+}
+
+// requestAttributes - returns the span attributes of a request with
+// metadata.
+func requestAttributes(method string, metadata requestMetadata) []attribute.KeyValue {
+	attrs := append(objectAttributes(metadata.bucketName, metadata.objectName), attrMethod.String(method))
+	if uploadID := metadata.queryValues.Get("uploadId"); uploadID != "" {
+		attrs = append(attrs, attrUploadID.String(uploadID))
+	}
+	if partNumber := metadata.queryValues.Get("partNumber"); partNumber != "" {
+		attrs = append(attrs, attrPartNumber.String(partNumber))
+	}
+	if metadata.contentLength > 0 {
+		attrs = append(attrs, attrBytes.Int64(metadata.contentLength))
+	}
+	return attrs
+}
+
+// startPresignSpan - starts the span of presigning operation on
+// bucketName/objectName.
+func (c Client) startPresignSpan(ctx context.Context, operation, bucketName, objectName string) (context.Context, trace.Span) {
+	return c.startSpan(ctx, "Presign "+operation, trace.SpanKindInternal, objectAttributes(bucketName, objectName)...)
+}
+
+// startSessionSpan - starts the span of phase name of the upload
+// uploadID of bucketName/objectName.
+func (c Client) startSessionSpan(ctx context.Context, name, bucketName, objectName, uploadID string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(objectAttributes(bucketName, objectName), append(attrs, attrUploadID.String(uploadID))...)
+	return c.startSpan(ctx, name, trace.SpanKindInternal, attrs...)
+}
+
+// startRequestSpan - starts the client span of a request with metadata,
+// covering all of its attempts.
+func (c Client) startRequestSpan(ctx context.Context, method string, metadata requestMetadata) (context.Context, trace.Span) {
+	return c.startSpan(ctx, operationName(method, metadata), trace.SpanKindClient, requestAttributes(method, metadata)...)
+}
+
+// addRetryEvent - records on span that attempt failed with err and is
+// retried after wait.
+func addRetryEvent(span trace.Span, attempt int, err error, wait time.Duration) {
+	attrs := []attribute.KeyValue{attrAttempts.Int(attempt), attribute.String("s3.wait", wait.String())}
+	if code := ToErrorResponse(err).Code; code != "" {
+		attrs = append(attrs, attribute.String("s3.error_code", code))
+	} else if err != nil {
+		attrs = append(attrs, attribute.String("exception.message", err.Error()))
+	}
+	span.AddEvent("retry", trace.WithAttributes(attrs...))
+}
+
+// injectTraceContext - adds the trace context of ctx to header.
+func injectTraceContext(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,77 @@
+package minio_ext
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// idleConnectionsCloser - implemented by transports which can drop
+// their idle keep-alive connections, like *http.Transport.
+type idleConnectionsCloser interface {
+	CloseIdleConnections()
+}
+
+// recyclingTransport - drains the keep-alive connections of the wrapped
+// transport after maxRequests requests or maxLifetime, whichever comes
+// first. Requests in flight finish on their connection, new requests
+// dial fresh connections.
+type recyclingTransport struct {
+	transport   http.RoundTripper
+	maxRequests int64
+	maxLifetime time.Duration
+
+	// mutex protects requests and since.
+	mutex    sync.Mutex
+	requests int64
+	since    time.Time
+}
+
+// RoundTrip - implements http.RoundTripper.
+func (t *recyclingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mutex.Lock()
+	t.requests++
+	recycle := (t.maxRequests > 0 && t.requests > t.maxRequests) ||
+		(t.maxLifetime > 0 && time.Since(t.since) >= t.maxLifetime)
+	if recycle {
+		t.requests = 1
+		t.since = time.Now()
+	}
+	t.mutex.Unlock()
+
+	if recycle {
+		if closer, ok := t.transport.(idleConnectionsCloser); ok {
+			closer.CloseIdleConnections()
+		}
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// CloseIdleConnections - closes idle connections of the wrapped transport.
+func (t *recyclingTransport) CloseIdleConnections() {
+	if closer, ok := t.transport.(idleConnectionsCloser); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// SetConnectionRecycling - recycles the connections to the server after
+// maxRequests requests or after maxLifetime, a zero value disables the
+// respective limit. This avoids idle resets by NAT gateways and load
+// balancers on long uploads and spreads load over all nodes of a
+// cluster behind a TCP load balancer.
+func (c *Client) SetConnectionRecycling(maxRequests int64, maxLifetime time.Duration) {
+	transport := c.httpClient.Transport
+	if recycling, ok := transport.(*recyclingTransport); ok {
+		transport = recycling.transport
+	}
+	if maxRequests <= 0 && maxLifetime <= 0 {
+		c.httpClient.Transport = transport
+		return
+	}
+	c.httpClient.Transport = &recyclingTransport{
+		transport:   transport,
+		maxRequests: maxRequests,
+		maxLifetime: maxLifetime,
+		since:       time.Now(),
+	}
+}
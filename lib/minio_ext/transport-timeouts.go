@@ -0,0 +1,160 @@
+package minio_ext
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeouts - limits of the phases of a request, independent of the
+// deadline of its context. Zero keeps the default of the
+// DefaultTransport: 30 seconds to connect, 10 seconds for the TLS
+// handshake, no limit for the response headers and no stall detection.
+type Timeouts struct {
+	Connect        time.Duration
+	TLSHandshake   time.Duration
+	ResponseHeader time.Duration
+
+	// Longest time the request body, while it is sent, or the response
+	// body, while it is read, may make no progress. A stalled request
+	// fails with a retryable error instead of blocking its worker.
+	IdleBody time.Duration
+}
+
+// stallError - a body made no progress for the idle body timeout.
+type stallError struct {
+	timeout time.Duration
+}
+
+// Error - implements error.
+func (e stallError) Error() string {
+	return fmt.Sprintf("body made no progress for %s", e.timeout)
+}
+
+// Timeout - implements net.Error.
+func (e stallError) Timeout() bool {
+	return true
+}
+
+// Temporary - implements net.Error.
+func (e stallError) Temporary() bool {
+	return true
+}
+
+// stallTransport - cancels requests whose body makes no progress for
+// timeout. Waiting for the response headers is not watched, that is
+// the response header timeout of the wrapped transport.
+type stallTransport struct {
+	transport http.RoundTripper
+	timeout   time.Duration
+}
+
+// RoundTrip - implements http.RoundTripper.
+func (t *stallTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	watch := &stallWatch{timeout: t.timeout, cancel: cancel}
+	req = req.WithContext(ctx)
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &stallBody{ReadCloser: req.Body, watch: watch}
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		stalled := watch.finish()
+		cancel()
+		if stalled {
+			return nil, stallError{t.timeout}
+		}
+		return nil, err
+	}
+	watch.finish()
+	resp.Body = &stallBody{ReadCloser: resp.Body, watch: watch, response: true}
+	return resp, nil
+}
+
+// CloseIdleConnections - closes idle connections of the wrapped transport.
+func (t *stallTransport) CloseIdleConnections() {
+	if closer, ok := t.transport.(idleConnectionsCloser); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// stallWatch - the stall timer of a request.
+type stallWatch struct {
+	timeout time.Duration
+	cancel  context.CancelFunc
+
+	// mutex protects timer and stalled.
+	mutex   sync.Mutex
+	timer   *time.Timer
+	stalled bool
+}
+
+// start - starts the timer or restarts it on progress.
+func (w *stallWatch) start() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.stalled {
+		return
+	}
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.timeout, w.fire)
+		return
+	}
+	w.timer.Reset(w.timeout)
+}
+
+// finish - stops the timer, returns whether the body stalled.
+func (w *stallWatch) finish() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	return w.stalled
+}
+
+// fire - cancels the request of a stalled body.
+func (w *stallWatch) fire() {
+	w.mutex.Lock()
+	w.stalled = true
+	w.mutex.Unlock()
+	w.cancel()
+}
+
+// stallBody - a request or response body watched by the stall timer.
+// The request body is watched while the transport sends it, between its
+// reads, the response body while it is read.
+type stallBody struct {
+	io.ReadCloser
+	watch    *stallWatch
+	response bool
+}
+
+// Read - implements io.Reader.
+func (b *stallBody) Read(p []byte) (int, error) {
+	b.watch.start()
+	n, err := b.ReadCloser.Read(p)
+	if b.response || err != nil {
+		if b.watch.finish() {
+			return n, stallError{b.watch.timeout}
+		}
+	}
+	if err == io.EOF && b.response {
+		b.watch.cancel()
+	}
+	return n, err
+}
+
+// Close - implements io.Closer, closing the response body releases
+// the request.
+func (b *stallBody) Close() error {
+	b.watch.finish()
+	if b.response {
+		b.watch.cancel()
+	}
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,427 @@
+package minio_ext
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// FileStatus - status of a file in an UploadQueue.
+type FileStatus string
+
+// Different file states in an UploadQueue.
+const (
+	FilePending   FileStatus = "pending"
+	FileUploading FileStatus = "uploading"
+	FilePaused    FileStatus = "paused"
+	FileDone      FileStatus = "done"
+	FileFailed    FileStatus = "failed"
+)
+
+// QueueItem - a file in an UploadQueue. Items are identified by bucket
+// and object name, the same key the state store uses.
+type QueueItem struct {
+	ID         string     `json:"id"`
+	FilePath   string     `json:"filePath"`
+	BucketName string     `json:"bucketName"`
+	ObjectName string     `json:"objectName"`
+	Status     FileStatus `json:"status"`
+
+	Size     int64  `json:"size"`
+	Uploaded int64  `json:"uploaded"`
+	ETag     string `json:"etag,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// QueueOptions - options for NewUploadQueue.
+type QueueOptions struct {
+	// Number of files uploaded at the same time, defaults to 2.
+	MaxActive int
+
+	// Options for every file upload.
+	Upload UploadOptions
+
+	// Optional store to resume interrupted file uploads.
+	States StateStore
+
+	// Optional file the queue is persisted to, a queue created with an
+	// existing file continues where the previous one stopped.
+	File string
+
+	// Optional callback invoked with the new state of an item whenever
+	// its status or progress changes.
+	OnChange func(QueueItem)
+}
+
+// defaultQueueMaxActive - default number of files uploaded at once.
+const defaultQueueMaxActive = 2
+
+// UploadQueue - uploads many files in order, at most MaxActive at the
+// same time. Files can be paused, resumed and removed while the queue
+// is running.
+type UploadQueue struct {
+	client *Client
+	opts   QueueOptions
+
+	// uploads tracks the running uploads.
+	uploads sync.WaitGroup
+
+	// mutex protects all fields below.
+	mutex    sync.Mutex
+	items    []*QueueItem
+	cancels  map[string]context.CancelFunc
+	sessions map[string]*UploadSession
+	active   int
+	shutdown bool
+
+	// wake signals Run that an upload finished or items changed.
+	wake chan struct{}
+}
+
+// NewUploadQueue - returns a queue uploading with c, loading the queue
+// file when it exists. Files which were uploading when the queue was
+// persisted are pending again.
+func NewUploadQueue(c *Client, opts QueueOptions) (*UploadQueue, error) {
+	if opts.MaxActive <= 0 {
+		opts.MaxActive = defaultQueueMaxActive
+	}
+	q := &UploadQueue{
+		client:   c,
+		opts:     opts,
+		cancels:  make(map[string]context.CancelFunc),
+		sessions: make(map[string]*UploadSession),
+		wake:     make(chan struct{}, 1),
+	}
+	if opts.File == "" {
+		return q, nil
+	}
+	data, err := ioutil.ReadFile(opts.File)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return q, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &q.items); err != nil {
+		return nil, err
+	}
+	for _, item := range q.items {
+		if item.Status == FileUploading {
+			item.Status = FilePending
+		}
+	}
+	return q, nil
+}
+
+// persist - writes the queue file, caller must hold the mutex.
+func (q *UploadQueue) persist() error {
+	if q.opts.File == "" {
+		return nil
+	}
+	data, err := json.Marshal(q.items)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(q.opts.File, data)
+}
+
+// find - returns the item with id, caller must hold the mutex.
+func (q *UploadQueue) find(id string) (int, *QueueItem) {
+	for i, item := range q.items {
+		if item.ID == id {
+			return i, item
+		}
+	}
+	return -1, nil
+}
+
+// notify - wakes up Run and reports the changed item.
+func (q *UploadQueue) notify(item QueueItem) {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	if q.opts.OnChange != nil {
+		q.opts.OnChange(item)
+	}
+}
+
+// Add - appends the local file filePath to the queue, it is uploaded
+// to bucketName/objectName. Returns the id of the new item.
+func (q *UploadQueue) Add(filePath, bucketName, objectName string) (string, error) {
+	st, err := os.Stat(filePath)
+	if err != nil {
+		return "", err
+	}
+	if !st.Mode().IsRegular() {
+		return "", ErrInvalidArgument("‘" + filePath + "’ is not a regular file.")
+	}
+	item := &QueueItem{
+		ID:         bucketName + "/" + objectName,
+		FilePath:   filePath,
+		BucketName: bucketName,
+		ObjectName: objectName,
+		Status:     FilePending,
+		Size:       st.Size(),
+	}
+
+	q.mutex.Lock()
+	if _, existing := q.find(item.ID); existing != nil {
+		q.mutex.Unlock()
+		return "", ErrInvalidArgument("‘" + item.ID + "’ is already queued.")
+	}
+	q.items = append(q.items, item)
+	err = q.persist()
+	snapshot := *item
+	q.mutex.Unlock()
+
+	q.notify(snapshot)
+	return item.ID, err
+}
+
+// Items - returns all items in queue order.
+func (q *UploadQueue) Items() []QueueItem {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	items := make([]QueueItem, 0, len(q.items))
+	for _, item := range q.items {
+		items = append(items, *item)
+	}
+	return items
+}
+
+// Item - returns the item with id.
+func (q *UploadQueue) Item(id string) (QueueItem, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if _, item := q.find(id); item != nil {
+		return *item, true
+	}
+	return QueueItem{}, false
+}
+
+// setStatus - changes the status of item id when it is in one of from.
+func (q *UploadQueue) setStatus(id string, status FileStatus, from ...FileStatus) error {
+	q.mutex.Lock()
+	_, item := q.find(id)
+	if item == nil {
+		q.mutex.Unlock()
+		return ErrInvalidArgument("‘" + id + "’ is not queued.")
+	}
+	allowed := false
+	for _, s := range from {
+		allowed = allowed || item.Status == s
+	}
+	if !allowed {
+		q.mutex.Unlock()
+		return ErrInvalidArgument("‘" + id + "’ is " + string(item.Status) + ".")
+	}
+	if cancel, ok := q.cancels[id]; ok {
+		// Stops the upload, its state stays in the state store.
+		cancel()
+	}
+	item.Status = status
+	item.Error = ""
+	err := q.persist()
+	snapshot := *item
+	q.mutex.Unlock()
+
+	q.notify(snapshot)
+	return err
+}
+
+// Pause - pauses a pending or uploading file, parts in flight are
+// interrupted.
+func (q *UploadQueue) Pause(id string) error {
+	return q.setStatus(id, FilePaused, FilePending, FileUploading)
+}
+
+// Resume - queues a paused or failed file again.
+func (q *UploadQueue) Resume(id string) error {
+	return q.setStatus(id, FilePending, FilePaused, FileFailed)
+}
+
+// Remove - removes a file from the queue, its upload is interrupted
+// when running.
+func (q *UploadQueue) Remove(id string) error {
+	q.mutex.Lock()
+	i, item := q.find(id)
+	if item == nil {
+		q.mutex.Unlock()
+		return ErrInvalidArgument("‘" + id + "’ is not queued.")
+	}
+	if cancel, ok := q.cancels[id]; ok {
+		cancel()
+	}
+	q.items = append(q.items[:i], q.items[i+1:]...)
+	err := q.persist()
+	q.mutex.Unlock()
+	return err
+}
+
+// Run - uploads pending files until ctx is done or the queue is shut
+// down, files added while running are picked up. Interrupted uploads
+// are pending again and resume when Run is called again. Returns the
+// error of ctx, nil after Shutdown.
+func (q *UploadQueue) Run(ctx context.Context) error {
+	defer q.uploads.Wait()
+	for {
+		q.mutex.Lock()
+		if q.shutdown {
+			q.mutex.Unlock()
+			return nil
+		}
+		var started []QueueItem
+		for _, item := range q.items {
+			if q.active >= q.opts.MaxActive || ctx.Err() != nil {
+				break
+			}
+			if _, running := q.cancels[item.ID]; running || item.Status != FilePending {
+				// A paused upload may still be winding down.
+				continue
+			}
+			itemCtx, cancel := context.WithCancel(ctx)
+			q.cancels[item.ID] = cancel
+			q.active++
+			item.Status = FileUploading
+			item.Uploaded = 0
+			started = append(started, *item)
+
+			q.uploads.Add(1)
+			go func(item *QueueItem) {
+				defer q.uploads.Done()
+				defer cancel()
+				q.upload(itemCtx, item)
+			}(item)
+		}
+		if len(started) > 0 {
+			q.persist()
+		}
+		q.mutex.Unlock()
+
+		for _, item := range started {
+			q.notify(item)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-q.wake:
+		}
+	}
+}
+
+// upload - uploads a single item and records the outcome.
+func (q *UploadQueue) upload(ctx context.Context, item *QueueItem) {
+	opts := q.opts.Upload
+	opts.Progress = func(n int64) {
+		q.mutex.Lock()
+		item.Uploaded += n
+		snapshot := *item
+		q.mutex.Unlock()
+
+		if q.opts.OnChange != nil {
+			q.opts.OnChange(snapshot)
+		}
+		if q.opts.Upload.Progress != nil {
+			q.opts.Upload.Progress(n)
+		}
+	}
+	opts.Started = func(session *UploadSession) {
+		q.mutex.Lock()
+		if q.shutdown {
+			session.drain()
+		}
+		q.sessions[item.ID] = session
+		q.mutex.Unlock()
+
+		if q.opts.Upload.Started != nil {
+			q.opts.Upload.Started(session)
+		}
+	}
+	etag, err := q.client.UploadFile(ctx, item.FilePath, item.BucketName, item.ObjectName, q.opts.States, opts)
+
+	q.mutex.Lock()
+	delete(q.cancels, item.ID)
+	delete(q.sessions, item.ID)
+	q.active--
+	switch {
+	case item.Status != FileUploading:
+		// Paused, resumed or removed meanwhile.
+	case err == nil:
+		item.Status = FileDone
+		item.ETag = etag
+	case ctx.Err() != nil || q.shutdown:
+		// The queue stopped, resume on the next Run.
+		item.Status = FilePending
+	default:
+		item.Status = FileFailed
+		item.Error = err.Error()
+	}
+	q.persist()
+	snapshot := *item
+	q.mutex.Unlock()
+
+	q.notify(snapshot)
+}
+
+// Shutdown - stops the queue from starting uploads and shuts down the
+// running sessions, waiting until their parts in flight are uploaded.
+// When ctx is done first the parts in flight are interrupted. The
+// interrupted files are pending again with their session state in the
+// state store, the persisted queue is returned and continues where it
+// stopped when a queue is created from the same file.
+func (q *UploadQueue) Shutdown(ctx context.Context) ([]QueueItem, error) {
+	q.mutex.Lock()
+	q.shutdown = true
+	sessions := make([]*UploadSession, 0, len(q.sessions))
+	for _, session := range q.sessions {
+		sessions = append(sessions, session)
+	}
+	q.mutex.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	var wg sync.WaitGroup
+	for _, session := range sessions {
+		wg.Add(1)
+		go func(session *UploadSession) {
+			defer wg.Done()
+			session.Shutdown(ctx)
+		}(session)
+	}
+	wg.Wait()
+
+	// Uploads which had no session yet stop as soon as it is created.
+	done := make(chan struct{})
+	go func() {
+		q.uploads.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		q.mutex.Lock()
+		for _, cancel := range q.cancels {
+			cancel()
+		}
+		q.mutex.Unlock()
+		<-done
+		err = ctx.Err()
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if persistErr := q.persist(); err == nil {
+		err = persistErr
+	}
+	items := make([]QueueItem, 0, len(q.items))
+	for _, item := range q.items {
+		items = append(items, *item)
+	}
+	return items, err
+}
@@ -0,0 +1,169 @@
+package minio_ext
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUploadQueueSetStatus(t *testing.T) {
+	testCases := []struct {
+		status     FileStatus
+		pause      bool
+		shouldPass bool
+		expected   FileStatus
+	}{
+		{FilePending, true, true, FilePaused},
+		{FileUploading, true, true, FilePaused},
+		{FilePaused, true, false, FilePaused},
+		{FileDone, true, false, FileDone},
+		{FileFailed, true, false, FileFailed},
+		{FilePaused, false, true, FilePending},
+		{FileFailed, false, true, FilePending},
+		{FilePending, false, false, FilePending},
+		{FileDone, false, false, FileDone},
+	}
+	for i, testCase := range testCases {
+		q, err := NewUploadQueue(nil, QueueOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.items = []*QueueItem{{ID: "bucket/object", Status: testCase.status, Error: "failed"}}
+		if testCase.pause {
+			err = q.Pause("bucket/object")
+		} else {
+			err = q.Resume("bucket/object")
+		}
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: expected to pass, failed with %v", i+1, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: expected to fail, passed", i+1)
+		}
+		if item, _ := q.Item("bucket/object"); item.Status != testCase.expected {
+			t.Errorf("Test %d: expected status %s, got %s", i+1, testCase.expected, item.Status)
+		}
+	}
+	q, err := NewUploadQueue(nil, QueueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Pause("bucket/object"); ToErrorResponse(err).Code != "InvalidArgument" {
+		t.Errorf("Expected InvalidArgument for an unknown item, got %v", err)
+	}
+}
+
+func TestUploadQueueRun(t *testing.T) {
+	const size = 2 * absMinPartSize
+	server := newMultipartServer()
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	c := newTestClient(t, ts.URL)
+	dir, err := ioutil.TempDir("", "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Statuses every item went through and a signal once the uploaded
+	// and the failed item are finished.
+	var mutex sync.Mutex
+	statuses := make(map[string][]FileStatus)
+	finished := make(chan struct{}, 2)
+	onChange := func(item QueueItem) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		seen := statuses[item.ID]
+		if len(seen) > 0 && seen[len(seen)-1] == item.Status {
+			return
+		}
+		statuses[item.ID] = append(seen, item.Status)
+		if item.Status == FileDone || item.Status == FileFailed {
+			finished <- struct{}{}
+		}
+	}
+	opts := QueueOptions{
+		MaxActive: 1,
+		Upload:    UploadOptions{PartSize: absMinPartSize, PartRetry: PartRetryPolicy{MaxAttempts: 1}},
+		File:      filepath.Join(dir, "queue.json"),
+		OnChange:  onChange,
+	}
+	q, err := NewUploadQueue(c, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"uploaded", "missing", "paused"} {
+		filePath := filepath.Join(dir, name)
+		if err = ioutil.WriteFile(filePath, make([]byte, size), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = q.Add(filePath, "bucket", name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = q.Add(filepath.Join(dir, "uploaded"), "bucket", "uploaded"); ToErrorResponse(err).Code != "InvalidArgument" {
+		t.Errorf("Expected InvalidArgument for a queued item, got %v", err)
+	}
+	if err = os.Remove(filepath.Join(dir, "missing")); err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Pause("bucket/paused"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- q.Run(ctx) }()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-finished:
+		case <-time.After(10 * time.Second):
+			t.Fatal("Expected the uploads to finish")
+		}
+	}
+	cancel()
+	if err = <-done; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	expected := map[string][]FileStatus{
+		"bucket/uploaded": {FilePending, FileUploading, FileDone},
+		"bucket/missing":  {FilePending, FileUploading, FileFailed},
+		"bucket/paused":   {FilePending, FilePaused},
+	}
+	mutex.Lock()
+	for id, sequence := range expected {
+		if !reflect.DeepEqual(statuses[id], sequence) {
+			t.Errorf("Expected %s to go through %v, got %v", id, sequence, statuses[id])
+		}
+	}
+	mutex.Unlock()
+	if obj, ok := server.object("/bucket/uploaded"); !ok || obj.size != size {
+		t.Errorf("Expected the object uploaded, got %+v", obj)
+	}
+
+	// The queue file keeps order and statuses.
+	opts.OnChange = nil
+	q, err = NewUploadQueue(c, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := q.Items()
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %+v", items)
+	}
+	for i, status := range []FileStatus{FileDone, FileFailed, FilePaused} {
+		if items[i].Status != status {
+			t.Errorf("Expected item %d %s, got %+v", i+1, status, items[i])
+		}
+	}
+	if items[0].Uploaded != size || items[0].ETag == "" || items[1].Error == "" {
+		t.Errorf("Unexpected items %+v", items)
+	}
+}
@@ -0,0 +1,127 @@
+package minio_ext
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// UploadStats - statistics of an UploadSession for capacity planning,
+// durations are encoded in nanoseconds in JSON.
+type UploadStats struct {
+	BucketName string `json:"bucketName"`
+	ObjectName string `json:"objectName"`
+	UploadID   string `json:"uploadId"`
+
+	// Size of the source and part data sent by this session, including
+	// attempts which failed.
+	TotalBytes       int64 `json:"totalBytes"`
+	TransferredBytes int64 `json:"transferredBytes"`
+
+	// Parts uploaded by this session and part attempts which had to be
+	// repeated.
+	Parts   int `json:"parts"`
+	Retries int `json:"retries"`
+
+	// Time spent in Upload, summed over all calls.
+	WallTime time.Duration `json:"wallTime"`
+
+	// Latencies of the successful part uploads.
+	MeanPartLatency time.Duration `json:"meanPartLatency"`
+	P95PartLatency  time.Duration `json:"p95PartLatency"`
+
+	// Transferred bytes per second of wall time.
+	Throughput float64 `json:"throughput"`
+}
+
+// sessionStats - statistics collected while a session uploads.
+type sessionStats struct {
+	mutex sync.Mutex
+
+	// wallTime sums up finished Upload calls, started is set while
+	// Upload runs.
+	wallTime time.Duration
+	started  time.Time
+
+	latencies []time.Duration
+	retries   int
+}
+
+// begin - marks the start of an Upload call.
+func (st *sessionStats) begin() {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	st.started = time.Now()
+}
+
+// end - marks the end of an Upload call.
+func (st *sessionStats) end() {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	st.wallTime += time.Since(st.started)
+	st.started = time.Time{}
+}
+
+// part - records a part upload which took attempts, latency is the
+// duration of the successful attempt, zero when all attempts failed.
+func (st *sessionStats) part(attempts int, latency time.Duration) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	if attempts > 1 {
+		st.retries += attempts - 1
+	}
+	if latency > 0 {
+		st.latencies = append(st.latencies, latency)
+	}
+}
+
+// Stats - returns the statistics of the session so far.
+func (s *UploadSession) Stats() UploadStats {
+	stats := UploadStats{
+		BucketName:       s.bucketName,
+		ObjectName:       s.objectName,
+		UploadID:         s.UploadID(),
+		TotalBytes:       s.size,
+		TransferredBytes: atomic.LoadInt64(&s.transferred),
+	}
+
+	s.stats.mutex.Lock()
+	stats.WallTime = s.stats.wallTime
+	if !s.stats.started.IsZero() {
+		stats.WallTime += time.Since(s.stats.started)
+	}
+	stats.Retries = s.stats.retries
+	latencies := append([]time.Duration(nil), s.stats.latencies...)
+	s.stats.mutex.Unlock()
+
+	stats.Parts = len(latencies)
+	if stats.Parts > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		var sum time.Duration
+		for _, latency := range latencies {
+			sum += latency
+		}
+		stats.MeanPartLatency = sum / time.Duration(stats.Parts)
+		stats.P95PartLatency = latencies[(stats.Parts*95+99)/100-1]
+	}
+	if stats.WallTime > 0 {
+		stats.Throughput = float64(stats.TransferredBytes) / stats.WallTime.Seconds()
+	}
+	return stats
+}
+
+// summarize - writes the statistics of the session to the summary
+// writer as one line of JSON. Write errors are ignored, the upload is
+// complete at this point.
+func (s *UploadSession) summarize() {
+	if s.opts.Summary == nil {
+		return
+	}
+	data, err := json.Marshal(s.Stats())
+	if err != nil {
+		return
+	}
+	s.opts.Summary.Write(append(data, '\n'))
+}
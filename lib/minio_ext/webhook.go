@@ -0,0 +1,269 @@
+package minio_ext
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers of completion webhook requests.
+const (
+	WebhookEventIDHeader   = "X-Upload-Event-Id"
+	WebhookTimestampHeader = "X-Upload-Timestamp"
+	WebhookSignatureHeader = "X-Upload-Signature"
+)
+
+// CompletedEventName - event name of completed multipart uploads.
+const CompletedEventName = "s3:ObjectCreated:CompleteMultipartUpload"
+
+// PutEventName - event name of objects uploaded in a single PUT.
+const PutEventName = "s3:ObjectCreated:Put"
+
+// CompletionEvent - JSON body posted to the webhooks after a multipart
+// upload completed or an object was uploaded in a single PUT, which
+// has no upload id.
+type CompletionEvent struct {
+	EventName   string            `json:"eventName"`
+	Bucket      string            `json:"bucket"`
+	Key         string            `json:"key"`
+	UploadID    string            `json:"uploadId,omitempty"`
+	Size        int64             `json:"size"`
+	ETag        string            `json:"etag"`
+	ContentType string            `json:"contentType,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Time        time.Time         `json:"time"`
+}
+
+// CompletionWebhook - delivery of completion events to downstream
+// systems, such as search indexers. Every event is posted to all URLs
+// and redelivered with exponential backoff until the URL answers 2xx,
+// it answers another 4xx than 408 and 429 or MaxRetry attempts failed.
+//
+// With Secret set each request carries the hex HMAC-SHA256 of the
+// timestamp header, a dot and the body, keyed with Secret, in
+// the signature header as "sha256=<hex>". Receivers check it with
+// VerifyWebhookSignature. The event id header holds the upload id and
+// stays the same across redeliveries.
+type CompletionWebhook struct {
+	URLs   []string
+	Secret []byte
+
+	// Attempts per URL, 8 by default.
+	MaxRetry int
+	// Backoff unit and cap, DefaultRetryUnit and 5 minutes by default.
+	RetryUnit time.Duration
+	RetryCap  time.Duration
+	// Timeout of one attempt, 10 seconds by default.
+	Timeout time.Duration
+
+	// HTTPClient posts the events, http.DefaultClient by default.
+	HTTPClient *http.Client
+
+	// OnError is called for every URL the event could not be delivered
+	// to after the last attempt, optional.
+	OnError func(url string, event CompletionEvent, err error)
+}
+
+// webhookNotifier - the webhook of a Client, shared by all copies of
+// the client.
+type webhookNotifier struct {
+	// mutex protects hook.
+	mutex sync.Mutex
+	hook  *CompletionWebhook
+
+	// pending counts the deliveries in flight.
+	pending sync.WaitGroup
+}
+
+// newWebhookNotifier - returns a notifier without webhook.
+func newWebhookNotifier() *webhookNotifier {
+	return &webhookNotifier{}
+}
+
+// SetCompletionWebhook - posts a CompletionEvent to the URLs of hook
+// after every multipart upload completed through the client, by
+// CompleteMultipartUpload, the upload sessions or the upload server,
+// and after every object uploaded by PutObject or the uploaders in a
+// single PUT.
+// Events are delivered in the background, a nil hook stops
+// notifications.
+func (c *Client) SetCompletionWebhook(hook *CompletionWebhook) {
+	if hook != nil {
+		copied := *hook
+		if copied.MaxRetry <= 0 {
+			copied.MaxRetry = 8
+		}
+		if copied.RetryUnit <= 0 {
+			copied.RetryUnit = DefaultRetryUnit
+		}
+		if copied.RetryCap <= 0 {
+			copied.RetryCap = 5 * time.Minute
+		}
+		if copied.Timeout <= 0 {
+			copied.Timeout = 10 * time.Second
+		}
+		if copied.HTTPClient == nil {
+			copied.HTTPClient = http.DefaultClient
+		}
+		hook = &copied
+	}
+	c.webhooks.mutex.Lock()
+	c.webhooks.hook = hook
+	c.webhooks.mutex.Unlock()
+}
+
+// WaitCompletionWebhooks - waits until the pending webhook deliveries
+// succeeded or gave up, call it before exiting.
+func (c *Client) WaitCompletionWebhooks() {
+	c.webhooks.pending.Wait()
+}
+
+// NotifyCompleted - delivers the completion event of the object
+// uploaded by uploadID, empty for a single PUT, with etag to the
+// completion webhook, if one is set. For uploads completed with a
+// context of WithoutCompletionWebhook.
+func (c Client) NotifyCompleted(bucketName, objectName, uploadID, etag string) {
+	c.notifyCompleted(context.Background(), bucketName, objectName, uploadID, etag)
+}
+
+// notifyCompleted - delivers the completion event of uploadID, empty
+// for a single PUT, in the background if a webhook is set and ctx does
+// not opt out of it. Size, content type and metadata are read with a
+// HEAD request on the object.
+func (c Client) notifyCompleted(ctx context.Context, bucketName, objectName, uploadID, etag string) {
+	if requestOptionsFrom(ctx).withoutWebhook {
+		return
+	}
+	c.webhooks.mutex.Lock()
+	hook := c.webhooks.hook
+	c.webhooks.mutex.Unlock()
+	if hook == nil || len(hook.URLs) == 0 {
+		return
+	}
+
+	eventName, eventID := CompletedEventName, uploadID
+	if uploadID == "" {
+		eventName, eventID = PutEventName, bucketName+"/"+objectName+"@"+trimEtag(etag)
+	}
+	event := CompletionEvent{
+		EventName: eventName,
+		Bucket:    bucketName,
+		Key:       objectName,
+		UploadID:  uploadID,
+		Size:      -1,
+		ETag:      trimEtag(etag),
+		Time:      time.Now().UTC(),
+	}
+	c.webhooks.pending.Add(1)
+	go func() {
+		defer c.webhooks.pending.Done()
+		// The event goes out without size and metadata if the object
+		// can not be read, it is there nevertheless.
+		if info, err := c.statObject(context.Background(), bucketName, objectName, nil); err == nil {
+			event.Size = info.Size
+			event.ContentType = info.ContentType
+			event.Metadata = info.UserMetadata
+		}
+		body, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, url := range hook.URLs {
+			wg.Add(1)
+			go func(url string) {
+				defer wg.Done()
+				if err := c.deliverWebhook(hook, url, eventID, body); err != nil && hook.OnError != nil {
+					hook.OnError(url, event, err)
+				}
+			}(url)
+		}
+		wg.Wait()
+	}()
+}
+
+// deliverWebhook - posts body to url until it is accepted, is rejected
+// or the attempts are exhausted.
+func (c Client) deliverWebhook(hook *CompletionWebhook, url, eventID string, body []byte) error {
+	var err error
+	// Deliveries run in the background, nothing cancels them.
+	for timer := newRetryTimer(hook.MaxRetry, hook.RetryUnit, hook.RetryCap, nil); timer.next(context.Background()); {
+		var retryable bool
+		if retryable, err = postWebhook(hook, url, eventID, body); err == nil || !retryable {
+			return err
+		}
+	}
+	return err
+}
+
+// postWebhook - posts body to url once, the boolean reports whether a
+// failure may be retried.
+func postWebhook(hook *CompletionWebhook, url, eventID string, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), hook.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventIDHeader, eventID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	if len(hook.Secret) > 0 {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+webhookSignature(hook.Secret, timestamp, body))
+	}
+
+	resp, err := hook.HTTPClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook %s answered %s", url, resp.Status)
+	retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retryable, err
+}
+
+// webhookSignature - returns the hex HMAC-SHA256 of timestamp and body.
+func webhookSignature(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature - reports whether signature, the value of the
+// signature header, signs timestamp and body with secret and timestamp
+// is at most tolerance away from now, a zero tolerance skips the age
+// check.
+func VerifyWebhookSignature(secret []byte, timestamp string, body []byte, signature string, tolerance time.Duration) bool {
+	if tolerance > 0 {
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return false
+		}
+		age := time.Since(time.Unix(seconds, 0))
+		if age > tolerance || age < -tolerance {
+			return false
+		}
+	}
+	expected := "sha256=" + webhookSignature(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
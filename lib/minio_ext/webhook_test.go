@@ -0,0 +1,150 @@
+package minio_ext
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"eventName":"s3:ObjectCreated:CompleteMultipartUpload"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	signature := func(timestamp string, body []byte) string {
+		return "sha256=" + webhookSignature(secret, timestamp, body)
+	}
+	testCases := []struct {
+		secret    []byte
+		timestamp string
+		body      []byte
+		signature string
+		tolerance time.Duration
+		valid     bool
+	}{
+		{secret, now, body, signature(now, body), time.Minute, true},
+		{secret, old, body, signature(old, body), 0, true},
+		{secret, old, body, signature(old, body), time.Minute, false},
+		{secret, "yesterday", body, signature("yesterday", body), time.Minute, false},
+		{[]byte("other"), now, body, signature(now, body), time.Minute, false},
+		{secret, now, []byte("{}"), signature(now, body), time.Minute, false},
+		{secret, now, body, signature(old, body), 0, false},
+		{secret, now, body, webhookSignature(secret, now, body), time.Minute, false},
+	}
+	for i, testCase := range testCases {
+		valid := VerifyWebhookSignature(testCase.secret, testCase.timestamp, testCase.body, testCase.signature, testCase.tolerance)
+		if valid != testCase.valid {
+			t.Errorf("Test %d: expected valid %v, got %v", i+1, testCase.valid, valid)
+		}
+	}
+}
+
+// webhookReceiver - webhook endpoint answering the statuses in turn,
+// the last one repeatedly.
+type webhookReceiver struct {
+	mutex    sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+// ServeHTTP - records the request and answers the next status.
+func (wr *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	wr.mutex.Lock()
+	defer wr.mutex.Unlock()
+	wr.requests = append(wr.requests, r)
+	wr.bodies = append(wr.bodies, body)
+	status := wr.statuses[0]
+	if len(wr.statuses) > 1 {
+		wr.statuses = wr.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestCompletionWebhook(t *testing.T) {
+	server := newMultipartServer()
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	c := newTestClient(t, ts.URL)
+
+	testCases := []struct {
+		name     string
+		statuses []int
+		attempts int
+		failed   bool
+	}{
+		{"accepted", []int{http.StatusNoContent}, 1, false},
+		{"redelivered", []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}, 3, false},
+		{"rejected", []int{http.StatusBadRequest}, 1, true},
+		{"exhausted", []int{http.StatusInternalServerError}, 4, true},
+	}
+	secret := []byte("secret")
+	receivers := make(map[string]*webhookReceiver)
+	hook := &CompletionWebhook{Secret: secret, MaxRetry: 4, RetryUnit: time.Millisecond, RetryCap: 5 * time.Millisecond}
+	for _, testCase := range testCases {
+		receiver := &webhookReceiver{statuses: testCase.statuses}
+		rs := httptest.NewServer(receiver)
+		defer rs.Close()
+		receivers[rs.URL] = receiver
+		hook.URLs = append(hook.URLs, rs.URL)
+	}
+	var mutex sync.Mutex
+	failed := make(map[string]bool)
+	hook.OnError = func(url string, event CompletionEvent, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		failed[url] = true
+	}
+	c.SetCompletionWebhook(hook)
+
+	uploadID := server.addUpload(map[int]ObjectPart{1: {PartNumber: 1, ETag: "etag", Size: 10}})
+	etag, err := c.CompleteMultipartUpload("bucket", "object", uploadID, []CompletePart{{PartNumber: 1, ETag: "etag"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.WaitCompletionWebhooks()
+
+	for i, testCase := range testCases {
+		url := hook.URLs[i]
+		receiver := receivers[url]
+		t.Run(testCase.name, func(t *testing.T) {
+			if len(receiver.requests) != testCase.attempts || failed[url] != testCase.failed {
+				t.Fatalf("Expected %d attempts failed %v, got %d failed %v", testCase.attempts, testCase.failed, len(receiver.requests), failed[url])
+			}
+			for j, r := range receiver.requests {
+				timestamp := r.Header.Get(WebhookTimestampHeader)
+				if !VerifyWebhookSignature(secret, timestamp, receiver.bodies[j], r.Header.Get(WebhookSignatureHeader), time.Minute) {
+					t.Errorf("Attempt %d: invalid signature", j+1)
+				}
+				if r.Header.Get(WebhookEventIDHeader) != uploadID {
+					t.Errorf("Attempt %d: unexpected event id %q", j+1, r.Header.Get(WebhookEventIDHeader))
+				}
+			}
+			var event CompletionEvent
+			if err := json.Unmarshal(receiver.bodies[0], &event); err != nil {
+				t.Fatal(err)
+			}
+			if event.EventName != CompletedEventName || event.Bucket != "bucket" || event.Key != "object" ||
+				event.UploadID != uploadID || event.Size != 10 || event.ETag != trimEtag(etag) {
+				t.Errorf("Unexpected event %+v", event)
+			}
+		})
+	}
+
+	// Without webhook nothing is posted.
+	c.SetCompletionWebhook(nil)
+	uploadID = server.addUpload(map[int]ObjectPart{1: {PartNumber: 1, ETag: "etag", Size: 10}})
+	if _, err = c.CompleteMultipartUpload("bucket", "other", uploadID, []CompletePart{{PartNumber: 1, ETag: "etag"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	c.WaitCompletionWebhooks()
+	if n := len(receivers[hook.URLs[0]].requests); n != 1 {
+		t.Errorf("Expected no event posted, got %d requests", n)
+	}
+}
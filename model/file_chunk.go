@@ -9,6 +9,7 @@ import (
 const (
 	FileNotUploaded int = iota
 	FileUploaded
+	FileUploadExpired
 )
 
 type FileChunk struct {
@@ -17,7 +18,7 @@ type FileChunk struct {
 
 	UUID          string `gorm:"UNIQUE"`
 	Md5			  string `gorm:"UNIQUE"`
-	IsUploaded    int `gorm:"DEFAULT 0"`  // not uploaded: 0, uploaded: 1
+	IsUploaded    int `gorm:"DEFAULT 0"`  // not uploaded: 0, uploaded: 1, upload id expired: 2
 	UploadID   	  string	`gorm:"UNIQUE"`//minio upload id
 	TotalChunks   int
 	Size		  int64
@@ -67,3 +68,17 @@ func UpdateFileChunk(fileChunk *FileChunk) error {
 	}
 	return nil
 }
+
+// RestartFileChunk records a new upload id for the given fileChunk and
+// forgets all parts of the previous upload
+func RestartFileChunk(fileChunk *FileChunk) error {
+	if err := mysql.Global.DB.Model(&fileChunk).Where("uuid = ?", fileChunk.UUID).
+		Updates(map[string]interface{}{
+			"upload_id":       fileChunk.UploadID,
+			"is_uploaded":     FileNotUploaded,
+			"completed_parts": "",
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -62,7 +62,7 @@ func getClients()(*minio.Client, *miniov6.Core, *minio_ext.Client, error){
 	client2 = coreClient
 
 	if nil == minioClientExt{
-		minioClientExt, err = minio_ext.New(aliasedURL, accessKeyID, secretAccessKey, secure)
+		minioClientExt, err = minio_ext.New(aliasedURL, minio_ext.WithCredentials(accessKeyID, secretAccessKey), minio_ext.WithSecure(secure))
 	}
 
 	if nil != err{
@@ -69,6 +69,28 @@ func NewMultipart(ctx *gin.Context) {
 		return
 	}
 
+	// every chunk has to fit into a single part
+	if (fileSize + int64(totalChunkCounts) - 1) / int64(totalChunkCounts) > minio_ext.MaxPartSize {
+		ctx.JSON(http.StatusBadRequest, "totalChunkCounts is illegal.")
+		return
+	}
+
+	if fileChunk, err := models.GetFileChunkByMD5(ctx.Query("md5")); err == nil && fileChunk.IsUploaded == models.FileUploadExpired {
+		// the previous upload of the file expired, start it over
+		if err = restartMultipart(fileChunk); err != nil {
+			logger.LOG.Error("restartMultipart failed:", err.Error())
+			ctx.JSON(http.StatusInternalServerError, "restartMultipart failed.")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"uuid": fileChunk.UUID,
+			"uploadID":  fileChunk.UploadID,
+			"requiredHeaders": requiredPartHeaders(),
+		})
+		return
+	}
+
 	uuid = gouuid.NewV4().String()
 	uploadID, err = newMultiPartUpload(uuid)
 	if err != nil {
@@ -95,13 +117,27 @@ func NewMultipart(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{
 		"uuid": uuid,
 		"uploadID":  uploadID,
+		"requiredHeaders": requiredPartHeaders(),
 	})
 }
 
+// requiredPartHeaders returns the headers clients must get signed for
+// every part and send with the part PUT.
+func requiredPartHeaders() []string {
+	headers := []string{}
+	if config.MinioRequireContentMD5 {
+		headers = append(headers, "Content-MD5")
+	}
+	return headers
+}
+
 func GetMultipartUploadUrl(ctx *gin.Context) {
 	var url string
+	var headers http.Header
 	uuid := ctx.Query("uuid")
 	uploadID := ctx.Query("uploadID")
+	// base64 encoded md5sum of the part
+	md5 := ctx.Query("md5")
 
 	partNumber,err := strconv.Atoi(ctx.Query("chunkNumber"))
 	if err != nil {
@@ -114,12 +150,29 @@ func GetMultipartUploadUrl(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, "size is illegal.")
 		return
 	}
-	if size > minio_ext.MinPartSize {
+	if size > minio_ext.MaxPartSize {
 		ctx.JSON(http.StatusBadRequest, "size is illegal.")
 		return
 	}
 
-	url,err = genMultiPartSignedUrl(uuid, uploadID, partNumber, size)
+	fileChunk, err := models.GetFileChunkByUUID(uuid)
+	if err != nil {
+		logger.LOG.Error("GetFileChunkByUUID failed:", err.Error())
+		ctx.JSON(http.StatusInternalServerError, "GetFileChunkByUUID failed.")
+		return
+	}
+	if fileChunk.IsUploaded == models.FileUploadExpired || fileChunk.UploadID != uploadID {
+		ctx.JSON(http.StatusGone, "uploadID is expired.")
+		return
+	}
+
+	if md5 == "" && config.MinioRequireContentMD5 {
+		ctx.JSON(http.StatusBadRequest, "md5 is illegal.")
+		return
+	}
+
+	expires := partUrlExpireTime()
+	url, headers, err = genMultiPartSignedUrl(uuid, uploadID, partNumber, size, md5, expires)
 	if err != nil {
 		logger.LOG.Error("genMultiPartSignedUrl failed:", err.Error())
 		ctx.JSON(http.StatusInternalServerError, "genMultiPartSignedUrl failed.")
@@ -129,6 +182,9 @@ func GetMultipartUploadUrl(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H {
 		"url": url,
+		"headers": headers,
+		// the client re-presigns the part once the url expired
+		"expiresIn": int64(expires / time.Second),
 	})
 }
 
@@ -144,6 +200,14 @@ func CompleteMultipart(ctx *gin.Context) {
 	}
 
 	_, err = completeMultiPartUpload(uuid, uploadID)
+	if isUploadExpired(err) {
+		logger.LOG.Info("the upload has expired:", uploadID)
+		if err = expireMultipart(fileChunk); err != nil {
+			logger.LOG.Error("expireMultipart failed:", err.Error())
+		}
+		ctx.JSON(http.StatusGone, "uploadID is expired.")
+		return
+	}
 	if err != nil {
 		logger.LOG.Error("completeMultiPartUpload failed:", err.Error())
 		ctx.JSON(http.StatusInternalServerError, "completeMultiPartUpload failed.")
@@ -187,6 +251,37 @@ func UpdateMultipart(ctx *gin.Context) {
 	})
 }
 
+// isUploadExpired reports whether err means that the upload id is gone
+// on the server, e.g. aborted by a bucket lifecycle rule
+func isUploadExpired(err error) bool {
+	return minio_ext.IsUploadExpired(err) || miniov6.ToErrorResponse(err).Code == "NoSuchUpload"
+}
+
+// expireMultipart handles an upload id which is gone on the server, it
+// is either restarted or marked expired depending on the configuration
+func expireMultipart(fileChunk *models.FileChunk) error {
+	if config.MinioRestartExpiredUploads {
+		return restartMultipart(fileChunk)
+	}
+
+	fileChunk.IsUploaded = models.FileUploadExpired
+	return models.UpdateFileChunk(fileChunk)
+}
+
+// restartMultipart starts a new upload for fileChunk, all parts of the
+// previous upload are forgotten
+func restartMultipart(fileChunk *models.FileChunk) error {
+	uploadID, err := newMultiPartUpload(fileChunk.UUID)
+	if err != nil {
+		return err
+	}
+
+	fileChunk.UploadID = uploadID
+	fileChunk.IsUploaded = models.FileNotUploaded
+	fileChunk.CompletedParts = ""
+	return models.RestartFileChunk(fileChunk)
+}
+
 func newMultiPartUpload(uuid string) (string, error){
 	_, core, _, err := getClients()
 	if err != nil {
@@ -200,18 +295,31 @@ func newMultiPartUpload(uuid string) (string, error){
 	return core.NewMultipartUpload(bucketName, objectName, miniov6.PutObjectOptions{})
 }
 
-func genMultiPartSignedUrl(uuid string, uploadId string, partNumber int, partSize int64) (string, error) {
+// partUrlExpireTime returns how long presigned part urls stay valid
+func partUrlExpireTime() time.Duration {
+	expires := time.Duration(config.MinioPartUrlExpireSeconds) * time.Second
+	if expires <= 0 || expires > PresignedUploadPartUrlExpireTime {
+		return PresignedUploadPartUrlExpireTime
+	}
+	return expires
+}
+
+func genMultiPartSignedUrl(uuid string, uploadId string, partNumber int, partSize int64, md5 string, expires time.Duration) (string, http.Header, error) {
 	_, _, minioClient, err := getClients()
 	if err != nil {
 		logger.LOG.Error("getClients failed:", err.Error())
-		return "", err
+		return "", nil, err
 	}
 
 	bucketName := config.MinioBucket
 	objectName := strings.TrimPrefix(path.Join(config.MinioBasePath, path.Join(uuid[0:1], uuid[1:2], uuid)), "/")
 
-	return minioClient.GenUploadPartSignedUrl(uploadId, bucketName, objectName, partNumber, partSize, PresignedUploadPartUrlExpireTime, config.MinioLocation)
+	if md5 != "" {
+		return minioClient.GenUploadPartSignedUrlMD5(uploadId, bucketName, objectName, partNumber, partSize, expires, config.MinioLocation, md5)
+	}
 
+	url, err := minioClient.GenUploadPartSignedUrl(uploadId, bucketName, objectName, partNumber, partSize, expires, config.MinioLocation)
+	return url, http.Header{}, err
 }
 
 func completeMultiPartUpload(uuid string, uploadID string) (string, error){
@@ -297,7 +405,26 @@ func GetSuccessChunks(ctx *gin.Context) {
 			break
 		}
 
+		if fileChunk.IsUploaded == models.FileUploadExpired {
+			uuid, uploadID = "", ""
+			break
+		}
+
 		partInfos, err := client.ListObjectParts(bucketName, objectName, uploadID)
+		if isUploadExpired(err) {
+			logger.LOG.Info("the upload has expired:", uploadID)
+			if err = expireMultipart(fileChunk); err != nil {
+				logger.LOG.Error("expireMultipart failed:", err.Error())
+				break
+			}
+			if fileChunk.IsUploaded == models.FileUploadExpired {
+				// the client has to start a new upload
+				uuid, uploadID = "", ""
+				break
+			}
+			uploadID = fileChunk.UploadID
+			break
+		}
 		if err != nil {
 			logger.LOG.Error("ListObjectParts failed:", err.Error())
 			break
@@ -316,6 +443,7 @@ func GetSuccessChunks(ctx *gin.Context) {
 		"uploaded": uploaded,
 		"uploadID": uploadID,
 		"chunks": chunks,
+		"requiredHeaders": requiredPartHeaders(),
 	})
 }
 